@@ -73,3 +73,30 @@ func CatchInfo(err error) {
 func CatchDebug(err error) {
 	std.CatchDebug(err)
 }
+
+// Panic Logging Helpers
+
+// CatchPanic logs the value, typically the result of recover(), at
+// the Emergency level with a stack trace, if the value is not nil,
+// and returns true if the value is not nil.
+func CatchPanic(recovered interface{}) bool {
+	return std.CatchPanic(recovered)
+}
+
+// Recover recovers from a panic and logs the recovered value at the
+// Emergency level with a stack trace. Recover must be deferred
+// directly, as in "defer grip.Recover()", to stop the panic.
+func Recover() {
+	if recovered := recover(); recovered != nil {
+		std.CatchPanic(recovered)
+	}
+}
+
+// RecoverPanic logs a panic as Recover does, and then panics again
+// with the recovered value. RecoverPanic must be deferred directly.
+func RecoverPanic() {
+	if recovered := recover(); recovered != nil {
+		std.CatchPanic(recovered)
+		panic(recovered)
+	}
+}
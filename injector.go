@@ -1,6 +1,10 @@
 package grip
 
-import "github.com/mongodb/grip/send"
+import (
+	"context"
+
+	"github.com/mongodb/grip/send"
+)
 
 // SetSender swaps send.Sender() implementations in a logging
 // instance. Calls the Close() method on the existing instance before
@@ -15,3 +19,11 @@ func SetSender(s send.Sender) error {
 func GetSender() send.Sender {
 	return std.GetSender()
 }
+
+// Flush ensures that all messages sent to the current Journaler's
+// sender have reached the logging backend, by calling the sender's
+// Flush method. Call Flush before the process exits to avoid losing
+// messages held by buffered or asynchronous senders.
+func Flush(ctx context.Context) error {
+	return std.Flush(ctx)
+}
@@ -0,0 +1,8 @@
+package grip
+
+// RunJob runs the function using the package level Journaler
+// instance, logging the start and completion of the job. Panics in
+// the function are recovered and returned as errors.
+func RunJob(name string, fn func() error) error {
+	return std.RunJob(name, fn)
+}
@@ -0,0 +1,41 @@
+/*
+Lazy Logging
+
+The logging methods that end with "Lazy" take a function that
+produces a message.Composer, and only call the function if the
+priority of the message is above the threshold of the logger.
+*/
+package grip
+
+import (
+	"github.com/mongodb/grip/level"
+	"github.com/mongodb/grip/message"
+)
+
+func LogLazy(l level.Priority, fn func() message.Composer) {
+	std.LogLazy(l, fn)
+}
+func EmergencyLazy(fn func() message.Composer) {
+	std.EmergencyLazy(fn)
+}
+func AlertLazy(fn func() message.Composer) {
+	std.AlertLazy(fn)
+}
+func CriticalLazy(fn func() message.Composer) {
+	std.CriticalLazy(fn)
+}
+func ErrorLazy(fn func() message.Composer) {
+	std.ErrorLazy(fn)
+}
+func WarningLazy(fn func() message.Composer) {
+	std.WarningLazy(fn)
+}
+func NoticeLazy(fn func() message.Composer) {
+	std.NoticeLazy(fn)
+}
+func InfoLazy(fn func() message.Composer) {
+	std.InfoLazy(fn)
+}
+func DebugLazy(fn func() message.Composer) {
+	std.DebugLazy(fn)
+}
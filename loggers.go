@@ -36,6 +36,12 @@ func Logln(l level.Priority, a ...interface{}) {
 	std.Logln(l, a...)
 }
 
+// LogString logs the string with the priority, without allocating
+// when the priority is below the threshold.
+func LogString(l level.Priority, msg string) {
+	std.LogString(l, msg)
+}
+
 // Leveled Logging Methods
 // Emergency-level logging methods
 
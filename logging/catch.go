@@ -11,43 +11,99 @@ import (
 )
 
 func (g *Grip) CatchLog(l level.Priority, err error) {
-	g.Send(message.NewErrorMessage(l, err))
+	g.sendError(l, err)
 }
 
 func (g *Grip) CatchEmergency(err error) {
-	g.Send(message.NewErrorMessage(level.Emergency, err))
+	g.sendError(level.Emergency, err)
 }
 func (g *Grip) CatchEmergencyPanic(err error) {
-	g.sendPanic(message.NewErrorMessage(level.Emergency, err))
+	if !g.belowThreshold(level.Emergency) {
+		g.sendPanic(message.NewErrorMessage(level.Emergency, err))
+	}
 }
 func (g *Grip) CatchEmergencyFatal(err error) {
-	g.sendFatal(message.NewErrorMessage(level.Emergency, err))
+	if !g.belowThreshold(level.Emergency) {
+		g.sendFatal(message.NewErrorMessage(level.Emergency, err))
+	}
 }
 
 func (g *Grip) CatchAlert(err error) {
-	g.Send(message.NewErrorMessage(level.Alert, err))
+	g.sendError(level.Alert, err)
 }
 
 func (g *Grip) CatchCritical(err error) {
-	g.Send(message.NewErrorMessage(level.Critical, err))
+	g.sendError(level.Critical, err)
 }
 
 func (g *Grip) CatchError(err error) {
-	g.Send(message.NewErrorMessage(level.Error, err))
+	g.sendError(level.Error, err)
 }
 
 func (g *Grip) CatchWarning(err error) {
-	g.Send(message.NewErrorMessage(level.Warning, err))
+	g.sendError(level.Warning, err)
 }
 
 func (g *Grip) CatchNotice(err error) {
-	g.Send(message.NewErrorMessage(level.Notice, err))
+	g.sendError(level.Notice, err)
 }
 
 func (g *Grip) CatchInfo(err error) {
-	g.Send(message.NewErrorMessage(level.Info, err))
+	g.sendError(level.Info, err)
 }
 
 func (g *Grip) CatchDebug(err error) {
-	g.Send(message.NewErrorMessage(level.Debug, err))
+	g.sendError(level.Debug, err)
+}
+
+// Panic Logging
+//
+// Helpers for logging values recovered from panics at the Emergency
+// level, as message.NewPanic messages, with the stack trace of the
+// goroutine that panicked.
+
+// panicFrames skips logPanic and the helper that calls it, so that
+// stack traces start at the caller of CatchPanic, or, for the Recover
+// helpers, at the panic.
+const panicFrames = 2
+
+// CatchPanic logs the value, typically the result of recover(), at
+// the Emergency level with the stack trace of the caller, if the
+// value is not nil. Returns true if the value is not nil, even if the
+// message is below the threshold.
+func (g *Grip) CatchPanic(recovered interface{}) bool {
+	if recovered == nil {
+		return false
+	}
+
+	g.logPanic(recovered, panicFrames)
+	return true
+}
+
+// Recover recovers from a panic, and logs the recovered value as
+// CatchPanic does. Recover must be deferred directly, as in
+// "defer g.Recover()", to stop the panic.
+func (g *Grip) Recover() {
+	if recovered := recover(); recovered != nil {
+		g.logPanic(recovered, panicFrames)
+	}
+}
+
+// RecoverPanic logs a panic as Recover does, and then panics again
+// with the recovered value, so that the panic still crashes the
+// program or reaches another recover. RecoverPanic must be deferred
+// directly.
+func (g *Grip) RecoverPanic() {
+	if recovered := recover(); recovered != nil {
+		g.logPanic(recovered, panicFrames)
+		panic(recovered)
+	}
+}
+
+func (g *Grip) logPanic(recovered interface{}, skip int) {
+	if g.belowThreshold(level.Emergency) {
+		return
+	}
+
+	g.Send(message.NewPanicWithOptions(recovered, message.PanicOptions{Skip: skip}))
 }
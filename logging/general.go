@@ -24,109 +24,132 @@ import (
 )
 
 func (g *Grip) Log(l level.Priority, msg interface{}) {
-	g.Send(message.ConvertToComposer(l, msg))
+	g.send(l, msg)
 }
 func (g *Grip) Logf(l level.Priority, msg string, a ...interface{}) {
-	g.Send(message.NewFormattedMessage(l, msg, a...))
+	g.sendf(l, msg, a)
 }
 func (g *Grip) Logln(l level.Priority, a ...interface{}) {
-	g.Send(message.NewLineMessage(l, a...))
+	g.sendln(l, a)
+}
+
+// LogString logs the string with the priority. Unlike Log, which
+// takes an interface{}, a string that is not a constant does not
+// allocate (to box it) when the priority is below the threshold.
+func (g *Grip) LogString(l level.Priority, msg string) {
+	if g.belowThreshold(l) {
+		return
+	}
+
+	g.Send(message.NewDefaultMessage(l, msg))
 }
 
 func (g *Grip) Emergency(msg interface{}) {
-	g.Send(message.ConvertToComposer(level.Emergency, msg))
+	g.send(level.Emergency, msg)
 }
 func (g *Grip) Emergencyf(msg string, a ...interface{}) {
-	g.Send(message.NewFormattedMessage(level.Emergency, msg, a...))
+	g.sendf(level.Emergency, msg, a)
 }
 func (g *Grip) Emergencyln(a ...interface{}) {
-	g.Send(message.NewLineMessage(level.Emergency, a...))
+	g.sendln(level.Emergency, a)
 }
 func (g *Grip) EmergencyPanic(msg interface{}) {
-	g.sendPanic(message.ConvertToComposer(level.Emergency, msg))
+	if !g.belowThreshold(level.Emergency) {
+		g.sendPanic(message.ConvertToComposer(level.Emergency, msg))
+	}
 }
 func (g *Grip) EmergencyPanicf(msg string, a ...interface{}) {
-	g.sendPanic(message.NewFormattedMessage(level.Emergency, msg, a...))
+	if !g.belowThreshold(level.Emergency) {
+		g.sendPanic(message.NewFormattedMessage(level.Emergency, msg, copyArgs(a)...))
+	}
 }
 func (g *Grip) EmergencyPanicln(a ...interface{}) {
-	g.sendPanic(message.NewLineMessage(level.Emergency, a...))
+	if !g.belowThreshold(level.Emergency) {
+		g.sendPanic(message.NewLineMessage(level.Emergency, copyArgs(a)...))
+	}
 }
 func (g *Grip) EmergencyFatal(msg interface{}) {
-	g.sendFatal(message.ConvertToComposer(level.Emergency, msg))
+	if !g.belowThreshold(level.Emergency) {
+		g.sendFatal(message.ConvertToComposer(level.Emergency, msg))
+	}
 }
 func (g *Grip) EmergencyFatalf(msg string, a ...interface{}) {
-	g.sendFatal(message.NewFormattedMessage(level.Emergency, msg, a...))
+	if !g.belowThreshold(level.Emergency) {
+		g.sendFatal(message.NewFormattedMessage(level.Emergency, msg, copyArgs(a)...))
+	}
 }
 func (g *Grip) EmergencyFatalln(a ...interface{}) {
-	g.sendFatal(message.NewLineMessage(level.Emergency, a...))
+	if !g.belowThreshold(level.Emergency) {
+		g.sendFatal(message.NewLineMessage(level.Emergency, copyArgs(a)...))
+	}
 }
 
 func (g *Grip) Alert(msg interface{}) {
-	g.Send(message.ConvertToComposer(level.Alert, msg))
+	g.send(level.Alert, msg)
 }
 func (g *Grip) Alertf(msg string, a ...interface{}) {
-	g.Send(message.NewFormattedMessage(level.Alert, msg, a...))
+	g.sendf(level.Alert, msg, a)
 }
 func (g *Grip) Alertln(a ...interface{}) {
-	g.Send(message.NewLineMessage(level.Alert, a...))
+	g.sendln(level.Alert, a)
 }
 
 func (g *Grip) Critical(msg interface{}) {
-	g.Send(message.ConvertToComposer(level.Critical, msg))
+	g.send(level.Critical, msg)
 }
 func (g *Grip) Criticalf(msg string, a ...interface{}) {
-	g.Send(message.NewFormattedMessage(level.Critical, msg, a...))
+	g.sendf(level.Critical, msg, a)
 }
 func (g *Grip) Criticalln(a ...interface{}) {
-	g.Send(message.NewLineMessage(level.Critical, a...))
+	g.sendln(level.Critical, a)
 }
 
 func (g *Grip) Error(msg interface{}) {
-	g.Send(message.ConvertToComposer(level.Error, msg))
+	g.send(level.Error, msg)
 }
 func (g *Grip) Errorf(msg string, a ...interface{}) {
-	g.Send(message.NewFormattedMessage(level.Error, msg, a...))
+	g.sendf(level.Error, msg, a)
 }
 func (g *Grip) Errorln(a ...interface{}) {
-	g.Send(message.NewLineMessage(level.Error, a...))
+	g.sendln(level.Error, a)
 }
 
 func (g *Grip) Warning(msg interface{}) {
-	g.Send(message.ConvertToComposer(level.Warning, msg))
+	g.send(level.Warning, msg)
 }
 func (g *Grip) Warningf(msg string, a ...interface{}) {
-	g.Send(message.NewFormattedMessage(level.Warning, msg, a...))
+	g.sendf(level.Warning, msg, a)
 }
 func (g *Grip) Warningln(a ...interface{}) {
-	g.Send(message.NewLineMessage(level.Warning, a...))
+	g.sendln(level.Warning, a)
 }
 
 func (g *Grip) Notice(msg interface{}) {
-	g.Send(message.ConvertToComposer(level.Notice, msg))
+	g.send(level.Notice, msg)
 }
 func (g *Grip) Noticef(msg string, a ...interface{}) {
-	g.Send(message.NewFormattedMessage(level.Notice, msg, a...))
+	g.sendf(level.Notice, msg, a)
 }
 func (g *Grip) Noticeln(a ...interface{}) {
-	g.Send(message.NewLineMessage(level.Notice, a...))
+	g.sendln(level.Notice, a)
 }
 
 func (g *Grip) Info(msg interface{}) {
-	g.Send(message.ConvertToComposer(level.Info, msg))
+	g.send(level.Info, msg)
 }
 func (g *Grip) Infof(msg string, a ...interface{}) {
-	g.Send(message.NewFormattedMessage(level.Info, msg, a...))
+	g.sendf(level.Info, msg, a)
 }
 func (g *Grip) Infoln(a ...interface{}) {
-	g.Send(message.NewLineMessage(level.Info, a...))
+	g.sendln(level.Info, a)
 }
 
 func (g *Grip) Debug(msg interface{}) {
-	g.Send(message.ConvertToComposer(level.Debug, msg))
+	g.send(level.Debug, msg)
 }
 func (g *Grip) Debugf(msg string, a ...interface{}) {
-	g.Send(message.NewFormattedMessage(level.Debug, msg, a...))
+	g.sendf(level.Debug, msg, a)
 }
 func (g *Grip) Debugln(a ...interface{}) {
-	g.Send(message.NewLineMessage(level.Debug, a...))
+	g.sendln(level.Debug, a)
 }
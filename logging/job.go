@@ -0,0 +1,28 @@
+package logging
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/mongodb/grip/level"
+	"github.com/mongodb/grip/message"
+)
+
+// RunJob runs the function, logging a message when the job starts
+// and a message.NewJobRun message when it completes. If the function
+// panics, RunJob recovers and logs and returns the panic as an
+// error. RunJob returns the error returned by the function.
+func (g *Grip) RunJob(name string, fn func() error) (err error) {
+	started := time.Now()
+	g.Send(message.NewFormattedMessage(level.Info, "job %s started", name))
+
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("job %s panicked: %v", name, p)
+		}
+
+		g.Send(message.NewJobRun(name, started, time.Since(started), err))
+	}()
+
+	return fn()
+}
@@ -0,0 +1,50 @@
+package logging
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/mongodb/grip/level"
+	"github.com/mongodb/grip/send"
+)
+
+func (s *GripInternalSuite) TestRunJobLogsStartAndCompletion() {
+	sink, err := send.NewInternalLogger("sink", send.LevelInfo{Default: level.Trace, Threshold: level.Trace})
+	s.NoError(err)
+	s.NoError(s.grip.SetSender(sink))
+	s.grip.SetThreshold(level.Trace)
+	_ = sink.GetMessage()
+
+	s.NoError(s.grip.RunJob("rollup", func() error { return nil }))
+	s.Equal(2, sink.Len())
+	s.Equal("job rollup started", sink.GetMessage().Rendered)
+
+	msg := sink.GetMessage()
+	s.Equal(level.Info, msg.Priority)
+	s.True(strings.HasPrefix(msg.Rendered, "job rollup completed in"))
+
+	s.Error(s.grip.RunJob("rollup", func() error { return errors.New("failed") }))
+	s.Equal(2, sink.Len())
+	_ = sink.GetMessage()
+	msg = sink.GetMessage()
+	s.Equal(level.Error, msg.Priority)
+	s.True(strings.HasSuffix(msg.Rendered, ": failed"))
+}
+
+func (s *GripInternalSuite) TestRunJobRecoversPanics() {
+	sink, err := send.NewInternalLogger("sink", send.LevelInfo{Default: level.Trace, Threshold: level.Trace})
+	s.NoError(err)
+	s.NoError(s.grip.SetSender(sink))
+	s.grip.SetThreshold(level.Trace)
+	_ = sink.GetMessage()
+
+	err = s.grip.RunJob("rollup", func() error { panic("boom") })
+	s.Error(err)
+	s.Contains(err.Error(), "boom")
+
+	s.Equal(2, sink.Len())
+	_ = sink.GetMessage()
+	msg := sink.GetMessage()
+	s.Equal(level.Error, msg.Priority)
+	s.Contains(msg.Rendered, "panicked: boom")
+}
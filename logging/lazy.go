@@ -0,0 +1,44 @@
+/*
+Lazy Logging
+
+The logging methods that end with "Lazy" take a function that
+produces a message.Composer, and only call the function if the
+priority of the message is above the threshold of the sender. Use
+these methods when constructing the message, or its arguments
+(e.g. a message.Fields map,) would allocate even though the message
+will not be sent.
+*/
+package logging
+
+import (
+	"github.com/mongodb/grip/level"
+	"github.com/mongodb/grip/message"
+)
+
+func (g *Grip) LogLazy(l level.Priority, fn func() message.Composer) {
+	g.sendLazy(l, fn)
+}
+func (g *Grip) EmergencyLazy(fn func() message.Composer) {
+	g.sendLazy(level.Emergency, fn)
+}
+func (g *Grip) AlertLazy(fn func() message.Composer) {
+	g.sendLazy(level.Alert, fn)
+}
+func (g *Grip) CriticalLazy(fn func() message.Composer) {
+	g.sendLazy(level.Critical, fn)
+}
+func (g *Grip) ErrorLazy(fn func() message.Composer) {
+	g.sendLazy(level.Error, fn)
+}
+func (g *Grip) WarningLazy(fn func() message.Composer) {
+	g.sendLazy(level.Warning, fn)
+}
+func (g *Grip) NoticeLazy(fn func() message.Composer) {
+	g.sendLazy(level.Notice, fn)
+}
+func (g *Grip) InfoLazy(fn func() message.Composer) {
+	g.sendLazy(level.Info, fn)
+}
+func (g *Grip) DebugLazy(fn func() message.Composer) {
+	g.sendLazy(level.Debug, fn)
+}
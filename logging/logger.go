@@ -1,7 +1,11 @@
 package logging
 
 import (
+	"context"
+	"fmt"
+	"io"
 	"os"
+	"time"
 
 	"github.com/mongodb/grip/level"
 	"github.com/mongodb/grip/message"
@@ -29,6 +33,17 @@ func NewGrip(name string) *Grip {
 
 // Internal
 
+// fatalFlushTimeout bounds the amount of time that the Fatal methods
+// wait for the sender to flush the fatal message before exiting.
+const fatalFlushTimeout = 5 * time.Second
+
+// exit and fatalFallback are variables so that tests can observe the
+// Fatal methods without exiting.
+var (
+	exit                    = os.Exit
+	fatalFallback io.Writer = os.Stderr
+)
+
 // For sending logging messages, in most cases, use the
 // Journaler.sender.Send() method, but we have a couple of methods to
 // use for the Panic/Fatal helpers.
@@ -45,7 +60,104 @@ func (g *Grip) sendFatal(m message.Composer) {
 	// the Send method in the Sender interface will perform this
 	// check but to add fatal methods we need to do this here.
 	if g.Level().ShouldLog(m) {
+		// render the message before sending it, as asynchronous
+		// senders may still be rendering it if the flush fails.
+		text := m.String()
 		g.Send(m)
-		os.Exit(1)
+		g.flushFatal(text)
+		exit(1)
+	}
+}
+
+// flushFatal flushes the sender, so that buffered and asynchronous
+// senders deliver the fatal message before the process exits. If the
+// sender cannot flush in time, the message is written to standard
+// error, as a last resort.
+func (g *Grip) flushFatal(text string) {
+	ctx, cancel := context.WithTimeout(context.Background(), fatalFlushTimeout)
+	defer cancel()
+
+	if err := g.Flush(ctx); err != nil {
+		fmt.Fprintf(fatalFallback, "[grip] sender '%s' could not flush before exit (%s): %s\n",
+			g.Name(), err, text)
+	}
+}
+
+// The following helpers check the priority of the message against
+// the sender's threshold before constructing a Composer, so that
+// logging calls that are below the threshold do not construct
+// messages.
+//
+// The f and ln helpers copy their variadic arguments before
+// constructing the message. Without the copy, the argument slice
+// escapes into the Composer, and the caller must allocate it on the
+// heap for every call, including calls below the threshold. With the
+// copy, the caller's slice can stay on the stack, but every call
+// *above* the threshold pays for one additional slice allocation and
+// copy. This trades a small cost on the enabled path for no cost on
+// the disabled path, which is the common case for debug logging.
+//
+// Arguments that are boxed into interfaces at the call site still
+// allocate regardless of the threshold: for example a message.Fields
+// literal (the map escapes into the Composer), a literal list of
+// Composers passed to the Many methods, or integers larger than 255.
+// Use the Lazy methods to defer constructing these arguments until
+// the message will be sent.
+
+func (g *Grip) belowThreshold(l level.Priority) bool {
+	return l < g.Level().Threshold
+}
+
+func (g *Grip) send(l level.Priority, msg interface{}) {
+	if g.belowThreshold(l) {
+		return
+	}
+
+	g.Send(message.ConvertToComposer(l, msg))
+}
+
+func (g *Grip) sendf(l level.Priority, msg string, a []interface{}) {
+	if g.belowThreshold(l) {
+		return
+	}
+
+	g.Send(message.NewFormattedMessage(l, msg, copyArgs(a)...))
+}
+
+func (g *Grip) sendln(l level.Priority, a []interface{}) {
+	if g.belowThreshold(l) {
+		return
+	}
+
+	g.Send(message.NewLineMessage(l, copyArgs(a)...))
+}
+
+func (g *Grip) sendError(l level.Priority, err error) {
+	if g.belowThreshold(l) {
+		return
+	}
+
+	g.Send(message.NewErrorMessage(l, err))
+}
+
+func (g *Grip) sendLazy(l level.Priority, fn func() message.Composer) {
+	if g.belowThreshold(l) {
+		return
 	}
+
+	m := fn()
+	if message.IsNil(m) {
+		return
+	}
+
+	_ = m.SetPriority(l)
+	g.Send(m)
+}
+
+func copyArgs(a []interface{}) []interface{} {
+	if len(a) == 0 {
+		return nil
+	}
+
+	return append(make([]interface{}, 0, len(a)), a...)
 }
@@ -10,7 +10,15 @@ import (
 // Multi Send
 
 func (g *Grip) multiSend(l level.Priority, msgs []message.Composer) {
+	if g.belowThreshold(l) {
+		return
+	}
+
 	for _, m := range msgs {
+		if message.IsNil(m) {
+			continue
+		}
+
 		_ = m.SetPriority(l)
 		g.Send(m)
 	}
@@ -0,0 +1,125 @@
+package logging
+
+import (
+	"testing"
+
+	"github.com/mongodb/grip/level"
+	"github.com/mongodb/grip/message"
+	"github.com/mongodb/grip/send"
+)
+
+// nilError and nilComposer dereference their receivers, so calling
+// their methods on nil pointers panics.
+type nilError struct{ msg string }
+
+func (e *nilError) Error() string { return e.msg }
+
+type nilComposer struct{ message.Composer }
+
+func (c *nilComposer) String() string                     { return c.Composer.String() }
+func (c *nilComposer) Loggable() bool                     { return c.Composer.Loggable() }
+func (c *nilComposer) Priority() level.Priority           { return c.Composer.Priority() }
+func (c *nilComposer) SetPriority(p level.Priority) error { return c.Composer.SetPriority(p) }
+
+func TestNilInputsAreDropped(t *testing.T) {
+	defer restoreFatalExit()
+
+	sink, err := send.NewInternalLogger("sink", send.LevelInfo{Default: level.Trace, Threshold: level.Trace})
+	if err != nil {
+		t.Fatal(err)
+	}
+	grip := &Grip{sink}
+
+	code, _ := stubFatalExit()
+
+	var (
+		nilErrPtr      *nilError
+		nilComposerPtr *nilComposer
+	)
+
+	inputs := map[string]interface{}{
+		"nil":                nil,
+		"nil error pointer":  error(nilErrPtr),
+		"nil composer":       message.Composer(nilComposerPtr),
+		"nil map":            map[string]interface{}(nil),
+		"nil fields":         message.Fields(nil),
+		"nil bytes":          []byte(nil),
+		"nil strings":        []string(nil),
+		"nil interfaces":     []interface{}(nil),
+		"nil composer slice": []message.Composer(nil),
+	}
+
+	asError := func(in interface{}) error {
+		err, _ := in.(error)
+		return err
+	}
+	asComposer := func(in interface{}) message.Composer {
+		m, _ := in.(message.Composer)
+		return m
+	}
+
+	entryPoints := map[string]func(interface{}){
+		"Log":                 func(in interface{}) { grip.Log(level.Info, in) },
+		"Emergency":           func(in interface{}) { grip.Emergency(in) },
+		"Alert":               func(in interface{}) { grip.Alert(in) },
+		"Critical":            func(in interface{}) { grip.Critical(in) },
+		"Error":               func(in interface{}) { grip.Error(in) },
+		"Warning":             func(in interface{}) { grip.Warning(in) },
+		"Notice":              func(in interface{}) { grip.Notice(in) },
+		"Info":                func(in interface{}) { grip.Info(in) },
+		"Debug":               func(in interface{}) { grip.Debug(in) },
+		"Logln":               func(in interface{}) { grip.Logln(level.Info, in) },
+		"Infoln":              func(in interface{}) { grip.Infoln(in) },
+		"Infoln (two)":        func(in interface{}) { grip.Infoln(in, in) },
+		"LogWhen":             func(in interface{}) { grip.LogWhen(true, level.Info, in) },
+		"InfoWhen":            func(in interface{}) { grip.InfoWhen(true, in) },
+		"InfoWhenln":          func(in interface{}) { grip.InfoWhenln(true, in) },
+		"EmergencyPanic":      func(in interface{}) { grip.EmergencyPanic(in) },
+		"EmergencyFatalln":    func(in interface{}) { grip.EmergencyFatalln(in) },
+		"EmergencyPanicln":    func(in interface{}) { grip.EmergencyPanicln(in) },
+		"EmergencyFatal":      func(in interface{}) { grip.EmergencyFatal(in) },
+		"CatchLog":            func(in interface{}) { grip.CatchLog(level.Info, asError(in)) },
+		"CatchError":          func(in interface{}) { grip.CatchError(asError(in)) },
+		"CatchEmergencyPanic": func(in interface{}) { grip.CatchEmergencyPanic(asError(in)) },
+		"CatchEmergencyFatal": func(in interface{}) { grip.CatchEmergencyFatal(asError(in)) },
+		"LogMany":             func(in interface{}) { grip.LogMany(level.Info, asComposer(in)) },
+		"InfoMany":            func(in interface{}) { grip.InfoMany(asComposer(in), asComposer(in)) },
+		"LogLazy":             func(in interface{}) { grip.LogLazy(level.Info, func() message.Composer { return asComposer(in) }) },
+		"InfoLazy":            func(in interface{}) { grip.InfoLazy(func() message.Composer { return asComposer(in) }) },
+		"Send":                func(in interface{}) { grip.Send(asComposer(in)) },
+	}
+
+	for inputName, in := range inputs {
+		for name, fn := range entryPoints {
+			func() {
+				defer func() {
+					if p := recover(); p != nil {
+						t.Errorf("%s(%s) panicked: %v", name, inputName, p)
+					}
+				}()
+
+				fn(in)
+			}()
+
+			// the internal sender records messages that are not
+			// loggable, but marks them as not logged.
+			for sink.HasMessage() {
+				if msg := sink.GetMessage(); msg.Logged {
+					t.Errorf("%s(%s) logged a message: %s", name, inputName, msg.Rendered)
+				}
+			}
+			if *code != -1 {
+				t.Errorf("%s(%s) exited", name, inputName)
+				*code = -1
+			}
+		}
+	}
+
+	// the senders drop nil messages too.
+	for _, m := range []message.Composer{nil, nilComposerPtr} {
+		sink.Send(m)
+		if sink.Len() != 0 {
+			t.Errorf("sender did not drop %T", m)
+		}
+	}
+}
@@ -1,11 +1,17 @@
 package logging
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"os"
 	"os/exec"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/mongodb/grip/level"
 	"github.com/mongodb/grip/message"
@@ -76,6 +82,60 @@ func (s *GripInternalSuite) TestPanicSenderRespectsTThreshold() {
 	s.grip.sendPanic(message.NewLineMessage(level.Debug, "foo"))
 }
 
+func (s *GripInternalSuite) TestRecoverLogsPanics() {
+	sink, err := send.NewInternalLogger("sink", send.LevelInfo{Default: level.Info, Threshold: level.Info})
+	s.NoError(err)
+	s.NoError(s.grip.SetSender(sink))
+
+	s.False(s.grip.CatchPanic(nil))
+	s.Equal(0, sink.Len())
+
+	s.True(s.grip.CatchPanic("boom"))
+	msg := sink.GetMessage()
+	s.Equal(level.Emergency, msg.Priority)
+	s.Contains(msg.Rendered, "panic: boom")
+	raw, err := json.Marshal(msg.Message.Raw())
+	s.Require().NoError(err)
+	panicked := struct {
+		Panic    string             `json:"panic"`
+		Location message.StackFrame `json:"location"`
+		Stack    string             `json:"stack"`
+	}{}
+	s.Require().NoError(json.Unmarshal(raw, &panicked))
+	s.Equal("boom", panicked.Panic)
+	s.Contains(panicked.Location.Function, "TestRecoverLogsPanics")
+	s.NotEmpty(panicked.Stack)
+
+	s.NotPanics(func() {
+		defer s.grip.Recover()
+		panic(errors.New("kaboom"))
+	})
+	msg = sink.GetMessage()
+	s.Equal(level.Emergency, msg.Priority)
+	s.Contains(msg.Rendered, "panic: kaboom")
+	s.Contains(msg.Rendered, "TestRecoverLogsPanics")
+
+	func() {
+		defer func() { s.Equal("again", recover()) }()
+		defer s.grip.RecoverPanic()
+		panic("again")
+	}()
+	s.Contains(sink.GetMessage().Rendered, "panic: again")
+
+	// functions that do not panic are not logged.
+	func() { defer s.grip.Recover() }()
+	s.Equal(0, sink.Len())
+
+	// panics below the threshold are recovered but not logged.
+	quiet := &Grip{&thresholdSender{InternalSender: sink}}
+	s.True(quiet.CatchPanic("quiet"))
+	s.NotPanics(func() {
+		defer quiet.Recover()
+		panic("quiet")
+	})
+	s.Equal(0, sink.Len())
+}
+
 func (s *GripInternalSuite) TestConditionalSend() {
 	// because sink is an internal type (implementation of
 	// sender,) and "GetMessage" isn't in the interface, though it
@@ -104,7 +164,7 @@ func (s *GripInternalSuite) TestConditionalSend() {
 
 func (s *GripInternalSuite) TestCatchMethods() {
 
-	sink, err := send.NewInternalLogger("sink", send.LevelInfo{level.Trace, level.Trace})
+	sink, err := send.NewInternalLogger("sink", send.LevelInfo{Default: level.Trace, Threshold: level.Trace})
 	s.NoError(err)
 	s.NoError(s.grip.SetSender(sink))
 
@@ -280,3 +340,98 @@ func TestSendFatalExits(t *testing.T) {
 		t.Errorf("sendFatal should have exited 0, instead: %+v", err)
 	}
 }
+
+// delayedSender is a buffered sender that delivers messages to an
+// internal sender after a delay.
+type delayedSender struct {
+	delay    time.Duration
+	flushErr error
+	pending  sync.WaitGroup
+	*send.InternalSender
+}
+
+func (s *delayedSender) Send(m message.Composer) {
+	s.pending.Add(1)
+	go func() {
+		defer s.pending.Done()
+		time.Sleep(s.delay)
+		s.InternalSender.Send(m)
+	}()
+}
+
+func (s *delayedSender) Flush(_ context.Context) error {
+	if s.flushErr != nil {
+		return s.flushErr
+	}
+
+	s.pending.Wait()
+	return nil
+}
+
+func stubFatalExit() (*int, *bytes.Buffer) {
+	code := -1
+	fallback := &bytes.Buffer{}
+	exit = func(c int) { code = c }
+	fatalFallback = fallback
+
+	return &code, fallback
+}
+
+func restoreFatalExit() {
+	exit = os.Exit
+	fatalFallback = os.Stderr
+}
+
+func TestFatalMessagesAreDeliveredBeforeExit(t *testing.T) {
+	defer restoreFatalExit()
+
+	internal, err := send.NewInternalLogger("delayed", send.LevelInfo{Default: level.Info, Threshold: level.Info})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sink := &delayedSender{delay: 50 * time.Millisecond, InternalSender: internal}
+	grip := &Grip{sink}
+
+	var delivered int
+	code, fallback := stubFatalExit()
+	exit = func(c int) {
+		*code = c
+		delivered = internal.Len()
+	}
+
+	grip.EmergencyFatal("shutting down")
+	if *code != 1 {
+		t.Errorf("expected exit code 1, got %d", *code)
+	}
+	if delivered != 1 {
+		t.Errorf("fatal message was not delivered before exit")
+	}
+	if fallback.Len() != 0 {
+		t.Errorf("unexpected fallback output: %s", fallback.String())
+	}
+	if msg := internal.GetMessage(); msg.Rendered != "shutting down" {
+		t.Errorf("unexpected message: %s", msg.Rendered)
+	}
+}
+
+func TestFatalMessagesFallBackToStandardErrorWhenFlushFails(t *testing.T) {
+	defer restoreFatalExit()
+
+	internal, err := send.NewInternalLogger("broken", send.LevelInfo{Default: level.Info, Threshold: level.Info})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sink := &delayedSender{flushErr: errors.New("backend unavailable"), InternalSender: internal}
+	grip := &Grip{sink}
+
+	code, fallback := stubFatalExit()
+	grip.EmergencyFatalf("disk %s is full", "/data")
+
+	if *code != 1 {
+		t.Errorf("expected exit code 1, got %d", *code)
+	}
+	out := fallback.String()
+	if !strings.Contains(out, "disk /data is full") || !strings.Contains(out, "backend unavailable") {
+		t.Errorf("unexpected fallback output: %s", out)
+	}
+}
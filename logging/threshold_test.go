@@ -0,0 +1,371 @@
+package logging
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/mongodb/grip/level"
+	"github.com/mongodb/grip/message"
+	"github.com/mongodb/grip/send"
+)
+
+// The Fields and Many cases use pre-built values: constructing a
+// message.Fields literal or a list of Composers at the call site
+// always allocates, because the values escape into the messages that
+// are sent when the message is above the threshold. See the
+// BenchmarkBelowThresholdDebugFieldsLiteral benchmark.
+var (
+	testFields    = message.Fields{"a": 1, "b": "two"}
+	testComposers = []message.Composer{message.NewString("one"), message.NewString("two")}
+	testErr       = errors.New("hello world")
+)
+
+// thresholdSender reports a threshold above Emergency so that every
+// logging method, including the Emergency methods, is below the
+// threshold.
+type thresholdSender struct {
+	*send.InternalSender
+}
+
+func (s *thresholdSender) Level() send.LevelInfo {
+	return send.LevelInfo{Default: level.Info, Threshold: level.Emergency + 1}
+}
+
+// loggingMethodCases returns a function that calls each logging
+// method of the Grip type. The methods must be called directly,
+// rather than via method values, so that the escape analysis of the
+// methods applies to the call sites. Cases with a "/" in the name
+// exercise a method with a different kind of argument.
+func loggingMethodCases(g *Grip) map[string]func() {
+	const msg = "hello world"
+	dynamic := strings.Repeat(msg, 2)
+	lazy := func() message.Composer { return message.NewString(msg) }
+
+	return map[string]func(){
+		"Log":         func() { g.Log(level.Debug, msg) },
+		"Logf":        func() { g.Logf(level.Debug, "%s: %d", msg, 3) },
+		"Logln":       func() { g.Logln(level.Debug, msg, 3) },
+		"LogString":   func() { g.LogString(level.Debug, dynamic) },
+		"LogWhen":     func() { g.LogWhen(true, level.Debug, msg) },
+		"LogWhenf":    func() { g.LogWhenf(true, level.Debug, "%s: %d", msg, 3) },
+		"LogWhenln":   func() { g.LogWhenln(true, level.Debug, msg, 3) },
+		"CatchLog":    func() { g.CatchLog(level.Debug, testErr) },
+		"LogMany":     func() { g.LogMany(level.Debug, testComposers...) },
+		"LogManyWhen": func() { g.LogManyWhen(true, level.Debug, testComposers...) },
+		"LogLazy":     func() { g.LogLazy(level.Debug, lazy) },
+		"Log/fields":  func() { g.Log(level.Debug, testFields) },
+
+		"Emergency":           func() { g.Emergency(msg) },
+		"Emergencyf":          func() { g.Emergencyf("%s: %d", msg, 3) },
+		"Emergencyln":         func() { g.Emergencyln(msg, 3) },
+		"EmergencyWhen":       func() { g.EmergencyWhen(true, msg) },
+		"EmergencyWhenf":      func() { g.EmergencyWhenf(true, "%s: %d", msg, 3) },
+		"EmergencyWhenln":     func() { g.EmergencyWhenln(true, msg, 3) },
+		"CatchEmergency":      func() { g.CatchEmergency(testErr) },
+		"EmergencyMany":       func() { g.EmergencyMany(testComposers...) },
+		"EmergencyManyWhen":   func() { g.EmergencyManyWhen(true, testComposers...) },
+		"EmergencyLazy":       func() { g.EmergencyLazy(lazy) },
+		"Emergency/fields":    func() { g.Emergency(testFields) },
+		"EmergencyPanic":      func() { g.EmergencyPanic(msg) },
+		"EmergencyPanicf":     func() { g.EmergencyPanicf("%s: %d", msg, 3) },
+		"EmergencyPanicln":    func() { g.EmergencyPanicln(msg, 3) },
+		"EmergencyFatal":      func() { g.EmergencyFatal(msg) },
+		"EmergencyFatalf":     func() { g.EmergencyFatalf("%s: %d", msg, 3) },
+		"EmergencyFatalln":    func() { g.EmergencyFatalln(msg, 3) },
+		"CatchEmergencyPanic": func() { g.CatchEmergencyPanic(testErr) },
+		"CatchEmergencyFatal": func() { g.CatchEmergencyFatal(testErr) },
+		"CatchPanic":          func() { g.CatchPanic(testErr) },
+		"Recover":             func() { g.Recover() },
+		"RecoverPanic":        func() { g.RecoverPanic() },
+
+		"Alert":         func() { g.Alert(msg) },
+		"Alertf":        func() { g.Alertf("%s: %d", msg, 3) },
+		"Alertln":       func() { g.Alertln(msg, 3) },
+		"AlertWhen":     func() { g.AlertWhen(true, msg) },
+		"AlertWhenf":    func() { g.AlertWhenf(true, "%s: %d", msg, 3) },
+		"AlertWhenln":   func() { g.AlertWhenln(true, msg, 3) },
+		"CatchAlert":    func() { g.CatchAlert(testErr) },
+		"AlertMany":     func() { g.AlertMany(testComposers...) },
+		"AlertManyWhen": func() { g.AlertManyWhen(true, testComposers...) },
+		"AlertLazy":     func() { g.AlertLazy(lazy) },
+		"Alert/fields":  func() { g.Alert(testFields) },
+
+		"Critical":         func() { g.Critical(msg) },
+		"Criticalf":        func() { g.Criticalf("%s: %d", msg, 3) },
+		"Criticalln":       func() { g.Criticalln(msg, 3) },
+		"CriticalWhen":     func() { g.CriticalWhen(true, msg) },
+		"CriticalWhenf":    func() { g.CriticalWhenf(true, "%s: %d", msg, 3) },
+		"CriticalWhenln":   func() { g.CriticalWhenln(true, msg, 3) },
+		"CatchCritical":    func() { g.CatchCritical(testErr) },
+		"CriticalMany":     func() { g.CriticalMany(testComposers...) },
+		"CriticalManyWhen": func() { g.CriticalManyWhen(true, testComposers...) },
+		"CriticalLazy":     func() { g.CriticalLazy(lazy) },
+		"Critical/fields":  func() { g.Critical(testFields) },
+
+		"Error":         func() { g.Error(msg) },
+		"Errorf":        func() { g.Errorf("%s: %d", msg, 3) },
+		"Errorln":       func() { g.Errorln(msg, 3) },
+		"ErrorWhen":     func() { g.ErrorWhen(true, msg) },
+		"ErrorWhenf":    func() { g.ErrorWhenf(true, "%s: %d", msg, 3) },
+		"ErrorWhenln":   func() { g.ErrorWhenln(true, msg, 3) },
+		"CatchError":    func() { g.CatchError(testErr) },
+		"ErrorMany":     func() { g.ErrorMany(testComposers...) },
+		"ErrorManyWhen": func() { g.ErrorManyWhen(true, testComposers...) },
+		"ErrorLazy":     func() { g.ErrorLazy(lazy) },
+		"Error/fields":  func() { g.Error(testFields) },
+
+		"Warning":         func() { g.Warning(msg) },
+		"Warningf":        func() { g.Warningf("%s: %d", msg, 3) },
+		"Warningln":       func() { g.Warningln(msg, 3) },
+		"WarningWhen":     func() { g.WarningWhen(true, msg) },
+		"WarningWhenf":    func() { g.WarningWhenf(true, "%s: %d", msg, 3) },
+		"WarningWhenln":   func() { g.WarningWhenln(true, msg, 3) },
+		"CatchWarning":    func() { g.CatchWarning(testErr) },
+		"WarningMany":     func() { g.WarningMany(testComposers...) },
+		"WarningManyWhen": func() { g.WarningManyWhen(true, testComposers...) },
+		"WarningLazy":     func() { g.WarningLazy(lazy) },
+		"Warning/fields":  func() { g.Warning(testFields) },
+
+		"Notice":         func() { g.Notice(msg) },
+		"Noticef":        func() { g.Noticef("%s: %d", msg, 3) },
+		"Noticeln":       func() { g.Noticeln(msg, 3) },
+		"NoticeWhen":     func() { g.NoticeWhen(true, msg) },
+		"NoticeWhenf":    func() { g.NoticeWhenf(true, "%s: %d", msg, 3) },
+		"NoticeWhenln":   func() { g.NoticeWhenln(true, msg, 3) },
+		"CatchNotice":    func() { g.CatchNotice(testErr) },
+		"NoticeMany":     func() { g.NoticeMany(testComposers...) },
+		"NoticeManyWhen": func() { g.NoticeManyWhen(true, testComposers...) },
+		"NoticeLazy":     func() { g.NoticeLazy(lazy) },
+		"Notice/fields":  func() { g.Notice(testFields) },
+
+		"Info":         func() { g.Info(msg) },
+		"Infof":        func() { g.Infof("%s: %d", msg, 3) },
+		"Infoln":       func() { g.Infoln(msg, 3) },
+		"InfoWhen":     func() { g.InfoWhen(true, msg) },
+		"InfoWhenf":    func() { g.InfoWhenf(true, "%s: %d", msg, 3) },
+		"InfoWhenln":   func() { g.InfoWhenln(true, msg, 3) },
+		"CatchInfo":    func() { g.CatchInfo(testErr) },
+		"InfoMany":     func() { g.InfoMany(testComposers...) },
+		"InfoManyWhen": func() { g.InfoManyWhen(true, testComposers...) },
+		"InfoLazy":     func() { g.InfoLazy(lazy) },
+		"Info/fields":  func() { g.Info(testFields) },
+
+		"Debug":         func() { g.Debug(msg) },
+		"Debugf":        func() { g.Debugf("%s: %d", msg, 3) },
+		"Debugln":       func() { g.Debugln(msg, 3) },
+		"DebugWhen":     func() { g.DebugWhen(true, msg) },
+		"DebugWhenf":    func() { g.DebugWhenf(true, "%s: %d", msg, 3) },
+		"DebugWhenln":   func() { g.DebugWhenln(true, msg, 3) },
+		"CatchDebug":    func() { g.CatchDebug(testErr) },
+		"DebugMany":     func() { g.DebugMany(testComposers...) },
+		"DebugManyWhen": func() { g.DebugManyWhen(true, testComposers...) },
+		"DebugLazy":     func() { g.DebugLazy(lazy) },
+		"Debug/fields":  func() { g.Debug(testFields) },
+	}
+}
+
+// nonLoggingMethods are the methods of the Grip type that are not
+// logging methods, and are not covered by loggingMethodCases.
+var nonLoggingMethods = map[string]bool{
+	"Name":            true,
+	"SetName":         true,
+	"GetSender":       true,
+	"SetSender":       true,
+	"SetThreshold":    true,
+	"ThresholdLevel":  true,
+	"SetDefaultLevel": true,
+	"DefaultLevel":    true,
+	"RunJob":          true,
+}
+
+func TestLoggingMethodCasesCoverAllMethods(t *testing.T) {
+	cases := loggingMethodCases(NewGrip("test"))
+	senderMethods := reflect.TypeOf((*send.Sender)(nil)).Elem()
+	gripType := reflect.TypeOf(&Grip{})
+
+	for i := 0; i < gripType.NumMethod(); i++ {
+		name := gripType.Method(i).Name
+		if _, ok := senderMethods.MethodByName(name); ok || nonLoggingMethods[name] {
+			continue
+		}
+
+		if _, ok := cases[name]; !ok {
+			t.Errorf("logging method %s has no below threshold test case", name)
+		}
+	}
+
+	for name := range cases {
+		name = strings.Split(name, "/")[0]
+		if _, ok := gripType.MethodByName(name); !ok {
+			t.Errorf("test case %s does not correspond to a logging method", name)
+		}
+	}
+}
+
+func TestBelowThresholdCallsDoNotAllocate(t *testing.T) {
+	sink := &thresholdSender{InternalSender: send.MakeInternalLogger()}
+	g := &Grip{sink}
+
+	for name, fn := range loggingMethodCases(g) {
+		if allocs := testing.AllocsPerRun(100, fn); allocs != 0 {
+			t.Errorf("%s allocated %v times per call below the threshold", name, allocs)
+		}
+
+		if sink.Len() != 0 {
+			t.Errorf("%s sent a message below the threshold", name)
+		}
+	}
+}
+
+func (s *GripInternalSuite) TestLazyMethodsOnlyBuildMessagesAboveThreshold() {
+	sink, err := send.NewInternalLogger("sink", send.LevelInfo{Default: level.Info, Threshold: level.Info})
+	s.NoError(err)
+	s.NoError(s.grip.SetSender(sink))
+	s.grip.SetThreshold(level.Info)
+	_ = sink.GetMessage()
+
+	count := 0
+	fn := func() message.Composer {
+		count++
+		return message.NewString("lazy")
+	}
+
+	s.grip.DebugLazy(fn)
+	s.grip.LogLazy(level.Trace, fn)
+	s.Equal(0, count)
+	s.Equal(0, sink.Len())
+
+	s.grip.InfoLazy(fn)
+	s.grip.LogLazy(level.Alert, fn)
+	s.Equal(2, count)
+	s.Equal(2, sink.Len())
+
+	msg := sink.GetMessage()
+	s.Equal("lazy", msg.Rendered)
+	s.Equal(level.Info, msg.Priority)
+	s.Equal(level.Alert, sink.GetMessage().Priority)
+
+	s.grip.InfoLazy(func() message.Composer { return nil })
+	s.Equal(0, sink.Len())
+}
+
+func benchmarkGrip() *Grip {
+	g := NewGrip("bench")
+	g.SetThreshold(level.Info)
+	return g
+}
+
+func BenchmarkBelowThresholdDebug(b *testing.B) {
+	g := benchmarkGrip()
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		g.Debug("hello world")
+	}
+}
+
+// BenchmarkBelowThresholdDebugDynamicString passes a string that is
+// not a constant, which allocates to box the string into an
+// interface{} even though the message is below the threshold. Use
+// LogString, as in BenchmarkBelowThresholdLogString, to avoid this
+// cost.
+func BenchmarkBelowThresholdDebugDynamicString(b *testing.B) {
+	g := benchmarkGrip()
+	msg := strings.Repeat("hello world", 2)
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		g.Debug(msg)
+	}
+}
+
+func BenchmarkBelowThresholdLogString(b *testing.B) {
+	g := benchmarkGrip()
+	msg := strings.Repeat("hello world", 2)
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		g.LogString(level.Debug, msg)
+	}
+}
+
+func BenchmarkBelowThresholdDebugf(b *testing.B) {
+	g := benchmarkGrip()
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		g.Debugf("x=%d", 42)
+	}
+}
+
+func BenchmarkBelowThresholdDebugln(b *testing.B) {
+	g := benchmarkGrip()
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		g.Debugln("x =", 42)
+	}
+}
+
+// BenchmarkBelowThresholdDebugFields passes a pre-built Fields value,
+// which does not allocate.
+func BenchmarkBelowThresholdDebugFields(b *testing.B) {
+	g := benchmarkGrip()
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		g.Debug(testFields)
+	}
+}
+
+// BenchmarkBelowThresholdDebugFieldsLiteral constructs the Fields map
+// at the call site, which allocates (two allocations per call, for
+// the map and its bucket) even though the message is below the
+// threshold. Use DebugLazy, as in BenchmarkBelowThresholdDebugLazy,
+// to avoid this cost.
+func BenchmarkBelowThresholdDebugFieldsLiteral(b *testing.B) {
+	g := benchmarkGrip()
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		g.Debug(message.Fields{"i": i})
+	}
+}
+
+// BenchmarkBelowThresholdDebugManyLiteral constructs the list of
+// Composers at the call site, which allocates (one allocation for
+// each Composer) even though the messages are below the threshold.
+func BenchmarkBelowThresholdDebugManyLiteral(b *testing.B) {
+	g := benchmarkGrip()
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		g.DebugMany(message.NewString("one"), message.NewString("two"))
+	}
+}
+
+// BenchmarkBelowThresholdDebugLazy builds the same message as
+// BenchmarkBelowThresholdDebugFieldsLiteral, but only calls the
+// function when the message is above the threshold, and does not
+// allocate below the threshold.
+func BenchmarkBelowThresholdDebugLazy(b *testing.B) {
+	g := benchmarkGrip()
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		g.DebugLazy(func() message.Composer {
+			return message.NewFields(level.Debug, message.Fields{"i": i})
+		})
+	}
+}
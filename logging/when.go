@@ -16,110 +16,128 @@ func (g *Grip) conditionalSend(conditional bool, m message.Composer) {
 	return
 }
 
+func (g *Grip) sendWhen(conditional bool, l level.Priority, m interface{}) {
+	if conditional {
+		g.send(l, m)
+	}
+}
+
+func (g *Grip) sendWhenf(conditional bool, l level.Priority, msg string, args []interface{}) {
+	if conditional {
+		g.sendf(l, msg, args)
+	}
+}
+
+func (g *Grip) sendWhenln(conditional bool, l level.Priority, msg []interface{}) {
+	if conditional {
+		g.sendln(l, msg)
+	}
+}
+
 /////////////
 
 func (g *Grip) LogWhen(conditional bool, l level.Priority, m interface{}) {
-	g.conditionalSend(conditional, message.ConvertToComposer(l, m))
+	g.sendWhen(conditional, l, m)
 }
 func (g *Grip) LogWhenln(conditional bool, l level.Priority, msg ...interface{}) {
-	g.conditionalSend(conditional, message.NewLineMessage(l, msg...))
+	g.sendWhenln(conditional, l, msg)
 }
 func (g *Grip) LogWhenf(conditional bool, l level.Priority, msg string, args ...interface{}) {
-	g.conditionalSend(conditional, message.NewFormattedMessage(l, msg, args...))
+	g.sendWhenf(conditional, l, msg, args)
 }
 
 /////////////
 
 func (g *Grip) EmergencyWhen(conditional bool, m interface{}) {
-	g.conditionalSend(conditional, message.ConvertToComposer(level.Emergency, m))
+	g.sendWhen(conditional, level.Emergency, m)
 }
 func (g *Grip) EmergencyWhenln(conditional bool, msg ...interface{}) {
-	g.conditionalSend(conditional, message.NewLineMessage(level.Emergency, msg...))
+	g.sendWhenln(conditional, level.Emergency, msg)
 }
 func (g *Grip) EmergencyWhenf(conditional bool, msg string, args ...interface{}) {
-	g.conditionalSend(conditional, message.NewFormattedMessage(level.Emergency, msg, args...))
+	g.sendWhenf(conditional, level.Emergency, msg, args)
 }
 
 /////////////
 
 func (g *Grip) AlertWhen(conditional bool, m interface{}) {
-	g.conditionalSend(conditional, message.ConvertToComposer(level.Alert, m))
+	g.sendWhen(conditional, level.Alert, m)
 }
 func (g *Grip) AlertWhenln(conditional bool, msg ...interface{}) {
-	g.conditionalSend(conditional, message.NewLineMessage(level.Alert, msg...))
+	g.sendWhenln(conditional, level.Alert, msg)
 }
 func (g *Grip) AlertWhenf(conditional bool, msg string, args ...interface{}) {
-	g.conditionalSend(conditional, message.NewFormattedMessage(level.Alert, msg, args...))
+	g.sendWhenf(conditional, level.Alert, msg, args)
 }
 
 /////////////
 
 func (g *Grip) CriticalWhen(conditional bool, m interface{}) {
-	g.conditionalSend(conditional, message.ConvertToComposer(level.Critical, m))
+	g.sendWhen(conditional, level.Critical, m)
 }
 func (g *Grip) CriticalWhenln(conditional bool, msg ...interface{}) {
-	g.conditionalSend(conditional, message.NewLineMessage(level.Critical, msg...))
+	g.sendWhenln(conditional, level.Critical, msg)
 }
 func (g *Grip) CriticalWhenf(conditional bool, msg string, args ...interface{}) {
-	g.conditionalSend(conditional, message.NewFormattedMessage(level.Critical, msg, args...))
+	g.sendWhenf(conditional, level.Critical, msg, args)
 }
 
 /////////////
 
 func (g *Grip) ErrorWhen(conditional bool, m interface{}) {
-	g.conditionalSend(conditional, message.ConvertToComposer(level.Critical, m))
+	g.sendWhen(conditional, level.Critical, m)
 }
 func (g *Grip) ErrorWhenln(conditional bool, msg ...interface{}) {
-	g.conditionalSend(conditional, message.NewLineMessage(level.Critical, msg...))
+	g.sendWhenln(conditional, level.Critical, msg)
 }
 func (g *Grip) ErrorWhenf(conditional bool, msg string, args ...interface{}) {
-	g.conditionalSend(conditional, message.NewFormattedMessage(level.Critical, msg, args...))
+	g.sendWhenf(conditional, level.Critical, msg, args)
 }
 
 /////////////
 
 func (g *Grip) WarningWhen(conditional bool, m interface{}) {
-	g.conditionalSend(conditional, message.ConvertToComposer(level.Warning, m))
+	g.sendWhen(conditional, level.Warning, m)
 }
 func (g *Grip) WarningWhenln(conditional bool, msg ...interface{}) {
-	g.conditionalSend(conditional, message.NewLineMessage(level.Warning, msg...))
+	g.sendWhenln(conditional, level.Warning, msg)
 }
 func (g *Grip) WarningWhenf(conditional bool, msg string, args ...interface{}) {
-	g.conditionalSend(conditional, message.NewFormattedMessage(level.Warning, msg, args...))
+	g.sendWhenf(conditional, level.Warning, msg, args)
 }
 
 /////////////
 
 func (g *Grip) NoticeWhen(conditional bool, m interface{}) {
-	g.conditionalSend(conditional, message.ConvertToComposer(level.Notice, m))
+	g.sendWhen(conditional, level.Notice, m)
 }
 func (g *Grip) NoticeWhenln(conditional bool, msg ...interface{}) {
-	g.conditionalSend(conditional, message.NewLineMessage(level.Notice, msg...))
+	g.sendWhenln(conditional, level.Notice, msg)
 }
 func (g *Grip) NoticeWhenf(conditional bool, msg string, args ...interface{}) {
-	g.conditionalSend(conditional, message.NewFormattedMessage(level.Notice, msg, args...))
+	g.sendWhenf(conditional, level.Notice, msg, args)
 }
 
 /////////////
 
 func (g *Grip) InfoWhen(conditional bool, m interface{}) {
-	g.conditionalSend(conditional, message.ConvertToComposer(level.Info, m))
+	g.sendWhen(conditional, level.Info, m)
 }
 func (g *Grip) InfoWhenln(conditional bool, msg ...interface{}) {
-	g.conditionalSend(conditional, message.NewLineMessage(level.Info, msg...))
+	g.sendWhenln(conditional, level.Info, msg)
 }
 func (g *Grip) InfoWhenf(conditional bool, msg string, args ...interface{}) {
-	g.conditionalSend(conditional, message.NewFormattedMessage(level.Info, msg, args...))
+	g.sendWhenf(conditional, level.Info, msg, args)
 }
 
 /////////////
 
 func (g *Grip) DebugWhen(conditional bool, m interface{}) {
-	g.conditionalSend(conditional, message.ConvertToComposer(level.Debug, m))
+	g.sendWhen(conditional, level.Debug, m)
 }
 func (g *Grip) DebugWhenln(conditional bool, msg ...interface{}) {
-	g.conditionalSend(conditional, message.NewLineMessage(level.Debug, msg...))
+	g.sendWhenln(conditional, level.Debug, msg)
 }
 func (g *Grip) DebugWhenf(conditional bool, msg string, args ...interface{}) {
-	g.conditionalSend(conditional, message.NewFormattedMessage(level.Debug, msg, args...))
+	g.sendWhenf(conditional, level.Debug, msg, args)
 }
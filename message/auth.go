@@ -0,0 +1,117 @@
+// Authentication Event Messages
+//
+// The authentication event composer provides a consistent record of
+// authentication attempts, suitable for intrusion detection. For
+// privacy, the subject of the event can be pseudonymized, so that
+// events for the same subject can be correlated without recording
+// the subject itself.
+package message
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/mongodb/grip/level"
+)
+
+type authEventMessage struct {
+	Subject       string `bson:"subject" json:"subject" yaml:"subject"`
+	Pseudonymized bool   `bson:"pseudonymized,omitempty" json:"pseudonymized,omitempty" yaml:"pseudonymized,omitempty"`
+	Method        string `bson:"method" json:"method" yaml:"method"`
+	Outcome       string `bson:"outcome" json:"outcome" yaml:"outcome"`
+	Reason        string `bson:"reason,omitempty" json:"reason,omitempty" yaml:"reason,omitempty"`
+	Base          `bson:"metadata" json:"metadata" yaml:"metadata"`
+}
+
+// NewAuthEvent constructs a Composer that records an authentication
+// attempt by the subject (e.g. a user name) using the method (e.g.
+// "oauth"), and for failed attempts, the reason (e.g.
+// "expired_token"). Failed attempts have Warning priority, otherwise
+// the message has Info priority. The message is not loggable if the
+// subject is empty.
+func NewAuthEvent(subject, method string, success bool, reason string) Composer {
+	m := &authEventMessage{
+		Subject: subject,
+		Method:  method,
+		Outcome: "success",
+		Reason:  reason,
+		Base:    newBase(),
+	}
+
+	if success {
+		_ = m.SetPriority(level.Info)
+	} else {
+		m.Outcome = "failure"
+		_ = m.SetPriority(level.Warning)
+	}
+
+	return m
+}
+
+// NewPseudonymizedAuthEvent is the same as NewAuthEvent, except that
+// the message records a pseudonym in place of the subject: the
+// hex-encoded HMAC-SHA256 of the subject, using the key. Events for
+// the same subject and key have the same pseudonym. Without a key,
+// the pseudonym is the SHA256 hash of the subject, which is easy to
+// reverse for predictable subjects, such as user names.
+func NewPseudonymizedAuthEvent(key []byte, subject, method string, success bool, reason string) Composer {
+	m := NewAuthEvent(subject, method, success, reason).(*authEventMessage)
+	if subject == "" {
+		return m
+	}
+
+	m.Subject = pseudonym(key, subject)
+	m.Pseudonymized = true
+
+	return m
+}
+
+func (m *authEventMessage) Loggable() bool { return m.Subject != "" }
+
+func (m *authEventMessage) String() string {
+	if !m.Loggable() {
+		return ""
+	}
+
+	outcome := "succeeded"
+	if m.Outcome == "failure" {
+		outcome = "failed"
+	}
+
+	out := fmt.Sprintf("auth %s for %s via %s", outcome, m.Subject, m.Method)
+	if m.Reason != "" {
+		out += ": " + m.Reason
+	}
+
+	return out
+}
+
+func (m *authEventMessage) Raw() interface{} {
+	_ = m.Collect()
+	return &authEventMessage{
+		Subject:       m.Subject,
+		Pseudonymized: m.Pseudonymized,
+		Method:        m.Method,
+		Outcome:       m.Outcome,
+		Reason:        m.Reason,
+		Base:          m.snapshot(),
+	}
+}
+
+// pseudonym returns the hex-encoded HMAC-SHA256 of the value using
+// the key, or without a key, the SHA256 hash of the value.
+func pseudonym(key []byte, value string) string {
+	var sum []byte
+	if len(key) == 0 {
+		hash := sha256.Sum256([]byte(value))
+		sum = hash[:]
+	} else {
+		mac := hmac.New(sha256.New, key)
+		_, _ = mac.Write([]byte(value))
+		sum = mac.Sum(nil)
+	}
+
+	return hex.EncodeToString(sum)
+}
@@ -0,0 +1,77 @@
+// Authorization Decision Messages
+//
+// The authorization decision composer provides a consistent record of
+// the decisions that access control policies make, suitable for
+// access reviews and auditing.
+package message
+
+import (
+	"fmt"
+
+	"github.com/mongodb/grip/level"
+)
+
+type authzDecisionMessage struct {
+	Subject  string `bson:"subject" json:"subject" yaml:"subject"`
+	Action   string `bson:"action" json:"action" yaml:"action"`
+	Resource string `bson:"resource" json:"resource" yaml:"resource"`
+	Decision string `bson:"decision" json:"decision" yaml:"decision"`
+	Policy   string `bson:"policy,omitempty" json:"policy,omitempty" yaml:"policy,omitempty"`
+	Base     `bson:"metadata" json:"metadata" yaml:"metadata"`
+}
+
+// NewAuthzDecision constructs a Composer that records whether the
+// policy allowed the subject (e.g. a user name) to perform the action
+// (e.g. "delete") on the resource. Denied requests have Warning
+// priority, otherwise the message has Info priority. The message is
+// not loggable if the subject or the resource is empty.
+func NewAuthzDecision(subject, action, resource string, allowed bool, policy string) Composer {
+	m := &authzDecisionMessage{
+		Subject:  subject,
+		Action:   action,
+		Resource: resource,
+		Decision: "allow",
+		Policy:   policy,
+		Base:     newBase(),
+	}
+
+	if allowed {
+		_ = m.SetPriority(level.Info)
+	} else {
+		m.Decision = "deny"
+		_ = m.SetPriority(level.Warning)
+	}
+
+	return m
+}
+
+func (m *authzDecisionMessage) Loggable() bool { return m.Subject != "" && m.Resource != "" }
+
+func (m *authzDecisionMessage) String() string {
+	if !m.Loggable() {
+		return ""
+	}
+
+	decision := "allowed"
+	if m.Decision == "deny" {
+		decision = "denied"
+	}
+
+	if m.Policy == "" {
+		return fmt.Sprintf("%s %s %s on %s", decision, m.Subject, m.Action, m.Resource)
+	}
+
+	return fmt.Sprintf("%s %s %s on %s (policy: %s)", decision, m.Subject, m.Action, m.Resource, m.Policy)
+}
+
+func (m *authzDecisionMessage) Raw() interface{} {
+	_ = m.Collect()
+	return &authzDecisionMessage{
+		Subject:  m.Subject,
+		Action:   m.Action,
+		Resource: m.Resource,
+		Decision: m.Decision,
+		Policy:   m.Policy,
+		Base:     m.snapshot(),
+	}
+}
@@ -3,6 +3,7 @@ package message
 import (
 	"fmt"
 	"os"
+	"sync"
 	"time"
 
 	"github.com/mongodb/grip/level"
@@ -12,18 +13,43 @@ import (
 // aspects of a message.Composer. Additionally the Collect() method
 // collects some simple metadata, that may be useful for some more
 // structured logging applications.
+//
+// The methods of Base are safe for concurrent use, so that senders
+// may annotate and render the same message from multiple
+// goroutines. Annotate never modifies the Context map in place, and
+// Composers that return themselves from Raw() should return a copy
+// made with the snapshot method instead.
 type Base struct {
 	Level    level.Priority `bson:"level,omitempty" json:"level,omitempty" yaml:"level,omitempty"`
 	Hostname string         `bson:"hostname,omitempty" json:"hostname,omitempty" yaml:"hostname,omitempty"`
 	Time     time.Time      `bson:"time,omitempty" json:"time,omitempty" yaml:"time,omitempty"`
 	Process  string         `bson:"process,omitempty" json:"process,omitempty" yaml:"process,omitempty"`
 	Logger   string         `bson:"logger,omitempty" json:"logger,omitempty" yaml:"logger,omitempty"`
+	Context  Fields         `bson:"context,omitempty" json:"context,omitempty" yaml:"context,omitempty"`
+	mutex    sync.RWMutex
 }
 
-// Collect records the time, process name, and hostname. Useful in the
-// context of a Raw() method.
+// newBase returns a Base that records the current time, so that the
+// timestamp of the message reflects when the message was created
+// rather than when a sender rendered it.
+func newBase() Base { return Base{Time: time.Now()} }
+
+// Collect records the process name and hostname, and the time, if
+// the message did not record a time when it was created. Useful in
+// the context of a Raw() method.
 func (b *Base) Collect() error {
-	if !b.Time.IsZero() {
+	b.mutex.RLock()
+	collected := b.Process != ""
+	b.mutex.RUnlock()
+
+	if collected {
+		return nil
+	}
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if b.Process != "" {
 		return nil
 	}
 
@@ -33,14 +59,30 @@ func (b *Base) Collect() error {
 		return err
 	}
 
-	b.Time = time.Now()
+	if b.Time.IsZero() {
+		b.Time = time.Now()
+	}
 	b.Process = os.Args[0]
 
 	return nil
 }
 
+// Timestamp returns the time that the message was created. Messages
+// that embed a Base without using a constructor from this package
+// report the time of the first call to Collect, or the zero time if
+// Collect has not been called.
+func (b *Base) Timestamp() time.Time {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+
+	return b.Time
+}
+
 // Priority returns the configured priority of the message.
 func (b *Base) Priority() level.Priority {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+
 	return b.Level
 }
 
@@ -51,7 +93,51 @@ func (b *Base) SetPriority(l level.Priority) error {
 		return fmt.Errorf("%s (%d) is not a valid priority", l, l)
 	}
 
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
 	b.Level = l
 
 	return nil
 }
+
+// Annotate adds the key and value to the message's Context. Returns
+// an error if the key already exists in the Context.
+func (b *Base) Annotate(key string, value interface{}) error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if _, ok := b.Context[key]; ok {
+		return fmt.Errorf("key '%s' already exists", key)
+	}
+
+	b.Context = b.Context.with(key, value)
+
+	return nil
+}
+
+// Annotation returns the value of the key in the message's Context,
+// and whether the Context has the key.
+func (b *Base) Annotation(key string) (interface{}, bool) {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+
+	value, ok := b.Context[key]
+	return value, ok
+}
+
+// snapshot returns a copy of the Base, which does not change when
+// the message is annotated or its priority changes.
+func (b *Base) snapshot() Base {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+
+	return Base{
+		Level:    b.Level,
+		Hostname: b.Hostname,
+		Time:     b.Time,
+		Process:  b.Process,
+		Logger:   b.Logger,
+		Context:  b.Context,
+	}
+}
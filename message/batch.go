@@ -0,0 +1,98 @@
+// Batch Summary Messages
+//
+// The batch summary composer provides a consistent record of jobs
+// that process records in batches, such as imports and ETL jobs,
+// with the counts of records that the job processed, the success
+// rate, and the throughput.
+package message
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/mongodb/grip/level"
+)
+
+type batchSummaryMessage struct {
+	Operation   string  `bson:"operation" json:"operation" yaml:"operation"`
+	Total       int64   `bson:"total" json:"total" yaml:"total"`
+	Succeeded   int64   `bson:"succeeded" json:"succeeded" yaml:"succeeded"`
+	Failed      int64   `bson:"failed" json:"failed" yaml:"failed"`
+	SuccessRate float64 `bson:"success_rate" json:"success_rate" yaml:"success_rate"`
+	Throughput  float64 `bson:"records_per_second" json:"records_per_second" yaml:"records_per_second"`
+	DurationMS  float64 `bson:"duration_ms" json:"duration_ms" yaml:"duration_ms"`
+	Base        `bson:"metadata" json:"metadata" yaml:"metadata"`
+
+	duration time.Duration
+}
+
+// NewBatchSummary constructs a Composer that summarizes a batch
+// operation (e.g. "imported") that processed the total number of
+// records in the duration, of which some succeeded and some failed.
+// The raw form includes the success rate, the fraction of the total
+// that succeeded, and the throughput, in records per second. The
+// message has Warning priority if any records failed, and Info
+// priority otherwise, and is only loggable if the total is positive.
+func NewBatchSummary(operation string, total, succeeded, failed int64, duration time.Duration) Composer {
+	m := &batchSummaryMessage{
+		Operation:  operation,
+		Total:      total,
+		Succeeded:  succeeded,
+		Failed:     failed,
+		DurationMS: float64(duration) / float64(time.Millisecond),
+		duration:   duration,
+		Base:       newBase(),
+	}
+
+	if total > 0 {
+		m.SuccessRate = float64(succeeded) / float64(total)
+	}
+
+	if total > 0 && duration > 0 {
+		m.Throughput = float64(total) / duration.Seconds()
+	}
+
+	if failed > 0 {
+		_ = m.SetPriority(level.Warning)
+	} else {
+		_ = m.SetPriority(level.Info)
+	}
+
+	return m
+}
+
+func (m *batchSummaryMessage) Loggable() bool { return m.Total > 0 }
+
+func (m *batchSummaryMessage) String() string {
+	if !m.Loggable() {
+		return ""
+	}
+
+	return fmt.Sprintf("%s %d (%d ok, %d failed) in %s",
+		m.Operation, m.Total, m.Succeeded, m.Failed, batchDuration(m.duration))
+}
+
+func (m *batchSummaryMessage) Raw() interface{} {
+	_ = m.Collect()
+	return &batchSummaryMessage{
+		Operation:   m.Operation,
+		Total:       m.Total,
+		Succeeded:   m.Succeeded,
+		Failed:      m.Failed,
+		SuccessRate: m.SuccessRate,
+		Throughput:  m.Throughput,
+		DurationMS:  m.DurationMS,
+		Base:        m.snapshot(),
+		duration:    m.duration,
+	}
+}
+
+// batchDuration rounds durations of a second or more to a tenth of a
+// second, and shorter durations to the millisecond.
+func batchDuration(d time.Duration) string {
+	if d >= time.Second {
+		return d.Round(100 * time.Millisecond).String()
+	}
+
+	return d.Round(time.Millisecond).String()
+}
@@ -0,0 +1,80 @@
+// Breaker Transition Messages
+//
+// The breaker transition composer provides a consistent record of
+// circuit breaker state changes, so that breakers trip and recover
+// with uniform log messages.
+package message
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mongodb/grip/level"
+)
+
+type breakerTransitionMessage struct {
+	Breaker      string `bson:"breaker" json:"breaker" yaml:"breaker"`
+	From         string `bson:"from" json:"from" yaml:"from"`
+	To           string `bson:"to" json:"to" yaml:"to"`
+	FailureCount int    `bson:"failure_count" json:"failure_count" yaml:"failure_count"`
+	Base         `bson:"metadata" json:"metadata" yaml:"metadata"`
+}
+
+// NewBreakerTransition constructs a Composer that records the
+// transition of the named circuit breaker from one state (e.g.
+// "closed", "open", or "half-open") to another, along with the number
+// of failures that the breaker observed. Transitions to the "open"
+// state have Warning priority, otherwise the message has Info
+// priority. The message is not loggable if the name is empty.
+func NewBreakerTransition(name, from, to string, failureCount int) Composer {
+	m := &breakerTransitionMessage{
+		Breaker:      name,
+		From:         from,
+		To:           to,
+		FailureCount: failureCount,
+		Base:         newBase(),
+	}
+
+	if strings.EqualFold(to, "open") {
+		_ = m.SetPriority(level.Warning)
+	} else {
+		_ = m.SetPriority(level.Info)
+	}
+
+	return m
+}
+
+func (m *breakerTransitionMessage) Loggable() bool { return m.Breaker != "" }
+
+func (m *breakerTransitionMessage) String() string {
+	if !m.Loggable() {
+		return ""
+	}
+
+	switch strings.ToLower(m.To) {
+	case "open":
+		failures := "failures"
+		if m.FailureCount == 1 {
+			failures = "failure"
+		}
+
+		return fmt.Sprintf("breaker %s opened after %d %s", m.Breaker, m.FailureCount, failures)
+	case "closed":
+		return fmt.Sprintf("breaker %s closed", m.Breaker)
+	case "half-open":
+		return fmt.Sprintf("breaker %s half-opened", m.Breaker)
+	default:
+		return fmt.Sprintf("breaker %s changed from %s to %s", m.Breaker, m.From, m.To)
+	}
+}
+
+func (m *breakerTransitionMessage) Raw() interface{} {
+	_ = m.Collect()
+	return &breakerTransitionMessage{
+		Breaker:      m.Breaker,
+		From:         m.From,
+		To:           m.To,
+		FailureCount: m.FailureCount,
+		Base:         m.snapshot(),
+	}
+}
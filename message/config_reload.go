@@ -0,0 +1,70 @@
+// Config Reload Messages
+//
+// The config reload composer provides a consistent record of
+// configuration reloads, including the keys that changed, so that
+// configuration drift can be audited.
+package message
+
+import (
+	"fmt"
+
+	"github.com/mongodb/grip/level"
+)
+
+type configReloadMessage struct {
+	Source      string   `bson:"source" json:"source" yaml:"source"`
+	ChangedKeys []string `bson:"changed_keys" json:"changed_keys" yaml:"changed_keys"`
+	Error       string   `bson:"error,omitempty" json:"error,omitempty" yaml:"error,omitempty"`
+	Base        `bson:"metadata" json:"metadata" yaml:"metadata"`
+}
+
+// NewConfigReload constructs a Composer that records a reload of the
+// configuration from the source (e.g. a file path), with the keys
+// that changed. Messages for failed reloads have Error priority,
+// otherwise the message has Info priority. The message is not
+// loggable if the source is empty.
+func NewConfigReload(source string, changedKeys []string, err error) Composer {
+	m := &configReloadMessage{
+		Source:      source,
+		ChangedKeys: append([]string{}, changedKeys...),
+		Base:        newBase(),
+	}
+
+	if err = nonNilError(err); err != nil {
+		m.Error = err.Error()
+		_ = m.SetPriority(level.Error)
+	} else {
+		_ = m.SetPriority(level.Info)
+	}
+
+	return m
+}
+
+func (m *configReloadMessage) Loggable() bool { return m.Source != "" }
+
+func (m *configReloadMessage) String() string {
+	if !m.Loggable() {
+		return ""
+	}
+
+	if m.Error != "" {
+		return fmt.Sprintf("failed to reload config from %s: %s", m.Source, m.Error)
+	}
+
+	keys := "keys"
+	if len(m.ChangedKeys) == 1 {
+		keys = "key"
+	}
+
+	return fmt.Sprintf("reloaded config from %s: %d %s changed", m.Source, len(m.ChangedKeys), keys)
+}
+
+func (m *configReloadMessage) Raw() interface{} {
+	_ = m.Collect()
+	return &configReloadMessage{
+		Source:      m.Source,
+		ChangedKeys: append([]string{}, m.ChangedKeys...),
+		Error:       m.Error,
+		Base:        m.snapshot(),
+	}
+}
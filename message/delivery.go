@@ -0,0 +1,101 @@
+// Delivery Attempt Messages
+//
+// The delivery attempt composer provides a consistent record of
+// attempts to deliver outbound webhooks or notifications, so that the
+// reliability of each endpoint can be tracked.
+package message
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/mongodb/grip/level"
+)
+
+// deliveryErrorAttempts is the number of the attempt at which failed
+// deliveries escalate from Warning to Error priority.
+const deliveryErrorAttempts = 3
+
+type deliveryAttemptMessage struct {
+	Endpoint   string `bson:"endpoint" json:"endpoint" yaml:"endpoint"`
+	StatusCode int    `bson:"status_code,omitempty" json:"status_code,omitempty" yaml:"status_code,omitempty"`
+	Attempt    int    `bson:"attempt" json:"attempt" yaml:"attempt"`
+	DurationMS int64  `bson:"duration_ms" json:"duration_ms" yaml:"duration_ms"`
+	Delivered  bool   `bson:"delivered" json:"delivered" yaml:"delivered"`
+	Error      string `bson:"error,omitempty" json:"error,omitempty" yaml:"error,omitempty"`
+	Base       `bson:"metadata" json:"metadata" yaml:"metadata"`
+
+	duration time.Duration
+}
+
+// NewDeliveryAttempt constructs a Composer that records an attempt to
+// deliver a request to the endpoint, with the HTTP status code of the
+// response (or 0 if there was no response), the number of the attempt
+// (starting at 1), and the time the attempt took. The delivery
+// succeeded if there is no error and the status code is 2xx.
+// Successful deliveries have Info priority; failed deliveries have
+// Warning priority, and Error priority from the third attempt. The
+// message is not loggable if the endpoint is empty.
+func NewDeliveryAttempt(endpoint string, statusCode, attempt int, duration time.Duration, err error) Composer {
+	m := &deliveryAttemptMessage{
+		Endpoint:   endpoint,
+		StatusCode: statusCode,
+		Attempt:    attempt,
+		DurationMS: int64(duration / time.Millisecond),
+		duration:   duration,
+		Base:       newBase(),
+	}
+
+	if err = nonNilError(err); err != nil {
+		m.Error = err.Error()
+	}
+	m.Delivered = err == nil && statusCode >= 200 && statusCode < 300
+
+	switch {
+	case m.Delivered:
+		_ = m.SetPriority(level.Info)
+	case attempt >= deliveryErrorAttempts:
+		_ = m.SetPriority(level.Error)
+	default:
+		_ = m.SetPriority(level.Warning)
+	}
+
+	return m
+}
+
+func (m *deliveryAttemptMessage) Loggable() bool { return m.Endpoint != "" }
+
+func (m *deliveryAttemptMessage) String() string {
+	if !m.Loggable() {
+		return ""
+	}
+
+	if m.Delivered {
+		return fmt.Sprintf("delivered to %s (%d) in %s", m.Endpoint, m.StatusCode, m.duration)
+	}
+
+	out := fmt.Sprintf("delivery to %s failed on attempt %d", m.Endpoint, m.Attempt)
+	if m.StatusCode != 0 {
+		out += fmt.Sprintf(" (%d)", m.StatusCode)
+	}
+	out += fmt.Sprintf(" after %s", m.duration)
+	if m.Error != "" {
+		out += ": " + m.Error
+	}
+
+	return out
+}
+
+func (m *deliveryAttemptMessage) Raw() interface{} {
+	_ = m.Collect()
+	return &deliveryAttemptMessage{
+		Endpoint:   m.Endpoint,
+		StatusCode: m.StatusCode,
+		Attempt:    m.Attempt,
+		DurationMS: m.DurationMS,
+		Delivered:  m.Delivered,
+		Error:      m.Error,
+		Base:       m.snapshot(),
+		duration:   m.duration,
+	}
+}
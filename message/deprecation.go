@@ -0,0 +1,117 @@
+// Deprecation Messages
+//
+// The deprecation composer reports the use of a deprecated feature,
+// and records the call site that constructed the message. To avoid
+// flooding logs with repeated warnings, only the first message from
+// each call site is loggable.
+package message
+
+import (
+	"fmt"
+	"path/filepath"
+	"runtime"
+	"sync"
+
+	"github.com/mongodb/grip/level"
+)
+
+var deprecationCallSites = struct {
+	seen  map[string]struct{}
+	mutex sync.Mutex
+}{seen: map[string]struct{}{}}
+
+// ResetDeprecations clears the record of call sites that have logged
+// deprecation messages, so that the next deprecation message from
+// every call site is loggable. Typically used in tests.
+func ResetDeprecations() {
+	deprecationCallSites.mutex.Lock()
+	defer deprecationCallSites.mutex.Unlock()
+
+	deprecationCallSites.seen = map[string]struct{}{}
+}
+
+// claimDeprecationCallSite returns true the first time it's called for
+// a call site, and false subsequently.
+func claimDeprecationCallSite(caller string) bool {
+	deprecationCallSites.mutex.Lock()
+	defer deprecationCallSites.mutex.Unlock()
+
+	if _, ok := deprecationCallSites.seen[caller]; ok {
+		return false
+	}
+
+	deprecationCallSites.seen[caller] = struct{}{}
+
+	return true
+}
+
+type deprecationMessage struct {
+	Feature     string `bson:"feature" json:"feature" yaml:"feature"`
+	Replacement string `bson:"replacement,omitempty" json:"replacement,omitempty" yaml:"replacement,omitempty"`
+	Caller      string `bson:"caller" json:"caller" yaml:"caller"`
+	Base        `bson:"metadata" json:"metadata" yaml:"metadata"`
+
+	loggable bool
+	once     sync.Once
+}
+
+// NewDeprecation constructs a Warning priority Composer that reports
+// the use of a deprecated feature, and the replacement for the
+// feature, if any. The message records the file and line number of
+// the caller of NewDeprecation.
+//
+// The first message from each call site is loggable, and subsequent
+// messages from the same call site are not, so the warning is
+// reported once per call site. A call site is only considered to have
+// reported its warning once a sender checks whether the message is
+// loggable; messages that are below the threshold of the logger do
+// not count. Use ResetDeprecations to clear the record of call sites.
+// Messages without a feature are never loggable.
+func NewDeprecation(feature, replacement string) Composer {
+	m := &deprecationMessage{
+		Feature:     feature,
+		Replacement: replacement,
+		Base:        newBase(),
+	}
+
+	if _, file, line, ok := runtime.Caller(1); ok {
+		dir, fileName := filepath.Split(file)
+		m.Caller = fmt.Sprintf("%s:%d", filepath.Join(filepath.Base(dir), fileName), line)
+	}
+
+	_ = m.SetPriority(level.Warning)
+
+	return m
+}
+
+func (m *deprecationMessage) Loggable() bool {
+	if m.Feature == "" {
+		return false
+	}
+
+	m.once.Do(func() { m.loggable = claimDeprecationCallSite(m.Caller) })
+
+	return m.loggable
+}
+
+func (m *deprecationMessage) String() string {
+	if m.Feature == "" {
+		return ""
+	}
+
+	if m.Replacement == "" {
+		return fmt.Sprintf("%s is deprecated [%s]", m.Feature, m.Caller)
+	}
+
+	return fmt.Sprintf("%s is deprecated, use %s instead [%s]", m.Feature, m.Replacement, m.Caller)
+}
+
+func (m *deprecationMessage) Raw() interface{} {
+	_ = m.Collect()
+	return &deprecationMessage{
+		Feature:     m.Feature,
+		Replacement: m.Replacement,
+		Caller:      m.Caller,
+		Base:        m.snapshot(),
+	}
+}
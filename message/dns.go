@@ -0,0 +1,101 @@
+// DNS Lookup Messages
+//
+// The DNS lookup composer records the result of resolving a host
+// name, for use in custom resolvers, so that slow and failed lookups
+// can be found when diagnosing intermittent resolution failures.
+package message
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mongodb/grip/level"
+)
+
+// DefaultDNSSlowThreshold is the duration at which DNS lookup messages
+// are slow lookups by default.
+const DefaultDNSSlowThreshold = 100 * time.Millisecond
+
+// DNSLookupOptions configures the priority of DNS lookup messages.
+type DNSLookupOptions struct {
+	// SlowThreshold is the duration at which successful lookups are
+	// Warning messages, rather than Info messages. Defaults to
+	// DefaultDNSSlowThreshold.
+	SlowThreshold time.Duration
+}
+
+type dnsLookupMessage struct {
+	Host      string   `bson:"host" json:"host" yaml:"host"`
+	Addresses []string `bson:"addrs" json:"addrs" yaml:"addrs"`
+	LatencyMS float64  `bson:"latency_ms" json:"latency_ms" yaml:"latency_ms"`
+	Error     string   `bson:"error,omitempty" json:"error,omitempty" yaml:"error,omitempty"`
+	Base      `bson:"metadata" json:"metadata" yaml:"metadata"`
+
+	duration time.Duration
+}
+
+// NewDNSLookup constructs a Composer that records the lookup of the
+// host, with the addresses that it resolved to, how long the lookup
+// took, and its error, if it failed. Failed lookups are Error
+// messages, lookups that took at least DefaultDNSSlowThreshold are
+// Warning messages, and other lookups are Info messages. The message
+// is not loggable if the host is empty.
+func NewDNSLookup(host string, addrs []string, duration time.Duration, err error) Composer {
+	return NewDNSLookupWithOptions(host, addrs, duration, err, DNSLookupOptions{})
+}
+
+// NewDNSLookupWithOptions is the same as NewDNSLookup, but uses the
+// slow threshold of the options.
+func NewDNSLookupWithOptions(host string, addrs []string, duration time.Duration, err error, opts DNSLookupOptions) Composer {
+	threshold := opts.SlowThreshold
+	if threshold <= 0 {
+		threshold = DefaultDNSSlowThreshold
+	}
+
+	m := &dnsLookupMessage{
+		Host:      host,
+		Addresses: append([]string{}, addrs...),
+		LatencyMS: float64(duration) / float64(time.Millisecond),
+		duration:  duration,
+		Base:      newBase(),
+	}
+
+	switch {
+	case err != nil:
+		m.Error = err.Error()
+		_ = m.SetPriority(level.Error)
+	case duration >= threshold:
+		_ = m.SetPriority(level.Warning)
+	default:
+		_ = m.SetPriority(level.Info)
+	}
+
+	return m
+}
+
+func (m *dnsLookupMessage) Loggable() bool { return m.Host != "" }
+
+func (m *dnsLookupMessage) String() string {
+	if !m.Loggable() {
+		return ""
+	}
+
+	if m.Error != "" {
+		return fmt.Sprintf("failed to resolve %s in %s: %s", m.Host, m.duration.Round(time.Millisecond), m.Error)
+	}
+
+	return fmt.Sprintf("resolved %s → [%s] in %s", m.Host, strings.Join(m.Addresses, ", "), m.duration.Round(time.Millisecond))
+}
+
+func (m *dnsLookupMessage) Raw() interface{} {
+	_ = m.Collect()
+	return &dnsLookupMessage{
+		Host:      m.Host,
+		Addresses: append([]string{}, m.Addresses...),
+		LatencyMS: m.LatencyMS,
+		Error:     m.Error,
+		Base:      m.snapshot(),
+		duration:  m.duration,
+	}
+}
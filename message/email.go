@@ -0,0 +1,119 @@
+// Email Result Messages
+//
+// The email result composer provides a consistent record of the
+// outcome of sending an email from an application, such as a
+// transactional email sent through a mail provider. Unlike the SMTP
+// sender, which sends log messages as email, this composer logs that
+// the application sent email. For privacy, the recipient of the email
+// can be pseudonymized.
+package message
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/mongodb/grip/level"
+)
+
+type emailResultMessage struct {
+	Recipient     string `bson:"recipient" json:"recipient" yaml:"recipient"`
+	Pseudonymized bool   `bson:"pseudonymized,omitempty" json:"pseudonymized,omitempty" yaml:"pseudonymized,omitempty"`
+	Template      string `bson:"template" json:"template" yaml:"template"`
+	MessageID     string `bson:"message_id,omitempty" json:"message_id,omitempty" yaml:"message_id,omitempty"`
+	Error         string `bson:"error,omitempty" json:"error,omitempty" yaml:"error,omitempty"`
+	Base          `bson:"metadata" json:"metadata" yaml:"metadata"`
+}
+
+// NewEmailResult constructs a Composer that records the outcome of
+// sending the email, rendered from the template, to the recipient.
+// The message ID is the identifier that the mail provider assigned to
+// the email, if any. Failed sends have Error priority, otherwise the
+// message has Info priority. The string form of the message masks the
+// recipient's address (e.g. "a***@example.com"), but the raw form
+// records the full address; use NewPseudonymizedEmailResult to omit
+// it. The message is not loggable if the recipient is empty.
+func NewEmailResult(to, template, messageID string, err error) Composer {
+	m := &emailResultMessage{
+		Recipient: to,
+		Template:  template,
+		MessageID: messageID,
+		Base:      newBase(),
+	}
+
+	if err = nonNilError(err); err != nil {
+		m.Error = err.Error()
+		_ = m.SetPriority(level.Error)
+	} else {
+		_ = m.SetPriority(level.Info)
+	}
+
+	return m
+}
+
+// NewPseudonymizedEmailResult is the same as NewEmailResult, except
+// that the message records a pseudonym in place of the recipient,
+// computed in the same way as for NewPseudonymizedAuthEvent.
+func NewPseudonymizedEmailResult(key []byte, to, template, messageID string, err error) Composer {
+	m := NewEmailResult(to, template, messageID, err).(*emailResultMessage)
+	if to == "" {
+		return m
+	}
+
+	m.Recipient = pseudonym(key, to)
+	m.Pseudonymized = true
+
+	return m
+}
+
+func (m *emailResultMessage) Loggable() bool { return m.Recipient != "" }
+
+func (m *emailResultMessage) String() string {
+	if !m.Loggable() {
+		return ""
+	}
+
+	recipient := m.Recipient
+	if !m.Pseudonymized {
+		recipient = maskAddress(recipient)
+	}
+
+	if m.Error != "" {
+		return fmt.Sprintf("failed to send %s to %s: %s", m.Template, recipient, m.Error)
+	}
+
+	out := fmt.Sprintf("sent %s to %s", m.Template, recipient)
+	if m.MessageID != "" {
+		out += fmt.Sprintf(" (id %s)", m.MessageID)
+	}
+
+	return out
+}
+
+func (m *emailResultMessage) Raw() interface{} {
+	_ = m.Collect()
+	return &emailResultMessage{
+		Recipient:     m.Recipient,
+		Pseudonymized: m.Pseudonymized,
+		Template:      m.Template,
+		MessageID:     m.MessageID,
+		Error:         m.Error,
+		Base:          m.snapshot(),
+	}
+}
+
+// maskAddress hides all but the first character of the local part of
+// an email address.
+func maskAddress(addr string) string {
+	local, domain := addr, ""
+	if idx := strings.LastIndex(addr, "@"); idx >= 0 {
+		local, domain = addr[:idx], addr[idx:]
+	}
+
+	if local == "" {
+		return "***" + domain
+	}
+
+	_, size := utf8.DecodeRuneInString(local)
+	return local[:size] + "***" + domain
+}
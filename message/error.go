@@ -23,7 +23,8 @@ type errorMessage struct {
 // non-nil.
 func NewErrorMessage(p level.Priority, err error) Composer {
 	m := &errorMessage{
-		err: err,
+		err:  nonNilError(err),
+		Base: newBase(),
 	}
 
 	_ = m.SetPriority(p)
@@ -34,7 +35,7 @@ func NewErrorMessage(p level.Priority, err error) Composer {
 // without the requirement to specify priority, which you may wish to
 // specify directly.
 func NewError(err error) Composer {
-	return &errorMessage{err: err}
+	return &errorMessage{err: nonNilError(err), Base: newBase()}
 }
 
 func (e *errorMessage) String() string {
@@ -45,6 +46,9 @@ func (e *errorMessage) String() string {
 	return e.Error
 }
 
+// Unwrap returns the error that the message renders.
+func (e *errorMessage) Unwrap() error { return e.err }
+
 func (e *errorMessage) Loggable() bool {
 	return e.err != nil
 }
@@ -58,5 +62,10 @@ func (e *errorMessage) Raw() interface{} {
 		e.Extended = extended
 	}
 
-	return e
+	return &errorMessage{
+		err:      e.err,
+		Error:    e.Error,
+		Extended: e.Extended,
+		Base:     e.snapshot(),
+	}
 }
@@ -25,7 +25,8 @@ func NewErrorWrapMessage(p level.Priority, err error, base string, args ...inter
 	m := &errorWrapMessage{
 		base: base,
 		args: args,
-		err:  err,
+		err:  nonNilError(err),
+		Base: newBase(),
 	}
 
 	_ = m.SetPriority(p)
@@ -42,7 +43,8 @@ func NewErrorWrap(err error, base string, args ...interface{}) Composer {
 	return &errorWrapMessage{
 		base: base,
 		args: args,
-		err:  err,
+		err:  nonNilError(err),
+		Base: newBase(),
 	}
 }
 
@@ -74,9 +76,17 @@ func (m *errorWrapMessage) Raw() interface{} {
 	_ = m.String()
 	_ = m.Collect()
 
-	return m
+	return &errorWrapMessage{
+		err:      m.err,
+		Message:  m.Message,
+		Extended: m.Extended,
+		Base:     m.snapshot(),
+	}
 }
 
+// Unwrap returns the error that the message wraps.
+func (m *errorWrapMessage) Unwrap() error { return m.err }
+
 func (m *errorWrapMessage) Loggable() bool {
 	return m.err != nil
 }
@@ -0,0 +1,82 @@
+// Cache Eviction Messages
+//
+// The eviction composer provides a consistent record of entries that
+// in-memory caches evict, to help tune the size and expiry of the
+// caches.
+package message
+
+import (
+	"fmt"
+
+	"github.com/mongodb/grip/level"
+)
+
+// evictionReasons describes the reasons that caches evict entries in
+// the string form of eviction messages.
+var evictionReasons = map[string]string{
+	"size": "size pressure",
+	"ttl":  "expired",
+}
+
+type evictionMessage struct {
+	Cache   string `bson:"cache" json:"cache" yaml:"cache"`
+	Evicted int    `bson:"evicted" json:"evicted" yaml:"evicted"`
+	Reason  string `bson:"reason,omitempty" json:"reason,omitempty" yaml:"reason,omitempty"`
+	Size    int    `bson:"size" json:"size" yaml:"size"`
+	Base    `bson:"metadata" json:"metadata" yaml:"metadata"`
+}
+
+// NewEviction constructs a Composer that records that the cache
+// evicted a number of entries for the reason (e.g. "size", "ttl", or
+// "manual"), leaving the cache with the new number of entries.
+// Evictions because the cache reached its size limit, with the reason
+// "size", have Warning priority, and other evictions have Info
+// priority. The message is only loggable if the cache name is not
+// empty.
+func NewEviction(cache string, evicted int, reason string, newSize int) Composer {
+	m := &evictionMessage{
+		Cache:   cache,
+		Evicted: evicted,
+		Reason:  reason,
+		Size:    newSize,
+		Base:    newBase(),
+	}
+
+	if reason == "size" {
+		_ = m.SetPriority(level.Warning)
+	} else {
+		_ = m.SetPriority(level.Info)
+	}
+
+	return m
+}
+
+func (m *evictionMessage) Loggable() bool { return m.Cache != "" }
+
+func (m *evictionMessage) String() string {
+	if !m.Loggable() {
+		return ""
+	}
+
+	reason := m.Reason
+	if described, ok := evictionReasons[reason]; ok {
+		reason = described
+	}
+
+	if reason == "" {
+		return fmt.Sprintf("evicted %d from %s, now %d", m.Evicted, m.Cache, m.Size)
+	}
+
+	return fmt.Sprintf("evicted %d from %s (%s), now %d", m.Evicted, m.Cache, reason, m.Size)
+}
+
+func (m *evictionMessage) Raw() interface{} {
+	_ = m.Collect()
+	return &evictionMessage{
+		Cache:   m.Cache,
+		Evicted: m.Evicted,
+		Reason:  m.Reason,
+		Size:    m.Size,
+		Base:    m.snapshot(),
+	}
+}
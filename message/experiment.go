@@ -0,0 +1,74 @@
+// Experiment Exposure Messages
+//
+// The experiment exposure composer records that a subject (e.g. a
+// user) was exposed to a variant of an experiment, in a consistent
+// form, so that experiment metrics can be computed from the logs. As
+// with authentication events, the subject can be pseudonymized.
+package message
+
+import (
+	"fmt"
+
+	"github.com/mongodb/grip/level"
+)
+
+type experimentExposureMessage struct {
+	Experiment    string `bson:"experiment" json:"experiment" yaml:"experiment"`
+	Variant       string `bson:"variant" json:"variant" yaml:"variant"`
+	Subject       string `bson:"subject" json:"subject" yaml:"subject"`
+	Pseudonymized bool   `bson:"pseudonymized,omitempty" json:"pseudonymized,omitempty" yaml:"pseudonymized,omitempty"`
+	Base          `bson:"metadata" json:"metadata" yaml:"metadata"`
+}
+
+// NewExperimentExposure constructs an Info Composer that records that
+// the subject was exposed to the variant (e.g. "treatment") of the
+// experiment (e.g. "checkout-v2"). The message is not loggable if the
+// experiment is empty.
+func NewExperimentExposure(experiment, variant, subject string) Composer {
+	m := &experimentExposureMessage{
+		Experiment: experiment,
+		Variant:    variant,
+		Subject:    subject,
+		Base:       newBase(),
+	}
+
+	_ = m.SetPriority(level.Info)
+	return m
+}
+
+// NewPseudonymizedExperimentExposure is the same as
+// NewExperimentExposure, except that the message records a pseudonym
+// in place of the subject, as NewPseudonymizedAuthEvent does, using
+// the salt as the key.
+func NewPseudonymizedExperimentExposure(salt []byte, experiment, variant, subject string) Composer {
+	m := NewExperimentExposure(experiment, variant, subject).(*experimentExposureMessage)
+	if subject == "" {
+		return m
+	}
+
+	m.Subject = pseudonym(salt, subject)
+	m.Pseudonymized = true
+
+	return m
+}
+
+func (m *experimentExposureMessage) Loggable() bool { return m.Experiment != "" }
+
+func (m *experimentExposureMessage) String() string {
+	if !m.Loggable() {
+		return ""
+	}
+
+	return fmt.Sprintf("exposed %s to %s:%s", m.Subject, m.Experiment, m.Variant)
+}
+
+func (m *experimentExposureMessage) Raw() interface{} {
+	_ = m.Collect()
+	return &experimentExposureMessage{
+		Experiment:    m.Experiment,
+		Variant:       m.Variant,
+		Subject:       m.Subject,
+		Pseudonymized: m.Pseudonymized,
+		Base:          m.snapshot(),
+	}
+}
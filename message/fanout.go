@@ -0,0 +1,96 @@
+// Notification Fan-out Messages
+//
+// The notification fan-out composer summarizes the delivery of a
+// notification to several channels (e.g. email, SMS, and push) in a
+// single message, with the outcome of each delivery, so that failed
+// deliveries are easy to query.
+package message
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/mongodb/grip/level"
+)
+
+type notificationFanoutMessage struct {
+	Event     string            `bson:"event" json:"event" yaml:"event"`
+	Outcomes  map[string]string `bson:"outcomes" json:"outcomes" yaml:"outcomes"`
+	Delivered int               `bson:"delivered" json:"delivered" yaml:"delivered"`
+	Failed    int               `bson:"failed" json:"failed" yaml:"failed"`
+	Base      `bson:"metadata" json:"metadata" yaml:"metadata"`
+
+	failures []string
+}
+
+// NewNotificationFanout constructs a Composer that summarizes the
+// delivery of the notification for the event to the channels, which
+// map the name of each channel to the error that delivering to the
+// channel returned, or nil if the delivery succeeded. The raw form
+// records the outcome of each delivery, "delivered" or the text of the
+// error. The message has Warning priority if any delivery failed, and
+// Info priority otherwise, and is not loggable if there are no
+// channels.
+func NewNotificationFanout(event string, channels map[string]error) Composer {
+	m := &notificationFanoutMessage{
+		Event:    event,
+		Outcomes: make(map[string]string, len(channels)),
+		Base:     newBase(),
+	}
+
+	for name, err := range channels {
+		if err == nil {
+			m.Outcomes[name] = "delivered"
+			m.Delivered++
+			continue
+		}
+
+		m.Outcomes[name] = err.Error()
+		m.Failed++
+		m.failures = append(m.failures, fmt.Sprintf("%s (%s)", name, err.Error()))
+	}
+	sort.Strings(m.failures)
+
+	if m.Failed > 0 {
+		_ = m.SetPriority(level.Warning)
+	} else {
+		_ = m.SetPriority(level.Info)
+	}
+
+	return m
+}
+
+func (m *notificationFanoutMessage) Loggable() bool { return len(m.Outcomes) > 0 }
+
+func (m *notificationFanoutMessage) String() string {
+	if !m.Loggable() {
+		return ""
+	}
+
+	total := m.Delivered + m.Failed
+	if m.Failed == 0 {
+		return fmt.Sprintf("notification %s delivered to %d channels", m.Event, total)
+	}
+
+	return fmt.Sprintf("notification %s delivered to %d of %d channels, failed: %s",
+		m.Event, m.Delivered, total, strings.Join(m.failures, ", "))
+}
+
+func (m *notificationFanoutMessage) Raw() interface{} {
+	_ = m.Collect()
+
+	outcomes := make(map[string]string, len(m.Outcomes))
+	for name, outcome := range m.Outcomes {
+		outcomes[name] = outcome
+	}
+
+	return &notificationFanoutMessage{
+		Event:     m.Event,
+		Outcomes:  outcomes,
+		Delivered: m.Delivered,
+		Failed:    m.Failed,
+		Base:      m.snapshot(),
+		failures:  m.failures,
+	}
+}
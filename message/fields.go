@@ -2,7 +2,9 @@ package message
 
 import (
 	"fmt"
+	"sort"
 	"strings"
+	"sync"
 
 	"github.com/mongodb/grip/level"
 )
@@ -11,6 +13,7 @@ type fieldMessage struct {
 	message      string
 	fields       Fields
 	cachedOutput string
+	mutex        sync.Mutex
 	Base
 }
 
@@ -41,52 +44,143 @@ func NewFields(p level.Priority, f Fields) Composer {
 	return m
 }
 
+// with returns a copy of the Fields that also contains the key and
+// value.
+func (f Fields) with(key string, value interface{}) Fields {
+	out := make(Fields, len(f)+1)
+	for k, v := range f {
+		out[k] = v
+	}
+	out[key] = value
+
+	return out
+}
+
 // MakeFieldsMessage constructs a fields Composer from a message string and
 // Fields object, without specifying the priority of the message.
 func MakeFieldsMessage(message string, f Fields) Composer {
-	return &fieldMessage{message: message, fields: f}
+	return &fieldMessage{message: message, fields: f, Base: newBase()}
 }
 
 // MakeFields creates a composer interface from *just* a Fields instance.
-func MakeFields(f Fields) Composer { return &fieldMessage{fields: f} }
+func MakeFields(f Fields) Composer { return &fieldMessage{fields: f, Base: newBase()} }
+
+func (m *fieldMessage) Loggable() bool {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	return m.loggable()
+}
+
+func (m *fieldMessage) loggable() bool { return hasContent(m.message, len(m.fields)) }
 
-func (m *fieldMessage) Loggable() bool { return m.message != "" || len(m.fields) > 0 }
 func (m *fieldMessage) String() string {
-	if !m.Loggable() {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if !m.loggable() {
 		return ""
 	}
 
 	if m.cachedOutput == "" {
-		const tmpl = "%s='%v'"
 		out := []string{}
 		if m.message != "" {
-			out = append(out, fmt.Sprintf(tmpl, "msg", m.message))
+			out = append(out, fmt.Sprintf("msg='%s'", m.message))
 		}
 
-		for k, v := range m.fields {
-			if k == "msg" && v == m.message {
-				continue
-			}
-			if k == "time" {
+		fields := m.fields
+		if msg, ok := fields["msg"]; ok && msg == m.message {
+			fields = fields.without("msg")
+		}
+
+		m.cachedOutput = fmt.Sprintf("[%s]", strings.Join(fields.render("", out), " "))
+	}
+
+	return m.cachedOutput
+}
+
+// without returns a copy of the Fields that does not contain the key.
+func (f Fields) without(key string) Fields {
+	out := make(Fields, len(f))
+	for k, v := range f {
+		if k != key {
+			out[k] = v
+		}
+	}
+
+	return out
+}
+
+// render appends the "key='value'" pairs of the fields to out, in the
+// order of their keys, and returns the extended slice. Nested Fields
+// and maps with string keys render as their own pairs, with dotted
+// keys (e.g. "request.id"). Nil values, and the top level "time" key,
+// do not render.
+func (f Fields) render(prefix string, out []string) []string {
+	keys := make([]string, 0, len(f))
+	for k := range f {
+		if prefix == "" && k == "time" {
+			continue
+		}
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		switch v := f[k].(type) {
+		case Fields:
+			out = v.render(prefix+k+".", out)
+		case map[string]interface{}:
+			out = Fields(v).render(prefix+k+".", out)
+		default:
+			if isNil(v) {
 				continue
 			}
 
-			out = append(out, fmt.Sprintf(tmpl, k, v))
+			out = append(out, fmt.Sprintf("%s%s='%v'", prefix, k, v))
 		}
+	}
+
+	return out
+}
+
+// Annotate adds the key to a copy of the message's Fields, so that
+// the Fields returned by previous calls to Raw() do not change.
+func (m *fieldMessage) Annotate(key string, value interface{}) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
 
-		m.cachedOutput = fmt.Sprintf("[%s]", strings.Join(out, " "))
+	if _, ok := m.fields[key]; ok {
+		return fmt.Errorf("key '%s' already exists", key)
 	}
 
-	return m.cachedOutput
+	m.fields = m.fields.with(key, value)
+	m.cachedOutput = ""
+
+	return nil
+}
+
+// Annotation returns the value of the key in the message's Fields,
+// where Annotate adds keys, and whether the Fields have the key.
+func (m *fieldMessage) Annotation(key string) (interface{}, bool) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	value, ok := m.fields[key]
+	return value, ok
 }
 
 func (m *fieldMessage) Raw() interface{} {
 	_ = m.Collect()
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
 	if _, ok := m.fields["msg"]; !ok {
-		m.fields["msg"] = m.message
+		m.fields = m.fields.with("msg", m.message)
 	}
 	if _, ok := m.fields["time"]; !ok {
-		m.fields["time"] = m.Time
+		m.fields = m.fields.with("time", m.Timestamp())
 	}
 
 	return m.fields
@@ -20,6 +20,7 @@ func NewFormattedMessage(p level.Priority, base string, args ...interface{}) Com
 	m := &formatMessenger{
 		base: base,
 		args: args,
+		Base: newBase(),
 	}
 	_ = m.SetPriority(p)
 
@@ -32,6 +33,7 @@ func NewFormatted(base string, args ...interface{}) Composer {
 	return &formatMessenger{
 		base: base,
 		args: args,
+		Base: newBase(),
 	}
 }
 
@@ -43,11 +45,10 @@ func (f *formatMessenger) String() string {
 	return f.Message
 }
 
-func (f *formatMessenger) Loggable() bool { return f.base != "" }
+func (f *formatMessenger) Loggable() bool { return hasContent(f.base, 0) }
 
 func (f *formatMessenger) Raw() interface{} {
 	_ = f.Collect()
-	_ = f.String()
 
-	return f
+	return &formatMessenger{Message: f.String(), Base: f.snapshot()}
 }
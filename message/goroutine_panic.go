@@ -0,0 +1,106 @@
+// Goroutine Panic Messages
+//
+// The goroutine panic composer records a panic recovered in a
+// background goroutine, with the name of the task that the goroutine
+// ran and the stack of the goroutine, for use in the deferred
+// function that recovers from the panic:
+//
+//	defer func() {
+//		grip.Send(message.NewGoroutinePanic("reindex", recover()))
+//	}()
+package message
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/mongodb/grip/level"
+)
+
+type goroutinePanicMessage struct {
+	Task       string       `bson:"task" json:"task" yaml:"task"`
+	PanicValue string       `bson:"panic_value" json:"panic_value" yaml:"panic_value"`
+	Frames     []StackFrame `bson:"frames" json:"frames" yaml:"frames"`
+	Base       `bson:"metadata" json:"metadata" yaml:"metadata"`
+
+	recovered interface{}
+}
+
+// NewGoroutinePanic constructs an Emergency Composer that records
+// that the goroutine running the task panicked with the recovered
+// value, and the stack of the goroutine, which, when the constructor
+// is called from a deferred function, includes the frames that
+// panicked. The message is not loggable if the recovered value is
+// nil, so that deferred functions may construct the message whether
+// or not the goroutine panicked.
+func NewGoroutinePanic(taskName string, recovered interface{}) Composer {
+	m := &goroutinePanicMessage{
+		Task:      taskName,
+		recovered: recovered,
+		Base:      newBase(),
+	}
+
+	if recovered != nil {
+		m.PanicValue = fmt.Sprintf("%v", recovered)
+		m.Frames = captureStack(1)
+	}
+
+	_ = m.SetPriority(level.Emergency)
+	return m
+}
+
+func (m *goroutinePanicMessage) Loggable() bool { return m.recovered != nil }
+
+func (m *goroutinePanicMessage) String() string {
+	if !m.Loggable() {
+		return ""
+	}
+
+	task := "goroutine"
+	if m.Task != "" {
+		task = fmt.Sprintf("task %s", m.Task)
+	}
+
+	out := fmt.Sprintf("%s panicked: %s", task, m.PanicValue)
+	if frame, ok := panicSite(m.Frames); ok {
+		dir, fileName := filepath.Split(frame.File)
+		out = fmt.Sprintf("%s [%s:%d]", out, filepath.Join(filepath.Base(dir), fileName), frame.Line)
+	}
+
+	return out
+}
+
+func (m *goroutinePanicMessage) Raw() interface{} {
+	_ = m.Collect()
+	return &goroutinePanicMessage{
+		Task:       m.Task,
+		PanicValue: m.PanicValue,
+		Frames:     m.Frames,
+		Base:       m.snapshot(),
+		recovered:  m.recovered,
+	}
+}
+
+// panicSite returns the frame that panicked: the first frame outside
+// of the runtime after the runtime's panic handler, or the first
+// frame if the stack does not include the panic handler.
+func panicSite(frames []StackFrame) (StackFrame, bool) {
+	for idx, frame := range frames {
+		if frame.Function != "runtime.gopanic" {
+			continue
+		}
+
+		for _, site := range frames[idx+1:] {
+			if !strings.HasPrefix(site.Function, "runtime.") {
+				return site, true
+			}
+		}
+	}
+
+	if len(frames) == 0 {
+		return StackFrame{}, false
+	}
+
+	return frames[0], true
+}
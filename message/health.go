@@ -0,0 +1,94 @@
+// Health Report Messages
+//
+// The health report composer provides a consistent record of the
+// results of a set of health checks, such as those behind a readiness
+// probe, so that the history of degraded dependencies is preserved.
+package message
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/mongodb/grip/level"
+)
+
+type healthReportMessage struct {
+	Checks  map[string]string `bson:"checks" json:"checks" yaml:"checks"`
+	Healthy bool              `bson:"healthy" json:"healthy" yaml:"healthy"`
+	Base    `bson:"metadata" json:"metadata" yaml:"metadata"`
+}
+
+// NewHealthReport constructs a Composer that records the results of
+// health checks, given a map of check names to the error that each
+// check returned. The raw form of the message records the status of
+// each check, either "ok" or the text of the error, and whether all
+// checks passed. Reports with failed checks have Error priority,
+// otherwise the message has Info priority. The message is not
+// loggable if there are no checks.
+func NewHealthReport(checks map[string]error) Composer {
+	m := &healthReportMessage{
+		Checks:  make(map[string]string, len(checks)),
+		Healthy: true,
+		Base:    newBase(),
+	}
+
+	for name, err := range checks {
+		if err = nonNilError(err); err != nil {
+			m.Checks[name] = err.Error()
+			m.Healthy = false
+		} else {
+			m.Checks[name] = "ok"
+		}
+	}
+
+	if m.Healthy {
+		_ = m.SetPriority(level.Info)
+	} else {
+		_ = m.SetPriority(level.Error)
+	}
+
+	return m
+}
+
+func (m *healthReportMessage) Loggable() bool { return len(m.Checks) > 0 }
+
+func (m *healthReportMessage) String() string {
+	if !m.Loggable() {
+		return ""
+	}
+
+	if m.Healthy {
+		return fmt.Sprintf("all %d health checks passed", len(m.Checks))
+	}
+
+	names := make([]string, 0, len(m.Checks))
+	for name, status := range m.Checks {
+		if status != "ok" {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	failures := make([]string, 0, len(names))
+	for _, name := range names {
+		failures = append(failures, fmt.Sprintf("%s (%s)", name, m.Checks[name]))
+	}
+
+	return fmt.Sprintf("%d of %d health checks failed: %s", len(names), len(m.Checks), strings.Join(failures, ", "))
+}
+
+func (m *healthReportMessage) Raw() interface{} {
+	_ = m.Collect()
+
+	checks := make(map[string]string, len(m.Checks))
+	for name, status := range m.Checks {
+		checks[name] = status
+	}
+
+	return &healthReportMessage{
+		Checks:  checks,
+		Healthy: m.Healthy,
+		Base:    m.snapshot(),
+	}
+}
@@ -1,6 +1,12 @@
 package message
 
-import "github.com/mongodb/grip/level"
+import (
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/mongodb/grip/level"
+)
 
 // Composer defines an interface with a "String()" method that
 // returns the message in string format. Objects that implement this
@@ -24,16 +30,89 @@ type Composer interface {
 	// logged, and false otherwise. When false, the sender can
 	// (and should!) ignore messages even if they are otherwise
 	// above the logging threshold.
+	//
+	// The composers in this package consider a message to have
+	// content if it has text that isn't only whitespace, or if it
+	// has any structured content (e.g. Fields), even when the
+	// String() form of the message is empty.
 	Loggable() bool
 
 	// Priority returns the priority of the message.
 	Priority() level.Priority
 	SetPriority(level.Priority) error
+
+	// Annotate makes it possible for Senders and Journalers to
+	// add structured data to a log message. Returns an error if
+	// the key already exists.
+	Annotate(string, interface{}) error
+}
+
+// Timestamped is an optional interface for Composers that record the
+// time that they were created. All of the Composers in this package
+// implement Timestamped, and senders that record the time of log
+// events use the timestamp of the message rather than the time that
+// they send it, which may be much later for senders that buffer
+// messages.
+type Timestamped interface {
+	Timestamp() time.Time
+}
+
+// Annotated is an optional interface for Composers that can report
+// the values that Annotate added to them, so that senders can act on
+// annotations (e.g. to route messages). All of the Composers in this
+// package implement Annotated.
+type Annotated interface {
+	Annotation(key string) (interface{}, bool)
+}
+
+// hasContent implements the loggability rule for the composers in
+// this package: messages with text that isn't only whitespace, or
+// with any structured values, are loggable.
+func hasContent(text string, structured int) bool {
+	return structured > 0 || strings.TrimSpace(text) != ""
+}
+
+// IsNil reports whether the Composer is nil, or is a nil pointer,
+// map, or other nil value of a type that implements Composer. Senders
+// drop nil Composers rather than calling their methods.
+func IsNil(m Composer) bool { return isNil(m) }
+
+// isNil reports whether the value is nil, including typed nil values
+// (e.g. nil pointers) stored in an interface.
+func isNil(value interface{}) bool {
+	if value == nil {
+		return true
+	}
+
+	switch v := reflect.ValueOf(value); v.Kind() {
+	case reflect.Ptr, reflect.Map, reflect.Slice, reflect.Func, reflect.Chan, reflect.Interface:
+		return v.IsNil()
+	default:
+		return false
+	}
+}
+
+// nonNilError returns nil for nil errors, including typed nil errors
+// (e.g. a nil pointer to a type that implements error), and the error
+// otherwise.
+func nonNilError(err error) error {
+	if isNil(err) {
+		return nil
+	}
+
+	return err
 }
 
 // ConvertToComposer can coerce unknown objects into Composer
-// instances, as possible.
+// instances, as possible. Nil values, including nil errors, nil maps,
+// and nil pointers to types that implement Composer, produce a
+// message that is not loggable. Functions that produce a Composer
+// become lazy messages (see NewLazy), and are not called.
 func ConvertToComposer(p level.Priority, message interface{}) Composer {
+	if isNil(message) {
+		return NewLineMessage(p)
+	}
+
 	switch message := message.(type) {
 	case Composer:
 		_ = message.SetPriority(p)
@@ -52,8 +131,8 @@ func ConvertToComposer(p level.Priority, message interface{}) Composer {
 		return NewFields(p, Fields(message))
 	case Fields:
 		return NewFields(p, message)
-	case nil:
-		return NewLineMessage(p)
+	case func() Composer:
+		return NewLazy(p, message)
 	default:
 		return NewFormattedMessage(p, "%+v", message)
 	}
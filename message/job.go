@@ -0,0 +1,75 @@
+// Job Run Messages
+//
+// The job run composer provides a consistent record of the execution
+// of a scheduled (e.g. cron-style) job, including its start time,
+// duration, and outcome.
+package message
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/mongodb/grip/level"
+)
+
+type jobRunMessage struct {
+	Job        string    `bson:"job" json:"job" yaml:"job"`
+	Started    time.Time `bson:"started" json:"started" yaml:"started"`
+	DurationMS int64     `bson:"duration_ms" json:"duration_ms" yaml:"duration_ms"`
+	Error      string    `bson:"error,omitempty" json:"error,omitempty" yaml:"error,omitempty"`
+	Base       `bson:"metadata" json:"metadata" yaml:"metadata"`
+
+	duration time.Duration
+	err      error
+}
+
+// NewJobRun constructs a Composer that records the outcome of a
+// single execution of a job. Messages for jobs that returned an error
+// have Error priority, otherwise the message has Info priority. The
+// message is not loggable if the job name is empty.
+func NewJobRun(job string, started time.Time, duration time.Duration, err error) Composer {
+	m := &jobRunMessage{
+		Job:        job,
+		Started:    started,
+		DurationMS: int64(duration / time.Millisecond),
+		duration:   duration,
+		err:        err,
+		Base:       newBase(),
+	}
+
+	if err != nil {
+		m.Error = err.Error()
+		_ = m.SetPriority(level.Error)
+	} else {
+		_ = m.SetPriority(level.Info)
+	}
+
+	return m
+}
+
+func (m *jobRunMessage) Loggable() bool { return m.Job != "" }
+
+func (m *jobRunMessage) String() string {
+	if !m.Loggable() {
+		return ""
+	}
+
+	if m.err != nil {
+		return fmt.Sprintf("job %s failed after %s: %s", m.Job, m.duration, m.Error)
+	}
+
+	return fmt.Sprintf("job %s completed in %s", m.Job, m.duration)
+}
+
+func (m *jobRunMessage) Raw() interface{} {
+	_ = m.Collect()
+	return &jobRunMessage{
+		Job:        m.Job,
+		Started:    m.Started,
+		DurationMS: m.DurationMS,
+		Error:      m.Error,
+		Base:       m.snapshot(),
+		duration:   m.duration,
+		err:        m.err,
+	}
+}
@@ -0,0 +1,101 @@
+package message
+
+import (
+	"sync"
+
+	"github.com/mongodb/grip/level"
+)
+
+type lazyMessage struct {
+	fn   func() Composer
+	msg  Composer
+	once sync.Once
+	Base
+}
+
+// NewLazy returns a Composer that calls the function to produce the
+// message only when the message's content is needed: the first time
+// that String(), Raw(), Loggable(), Annotate(), or Annotation() is
+// called. The priority of the message is known without calling the
+// function, so senders that check the priority of messages before
+// their content never call the function for messages below their
+// threshold. The function is called at most once, and the message
+// that it returns takes the priority of the lazy message. Functions
+// that return nil produce a message that is not loggable.
+func NewLazy(p level.Priority, fn func() Composer) Composer {
+	m := MakeLazy(fn)
+	_ = m.SetPriority(p)
+
+	return m
+}
+
+// MakeLazy returns a lazy Composer, as NewLazy, without specifying
+// the priority of the message.
+func MakeLazy(fn func() Composer) Composer {
+	return &lazyMessage{fn: fn, Base: newBase()}
+}
+
+// resolve calls the function, once, and returns the message that it
+// produced.
+func (m *lazyMessage) resolve() Composer {
+	m.once.Do(func() {
+		var msg Composer
+		if m.fn != nil {
+			msg = m.fn()
+		}
+
+		if isNil(msg) {
+			msg = NewLineMessage(m.Priority())
+		} else {
+			_ = msg.SetPriority(m.Priority())
+		}
+
+		m.mutex.Lock()
+		m.msg = msg
+		m.mutex.Unlock()
+	})
+
+	return m.msg
+}
+
+func (m *lazyMessage) String() string   { return m.resolve().String() }
+func (m *lazyMessage) Raw() interface{} { return m.resolve().Raw() }
+func (m *lazyMessage) Loggable() bool   { return m.resolve().Loggable() }
+
+func (m *lazyMessage) Annotate(key string, value interface{}) error {
+	return m.resolve().Annotate(key, value)
+}
+
+// Annotation returns the annotation of the message that the function
+// produced.
+func (m *lazyMessage) Annotation(key string) (interface{}, bool) {
+	annotated, ok := m.resolve().(Annotated)
+	if !ok {
+		return nil, false
+	}
+
+	return annotated.Annotation(key)
+}
+
+// SetPriority sets the priority of the lazy message, and of the
+// message that the function produced, if it has been called.
+func (m *lazyMessage) SetPriority(p level.Priority) error {
+	if err := m.Base.SetPriority(p); err != nil {
+		return err
+	}
+
+	if msg := m.resolved(); msg != nil {
+		return msg.SetPriority(p)
+	}
+
+	return nil
+}
+
+// resolved returns the message that the function produced, or nil if
+// the function has not been called.
+func (m *lazyMessage) resolved() Composer {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	return m.msg
+}
@@ -24,11 +24,11 @@ func NewLineMessage(p level.Priority, args ...interface{}) Composer {
 }
 
 // NewLine returns a message Composer roughly equivalent to
-// fmt.Sprintln().
+// fmt.Sprintln(). Nil arguments, including nil pointers, are omitted.
 func NewLine(args ...interface{}) Composer {
-	m := &lineMessenger{}
+	m := &lineMessenger{Base: newBase()}
 	for _, arg := range args {
-		if arg != nil {
+		if !isNil(arg) {
 			m.Lines = append(m.Lines, arg)
 		}
 	}
@@ -37,7 +37,7 @@ func NewLine(args ...interface{}) Composer {
 }
 
 func newLinesFromStrings(p level.Priority, args []string) Composer {
-	m := &lineMessenger{}
+	m := &lineMessenger{Base: newBase()}
 	_ = m.SetPriority(p)
 	for _, arg := range args {
 		if arg != "" {
@@ -48,8 +48,21 @@ func newLinesFromStrings(p level.Priority, args []string) Composer {
 	return m
 }
 
+// Loggable treats string arguments as text, and all other arguments
+// as structured content.
 func (l *lineMessenger) Loggable() bool {
-	return len(l.Lines) > 0
+	for _, line := range l.Lines {
+		if text, ok := line.(string); ok {
+			if hasContent(text, 0) {
+				return true
+			}
+			continue
+		}
+
+		return true
+	}
+
+	return false
 }
 
 func (l *lineMessenger) String() string {
@@ -62,5 +75,5 @@ func (l *lineMessenger) String() string {
 
 func (l *lineMessenger) Raw() interface{} {
 	_ = l.Collect()
-	return l
+	return &lineMessenger{Lines: l.Lines, Base: l.snapshot()}
 }
@@ -0,0 +1,246 @@
+// Panic Messages
+//
+// The panic composer records a recovered panic, with the stack of the
+// goroutine that panicked, captured when the message is constructed,
+// for use in the deferred function that recovers from the panic:
+//
+//	defer func() {
+//		if p := recover(); p != nil {
+//			grip.Send(message.NewPanic(p))
+//		}
+//	}()
+package message
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"github.com/mongodb/grip/level"
+)
+
+// DefaultMaxPanicStackBytes is the size, in bytes, of the stack that
+// panic messages capture by default.
+const DefaultMaxPanicStackBytes = 64 * 1024
+
+// PanicOptions configures the stack that a panic message captures.
+type PanicOptions struct {
+	// Skip is the number of callers of the constructor to omit from
+	// the stack when the constructor is not called while the
+	// goroutine panics, for helpers that wrap the constructor. The
+	// stack of a panicking goroutine always starts at the function
+	// that panicked.
+	Skip int
+
+	// MaxFrames, if positive, limits the number of frames that the
+	// message records.
+	MaxFrames int
+
+	// MaxStackBytes limits the size of the stack that the message
+	// captures, and defaults to DefaultMaxPanicStackBytes.
+	MaxStackBytes int
+}
+
+type panicMessage struct {
+	Panic       string     `bson:"panic" json:"panic" yaml:"panic"`
+	GoroutineID int64      `bson:"goroutine_id" json:"goroutine_id" yaml:"goroutine_id"`
+	Location    StackFrame `bson:"location" json:"location" yaml:"location"`
+	Stack       string     `bson:"stack" json:"stack" yaml:"stack"`
+	Truncated   bool       `bson:"truncated,omitempty" json:"truncated,omitempty" yaml:"truncated,omitempty"`
+	Base        `bson:"metadata" json:"metadata" yaml:"metadata"`
+
+	recovered interface{}
+	frames    []StackFrame
+}
+
+// NewPanic constructs an Emergency Composer that records the value
+// recovered from a panic, and the stack of the goroutine, which, when
+// the constructor is called from the deferred function that
+// recovered, starts at the function that panicked. The String() form
+// has the value, the location of the panic, and the stack, without
+// the arguments and offsets of its frames; the Raw() form has the
+// "panic", "goroutine_id", "location", and "stack" fields. The message
+// is not loggable if the recovered value is nil.
+func NewPanic(recovered interface{}) Composer {
+	return newPanic(recovered, PanicOptions{})
+}
+
+// NewPanicWithOptions is the same as NewPanic, but limits the stack
+// that the message captures with the options.
+func NewPanicWithOptions(recovered interface{}, opts PanicOptions) Composer {
+	return newPanic(recovered, opts)
+}
+
+func newPanic(recovered interface{}, opts PanicOptions) (m *panicMessage) {
+	m = &panicMessage{recovered: recovered, Base: newBase()}
+	_ = m.SetPriority(level.Emergency)
+
+	if recovered == nil {
+		return m
+	}
+
+	// values whose String or Error methods panic, and stacks that
+	// cannot be parsed, must not turn the message into a second
+	// panic.
+	defer func() {
+		if p := recover(); p != nil {
+			if m.Panic == "" {
+				m.Panic = fmt.Sprintf("%T", recovered)
+			}
+			m.Stack = fmt.Sprintf("stack unavailable: %v", p)
+		}
+	}()
+
+	m.Panic = fmt.Sprintf("%v", recovered)
+	m.capture(opts)
+
+	return m
+}
+
+// capture records the stack of the goroutine, which the caller of
+// newPanic is at the top of, from the panic, if the goroutine is
+// panicking, and otherwise after the skipped callers.
+func (m *panicMessage) capture(opts PanicOptions) {
+	size := opts.MaxStackBytes
+	if size <= 0 {
+		size = DefaultMaxPanicStackBytes
+	}
+
+	buf := make([]byte, size)
+	buf = buf[:runtime.Stack(buf, false)]
+	truncated := len(buf) == size
+
+	header := buf
+	if idx := bytes.IndexByte(buf, '\n'); idx >= 0 {
+		header = buf[:idx]
+	}
+	m.GoroutineID = goroutineID(string(header))
+
+	frames := parseStack(string(buf), truncated)
+
+	// the stack starts with capture, newPanic, and the constructor.
+	start := 3 + opts.Skip
+	for idx, frame := range frames {
+		if frame.Function == "panic" || frame.Function == "runtime.gopanic" {
+			start = idx + 1
+			break
+		}
+	}
+	if start > len(frames) {
+		start = len(frames)
+	}
+	frames = frames[start:]
+
+	if opts.MaxFrames > 0 && len(frames) > opts.MaxFrames {
+		frames = frames[:opts.MaxFrames]
+		truncated = true
+	}
+
+	m.frames = frames
+	m.Truncated = truncated
+	if len(frames) > 0 {
+		m.Location = frames[0]
+	}
+
+	lines := make([]string, 0, len(frames))
+	for _, frame := range frames {
+		lines = append(lines, fmt.Sprintf("%s\n\t%s:%d", frame.Function, frame.File, frame.Line))
+	}
+	if truncated {
+		lines = append(lines, "...")
+	}
+	m.Stack = strings.Join(lines, "\n")
+}
+
+// goroutineID returns the ID in the "goroutine <id> [<state>]:" header
+// of a stack, or 0 if the header does not have one.
+func goroutineID(header string) int64 {
+	fields := strings.Fields(header)
+	if len(fields) < 2 || fields[0] != "goroutine" {
+		return 0
+	}
+
+	id, _ := strconv.ParseInt(fields[1], 10, 64)
+	return id
+}
+
+// parseStack returns the frames of the stack that runtime.Stack
+// renders, in which every frame is a line with the function and its
+// arguments, followed by an indented line with the file, the line,
+// and the offset. The final frame of a truncated stack may be
+// incomplete, and is omitted.
+func parseStack(stack string, truncated bool) []StackFrame {
+	lines := strings.Split(stack, "\n")
+	if truncated && len(lines) > 0 {
+		lines = lines[:len(lines)-1]
+	}
+
+	frames := []StackFrame{}
+	for idx := 1; idx+1 < len(lines); idx++ {
+		function, location := lines[idx], lines[idx+1]
+		if function == "" || strings.HasPrefix(function, "\t") || !strings.HasPrefix(location, "\t") {
+			continue
+		}
+		idx++
+
+		frame := StackFrame{Function: strings.TrimPrefix(function, "created by ")}
+		if strings.HasSuffix(frame.Function, ")") {
+			if open := strings.LastIndex(frame.Function, "("); open > 0 {
+				frame.Function = frame.Function[:open]
+			}
+		} else if in := strings.Index(frame.Function, " in goroutine "); in > 0 {
+			frame.Function = frame.Function[:in]
+		}
+
+		location = strings.TrimSpace(location)
+		if offset := strings.LastIndex(location, " +0x"); offset > 0 {
+			location = location[:offset]
+		}
+		if colon := strings.LastIndex(location, ":"); colon > 0 {
+			frame.Line, _ = strconv.Atoi(location[colon+1:])
+			location = location[:colon]
+		}
+		frame.File = location
+
+		frames = append(frames, frame)
+	}
+
+	return frames
+}
+
+func (m *panicMessage) Loggable() bool { return m.recovered != nil }
+
+func (m *panicMessage) String() string {
+	if !m.Loggable() {
+		return ""
+	}
+
+	out := fmt.Sprintf("panic: %s", m.Panic)
+	if m.Location.File != "" {
+		dir, fileName := filepath.Split(m.Location.File)
+		out = fmt.Sprintf("%s [%s:%d]", out, filepath.Join(filepath.Base(dir), fileName), m.Location.Line)
+	}
+
+	if m.Stack != "" {
+		out = fmt.Sprintf("%s\ngoroutine %d:\n%s", out, m.GoroutineID, m.Stack)
+	}
+
+	return out
+}
+
+func (m *panicMessage) Raw() interface{} {
+	_ = m.Collect()
+	return &panicMessage{
+		Panic:       m.Panic,
+		GoroutineID: m.GoroutineID,
+		Location:    m.Location,
+		Stack:       m.Stack,
+		Truncated:   m.Truncated,
+		Base:        m.snapshot(),
+		recovered:   m.recovered,
+		frames:      m.frames,
+	}
+}
@@ -65,7 +65,7 @@ func CollectProcessInfoWithChildren(pid int32) []Composer {
 		return results
 	}
 
-	parentMsg := &ProcessInfo{}
+	parentMsg := &ProcessInfo{Base: newBase()}
 	parentMsg.loggable = true
 	parentMsg.populate(parent)
 	results = append(results, parentMsg)
@@ -77,7 +77,7 @@ func CollectProcessInfoWithChildren(pid int32) []Composer {
 	}
 
 	for _, child := range children {
-		cm := &ProcessInfo{}
+		cm := &ProcessInfo{Base: newBase()}
 		cm.loggable = true
 		cm.populate(child)
 		results = append(results, cm)
@@ -92,6 +92,7 @@ func NewProcessInfo(priority level.Priority, pid int32, message string) Composer
 	p := &ProcessInfo{
 		Message: message,
 		Pid:     pid,
+		Base:    newBase(),
 	}
 
 	if err := p.SetPriority(priority); err != nil {
@@ -121,9 +122,26 @@ func NewProcessInfo(priority level.Priority, pid int32, message string) Composer
 // populated.
 func (p *ProcessInfo) Loggable() bool { return p.loggable }
 
-// Raw always returns the ProcessInfo object, however it will call the
-// Collect method of the base operation first.
-func (p *ProcessInfo) Raw() interface{} { _ = p.Collect(); return p }
+// Raw always returns a copy of the ProcessInfo object, however it will
+// call the Collect method of the base operation first.
+func (p *ProcessInfo) Raw() interface{} {
+	_ = p.Collect()
+	return &ProcessInfo{
+		Message:        p.Message,
+		Pid:            p.Pid,
+		Parent:         p.Parent,
+		Threads:        p.Threads,
+		Command:        p.Command,
+		CPU:            p.CPU,
+		IoStat:         p.IoStat,
+		NetStat:        p.NetStat,
+		Memory:         p.Memory,
+		MemoryPlatform: p.MemoryPlatform,
+		Errors:         p.Errors,
+		Base:           p.snapshot(),
+		loggable:       p.loggable,
+	}
+}
 
 // String returns a string representation of the message, lazily
 // rendering the message, and caching it privately.
@@ -0,0 +1,103 @@
+// Data Quality Messages
+//
+// The data quality composer provides a consistent record of the
+// quality checks that pipelines run on samples of their records, with
+// the number of sampled records that have each kind of issue.
+package message
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/mongodb/grip/level"
+)
+
+const (
+	// dataQualityWarningRate is the fraction of the sample that
+	// must have an issue for the report to have Warning priority.
+	dataQualityWarningRate = 0.01
+
+	// dataQualityTopIssues is the number of issues that the string
+	// form of the report lists.
+	dataQualityTopIssues = 3
+)
+
+type dataQualityMessage struct {
+	Dataset string         `bson:"dataset" json:"dataset" yaml:"dataset"`
+	Sampled int            `bson:"sampled" json:"sampled" yaml:"sampled"`
+	Issues  map[string]int `bson:"issues" json:"issues" yaml:"issues"`
+	Base    `bson:"metadata" json:"metadata" yaml:"metadata"`
+}
+
+// NewDataQualityReport constructs a Composer that reports the number
+// of records in a sample of the dataset that have each kind of issue
+// (e.g. "null_email"). The report has Warning priority if more than 1%
+// of the sample has any one issue, otherwise the report has Info
+// priority. The string form lists the most frequent issues. The
+// message is only loggable if the sample is not empty.
+func NewDataQualityReport(dataset string, sampled int, issues map[string]int) Composer {
+	m := &dataQualityMessage{
+		Dataset: dataset,
+		Sampled: sampled,
+		Issues:  make(map[string]int, len(issues)),
+		Base:    newBase(),
+	}
+
+	_ = m.SetPriority(level.Info)
+	for issue, count := range issues {
+		m.Issues[issue] = count
+		if sampled > 0 && float64(count) > dataQualityWarningRate*float64(sampled) {
+			_ = m.SetPriority(level.Warning)
+		}
+	}
+
+	return m
+}
+
+func (m *dataQualityMessage) Loggable() bool { return m.Sampled > 0 }
+
+func (m *dataQualityMessage) String() string {
+	if !m.Loggable() {
+		return ""
+	}
+
+	issues := []string{}
+	for issue, count := range m.Issues {
+		if count > 0 {
+			issues = append(issues, issue)
+		}
+	}
+
+	if len(issues) == 0 {
+		return fmt.Sprintf("sampled %d from %s: no issues", m.Sampled, m.Dataset)
+	}
+
+	sort.Slice(issues, func(i, j int) bool {
+		if m.Issues[issues[i]] != m.Issues[issues[j]] {
+			return m.Issues[issues[i]] > m.Issues[issues[j]]
+		}
+		return issues[i] < issues[j]
+	})
+
+	top := []string{}
+	for _, issue := range issues {
+		if len(top) == dataQualityTopIssues {
+			top = append(top, fmt.Sprintf("%d more", len(issues)-dataQualityTopIssues))
+			break
+		}
+		top = append(top, fmt.Sprintf("%s %d", issue, m.Issues[issue]))
+	}
+
+	return fmt.Sprintf("sampled %d from %s: %s", m.Sampled, m.Dataset, strings.Join(top, ", "))
+}
+
+func (m *dataQualityMessage) Raw() interface{} {
+	_ = m.Collect()
+	return &dataQualityMessage{
+		Dataset: m.Dataset,
+		Sampled: m.Sampled,
+		Issues:  m.Issues,
+		Base:    m.snapshot(),
+	}
+}
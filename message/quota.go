@@ -0,0 +1,80 @@
+// Quota Event Messages
+//
+// The quota event composer provides a consistent record of the usage
+// of quotas and licensed limits by accounts, and of the actions that
+// the limits blocked, for billing and support.
+package message
+
+import (
+	"fmt"
+
+	"github.com/mongodb/grip/level"
+)
+
+// quotaWarningRatio is the share of the limit at which quota events
+// have Warning priority.
+const quotaWarningRatio = 0.9
+
+type quotaEventMessage struct {
+	Account  string `bson:"account" json:"account" yaml:"account"`
+	Resource string `bson:"resource" json:"resource" yaml:"resource"`
+	Used     int64  `bson:"used" json:"used" yaml:"used"`
+	Limit    int64  `bson:"limit" json:"limit" yaml:"limit"`
+	Blocked  bool   `bson:"blocked" json:"blocked" yaml:"blocked"`
+	Base     `bson:"metadata" json:"metadata" yaml:"metadata"`
+}
+
+// NewQuotaEvent constructs a Composer that records the account's
+// usage of its limit for the resource (e.g. "api-calls"), and whether
+// the limit blocked the action. Blocked actions have Error priority,
+// usage of at least 90% of the limit has Warning priority, and the
+// message otherwise has Info priority. The message is not loggable if
+// the account or the resource is empty.
+func NewQuotaEvent(account, resource string, used, limit int64, blocked bool) Composer {
+	m := &quotaEventMessage{
+		Account:  account,
+		Resource: resource,
+		Used:     used,
+		Limit:    limit,
+		Blocked:  blocked,
+		Base:     newBase(),
+	}
+
+	switch {
+	case blocked:
+		_ = m.SetPriority(level.Error)
+	case limit > 0 && float64(used) >= quotaWarningRatio*float64(limit):
+		_ = m.SetPriority(level.Warning)
+	default:
+		_ = m.SetPriority(level.Info)
+	}
+
+	return m
+}
+
+func (m *quotaEventMessage) Loggable() bool { return m.Account != "" && m.Resource != "" }
+
+func (m *quotaEventMessage) String() string {
+	if !m.Loggable() {
+		return ""
+	}
+
+	out := fmt.Sprintf("account %s %s %d/%d", m.Account, m.Resource, m.Used, m.Limit)
+	if m.Blocked {
+		out += " (blocked)"
+	}
+
+	return out
+}
+
+func (m *quotaEventMessage) Raw() interface{} {
+	_ = m.Collect()
+	return &quotaEventMessage{
+		Account:  m.Account,
+		Resource: m.Resource,
+		Used:     m.Used,
+		Limit:    m.Limit,
+		Blocked:  m.Blocked,
+		Base:     m.snapshot(),
+	}
+}
@@ -15,6 +15,7 @@ type bytesMessage struct {
 func NewBytesMessage(p level.Priority, b []byte) Composer {
 	m := &bytesMessage{
 		data: b,
+		Base: newBase(),
 	}
 
 	_ = m.SetPriority(p)
@@ -24,7 +25,7 @@ func NewBytesMessage(p level.Priority, b []byte) Composer {
 
 // NewBytes provides a basic message consisting of a single line.
 func NewBytes(b []byte) Composer {
-	return &bytesMessage{data: b}
+	return &bytesMessage{data: b, Base: newBase()}
 }
 
 func (s *bytesMessage) String() string {
@@ -32,16 +33,17 @@ func (s *bytesMessage) String() string {
 }
 
 func (s *bytesMessage) Loggable() bool {
-	return len(s.data) > 0
+	return hasContent(string(s.data), 0)
 }
 
 func (s *bytesMessage) Raw() interface{} {
 	_ = s.Collect()
+	base := s.snapshot()
 	return struct {
 		Metadata *Base  `bson:"metadata" json:"metadata" yaml:"metadata"`
 		Message  string `bson:"message" json:"message" yaml:"message"`
 	}{
-		Metadata: &s.Base,
+		Metadata: &base,
 		Message:  string(s.data),
 	}
 }
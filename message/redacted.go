@@ -0,0 +1,236 @@
+package message
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// RedactedValue replaces the values that a redacted Composer masks.
+const RedactedValue = "***REDACTED***"
+
+// RedactionRules describe the values that a redacted Composer masks.
+type RedactionRules struct {
+	// Keys are patterns for the keys of fields whose values are
+	// masked, matched case-insensitively, where "*" matches any
+	// sequence of characters (e.g. "password" or "*secret*").
+	Keys []string
+
+	// Values are expressions for string values that are masked,
+	// regardless of their key. Fields values that match are
+	// replaced entirely, while the text of other messages has
+	// only the matching parts replaced.
+	Values []*regexp.Regexp
+}
+
+// DefaultRedactionRules returns rules that mask common credentials:
+// fields named like passwords, tokens, secrets, and API keys, as well
+// as values shaped like credit card numbers, AWS access key IDs, and
+// connection strings with a password.
+func DefaultRedactionRules() RedactionRules {
+	return RedactionRules{
+		Keys: []string{"password", "passwd", "*token*", "*secret*", "*api_key*", "*apikey*", "authorization"},
+		Values: []*regexp.Regexp{
+			regexp.MustCompile(`\b(?:\d[ -]?){12,18}\d\b`),
+			regexp.MustCompile(`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`),
+			regexp.MustCompile(`[a-zA-Z][a-zA-Z0-9+.-]*://[^\s:/@]+:[^\s@]+@\S+`),
+		},
+	}
+}
+
+type redactedMessage struct {
+	rules   RedactionRules
+	created time.Time
+	Composer
+}
+
+// NewRedacted wraps a Composer so that its String() and Raw() forms
+// mask the values that the rules describe with RedactedValue. For
+// messages with fields, such as the messages that the Fields
+// constructors produce, the values of matching keys, and string
+// values that match the value expressions, are masked, including in
+// nested fields. The text of other messages has the parts that match
+// the value expressions masked, and their raw form becomes its JSON
+// representation, with the same masking applied to its keys and
+// strings. The redacted form is a copy: the wrapped message, and the
+// fields that back it, do not change.
+func NewRedacted(c Composer, rules RedactionRules) Composer {
+	return &redactedMessage{
+		rules:    rules,
+		created:  time.Now(),
+		Composer: c,
+	}
+}
+
+func (m *redactedMessage) String() string {
+	fields, ok := m.fields()
+	if !ok {
+		return m.redactText(m.Composer.String())
+	}
+
+	if !m.Composer.Loggable() {
+		return ""
+	}
+
+	out := []string{}
+	if msg, ok := fields["msg"]; ok {
+		if text, ok := msg.(string); ok && text != "" {
+			out = append(out, fmt.Sprintf("msg='%s'", text))
+		}
+		fields = fields.without("msg")
+	}
+
+	return fmt.Sprintf("[%s]", strings.Join(fields.render("", out), " "))
+}
+
+func (m *redactedMessage) Raw() interface{} {
+	if fields, ok := m.fields(); ok {
+		return fields
+	}
+
+	raw := m.Composer.Raw()
+	if text, ok := raw.(string); ok {
+		return m.redactText(text)
+	}
+
+	// other raw forms are structs, which can only be redacted by
+	// converting them to their JSON form.
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return m.redactText(m.Composer.String())
+	}
+
+	var doc interface{}
+	if err = json.Unmarshal(data, &doc); err != nil {
+		return m.redactText(m.Composer.String())
+	}
+
+	return m.redactDocument(doc)
+}
+
+// Timestamp returns the timestamp of the wrapped message, or the time
+// that it was wrapped, if the wrapped message does not record one.
+func (m *redactedMessage) Timestamp() time.Time {
+	if ts, ok := m.Composer.(Timestamped); ok {
+		return ts.Timestamp()
+	}
+
+	return m.created
+}
+
+// Annotation returns the redacted value of the annotation of the
+// wrapped message.
+func (m *redactedMessage) Annotation(key string) (interface{}, bool) {
+	annotated, ok := m.Composer.(Annotated)
+	if !ok {
+		return nil, false
+	}
+
+	value, ok := annotated.Annotation(key)
+	if !ok {
+		return nil, false
+	}
+
+	return m.redactValue(key, value), true
+}
+
+// fields returns a redacted copy of the fields of the wrapped
+// message, and whether the message has fields. The "msg" field is the
+// text of the message, and only has the matching parts masked.
+func (m *redactedMessage) fields() (Fields, bool) {
+	var raw map[string]interface{}
+	switch r := m.Composer.Raw().(type) {
+	case Fields:
+		raw = r
+	case map[string]interface{}:
+		raw = r
+	default:
+		return nil, false
+	}
+
+	out := m.redactFields(raw)
+	if msg, ok := raw["msg"].(string); ok && !m.matchesKey("msg") {
+		out["msg"] = m.redactText(msg)
+	}
+
+	return out, true
+}
+
+func (m *redactedMessage) redactFields(f map[string]interface{}) Fields {
+	out := make(Fields, len(f))
+	for k, v := range f {
+		out[k] = m.redactValue(k, v)
+	}
+
+	return out
+}
+
+func (m *redactedMessage) redactValue(key string, value interface{}) interface{} {
+	if m.matchesKey(key) {
+		return RedactedValue
+	}
+
+	switch v := value.(type) {
+	case Fields:
+		return m.redactFields(v)
+	case map[string]interface{}:
+		return m.redactFields(v)
+	case string:
+		for _, re := range m.rules.Values {
+			if re.MatchString(v) {
+				return RedactedValue
+			}
+		}
+	}
+
+	return value
+}
+
+func (m *redactedMessage) matchesKey(key string) bool {
+	key = strings.ToLower(key)
+	for _, pattern := range m.rules.Keys {
+		if ok, _ := path.Match(strings.ToLower(pattern), key); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// redactDocument masks the values of matching keys in the decoded
+// JSON document, and the matching parts of its strings.
+func (m *redactedMessage) redactDocument(doc interface{}) interface{} {
+	switch v := doc.(type) {
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(v))
+		for k, val := range v {
+			if m.matchesKey(k) {
+				out[k] = RedactedValue
+			} else {
+				out[k] = m.redactDocument(val)
+			}
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i, val := range v {
+			out[i] = m.redactDocument(val)
+		}
+		return out
+	case string:
+		return m.redactText(v)
+	default:
+		return v
+	}
+}
+
+func (m *redactedMessage) redactText(text string) string {
+	for _, re := range m.rules.Values {
+		text = re.ReplaceAllString(text, RedactedValue)
+	}
+
+	return text
+}
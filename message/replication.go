@@ -0,0 +1,90 @@
+// Replication Lag Messages
+//
+// The replication lag composer provides a consistent record of the
+// lag between a database and its replicas. The composer compares the
+// lag with thresholds, so that replication monitors can log every
+// measurement, and only significant lags are loggable.
+package message
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/mongodb/grip/level"
+)
+
+const (
+	replicationLagWarning = 5 * time.Second
+	replicationLagError   = 30 * time.Second
+)
+
+type replicationLagMessage struct {
+	Source     string  `bson:"source" json:"source" yaml:"source"`
+	Replica    string  `bson:"replica" json:"replica" yaml:"replica"`
+	LagSeconds float64 `bson:"lag_secs" json:"lag_secs" yaml:"lag_secs"`
+	Base       `bson:"metadata" json:"metadata" yaml:"metadata"`
+
+	lag        time.Duration
+	negligible time.Duration
+}
+
+// NewReplicationLag constructs a Composer that records the lag of the
+// replica behind the source, with the default thresholds: lags of
+// more than 5 seconds have Warning priority, and lags of more than 30
+// seconds have Error priority. See NewReplicationLagWithThresholds.
+func NewReplicationLag(source, replica string, lag time.Duration) Composer {
+	return NewReplicationLagWithThresholds(source, replica, lag, replicationLagWarning, replicationLagError)
+}
+
+// NewReplicationLagWithThresholds constructs a Composer that records
+// the lag of the replica behind the source. Lags of more than the
+// warning threshold have Warning priority, lags of more than the
+// error threshold have Error priority, and other lags have Info
+// priority. Lags of less than a tenth of the warning threshold are
+// negligible, and the message is not loggable; the message is also
+// not loggable if the replica is empty.
+func NewReplicationLagWithThresholds(source, replica string, lag, warning, err time.Duration) Composer {
+	m := &replicationLagMessage{
+		Source:     source,
+		Replica:    replica,
+		LagSeconds: lag.Seconds(),
+		lag:        lag,
+		negligible: warning / 10,
+		Base:       newBase(),
+	}
+
+	switch {
+	case lag > err:
+		_ = m.SetPriority(level.Error)
+	case lag > warning:
+		_ = m.SetPriority(level.Warning)
+	default:
+		_ = m.SetPriority(level.Info)
+	}
+
+	return m
+}
+
+func (m *replicationLagMessage) Loggable() bool {
+	return m.Replica != "" && m.lag >= m.negligible
+}
+
+func (m *replicationLagMessage) String() string {
+	if !m.Loggable() {
+		return ""
+	}
+
+	return fmt.Sprintf("replica %s lag %s", m.Replica, slaDuration(m.lag))
+}
+
+func (m *replicationLagMessage) Raw() interface{} {
+	_ = m.Collect()
+	return &replicationLagMessage{
+		Source:     m.Source,
+		Replica:    m.Replica,
+		LagSeconds: m.LagSeconds,
+		Base:       m.snapshot(),
+		lag:        m.lag,
+		negligible: m.negligible,
+	}
+}
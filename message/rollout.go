@@ -0,0 +1,64 @@
+// Rollout Decision Messages
+//
+// The rollout decision composer provides a consistent record of the
+// decisions of gradual feature rollouts, so that the distribution of
+// subjects across buckets can be verified.
+package message
+
+import (
+	"fmt"
+
+	"github.com/mongodb/grip/level"
+)
+
+type rolloutDecisionMessage struct {
+	Feature    string `bson:"feature" json:"feature" yaml:"feature"`
+	Percentage int    `bson:"percentage" json:"percentage" yaml:"percentage"`
+	Included   bool   `bson:"included" json:"included" yaml:"included"`
+	Bucket     int    `bson:"bucket" json:"bucket" yaml:"bucket"`
+	Base       `bson:"metadata" json:"metadata" yaml:"metadata"`
+}
+
+// NewRolloutDecision constructs a Composer that records whether a
+// subject, assigned to the bucket, is included in the rollout of the
+// feature to the percentage of subjects. The message has Info
+// priority, and is not loggable if the feature is empty.
+func NewRolloutDecision(feature string, percentage int, included bool, bucket int) Composer {
+	m := &rolloutDecisionMessage{
+		Feature:    feature,
+		Percentage: percentage,
+		Included:   included,
+		Bucket:     bucket,
+		Base:       newBase(),
+	}
+
+	_ = m.SetPriority(level.Info)
+
+	return m
+}
+
+func (m *rolloutDecisionMessage) Loggable() bool { return m.Feature != "" }
+
+func (m *rolloutDecisionMessage) String() string {
+	if !m.Loggable() {
+		return ""
+	}
+
+	decision := "excluded"
+	if m.Included {
+		decision = "included"
+	}
+
+	return fmt.Sprintf("rollout %s: user in bucket %d, %s (%d%%)", m.Feature, m.Bucket, decision, m.Percentage)
+}
+
+func (m *rolloutDecisionMessage) Raw() interface{} {
+	_ = m.Collect()
+	return &rolloutDecisionMessage{
+		Feature:    m.Feature,
+		Percentage: m.Percentage,
+		Included:   m.Included,
+		Bucket:     m.Bucket,
+		Base:       m.snapshot(),
+	}
+}
@@ -0,0 +1,91 @@
+// SLA Breach Messages
+//
+// The SLA breach composer provides a consistent record of operations
+// that exceed their latency budget. The composer compares the latency
+// with the budget, and is only loggable for breaches, so that
+// monitors can log every measurement without repeating the
+// comparison.
+package message
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/mongodb/grip/level"
+)
+
+type slaBreachMessage struct {
+	Operation string  `bson:"operation" json:"operation" yaml:"operation"`
+	ActualMS  float64 `bson:"actual_ms" json:"actual_ms" yaml:"actual_ms"`
+	BudgetMS  float64 `bson:"budget_ms" json:"budget_ms" yaml:"budget_ms"`
+	Overage   float64 `bson:"overage_ratio" json:"overage_ratio" yaml:"overage_ratio"`
+	Base      `bson:"metadata" json:"metadata" yaml:"metadata"`
+
+	actual time.Duration
+	budget time.Duration
+}
+
+// NewSLABreach constructs a Composer that records that the operation
+// took the actual time, exceeding its budget. The raw form includes
+// the overage ratio, the actual time divided by the budget. Breaches
+// of less than twice the budget have Warning priority, otherwise the
+// message has Error priority. The message is only loggable if the
+// operation is not empty, the budget is positive, and the actual
+// time exceeds the budget.
+func NewSLABreach(operation string, actual, budget time.Duration) Composer {
+	m := &slaBreachMessage{
+		Operation: operation,
+		ActualMS:  float64(actual) / float64(time.Millisecond),
+		BudgetMS:  float64(budget) / float64(time.Millisecond),
+		actual:    actual,
+		budget:    budget,
+		Base:      newBase(),
+	}
+
+	if budget > 0 {
+		m.Overage = float64(actual) / float64(budget)
+	}
+
+	if m.Overage < 2 {
+		_ = m.SetPriority(level.Warning)
+	} else {
+		_ = m.SetPriority(level.Error)
+	}
+
+	return m
+}
+
+func (m *slaBreachMessage) Loggable() bool {
+	return m.Operation != "" && m.budget > 0 && m.actual > m.budget
+}
+
+func (m *slaBreachMessage) String() string {
+	if !m.Loggable() {
+		return ""
+	}
+
+	return fmt.Sprintf("SLA breach %s: %s > %s budget", m.Operation, slaDuration(m.actual), slaDuration(m.budget))
+}
+
+func (m *slaBreachMessage) Raw() interface{} {
+	_ = m.Collect()
+	return &slaBreachMessage{
+		Operation: m.Operation,
+		ActualMS:  m.ActualMS,
+		BudgetMS:  m.BudgetMS,
+		Overage:   m.Overage,
+		Base:      m.snapshot(),
+		actual:    m.actual,
+		budget:    m.budget,
+	}
+}
+
+// slaDuration renders durations of a second or more in seconds, with
+// one decimal place, and shorter durations in milliseconds.
+func slaDuration(d time.Duration) string {
+	if d >= time.Second {
+		return fmt.Sprintf("%.1fs", d.Seconds())
+	}
+
+	return fmt.Sprintf("%gms", float64(d.Round(10*time.Microsecond))/float64(time.Millisecond))
+}
@@ -69,6 +69,7 @@ func NewStack(skip int, message string) Composer {
 	return &stackMessage{
 		trace:   captureStack(skip),
 		message: message,
+		Base:    newBase(),
 	}
 }
 
@@ -79,6 +80,7 @@ func NewStackLines(skip int, messages ...interface{}) Composer {
 	return &stackMessage{
 		trace: captureStack(skip),
 		args:  messages,
+		Base:  newBase(),
 	}
 }
 
@@ -90,6 +92,7 @@ func NewStackFormatted(skip int, message string, args ...interface{}) Composer {
 		trace:   captureStack(skip),
 		message: message,
 		args:    args,
+		Base:    newBase(),
 	}
 }
 
@@ -99,7 +102,7 @@ func NewStackFormatted(skip int, message string, args ...interface{}) Composer {
 //
 ////////////////////////////////////////////////////////////////////////
 
-func (m *stackMessage) Loggable() bool { return m.message != "" || len(m.args) > 0 }
+func (m *stackMessage) Loggable() bool { return hasContent(m.message, len(m.args)) }
 func (m *stackMessage) String() string {
 	if len(m.args) > 0 && m.message == "" {
 		m.message = fmt.Sprintln(append([]interface{}{m.getTag()}, m.args...))
@@ -12,6 +12,7 @@ type stringMessage struct {
 func NewDefaultMessage(p level.Priority, message string) Composer {
 	m := &stringMessage{
 		Message: message,
+		Base:    newBase(),
 	}
 
 	_ = m.SetPriority(p)
@@ -21,7 +22,7 @@ func NewDefaultMessage(p level.Priority, message string) Composer {
 
 // NewString provides a basic message consisting of a single line.
 func NewString(m string) Composer {
-	return &stringMessage{Message: m}
+	return &stringMessage{Message: m, Base: newBase()}
 }
 
 func (s *stringMessage) String() string {
@@ -29,10 +30,10 @@ func (s *stringMessage) String() string {
 }
 
 func (s *stringMessage) Loggable() bool {
-	return s.Message != ""
+	return hasContent(s.Message, 0)
 }
 
 func (s *stringMessage) Raw() interface{} {
 	_ = s.Collect()
-	return s
+	return &stringMessage{Message: s.Message, Base: s.snapshot()}
 }
@@ -49,6 +49,7 @@ func NewSystemInfo(priority level.Priority, message string) Composer {
 	s := &SystemInfo{
 		Message: message,
 		NumCPU:  runtime.NumCPU(),
+		Base:    newBase(),
 	}
 
 	if err = s.SetPriority(priority); err != nil {
@@ -107,9 +108,24 @@ func NewSystemInfo(priority level.Priority, message string) Composer {
 // populated.
 func (s *SystemInfo) Loggable() bool { return s.loggable }
 
-// Raw always returns the SystemInfo object, however it will call the
-// Collect method of the base operation first.
-func (s *SystemInfo) Raw() interface{} { _ = s.Collect(); return s }
+// Raw always returns a copy of the SystemInfo object, however it will
+// call the Collect method of the base operation first.
+func (s *SystemInfo) Raw() interface{} {
+	_ = s.Collect()
+	return &SystemInfo{
+		Message:    s.Message,
+		CPU:        s.CPU,
+		NumCPU:     s.NumCPU,
+		VMStat:     s.VMStat,
+		NetStat:    s.NetStat,
+		Partitions: s.Partitions,
+		Usage:      s.Usage,
+		IOStat:     s.IOStat,
+		Errors:     s.Errors,
+		Base:       s.snapshot(),
+		loggable:   s.loggable,
+	}
+}
 
 // String returns a string representation of the message, lazily
 // rendering the message, and caching it privately.
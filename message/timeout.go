@@ -0,0 +1,73 @@
+// Timeout Messages
+//
+// The timeout composer provides a consistent record of operations
+// that exceeded their time budget, whether the operation is an HTTP
+// request, a database query, or an RPC, so that all timeouts can be
+// queried in the same way.
+package message
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/mongodb/grip/level"
+)
+
+type timeoutMessage struct {
+	Operation string  `bson:"operation" json:"operation" yaml:"operation"`
+	BudgetMS  float64 `bson:"budget_ms" json:"budget_ms" yaml:"budget_ms"`
+	ElapsedMS float64 `bson:"elapsed_ms" json:"elapsed_ms" yaml:"elapsed_ms"`
+	OverageMS float64 `bson:"overage_ms" json:"overage_ms" yaml:"overage_ms"`
+	Base      `bson:"metadata" json:"metadata" yaml:"metadata"`
+
+	budget  time.Duration
+	elapsed time.Duration
+}
+
+// NewTimeout constructs an Error Composer that records that the
+// operation (e.g. "calling payments") timed out after the elapsed
+// time, given its budget. The raw form includes the overage, the time
+// by which the elapsed time exceeded the budget, or zero if it did
+// not. The message is not loggable if the operation is empty.
+func NewTimeout(operation string, budget, elapsed time.Duration) Composer {
+	overage := elapsed - budget
+	if overage < 0 {
+		overage = 0
+	}
+
+	m := &timeoutMessage{
+		Operation: operation,
+		BudgetMS:  float64(budget) / float64(time.Millisecond),
+		ElapsedMS: float64(elapsed) / float64(time.Millisecond),
+		OverageMS: float64(overage) / float64(time.Millisecond),
+		budget:    budget,
+		elapsed:   elapsed,
+		Base:      newBase(),
+	}
+
+	_ = m.SetPriority(level.Error)
+	return m
+}
+
+func (m *timeoutMessage) Loggable() bool { return m.Operation != "" }
+
+func (m *timeoutMessage) String() string {
+	if !m.Loggable() {
+		return ""
+	}
+
+	return fmt.Sprintf("timeout %s: %s > %s", m.Operation, batchDuration(m.elapsed), batchDuration(m.budget))
+}
+
+func (m *timeoutMessage) Raw() interface{} {
+	_ = m.Collect()
+	return &timeoutMessage{
+		Operation: m.Operation,
+		BudgetMS:  m.BudgetMS,
+		ElapsedMS: m.ElapsedMS,
+		OverageMS: m.OverageMS,
+		Base:      m.snapshot(),
+		budget:    m.budget,
+		elapsed:   m.elapsed,
+	}
+}
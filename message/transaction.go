@@ -0,0 +1,173 @@
+// Transaction Messages
+//
+// The transaction composer provides a consistent record of payment
+// transactions for audit logs. The messages never contain card data:
+// the composer redacts values that resemble card numbers (PANs), and
+// rejects annotations that contain them.
+package message
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/mongodb/grip/level"
+)
+
+const redactedCardNumber = "[REDACTED]"
+
+type transactionMessage struct {
+	ID       string `bson:"id" json:"id" yaml:"id"`
+	Amount   int64  `bson:"amount" json:"amount" yaml:"amount"`
+	Currency string `bson:"currency" json:"currency" yaml:"currency"`
+	Status   string `bson:"status" json:"status" yaml:"status"`
+	Base     `bson:"metadata" json:"metadata" yaml:"metadata"`
+}
+
+// NewTransaction constructs a Composer that records a payment
+// transaction, with the amount in the minor units of the currency
+// (e.g. cents). Transactions with a "failed" status have Error
+// priority, otherwise the message has Info priority. The message is
+// not loggable if the transaction id or currency is empty.
+//
+// Any part of the id, currency, or status that resembles a card
+// number is redacted, and Annotate returns an error for values that
+// resemble card numbers.
+func NewTransaction(id string, amount int64, currency, status string) Composer {
+	m := &transactionMessage{
+		ID:       redactCardNumbers(id),
+		Amount:   amount,
+		Currency: strings.ToUpper(redactCardNumbers(currency)),
+		Status:   redactCardNumbers(status),
+		Base:     newBase(),
+	}
+
+	if strings.EqualFold(m.Status, "failed") {
+		_ = m.SetPriority(level.Error)
+	} else {
+		_ = m.SetPriority(level.Info)
+	}
+
+	return m
+}
+
+func (m *transactionMessage) Loggable() bool { return m.ID != "" && m.Currency != "" }
+
+func (m *transactionMessage) String() string {
+	if !m.Loggable() {
+		return ""
+	}
+
+	return fmt.Sprintf("txn %s %s %s %s", m.ID, formatMinorUnits(m.Amount, m.Currency), m.Currency, m.Status)
+}
+
+func (m *transactionMessage) Annotate(key string, value interface{}) error {
+	if redactCardNumbers(fmt.Sprint(value)) != fmt.Sprint(value) {
+		return errors.New("cannot annotate transactions with card numbers")
+	}
+
+	return m.Base.Annotate(key, value)
+}
+
+func (m *transactionMessage) Raw() interface{} {
+	_ = m.Collect()
+	return &transactionMessage{
+		ID:       m.ID,
+		Amount:   m.Amount,
+		Currency: m.Currency,
+		Status:   m.Status,
+		Base:     m.snapshot(),
+	}
+}
+
+var (
+	currencySymbols = map[string]string{"USD": "$", "EUR": "€", "GBP": "£", "JPY": "¥"}
+
+	// currencyExponents holds the number of digits in the minor
+	// unit of currencies that do not use two.
+	currencyExponents = map[string]int{"JPY": 0, "KRW": 0, "BHD": 3, "KWD": 3}
+)
+
+// formatMinorUnits renders an amount of the currency's minor units
+// as a decimal amount (e.g. 1250 USD is "$12.50").
+func formatMinorUnits(amount int64, currency string) string {
+	exp, ok := currencyExponents[currency]
+	if !ok {
+		exp = 2
+	}
+
+	sign := ""
+	if amount < 0 {
+		sign = "-"
+		amount = -amount
+	}
+
+	divisor := int64(1)
+	for i := 0; i < exp; i++ {
+		divisor *= 10
+	}
+
+	out := fmt.Sprintf("%s%s%d", sign, currencySymbols[currency], amount/divisor)
+	if exp > 0 {
+		out += fmt.Sprintf(".%0*d", exp, amount%divisor)
+	}
+
+	return out
+}
+
+// redactCardNumbers replaces every sequence of 13 to 19 digits,
+// optionally separated by spaces or dashes, that passes the Luhn
+// check, with a placeholder.
+func redactCardNumbers(s string) string {
+	var out bytes.Buffer
+	for i := 0; i < len(s); {
+		if !isDigit(s[i]) {
+			out.WriteByte(s[i])
+			i++
+			continue
+		}
+
+		// find the end of the run of digits and separators,
+		// where separators must be followed by a digit.
+		digits := []byte{}
+		end := i
+		for end < len(s) {
+			if isDigit(s[end]) {
+				digits = append(digits, s[end])
+				end++
+			} else if (s[end] == ' ' || s[end] == '-') && end+1 < len(s) && isDigit(s[end+1]) {
+				end++
+			} else {
+				break
+			}
+		}
+
+		if len(digits) >= 13 && len(digits) <= 19 && passesLuhn(digits) {
+			out.WriteString(redactedCardNumber)
+		} else {
+			out.WriteString(s[i:end])
+		}
+		i = end
+	}
+
+	return out.String()
+}
+
+func isDigit(b byte) bool { return b >= '0' && b <= '9' }
+
+func passesLuhn(digits []byte) bool {
+	sum := 0
+	for i := len(digits) - 1; i >= 0; i-- {
+		d := int(digits[i] - '0')
+		if (len(digits)-i)%2 == 0 {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+	}
+
+	return sum%10 == 0
+}
@@ -0,0 +1,92 @@
+// State Transition Messages
+//
+// The state transition composer provides a consistent record of
+// objects that move between the states of a state machine, so that
+// the history of an object can be reconstructed from its log.
+package message
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mongodb/grip/level"
+)
+
+type stateTransitionMessage struct {
+	Entity  string `bson:"entity" json:"entity" yaml:"entity"`
+	ID      string `bson:"id" json:"id" yaml:"id"`
+	From    string `bson:"from" json:"from" yaml:"from"`
+	To      string `bson:"to" json:"to" yaml:"to"`
+	Trigger string `bson:"trigger,omitempty" json:"trigger,omitempty" yaml:"trigger,omitempty"`
+	Self    bool   `bson:"self,omitempty" json:"self,omitempty" yaml:"self,omitempty"`
+	Base    `bson:"metadata" json:"metadata" yaml:"metadata"`
+}
+
+// NewStateTransition constructs an Info Composer that records that
+// the entity (e.g. "order") with the id moved from one state to
+// another because of the trigger. The message is only loggable if the
+// entity and the new state are not empty, and the states differ; use
+// NewSelfTransition for transitions that return to the same state.
+func NewStateTransition(entity, id, from, to string, trigger string) Composer {
+	return newStateTransition(entity, id, from, to, trigger, false)
+}
+
+// NewSelfTransition constructs an Info Composer that records that the
+// entity with the id transitioned from the state back to the same
+// state because of the trigger, as state machines do for events that
+// do not change the state (e.g. a retry). The message is only
+// loggable if the entity and the state are not empty.
+func NewSelfTransition(entity, id, state string, trigger string) Composer {
+	return newStateTransition(entity, id, state, state, trigger, true)
+}
+
+func newStateTransition(entity, id, from, to, trigger string, self bool) Composer {
+	m := &stateTransitionMessage{
+		Entity:  entity,
+		ID:      id,
+		From:    from,
+		To:      to,
+		Trigger: trigger,
+		Self:    self,
+		Base:    newBase(),
+	}
+
+	_ = m.SetPriority(level.Info)
+	return m
+}
+
+func (m *stateTransitionMessage) Loggable() bool {
+	return m.Entity != "" && m.To != "" && (m.Self || m.From != m.To)
+}
+
+func (m *stateTransitionMessage) String() string {
+	if !m.Loggable() {
+		return ""
+	}
+
+	from := m.From
+	if from == "" {
+		from = "(none)"
+	}
+
+	out := strings.TrimSpace(fmt.Sprintf("%s %s", m.Entity, m.ID))
+	out = fmt.Sprintf("%s: %s → %s", out, from, m.To)
+	if m.Trigger != "" {
+		out = fmt.Sprintf("%s (%s)", out, m.Trigger)
+	}
+
+	return out
+}
+
+func (m *stateTransitionMessage) Raw() interface{} {
+	_ = m.Collect()
+	return &stateTransitionMessage{
+		Entity:  m.Entity,
+		ID:      m.ID,
+		From:    m.From,
+		To:      m.To,
+		Trigger: m.Trigger,
+		Self:    m.Self,
+		Base:    m.snapshot(),
+	}
+}
@@ -0,0 +1,69 @@
+// Webhook Received Messages
+//
+// The webhook received composer provides a consistent record of
+// inbound webhook deliveries, including whether the delivery's
+// signature was verified.
+package message
+
+import (
+	"fmt"
+
+	"github.com/mongodb/grip/level"
+)
+
+type webhookReceivedMessage struct {
+	Source      string `bson:"source" json:"source" yaml:"source"`
+	Event       string `bson:"event" json:"event" yaml:"event"`
+	Verified    bool   `bson:"verified" json:"verified" yaml:"verified"`
+	PayloadSize int    `bson:"payload_size" json:"payload_size" yaml:"payload_size"`
+	Base        `bson:"metadata" json:"metadata" yaml:"metadata"`
+}
+
+// NewWebhookReceived constructs a Composer that records the receipt
+// of a webhook from the source service. Messages for deliveries whose
+// signature could not be verified have Warning priority, otherwise
+// the message has Info priority. The message is not loggable if the
+// source is empty.
+func NewWebhookReceived(source, event string, verified bool, payloadSize int) Composer {
+	m := &webhookReceivedMessage{
+		Source:      source,
+		Event:       event,
+		Verified:    verified,
+		PayloadSize: payloadSize,
+		Base:        newBase(),
+	}
+
+	if verified {
+		_ = m.SetPriority(level.Info)
+	} else {
+		_ = m.SetPriority(level.Warning)
+	}
+
+	return m
+}
+
+func (m *webhookReceivedMessage) Loggable() bool { return m.Source != "" }
+
+func (m *webhookReceivedMessage) String() string {
+	if !m.Loggable() {
+		return ""
+	}
+
+	verified := "verified"
+	if !m.Verified {
+		verified = "unverified"
+	}
+
+	return fmt.Sprintf("received %s webhook '%s' from %s (%d bytes)", verified, m.Event, m.Source, m.PayloadSize)
+}
+
+func (m *webhookReceivedMessage) Raw() interface{} {
+	_ = m.Collect()
+	return &webhookReceivedMessage{
+		Source:      m.Source,
+		Event:       m.Event,
+		Verified:    m.Verified,
+		PayloadSize: m.PayloadSize,
+		Base:        m.snapshot(),
+	}
+}
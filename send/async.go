@@ -0,0 +1,357 @@
+package send
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/mongodb/grip/message"
+)
+
+const (
+	defaultAsyncQueueDepth   = 100
+	defaultAsyncCloseTimeout = 10 * time.Second
+)
+
+// OverflowPolicy controls how an async sender handles messages when
+// its queue is full.
+type OverflowPolicy int
+
+const (
+	// DropOverflow discards the message that the caller is
+	// sending, and is the default.
+	DropOverflow OverflowPolicy = iota
+
+	// BlockOverflow makes the caller wait until the queue has room
+	// for the message, or until the sender closes, when the message
+	// goes to the underlying sender.
+	BlockOverflow
+
+	// DropOldestOverflow discards the oldest message in the queue,
+	// to make room for the message that the caller is sending.
+	DropOldestOverflow
+)
+
+// Validate returns an error if the policy is not one of the defined
+// policies.
+func (p OverflowPolicy) Validate() error {
+	if p < DropOverflow || p > DropOldestOverflow {
+		return fmt.Errorf("%d is not a valid overflow policy", p)
+	}
+
+	return nil
+}
+
+func (p OverflowPolicy) String() string {
+	switch p {
+	case DropOverflow:
+		return "drop"
+	case BlockOverflow:
+		return "block"
+	case DropOldestOverflow:
+		return "drop-oldest"
+	default:
+		return fmt.Sprintf("OverflowPolicy(%d)", int(p))
+	}
+}
+
+// AsyncSenderOptions configures the queue of an async sender.
+type AsyncSenderOptions struct {
+	// QueueDepth is the number of messages that the sender queues
+	// for the underlying sender. Defaults to 100.
+	QueueDepth int
+
+	// Policy controls how the sender handles messages when the
+	// queue is full.
+	Policy OverflowPolicy
+
+	// CloseTimeout limits the time that Close waits for the
+	// underlying sender to send the queued messages. Defaults to
+	// 10 seconds.
+	CloseTimeout time.Duration
+}
+
+// Validate checks the options, and sets defaults for unspecified
+// values.
+func (o *AsyncSenderOptions) Validate() error {
+	errs := []string{}
+
+	if o.QueueDepth < 0 {
+		errs = append(errs, "queue depth cannot be negative")
+	}
+
+	if err := o.Policy.Validate(); err != nil {
+		errs = append(errs, err.Error())
+	}
+
+	if o.CloseTimeout < 0 {
+		errs = append(errs, "close timeout cannot be negative")
+	}
+
+	if o.QueueDepth == 0 {
+		o.QueueDepth = defaultAsyncQueueDepth
+	}
+
+	if o.CloseTimeout == 0 {
+		o.CloseTimeout = defaultAsyncCloseTimeout
+	}
+
+	if len(errs) > 0 {
+		return errors.New(strings.Join(errs, "; "))
+	}
+
+	return nil
+}
+
+// AsyncSenderStats counts the messages that an async sender has
+// handled.
+type AsyncSenderStats struct {
+	// Enqueued is the number of messages that the sender has
+	// added to its queue, including messages that it later
+	// dropped to make room for newer messages.
+	Enqueued int64 `json:"enqueued"`
+
+	// Sent is the number of queued messages that the sender has
+	// delivered to the underlying sender.
+	Sent int64 `json:"sent"`
+
+	// Dropped is the number of messages that the sender has
+	// discarded because its queue was full.
+	Dropped int64 `json:"dropped"`
+}
+
+type asyncSender struct {
+	pending   int64
+	enqueued  int64
+	sent      int64
+	dropped   int64
+	flushes   flushTimer
+	opts      AsyncSenderOptions
+	queue     chan message.Composer
+	closed    bool
+	mutex     sync.RWMutex
+	closing   chan struct{}
+	blocked   sync.WaitGroup
+	done      chan struct{}
+	closeOnce sync.Once
+	Sender
+}
+
+// NewAsyncSender wraps an existing Sender, and sends messages to it in
+// the background, so that callers do not wait for slow senders, such
+// as the SMTP and Slack senders. The sender queues at most queueDepth
+// messages, and handles messages that do not fit in the queue
+// according to the policy. Use DroppedMessages or BufferMetrics to
+// find the number of messages that the sender has dropped, and
+// AsyncStats for all of the sender's counters.
+func NewAsyncSender(inner Sender, queueDepth int, policy OverflowPolicy) (Sender, error) {
+	return NewAsyncSenderWithOptions(inner, AsyncSenderOptions{QueueDepth: queueDepth, Policy: policy})
+}
+
+// NewAsyncSenderWithOptions is the same as NewAsyncSender, but
+// configures the sender with the options.
+//
+// Flush waits for the underlying sender to send the queued messages,
+// and flushes the underlying sender. Close stops the sender from
+// queueing messages, waits at most the CloseTimeout for the underlying
+// sender to send the queued messages, and closes the underlying
+// sender. Messages that the underlying sender would not log, and
+// messages sent after Close, go directly to the underlying sender.
+func NewAsyncSenderWithOptions(inner Sender, opts AsyncSenderOptions) (Sender, error) {
+	if inner == nil {
+		return nil, errors.New("no underlying sender specified")
+	}
+
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+
+	s := &asyncSender{
+		opts:    opts,
+		queue:   make(chan message.Composer, opts.QueueDepth),
+		closing: make(chan struct{}),
+		done:    make(chan struct{}),
+		Sender:  inner,
+	}
+
+	go s.deliver()
+
+	return s, nil
+}
+
+func (s *asyncSender) Send(m message.Composer) {
+	if !s.Level().ShouldLog(m) {
+		s.Sender.Send(m)
+		return
+	}
+
+	s.mutex.RLock()
+
+	// after close, the underlying sender reports the message to
+	// its error handler.
+	if s.closed {
+		s.mutex.RUnlock()
+		s.Sender.Send(m)
+		return
+	}
+
+	atomic.AddInt64(&s.pending, 1)
+
+	if s.opts.Policy == BlockOverflow && !s.enqueue(m) {
+		// wait for room in the queue without holding the mutex,
+		// so that Close does not wait for the underlying sender.
+		s.blocked.Add(1)
+		s.mutex.RUnlock()
+		s.wait(m)
+		return
+	}
+	defer s.mutex.RUnlock()
+
+	switch s.opts.Policy {
+	case BlockOverflow:
+		// the message is in the queue.
+	case DropOldestOverflow:
+		for !s.enqueue(m) {
+			select {
+			case <-s.queue:
+				atomic.AddInt64(&s.pending, -1)
+				atomic.AddInt64(&s.dropped, 1)
+			default:
+				// the underlying sender took the oldest
+				// message first.
+			}
+		}
+	default:
+		if !s.enqueue(m) {
+			atomic.AddInt64(&s.pending, -1)
+			atomic.AddInt64(&s.dropped, 1)
+			return
+		}
+	}
+
+	atomic.AddInt64(&s.enqueued, 1)
+}
+
+// wait adds the message to the queue when it has room, or, if the
+// sender closes first, sends the message to the underlying sender,
+// which reports it to its error handler once it has closed.
+func (s *asyncSender) wait(m message.Composer) {
+	select {
+	case s.queue <- m:
+		s.blocked.Done()
+		atomic.AddInt64(&s.enqueued, 1)
+	case <-s.closing:
+		s.blocked.Done()
+		atomic.AddInt64(&s.pending, -1)
+		s.Sender.Send(m)
+	}
+}
+
+// enqueue adds the message to the queue, if it has room.
+func (s *asyncSender) enqueue(m message.Composer) bool {
+	select {
+	case s.queue <- m:
+		return true
+	default:
+		return false
+	}
+}
+
+func (s *asyncSender) deliver() {
+	defer close(s.done)
+
+	for m := range s.queue {
+		s.Sender.Send(m)
+		atomic.AddInt64(&s.sent, 1)
+		atomic.AddInt64(&s.pending, -1)
+	}
+}
+
+// AsyncStats returns the counters of an async sender, or an error if
+// the Sender is not an async sender.
+func AsyncStats(s Sender) (AsyncSenderStats, error) {
+	sender, ok := s.(*asyncSender)
+	if !ok {
+		return AsyncSenderStats{}, fmt.Errorf("%s is not an async sender", s.Name())
+	}
+
+	return sender.Stats(), nil
+}
+
+// Stats returns the number of messages that the sender has queued,
+// sent to the underlying sender, and dropped.
+func (s *asyncSender) Stats() AsyncSenderStats {
+	return AsyncSenderStats{
+		Enqueued: atomic.LoadInt64(&s.enqueued),
+		Sent:     atomic.LoadInt64(&s.sent),
+		Dropped:  atomic.LoadInt64(&s.dropped),
+	}
+}
+
+// Flush waits until the underlying sender has sent the queued
+// messages, or until the context is canceled, and flushes the
+// underlying sender.
+func (s *asyncSender) Flush(ctx context.Context) error {
+	defer s.flushes.observe(time.Now())
+
+	ticker := time.NewTicker(10 * time.Millisecond)
+	defer ticker.Stop()
+
+	for atomic.LoadInt64(&s.pending) > 0 {
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	return s.Sender.Flush(ctx)
+}
+
+func (s *asyncSender) Close() error {
+	var err error
+	s.closeOnce.Do(func() {
+		// wake the callers that are waiting for room in a full
+		// queue, and wait for the callers that are queueing
+		// messages, before closing the queue.
+		close(s.closing)
+		s.mutex.Lock()
+		s.closed = true
+		s.mutex.Unlock()
+		s.blocked.Wait()
+		close(s.queue)
+
+		select {
+		case <-s.done:
+		case <-time.After(s.opts.CloseTimeout):
+			err = fmt.Errorf("timed out after %s sending %d queued messages", s.opts.CloseTimeout, atomic.LoadInt64(&s.pending))
+		}
+	})
+
+	// messages that remain after a timeout reach the closed
+	// underlying sender, which reports them to its error handler.
+	if cerr := s.Sender.Close(); err == nil {
+		err = cerr
+	}
+
+	return err
+}
+
+// BufferMetrics reports the number of messages that the sender has
+// queued and not yet sent, including the message that the underlying
+// sender is sending, and the number of messages that the sender has
+// queued and dropped.
+func (s *asyncSender) BufferMetrics() BufferMetrics {
+	m := BufferMetrics{
+		QueueDepth: atomic.LoadInt64(&s.pending),
+		Enqueued:   atomic.LoadInt64(&s.enqueued),
+		Dropped:    atomic.LoadInt64(&s.dropped),
+	}
+	s.flushes.load(&m)
+
+	return m
+}
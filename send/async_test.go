@@ -0,0 +1,236 @@
+package send
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mongodb/grip/message"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// gatedSender blocks sending messages until its gate opens, and
+// records the text of the messages that it sends.
+type gatedSender struct {
+	gate    chan struct{}
+	entered chan struct{}
+	sent    []string
+	mutex   sync.Mutex
+	*Base
+}
+
+func newGatedSender() *gatedSender {
+	return &gatedSender{
+		gate:    make(chan struct{}),
+		entered: make(chan struct{}, 100),
+		Base:    NewBase("gated"),
+	}
+}
+
+func (s *gatedSender) Send(m message.Composer) {
+	if !s.Level().ShouldLog(m) {
+		return
+	}
+
+	s.entered <- struct{}{}
+	<-s.gate
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.sent = append(s.sent, m.String())
+}
+
+func (s *gatedSender) messages() []string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return append([]string{}, s.sent...)
+}
+
+func TestAsyncSenderOverflowPolicies(t *testing.T) {
+	for policy, expected := range map[OverflowPolicy][]string{
+		DropOverflow:       {"0", "1"},
+		DropOldestOverflow: {"0", "2"},
+		BlockOverflow:      {"0", "1", "2"},
+	} {
+		t.Run(policy.String(), func(t *testing.T) {
+			inner := newGatedSender()
+			s, err := NewAsyncSender(inner, 1, policy)
+			require.NoError(t, err)
+
+			// the underlying sender takes the first message, and
+			// the second message fills the queue.
+			s.Send(message.NewString("0"))
+			<-inner.entered
+			s.Send(message.NewString("1"))
+
+			sent := make(chan struct{})
+			go func() {
+				s.Send(message.NewString("2"))
+				close(sent)
+			}()
+
+			select {
+			case <-sent:
+				assert.NotEqual(t, BlockOverflow, policy, "send did not block")
+			case <-time.After(50 * time.Millisecond):
+				assert.Equal(t, BlockOverflow, policy, "send blocked")
+			}
+
+			close(inner.gate)
+			<-sent
+			require.NoError(t, s.Flush(context.Background()))
+			assert.Equal(t, expected, inner.messages())
+
+			dropped, err := DroppedMessages(s)
+			require.NoError(t, err)
+			assert.Equal(t, int64(3-len(expected)), dropped)
+
+			metrics := s.(MetricsSender).BufferMetrics()
+			assert.Equal(t, int64(0), metrics.QueueDepth)
+			stats, err := AsyncStats(s)
+			require.NoError(t, err)
+			assert.Equal(t, int64(len(expected)), stats.Sent)
+			assert.Equal(t, dropped, stats.Dropped)
+			assert.Equal(t, metrics.Enqueued, stats.Enqueued)
+
+			// the oldest message that the sender drops was queued.
+			if policy == DropOverflow {
+				assert.Equal(t, int64(2), stats.Enqueued)
+			} else {
+				assert.Equal(t, int64(3), stats.Enqueued)
+			}
+			assert.Equal(t, dropped, metrics.Dropped)
+
+			require.NoError(t, s.Close())
+		})
+	}
+}
+
+func TestAsyncSenderWithConcurrentProducers(t *testing.T) {
+	const producers, messages = 20, 20
+
+	for _, policy := range []OverflowPolicy{DropOverflow, DropOldestOverflow, BlockOverflow} {
+		t.Run(policy.String(), func(t *testing.T) {
+			inner := newSlowSender(time.Millisecond)
+			s, err := NewAsyncSender(inner, 10, policy)
+			require.NoError(t, err)
+
+			wg := &sync.WaitGroup{}
+			for i := 0; i < producers; i++ {
+				wg.Add(1)
+				go func(i int) {
+					defer wg.Done()
+					for j := 0; j < messages; j++ {
+						s.Send(message.NewString(fmt.Sprintf("producer %d message %d", i, j)))
+					}
+				}(i)
+			}
+			wg.Wait()
+			require.NoError(t, s.Close())
+
+			dropped, err := DroppedMessages(s)
+			require.NoError(t, err)
+			assert.Equal(t, int64(producers*messages), inner.sent()+dropped)
+
+			stats, err := AsyncStats(s)
+			require.NoError(t, err)
+			assert.Equal(t, inner.sent(), stats.Sent)
+
+			if policy == BlockOverflow {
+				assert.Zero(t, dropped)
+			} else {
+				assert.NotZero(t, dropped)
+			}
+		})
+	}
+}
+
+func TestAsyncSenderCloseDrainsQueue(t *testing.T) {
+	inner := newSlowSender(time.Millisecond)
+	s, err := NewAsyncSender(inner, 100, DropOverflow)
+	require.NoError(t, err)
+
+	for i := 0; i < 50; i++ {
+		s.Send(message.NewString("queued"))
+	}
+	require.NoError(t, s.Close())
+	assert.Equal(t, int64(50), inner.sent())
+}
+
+func TestAsyncSenderCloseTimesOut(t *testing.T) {
+	inner := newGatedSender()
+	defer close(inner.gate)
+
+	s, err := NewAsyncSenderWithOptions(inner, AsyncSenderOptions{CloseTimeout: 20 * time.Millisecond})
+	require.NoError(t, err)
+
+	s.Send(message.NewString("stuck"))
+	s.Send(message.NewString("queued"))
+	<-inner.entered
+
+	err = s.Close()
+	require.Error(t, err)
+	assert.Equal(t, "timed out after 20ms sending 2 queued messages", err.Error())
+}
+
+func TestAsyncSenderCloseWakesBlockedCallers(t *testing.T) {
+	inner := newGatedSender()
+
+	s, err := NewAsyncSenderWithOptions(inner, AsyncSenderOptions{QueueDepth: 1, Policy: BlockOverflow, CloseTimeout: 20 * time.Millisecond})
+	require.NoError(t, err)
+
+	s.Send(message.NewString("stuck"))
+	<-inner.entered
+	s.Send(message.NewString("queued"))
+
+	sent := make(chan struct{})
+	go func() {
+		s.Send(message.NewString("blocked"))
+		close(sent)
+	}()
+	time.Sleep(20 * time.Millisecond)
+
+	closed := make(chan error, 1)
+	go func() { closed <- s.Close() }()
+
+	select {
+	case err = <-closed:
+		require.Error(t, err)
+		assert.Equal(t, "timed out after 20ms sending 2 queued messages", err.Error())
+	case <-time.After(5 * time.Second):
+		t.Fatal("close waited for the blocked caller")
+	}
+
+	// the caller sends the message to the underlying sender.
+	<-inner.entered
+	close(inner.gate)
+	<-sent
+	assert.Contains(t, inner.messages(), "blocked")
+}
+
+func TestAsyncSenderOptionsValidate(t *testing.T) {
+	opts := AsyncSenderOptions{}
+	require.NoError(t, opts.Validate())
+	assert.Equal(t, defaultAsyncQueueDepth, opts.QueueDepth)
+	assert.Equal(t, DropOverflow, opts.Policy)
+	assert.Equal(t, defaultAsyncCloseTimeout, opts.CloseTimeout)
+
+	opts = AsyncSenderOptions{QueueDepth: -1, Policy: OverflowPolicy(7), CloseTimeout: -time.Second}
+	err := opts.Validate()
+	require.Error(t, err)
+	assert.Equal(t, "queue depth cannot be negative; 7 is not a valid overflow policy; close timeout cannot be negative", err.Error())
+
+	_, err = NewAsyncSender(nil, 1, DropOverflow)
+	assert.Error(t, err)
+
+	s, err := NewAsyncSender(MakeInternalLogger(), 1, BlockOverflow)
+	require.NoError(t, err)
+	assert.Implements(t, (*MetricsSender)(nil), s)
+	require.NoError(t, s.Close())
+
+	_, err = AsyncStats(MakeInternalLogger())
+	assert.Error(t, err)
+}
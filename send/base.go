@@ -1,13 +1,20 @@
 package send
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/mongodb/grip/message"
 )
 
+// ErrSenderClosed is the error that senders report to their error
+// handlers for messages that they receive after they are closed.
+var ErrSenderClosed = errors.New("sender is closed")
+
 // Base provides most of the functionality of the Sender interface,
 // except for the Send method, to facilitate writing novel Sender
 // implementations. All implementations of the functions
@@ -19,21 +26,33 @@ type Base struct {
 
 	// function literals which allow customizable functionality.
 	// they are set either in the constructor (e.g. MakeBase) of
-	// via the SetErrorHandler/SetFormatter injector.
-	errHandler ErrorHandler
-	reset      func()
-	closer     func() error
-	formatter  MessageFormatter
+	// via the SetErrorHandler/AddErrorHandler/SetFormatter
+	// injectors.
+	errHandlers []SendErrorHandler
+	reset       func()
+	closer      func() error
+	formatter   MessageFormatter
+
+	// when set, the sender records the time that it sends
+	// messages rather than the timestamp of the message.
+	sendTime bool
+
+	// closed is set, atomically, when Close is first called, and
+	// closeErr holds the result of the closer.
+	closed    int32
+	closeOnce sync.Once
+	closeErr  error
 }
 
 // NewBase constructs a basic Base structure with no op functions for
-// reset, close, and error handling.
+// reset and close, and the default error handler (see
+// MakeDefaultErrorHandler).
 func NewBase(n string) *Base {
 	return &Base{
-		name:       n,
-		reset:      func() {},
-		closer:     func() error { return nil },
-		errHandler: func(error, message.Composer) {},
+		name:        n,
+		reset:       func() {},
+		closer:      func() error { return nil },
+		errHandlers: []SendErrorHandler{MakeDefaultErrorHandler()},
 	}
 }
 
@@ -47,8 +66,34 @@ func MakeBase(n string, reseter func(), closer func() error) *Base {
 	return b
 }
 
-// Close calls the closer function.
-func (b *Base) Close() error { return b.closer() }
+// Flush is a no-op for senders that deliver messages synchronously,
+// and senders that buffer messages must implement Flush.
+func (b *Base) Flush(_ context.Context) error { return nil }
+
+// Close calls the closer function. Close is idempotent: the closer
+// runs only once, and later calls return the result of the first
+// call. Senders report ErrSenderClosed to their error handlers for
+// messages that they receive after Close.
+func (b *Base) Close() error {
+	b.closeOnce.Do(func() {
+		atomic.StoreInt32(&b.closed, 1)
+		b.closeErr = b.closer()
+	})
+
+	return b.closeErr
+}
+
+// reportClosed reports ErrSenderClosed to the error handlers, and
+// returns true, if the sender is closed. Implementations of Send
+// should not send messages when reportClosed returns true.
+func (b *Base) reportClosed(m message.Composer) bool {
+	if atomic.LoadInt32(&b.closed) == 0 {
+		return false
+	}
+
+	b.ErrorHandler(ErrSenderClosed, m)
+	return true
+}
 
 // Name returns the name of the Sender.
 func (b *Base) Name() string {
@@ -80,7 +125,10 @@ func (b *Base) SetFormatter(mf MessageFormatter) error {
 	return nil
 }
 
-// SetErrorHandler configures the error handling function for this Sender.
+// SetErrorHandler configures the error handling function for this
+// Sender, and replaces all existing error handlers, including the
+// default error handler. To disable error handling, set a handler
+// that does nothing.
 func (b *Base) SetErrorHandler(eh ErrorHandler) error {
 	if eh == nil {
 		return errors.New("error handler must be non-nil")
@@ -88,18 +136,43 @@ func (b *Base) SetErrorHandler(eh ErrorHandler) error {
 
 	b.mutex.Lock()
 	defer b.mutex.Unlock()
-	b.errHandler = eh
+	b.errHandlers = []SendErrorHandler{func(_ string, err error, m message.Composer) { eh(err, m) }}
+
+	return nil
+}
+
+// AddErrorHandler registers an additional error handler, which the
+// Sender calls after all existing handlers. It is not part of the
+// Sender interface; use the AddErrorHandler function to add error
+// handlers to Sender values.
+func (b *Base) AddErrorHandler(eh SendErrorHandler) error {
+	if eh == nil {
+		return errors.New("error handler must be non-nil")
+	}
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.errHandlers = append(b.errHandlers, eh)
 
 	return nil
 }
 
-// ErrorHandler calls the error handler, and is a wrapper around the
-// embedded ErrorHandler function. It is not part of the Sender interface.
+// ErrorHandler calls the error handlers, in order, for non-nil
+// errors. All Sender implementations that embed Base should report
+// errors using this method. It is not part of the Sender interface.
 func (b *Base) ErrorHandler(err error, m message.Composer) {
+	if err == nil {
+		return
+	}
+
 	b.mutex.RLock()
-	defer b.mutex.RUnlock()
+	name := b.name
+	handlers := b.errHandlers
+	b.mutex.RUnlock()
 
-	b.errHandler(err, m)
+	for _, eh := range handlers {
+		eh(name, err, m)
+	}
 }
 
 // SetLevel configures the level (default levels and threshold levels)
@@ -124,3 +197,59 @@ func (b *Base) Level() LevelInfo {
 
 	return b.level
 }
+
+// SetUseSendTime configures whether the Sender records the time that
+// it sends messages, rather than the time that the messages were
+// created, for outputs that include a timestamp. Senders use the
+// timestamp of the message by default. It is not part of the Sender
+// interface; use the SetUseSendTime function to configure Sender
+// values.
+func (b *Base) SetUseSendTime(on bool) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.sendTime = on
+}
+
+// timestamp returns the time that the Sender should record for the
+// message.
+func (b *Base) timestamp(m message.Composer) time.Time {
+	b.mutex.RLock()
+	sendTime := b.sendTime
+	b.mutex.RUnlock()
+
+	if sendTime {
+		return time.Now()
+	}
+
+	return messageTime(m)
+}
+
+// SetUseSendTime configures the sender to record the time that it
+// sends messages, rather than the time that the messages were
+// created, which was the behavior of previous versions of grip.
+// Returns an error if the sender does not support the option. All
+// senders that embed Base support the option.
+func SetUseSendTime(s Sender, on bool) error {
+	sender, ok := s.(interface {
+		SetUseSendTime(bool)
+	})
+	if !ok {
+		return fmt.Errorf("sender %s does not support configuring timestamps", s.Name())
+	}
+
+	sender.SetUseSendTime(on)
+	return nil
+}
+
+// messageTime returns the time that the message was created, for
+// messages that record it, and the current time otherwise.
+func messageTime(m message.Composer) time.Time {
+	if tm, ok := m.(message.Timestamped); ok {
+		if t := tm.Timestamp(); !t.IsZero() {
+			return t
+		}
+	}
+
+	return time.Now()
+}
@@ -0,0 +1,83 @@
+package send
+
+import (
+	"sync"
+	"time"
+)
+
+// levelBoost is an active call to BoostLevel.
+type levelBoost struct {
+	level LevelInfo
+}
+
+// boostedSender records the level of a Sender before its first active
+// boost, and its active boosts, in the order they were applied.
+type boostedSender struct {
+	original LevelInfo
+	boosts   []*levelBoost
+}
+
+var (
+	boostedSenders      = map[Sender]*boostedSender{}
+	boostedSendersMutex sync.Mutex
+)
+
+// BoostLevel sets the level of the Sender for the duration, and
+// then reverts it, for example to log debug messages from a running
+// process during an incident. The returned function reverts the
+// boost before the duration elapses, and may be called more than
+// once.
+//
+// Boosts nest: while boosts are active, the sender uses the level of
+// the most recent active boost, and when the last active boost
+// reverts, the sender returns to its level from before the first
+// boost. If the level is not valid, BoostLevel does not change the
+// sender, and the returned function does nothing.
+func BoostLevel(s Sender, l LevelInfo, d time.Duration) (revert func()) {
+	boostedSendersMutex.Lock()
+	defer boostedSendersMutex.Unlock()
+
+	original := s.Level()
+	if err := s.SetLevel(l); err != nil {
+		return func() {}
+	}
+
+	state, ok := boostedSenders[s]
+	if !ok {
+		state = &boostedSender{original: original}
+		boostedSenders[s] = state
+	}
+
+	boost := &levelBoost{level: l}
+	state.boosts = append(state.boosts, boost)
+
+	once := &sync.Once{}
+	expire := func() { once.Do(func() { unboost(s, boost) }) }
+	timer := time.AfterFunc(d, expire)
+
+	return func() {
+		timer.Stop()
+		expire()
+	}
+}
+
+func unboost(s Sender, boost *levelBoost) {
+	boostedSendersMutex.Lock()
+	defer boostedSendersMutex.Unlock()
+
+	state := boostedSenders[s]
+	for idx, active := range state.boosts {
+		if active == boost {
+			state.boosts = append(state.boosts[:idx], state.boosts[idx+1:]...)
+			break
+		}
+	}
+
+	if len(state.boosts) == 0 {
+		delete(boostedSenders, s)
+		_ = s.SetLevel(state.original)
+		return
+	}
+
+	_ = s.SetLevel(state.boosts[len(state.boosts)-1].level)
+}
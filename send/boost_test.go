@@ -0,0 +1,75 @@
+package send
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mongodb/grip/level"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBoostLevelRevertsAfterDuration(t *testing.T) {
+	assert := assert.New(t)
+
+	original := LevelInfo{level.Info, level.Info}
+	sender, err := NewStreamLogger("boost", discardWriter{}, original)
+	require.NoError(t, err)
+
+	BoostLevel(sender, LevelInfo{level.Info, level.Debug}, 20*time.Millisecond)
+	assert.Equal(level.Debug, sender.Level().Threshold)
+
+	time.Sleep(100 * time.Millisecond)
+	assert.Equal(original, sender.Level())
+}
+
+func TestBoostLevelNests(t *testing.T) {
+	assert := assert.New(t)
+
+	original := LevelInfo{level.Info, level.Warning}
+	sender, err := NewStreamLogger("boost", discardWriter{}, original)
+	require.NoError(t, err)
+
+	info := LevelInfo{level.Info, level.Info}
+	debug := LevelInfo{level.Info, level.Debug}
+	trace := LevelInfo{level.Info, level.Trace}
+
+	revertInfo := BoostLevel(sender, info, time.Hour)
+	revertDebug := BoostLevel(sender, debug, time.Hour)
+	assert.Equal(debug, sender.Level())
+
+	// reverting an earlier boost keeps the most recent boost.
+	revertInfo()
+	assert.Equal(debug, sender.Level())
+
+	revertTrace := BoostLevel(sender, trace, time.Hour)
+	assert.Equal(trace, sender.Level())
+	revertTrace()
+	assert.Equal(debug, sender.Level())
+
+	// reverting is idempotent.
+	revertTrace()
+	revertInfo()
+	assert.Equal(debug, sender.Level())
+
+	revertDebug()
+	assert.Equal(original, sender.Level())
+
+	revertInfo = BoostLevel(sender, info, time.Hour)
+	assert.Equal(info, sender.Level())
+	revertInfo()
+	assert.Equal(original, sender.Level())
+}
+
+func TestBoostLevelWithInvalidLevel(t *testing.T) {
+	assert := assert.New(t)
+
+	original := LevelInfo{level.Info, level.Info}
+	sender, err := NewStreamLogger("boost", discardWriter{}, original)
+	require.NoError(t, err)
+
+	revert := BoostLevel(sender, LevelInfo{level.Invalid, level.Invalid}, time.Hour)
+	assert.Equal(original, sender.Level())
+	assert.NotPanics(revert)
+	assert.Equal(original, sender.Level())
+}
@@ -0,0 +1,264 @@
+package send
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mongodb/grip/message"
+)
+
+const (
+	defaultBufferedFlushInterval = time.Minute
+	defaultBufferedBufferSize    = 100
+)
+
+// BufferedSenderOptions configures when a buffered sender flushes
+// the messages that it buffers to the underlying sender.
+type BufferedSenderOptions struct {
+	// FlushInterval is how often the sender flushes the buffer.
+	// Defaults to a minute.
+	FlushInterval time.Duration
+
+	// BufferSize is the number of messages at which the sender
+	// flushes the buffer. Defaults to 100.
+	BufferSize int
+
+	// MaxBatchBytes, if specified, is the total size, in bytes, of
+	// the text of the buffered messages at which the sender flushes
+	// the buffer, for underlying senders that limit the size of
+	// their payloads. The sender flushes the buffer before adding a
+	// message that would exceed the limit, and sends messages that
+	// are larger than the limit on their own.
+	MaxBatchBytes int
+}
+
+// Validate checks the options, and sets defaults for unspecified
+// values.
+func (o *BufferedSenderOptions) Validate() error {
+	errs := []string{}
+
+	if o.FlushInterval < 0 {
+		errs = append(errs, "flush interval cannot be negative")
+	}
+
+	if o.BufferSize < 0 {
+		errs = append(errs, "buffer size cannot be negative")
+	}
+
+	if o.MaxBatchBytes < 0 {
+		errs = append(errs, "max batch bytes cannot be negative")
+	}
+
+	if o.FlushInterval == 0 {
+		o.FlushInterval = defaultBufferedFlushInterval
+	}
+
+	if o.BufferSize == 0 {
+		o.BufferSize = defaultBufferedBufferSize
+	}
+
+	if len(errs) > 0 {
+		return errors.New(strings.Join(errs, "; "))
+	}
+
+	return nil
+}
+
+type bufferedSender struct {
+	flushes    flushTimer
+	opts       BufferedSenderOptions
+	buffer     []message.Composer
+	bytes      int
+	enqueued   int64
+	dropped    int64
+	closed     bool
+	mutex      sync.Mutex
+	flushMutex sync.Mutex
+	stop       chan struct{}
+	done       chan struct{}
+	closeOnce  sync.Once
+	Sender
+}
+
+// NewBufferedSender wraps an existing Sender, and buffers the messages
+// that it sends, sending them to the underlying sender when the buffer
+// holds size messages, and every interval, for underlying senders that
+// are more efficient when they send messages in bursts. Intervals and
+// sizes that are not positive use the defaults of a minute and 100
+// messages. Use NewBufferedSenderWithOptions to also limit the size,
+// in bytes, of the buffered messages.
+func NewBufferedSender(sender Sender, interval time.Duration, size int) Sender {
+	if interval < 0 {
+		interval = 0
+	}
+
+	if size < 0 {
+		size = 0
+	}
+
+	s, _ := NewBufferedSenderWithOptions(sender, BufferedSenderOptions{FlushInterval: interval, BufferSize: size})
+	return s
+}
+
+// NewBufferedSenderWithOptions is the same as NewBufferedSender, but
+// configures the sender with the options, and returns an error if the
+// options are not valid.
+//
+// The sender flushes the buffer on Flush, and on Close, before it
+// closes the underlying sender. Messages that the underlying sender
+// would not log are not buffered.
+func NewBufferedSenderWithOptions(sender Sender, opts BufferedSenderOptions) (Sender, error) {
+	if sender == nil {
+		return nil, errors.New("no underlying sender specified")
+	}
+
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+
+	s := &bufferedSender{
+		opts:   opts,
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+		Sender: sender,
+	}
+
+	go s.flushPeriodically()
+
+	return s, nil
+}
+
+func (s *bufferedSender) Send(m message.Composer) {
+	if !s.Level().ShouldLog(m) {
+		s.Sender.Send(m)
+		return
+	}
+
+	size := len(m.String())
+
+	s.mutex.Lock()
+
+	// after close, the underlying sender reports the message to
+	// its error handler.
+	if s.closed {
+		s.dropped++
+		s.mutex.Unlock()
+		s.Sender.Send(m)
+		return
+	}
+
+	var batches [][]message.Composer
+	if s.opts.MaxBatchBytes > 0 && len(s.buffer) > 0 && s.bytes+size > s.opts.MaxBatchBytes {
+		batches = append(batches, s.take())
+	}
+
+	s.buffer = append(s.buffer, m)
+	s.bytes += size
+	s.enqueued++
+
+	if len(s.buffer) >= s.opts.BufferSize || (s.opts.MaxBatchBytes > 0 && s.bytes >= s.opts.MaxBatchBytes) {
+		batches = append(batches, s.take())
+	}
+
+	s.send(batches...)
+}
+
+// take returns the buffered messages, and empties the buffer. The
+// caller must hold the mutex.
+func (s *bufferedSender) take() []message.Composer {
+	batch := s.buffer
+	s.buffer = nil
+	s.bytes = 0
+
+	return batch
+}
+
+// send releases the mutex, which the caller must hold, and sends the
+// batches to the underlying sender. Batches are sent in the order that
+// they were taken from the buffer, even when several goroutines flush
+// the buffer at once.
+func (s *bufferedSender) send(batches ...[]message.Composer) {
+	if len(batches) == 0 {
+		s.mutex.Unlock()
+		return
+	}
+
+	s.flushMutex.Lock()
+	defer s.flushMutex.Unlock()
+	s.mutex.Unlock()
+
+	for _, batch := range batches {
+		for _, m := range batch {
+			s.Sender.Send(m)
+		}
+	}
+}
+
+func (s *bufferedSender) flushBuffer() {
+	s.mutex.Lock()
+	if len(s.buffer) == 0 {
+		s.mutex.Unlock()
+		return
+	}
+
+	s.send(s.take())
+}
+
+func (s *bufferedSender) flushPeriodically() {
+	defer close(s.done)
+
+	ticker := time.NewTicker(s.opts.FlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			s.flushBuffer()
+		case <-s.stop:
+			return
+		}
+	}
+}
+
+// Flush sends the buffered messages to the underlying sender, and
+// flushes the underlying sender.
+func (s *bufferedSender) Flush(ctx context.Context) error {
+	defer s.flushes.observe(time.Now())
+
+	s.flushBuffer()
+
+	return s.Sender.Flush(ctx)
+}
+
+func (s *bufferedSender) Close() error {
+	s.closeOnce.Do(func() {
+		close(s.stop)
+		<-s.done
+
+		s.mutex.Lock()
+		s.closed = true
+		s.send(s.take())
+	})
+
+	return s.Sender.Close()
+}
+
+// BufferMetrics reports the number of messages in the buffer, the
+// number of messages that the sender has buffered, and the number of
+// messages that the sender did not buffer because it was closed.
+func (s *bufferedSender) BufferMetrics() BufferMetrics {
+	s.mutex.Lock()
+	m := BufferMetrics{
+		QueueDepth: int64(len(s.buffer)),
+		Enqueued:   s.enqueued,
+		Dropped:    s.dropped,
+	}
+	s.mutex.Unlock()
+
+	s.flushes.load(&m)
+
+	return m
+}
@@ -0,0 +1,150 @@
+package send
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mongodb/grip/level"
+	"github.com/mongodb/grip/message"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBufferedSenderOptionsValidate(t *testing.T) {
+	assert := assert.New(t)
+
+	opts := BufferedSenderOptions{}
+	assert.NoError(opts.Validate())
+	assert.Equal(time.Minute, opts.FlushInterval)
+	assert.Equal(100, opts.BufferSize)
+	assert.Equal(0, opts.MaxBatchBytes)
+
+	for _, opts := range []BufferedSenderOptions{
+		{FlushInterval: -1},
+		{BufferSize: -1},
+		{MaxBatchBytes: -1},
+	} {
+		assert.Error(opts.Validate(), "%+v", opts)
+	}
+
+	_, err := NewBufferedSenderWithOptions(nil, BufferedSenderOptions{})
+	assert.Error(err)
+}
+
+func TestBufferedSenderFlushTriggers(t *testing.T) {
+	newSink := func(t *testing.T) *InternalSender {
+		sink, err := NewInternalLogger("sink", LevelInfo{level.Info, level.Info})
+		require.NoError(t, err)
+		return sink
+	}
+	rendered := func(sink *InternalSender) []string {
+		out := []string{}
+		for sink.HasMessage() {
+			out = append(out, sink.GetMessage().Rendered)
+		}
+		return out
+	}
+	msg := func(text string) message.Composer { return message.NewDefaultMessage(level.Info, text) }
+
+	t.Run("Count", func(t *testing.T) {
+		assert := assert.New(t)
+
+		sink := newSink(t)
+		s := NewBufferedSender(sink, time.Hour, 3)
+		defer s.Close()
+
+		s.Send(msg("one"))
+		s.Send(msg("two"))
+		assert.Equal(0, sink.Len())
+		s.Send(msg("three"))
+		assert.Equal([]string{"one", "two", "three"}, rendered(sink))
+
+		// messages that would not be logged are not buffered.
+		s.Send(message.NewDefaultMessage(level.Debug, "quiet"))
+		assert.Equal(1, sink.Len())
+		assert.False(sink.GetMessage().Logged)
+	})
+	t.Run("Interval", func(t *testing.T) {
+		assert := assert.New(t)
+
+		sink := newSink(t)
+		s := NewBufferedSender(sink, 20*time.Millisecond, 100)
+		defer s.Close()
+
+		s.Send(msg("one"))
+		s.Send(msg("two"))
+		for deadline := time.Now().Add(time.Second); sink.Len() < 2 && time.Now().Before(deadline); {
+			time.Sleep(5 * time.Millisecond)
+		}
+		assert.Equal([]string{"one", "two"}, rendered(sink))
+	})
+	t.Run("Bytes", func(t *testing.T) {
+		assert := assert.New(t)
+
+		sink := newSink(t)
+		s, err := NewBufferedSenderWithOptions(sink, BufferedSenderOptions{
+			FlushInterval: time.Hour,
+			BufferSize:    100,
+			MaxBatchBytes: 10,
+		})
+		require.NoError(t, err)
+		defer s.Close()
+
+		// adding "cccc" would exceed the limit, so the buffer
+		// flushes first.
+		s.Send(msg("aaaa"))
+		s.Send(msg("bbbb"))
+		assert.Equal(0, sink.Len())
+		s.Send(msg("cccc"))
+		assert.Equal([]string{"aaaa", "bbbb"}, rendered(sink))
+
+		// reaching the limit flushes the buffer.
+		s.Send(msg("dddddd"))
+		assert.Equal([]string{"cccc", "dddddd"}, rendered(sink))
+
+		// messages larger than the limit are sent on their own.
+		s.Send(msg("ee"))
+		large := strings.Repeat("f", 25)
+		s.Send(msg(large))
+		assert.Equal([]string{"ee", large}, rendered(sink))
+	})
+	t.Run("CountAndBytes", func(t *testing.T) {
+		assert := assert.New(t)
+
+		sink := newSink(t)
+		s, err := NewBufferedSenderWithOptions(sink, BufferedSenderOptions{
+			FlushInterval: time.Hour,
+			BufferSize:    3,
+			MaxBatchBytes: 100,
+		})
+		require.NoError(t, err)
+		defer s.Close()
+
+		for i := 0; i < 3; i++ {
+			s.Send(msg(fmt.Sprint(i)))
+		}
+		assert.Equal([]string{"0", "1", "2"}, rendered(sink))
+
+		s.Send(msg(strings.Repeat("x", 60)))
+		s.Send(msg(strings.Repeat("y", 60)))
+		assert.Equal([]string{strings.Repeat("x", 60)}, rendered(sink))
+	})
+	t.Run("FlushAndClose", func(t *testing.T) {
+		assert := assert.New(t)
+
+		sink := newSink(t)
+		s := NewBufferedSender(sink, -1, -1)
+
+		s.Send(msg("one"))
+		assert.NoError(s.Flush(context.Background()))
+		assert.Equal([]string{"one"}, rendered(sink))
+
+		s.Send(msg("two"))
+		assert.NoError(s.Close())
+		assert.Equal([]string{"two"}, rendered(sink))
+		assert.NoError(s.Close())
+	})
+}
@@ -2,6 +2,7 @@ package send
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"io"
@@ -21,6 +22,8 @@ type buildlogger struct {
 	name   string
 	testID string
 	cache  chan []interface{}
+	flush  chan chan struct{}
+	closed chan struct{}
 	client *http.Client
 	*Base
 }
@@ -180,6 +183,7 @@ func MakeBuildlogger(name string, conf *BuildloggerConfig) (Sender, error) {
 		name:   name,
 		conf:   conf,
 		cache:  make(chan []interface{}),
+		flush:  make(chan chan struct{}),
 		client: &http.Client{Timeout: 10 * time.Second},
 		Base:   NewBase(name),
 	}
@@ -240,6 +244,7 @@ func MakeBuildlogger(name string, conf *BuildloggerConfig) (Sender, error) {
 
 	stop := make(chan struct{})
 	finished := make(chan struct{})
+	b.closed = finished
 	b.closer = func() error {
 		signal := struct{}{}
 		for {
@@ -257,8 +262,16 @@ func MakeBuildlogger(name string, conf *BuildloggerConfig) (Sender, error) {
 }
 
 func (b *buildlogger) Send(m message.Composer) {
-	if b.level.ShouldLog(m) {
-		b.cache <- []interface{}{float64(time.Now().Unix()), m.String()}
+	if !b.Level().ShouldLog(m) || b.reportClosed(m) {
+		return
+	}
+
+	// the background sender stops when the sender closes, so the
+	// cache may never drain.
+	select {
+	case b.cache <- []interface{}{float64(b.timestamp(m).Unix()), messageText(m, " ")}:
+	case <-b.closed:
+		b.ErrorHandler(ErrSenderClosed, m)
 	}
 }
 
@@ -278,6 +291,11 @@ func (b *buildlogger) backgroundSender(stop <-chan struct{}, finished chan<- str
 				buffer = [][]interface{}{}
 				timer.Reset(b.conf.BufferInterval)
 			}
+		case done := <-b.flush:
+			b.sendMessages(buffer)
+			buffer = [][]interface{}{}
+			timer.Reset(b.conf.BufferInterval)
+			close(done)
 		case <-timer.C:
 			b.sendMessages(buffer)
 			buffer = [][]interface{}{}
@@ -291,6 +309,31 @@ func (b *buildlogger) backgroundSender(stop <-chan struct{}, finished chan<- str
 
 }
 
+// Flush posts all buffered messages to the buildlogger service and
+// waits for the post to complete, or for the context to be canceled.
+func (b *buildlogger) Flush(ctx context.Context) error {
+	if b.flush == nil {
+		return nil
+	}
+
+	// after the sender is closed, there are no buffered messages.
+	done := make(chan struct{})
+	select {
+	case b.flush <- done:
+	case <-b.closed:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
 func (b *buildlogger) sendMessages(buffer [][]interface{}) {
 	if len(buffer) == 0 {
 		return
@@ -301,7 +344,7 @@ func (b *buildlogger) sendMessages(buffer [][]interface{}) {
 	}
 
 	if err := b.postLines(bytes.NewBuffer(out)); err != nil {
-		b.errHandler(err, message.NewBytesMessage(b.level.Default, out))
+		b.ErrorHandler(err, message.NewBytesMessage(b.Level().Default, out))
 	}
 }
 
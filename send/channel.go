@@ -0,0 +1,134 @@
+package send
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/mongodb/grip/level"
+	"github.com/mongodb/grip/message"
+)
+
+type channelSender struct {
+	dropped  int64
+	enqueued int64
+	flushes  flushTimer
+	output   chan message.Composer
+	closed   bool
+	mutex    sync.RWMutex
+	*Base
+}
+
+// NewChannelSender constructs a Sender that puts all loggable
+// messages into a buffered channel, which the caller can use to
+// consume the messages as a stream. When the channel is full, the
+// sender drops messages rather than blocking; use DroppedMessages to
+// find the number of dropped messages.
+//
+// Close stops the sender from accepting further messages and closes
+// the channel. Messages in the channel's buffer remain available to
+// consumers after Close. Flush waits for consumers to drain the
+// channel. If the level is not valid, the sender reports an error to
+// its error handler, and uses a Trace threshold.
+func NewChannelSender(name string, l LevelInfo, buffer int) (Sender, <-chan message.Composer) {
+	s := &channelSender{
+		output: make(chan message.Composer, buffer),
+		Base:   NewBase(name),
+	}
+
+	s.level = LevelInfo{level.Trace, level.Trace}
+	if err := s.SetLevel(l); err != nil {
+		s.ErrorHandler(err, message.NewString("configuring channel sender"))
+	}
+
+	s.closer = func() error {
+		s.mutex.Lock()
+		defer s.mutex.Unlock()
+
+		if !s.closed {
+			s.closed = true
+			close(s.output)
+		}
+
+		return nil
+	}
+
+	return s, s.output
+}
+
+// DroppedMessages returns the number of messages that a channel or
+// async sender has dropped because its channel or queue was full, or
+// that a spooling sender has dropped because its spool was full.
+// Returns an error if the Sender is not a channel, async, or spooling
+// sender.
+func DroppedMessages(s Sender) (int64, error) {
+	switch sender := s.(type) {
+	case *channelSender:
+		return atomic.LoadInt64(&sender.dropped), nil
+	case *asyncSender:
+		return atomic.LoadInt64(&sender.dropped), nil
+	case *spoolingSender:
+		sender.mutex.Lock()
+		defer sender.mutex.Unlock()
+
+		return sender.dropped, nil
+	default:
+		return 0, fmt.Errorf("%s is not a channel, async, or spooling sender", s.Name())
+	}
+}
+
+func (s *channelSender) Send(m message.Composer) {
+	if !s.Level().ShouldLog(m) {
+		return
+	}
+
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	if s.closed {
+		s.ErrorHandler(ErrSenderClosed, m)
+		return
+	}
+
+	select {
+	case s.output <- m:
+		atomic.AddInt64(&s.enqueued, 1)
+	default:
+		atomic.AddInt64(&s.dropped, 1)
+	}
+}
+
+// Flush waits until consumers have received all of the messages in
+// the channel, or until the context is canceled.
+func (s *channelSender) Flush(ctx context.Context) error {
+	defer s.flushes.observe(time.Now())
+
+	ticker := time.NewTicker(10 * time.Millisecond)
+	defer ticker.Stop()
+
+	for len(s.output) > 0 {
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	return nil
+}
+
+// BufferMetrics reports the number of messages in the channel, and
+// the number of messages that the sender has put into the channel and
+// dropped.
+func (s *channelSender) BufferMetrics() BufferMetrics {
+	m := BufferMetrics{
+		QueueDepth: int64(len(s.output)),
+		Enqueued:   atomic.LoadInt64(&s.enqueued),
+		Dropped:    atomic.LoadInt64(&s.dropped),
+	}
+	s.flushes.load(&m)
+
+	return m
+}
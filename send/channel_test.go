@@ -0,0 +1,45 @@
+package send
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/mongodb/grip/level"
+	"github.com/mongodb/grip/message"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChannelSender(t *testing.T) {
+	assert := assert.New(t)
+
+	sender, messages := NewChannelSender("channel", LevelInfo{level.Info, level.Info}, 2)
+	assert.Equal("channel", sender.Name())
+
+	sender.Send(message.NewDefaultMessage(level.Debug, "below threshold"))
+	sender.Send(message.NewDefaultMessage(level.Info, "one"))
+	sender.Send(message.NewDefaultMessage(level.Info, "two"))
+	sender.Send(message.NewDefaultMessage(level.Info, "dropped"))
+
+	dropped, err := DroppedMessages(sender)
+	assert.NoError(err)
+	assert.Equal(int64(1), dropped)
+	assert.Equal("one", (<-messages).String())
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	assert.Error(sender.Flush(ctx))
+	cancel()
+
+	assert.NoError(sender.Close())
+	assert.NoError(sender.Close())
+	assert.NotPanics(func() { sender.Send(message.NewDefaultMessage(level.Info, "closed")) })
+
+	// buffered messages are available after close
+	assert.Equal("two", (<-messages).String())
+	_, ok := <-messages
+	assert.False(ok)
+	assert.NoError(sender.Flush(context.Background()))
+
+	_, err = DroppedMessages(MakeNative())
+	assert.Error(err)
+}
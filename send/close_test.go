@@ -0,0 +1,358 @@
+package send
+
+import (
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mongodb/grip/level"
+	"github.com/mongodb/grip/message"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// senderConstructor builds a sender for the close conformance test,
+// and returns a function that releases the resources that the sender
+// uses, after the sender is closed.
+type senderConstructor func(t *testing.T, dir string) (Sender, func())
+
+// discardWriter is a WriteStringer that is safe for concurrent use.
+type discardWriter struct{}
+
+func (discardWriter) WriteString(s string) (int, error) { return len(s), nil }
+
+func noCleanup() {}
+
+// quiet stops native senders that write to standard output or
+// standard error from writing during the test.
+func quiet(s Sender, err error) (Sender, error) {
+	if native, ok := s.(*nativeLogger); ok {
+		native.logger = log.New(ioutil.Discard, "", 0)
+	}
+
+	return s, err
+}
+
+var closeConformanceLevel = LevelInfo{level.Info, level.Info}
+
+var closeConformanceSenders = map[string]senderConstructor{
+	"native": func(t *testing.T, _ string) (Sender, func()) {
+		s, err := quiet(NewNativeLogger("native", closeConformanceLevel))
+		require.NoError(t, err)
+		return s, noCleanup
+	},
+	"error": func(t *testing.T, _ string) (Sender, func()) {
+		s, err := quiet(NewErrorLogger("error", closeConformanceLevel))
+		require.NoError(t, err)
+		return s, noCleanup
+	},
+	"file": func(t *testing.T, dir string) (Sender, func()) {
+		s, err := NewFileLogger("file", filepath.Join(dir, "file.log"), closeConformanceLevel)
+		require.NoError(t, err)
+		return s, noCleanup
+	},
+	"callsite": func(t *testing.T, _ string) (Sender, func()) {
+		s, err := quiet(NewCallSiteConsoleLogger("callsite", 1, closeConformanceLevel))
+		require.NoError(t, err)
+		return s, noCleanup
+	},
+	"callsite-file": func(t *testing.T, dir string) (Sender, func()) {
+		s, err := NewCallSiteFileLogger("callsite-file", filepath.Join(dir, "cs.log"), 1, closeConformanceLevel)
+		require.NoError(t, err)
+		return s, noCleanup
+	},
+	"json": func(t *testing.T, _ string) (Sender, func()) {
+		s, err := quiet(NewJSONConsoleLogger("json", closeConformanceLevel))
+		require.NoError(t, err)
+		return s, noCleanup
+	},
+	"json-file": func(t *testing.T, dir string) (Sender, func()) {
+		s, err := NewJSONFileLogger("json-file", filepath.Join(dir, "json.log"), closeConformanceLevel)
+		require.NoError(t, err)
+		return s, noCleanup
+	},
+	"stream": func(t *testing.T, _ string) (Sender, func()) {
+		s, err := NewStreamLogger("stream", discardWriter{}, closeConformanceLevel)
+		require.NoError(t, err)
+		return s, noCleanup
+	},
+	"channel": func(t *testing.T, _ string) (Sender, func()) {
+		s, output := NewChannelSender("channel", closeConformanceLevel, 10)
+		go func() {
+			for range output {
+			}
+		}()
+		return s, noCleanup
+	},
+	"internal": func(t *testing.T, _ string) (Sender, func()) {
+		s, err := NewInternalLogger("internal", closeConformanceLevel)
+		require.NoError(t, err)
+		go func() {
+			for range s.output {
+			}
+		}()
+		return s, noCleanup
+	},
+	"multi": func(t *testing.T, dir string) (Sender, func()) {
+		members := []Sender{}
+		for _, fn := range []string{"one.log", "two.log"} {
+			member, err := NewFileLogger(fn, filepath.Join(dir, fn), closeConformanceLevel)
+			require.NoError(t, err)
+			members = append(members, member)
+		}
+
+		s, err := NewMultiSender("multi", closeConformanceLevel, members)
+		require.NoError(t, err)
+		require.NoError(t, SetMultiSenderOptions(s, MultiSenderOptions{Parallel: true}))
+		return s, noCleanup
+	},
+	"epoch": func(t *testing.T, dir string) (Sender, func()) {
+		underlying, err := NewFileLogger("epoch", filepath.Join(dir, "epoch.log"), closeConformanceLevel)
+		require.NoError(t, err)
+		return NewEpochSender(underlying), noCleanup
+	},
+	"enriching": func(t *testing.T, dir string) (Sender, func()) {
+		underlying, err := NewFileLogger("enriching", filepath.Join(dir, "enriching.log"), closeConformanceLevel)
+		require.NoError(t, err)
+		s, err := NewEnrichingSender(underlying, NewGeoIPEnricher("ip", mockGeoIPLookup{}))
+		require.NoError(t, err)
+		return s, noCleanup
+	},
+	"hash-chain": func(t *testing.T, dir string) (Sender, func()) {
+		underlying, err := NewFileLogger("hash-chain", filepath.Join(dir, "chain.log"), closeConformanceLevel)
+		require.NoError(t, err)
+		s, err := NewHashChainSender(underlying)
+		require.NoError(t, err)
+		return s, noCleanup
+	},
+	"rate-limited": func(t *testing.T, dir string) (Sender, func()) {
+		underlying, err := NewFileLogger("rate-limited", filepath.Join(dir, "limited.log"), closeConformanceLevel)
+		require.NoError(t, err)
+		s, err := NewRateLimitedSender(underlying, time.Minute, 1)
+		require.NoError(t, err)
+		return s, noCleanup
+	},
+	"buffered": func(t *testing.T, dir string) (Sender, func()) {
+		underlying, err := NewFileLogger("buffered", filepath.Join(dir, "buffered.log"), closeConformanceLevel)
+		require.NoError(t, err)
+		return NewBufferedSender(underlying, time.Minute, 10), noCleanup
+	},
+	"rotating-file": func(t *testing.T, dir string) (Sender, func()) {
+		s, err := NewRotatingFileLogger("rotating-file", filepath.Join(dir, "rotating.log"), 1024, time.Hour, 2, closeConformanceLevel)
+		require.NoError(t, err)
+		return s, noCleanup
+	},
+	"teams": func(t *testing.T, dir string) (Sender, func()) {
+		srv := newTeamsServer()
+		s, err := NewTeamsLoggerWithClient("teams", srv.URL, srv.Client(), closeConformanceLevel)
+		require.NoError(t, err)
+		return s, srv.Close
+	},
+	"async": func(t *testing.T, dir string) (Sender, func()) {
+		underlying, err := NewFileLogger("async", filepath.Join(dir, "async.log"), closeConformanceLevel)
+		require.NoError(t, err)
+		s, err := NewAsyncSender(underlying, 10, BlockOverflow)
+		require.NoError(t, err)
+		return s, noCleanup
+	},
+	"pagerduty": func(t *testing.T, dir string) (Sender, func()) {
+		srv := newPagerDutyServer()
+		s, err := NewPagerDutyLoggerWithOptions("pagerduty", "routing-key", srv.options(), closeConformanceLevel)
+		require.NoError(t, err)
+		return s, srv.Close
+	},
+	"file-with-options": func(t *testing.T, dir string) (Sender, func()) {
+		s, err := NewFileLoggerWithOptions("file-with-options", filepath.Join(dir, "options.log"), FileOptions{MaxSizeBytes: 1024}, closeConformanceLevel)
+		require.NoError(t, err)
+		return s, noCleanup
+	},
+	"webhook": func(t *testing.T, dir string) (Sender, func()) {
+		srv := newWebhookServer()
+		s, err := NewWebhookSenderWithOptions("webhook", srv.URL, WebhookOptions{}, closeConformanceLevel)
+		require.NoError(t, err)
+		return s, srv.Close
+	},
+	"sampling": func(t *testing.T, dir string) (Sender, func()) {
+		underlying, err := NewFileLogger("sampling", filepath.Join(dir, "sampling.log"), closeConformanceLevel)
+		require.NoError(t, err)
+		s, err := NewSamplingSender(underlying, 0.5)
+		require.NoError(t, err)
+		return s, noCleanup
+	},
+	"dedup": func(t *testing.T, dir string) (Sender, func()) {
+		underlying, err := NewFileLogger("dedup", filepath.Join(dir, "dedup.log"), closeConformanceLevel)
+		require.NoError(t, err)
+		s, err := NewDeduplicatingSender(underlying, time.Minute)
+		require.NoError(t, err)
+		return s, noCleanup
+	},
+	"splunk": func(t *testing.T, _ string) (Sender, func()) {
+		srv := newHECServer()
+		s, err := NewSplunkLoggerWithOptions("splunk", srv.info(), SplunkOptions{BatchSize: 10}, closeConformanceLevel)
+		require.NoError(t, err)
+		return s, srv.Close
+	},
+	"github": func(t *testing.T, _ string) (Sender, func()) {
+		srv := newGitHubServer()
+		s, err := NewGitHubIssuesLogger("github", srv.options(), closeConformanceLevel)
+		require.NoError(t, err)
+		return s, srv.Close
+	},
+	"loki": func(t *testing.T, _ string) (Sender, func()) {
+		srv := newLokiServer()
+		s, err := MakeLokiLoggerWithOptions("loki", srv.URL, LokiOptions{BatchSize: 10, Client: srv.Client()}, closeConformanceLevel)
+		require.NoError(t, err)
+		return s, srv.Close
+	},
+	"instrumented": func(t *testing.T, dir string) (Sender, func()) {
+		underlying, err := NewFileLogger("instrumented", filepath.Join(dir, "instrumented.log"), closeConformanceLevel)
+		require.NoError(t, err)
+		s, err := NewInstrumented(underlying)
+		require.NoError(t, err)
+		return s, noCleanup
+	},
+	"require-fields": func(t *testing.T, dir string) (Sender, func()) {
+		underlying, err := NewFileLogger("require-fields", filepath.Join(dir, "require.log"), closeConformanceLevel)
+		require.NoError(t, err)
+		s, err := NewRequireFieldsSender(underlying, []string{"service"}, DropMissing)
+		require.NoError(t, err)
+		return s, noCleanup
+	},
+	"spool": func(t *testing.T, dir string) (Sender, func()) {
+		underlying, err := NewFileLogger("spool", filepath.Join(dir, "spool.log"), closeConformanceLevel)
+		require.NoError(t, err)
+		s, err := NewSpoolingSender(underlying, filepath.Join(dir, "spool"), SpoolOptions{ProbeInterval: time.Millisecond})
+		require.NoError(t, err)
+		return s, noCleanup
+	},
+	"otlp": func(t *testing.T, dir string) (Sender, func()) {
+		s, err := NewOTLPFileSender("otlp", filepath.Join(dir, "otlp.json"), OTLPFileOptions{BufferSize: 10}, closeConformanceLevel)
+		require.NoError(t, err)
+		return s, noCleanup
+	},
+	"statsd": func(t *testing.T, _ string) (Sender, func()) {
+		server := newStatsDServer(t)
+		s, err := NewStatsDSender("statsd", StatsDOptions{Address: server.LocalAddr().String()}, closeConformanceLevel)
+		require.NoError(t, err)
+		return s, func() { _ = server.Close() }
+	},
+	"papertrail": func(t *testing.T, _ string) (Sender, func()) {
+		server := newPapertrailServer(t)
+		s, err := NewPapertrailSender("papertrail", PapertrailOptions{
+			Address:   server.listener.Addr().String(),
+			TLSConfig: server.client,
+		}, closeConformanceLevel)
+		require.NoError(t, err)
+		return s, func() { _ = server.listener.Close() }
+	},
+	"smtp": func(t *testing.T, _ string) (Sender, func()) {
+		opts := &SMTPOptions{client: &smtpClientMock{}, Name: "smtp", NameAsSubject: true}
+		require.NoError(t, opts.AddRecipient("one", "one@example.net"))
+		s, err := NewSMTPLogger(opts, closeConformanceLevel)
+		require.NoError(t, err)
+		return s, noCleanup
+	},
+	"slack": func(t *testing.T, _ string) (Sender, func()) {
+		s, err := NewSlackLogger(&SlackOptions{
+			client:   &slackClientMock{},
+			Hostname: "testhost",
+			Channel:  "#test",
+			Name:     "slack",
+		}, "token", closeConformanceLevel)
+		require.NoError(t, err)
+		return s, noCleanup
+	},
+	"xmpp": func(t *testing.T, _ string) (Sender, func()) {
+		s, err := NewXMPPLogger("xmpp", "target", XMPPConnectionInfo{client: &xmppClientMock{}}, closeConformanceLevel)
+		require.NoError(t, err)
+		return s, noCleanup
+	},
+	"buildlogger": func(t *testing.T, dir string) (Sender, func()) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			_, _ = w.Write([]byte(`{"id": "build"}`))
+		}))
+
+		local, err := NewFileLogger("local", filepath.Join(dir, "local.log"), closeConformanceLevel)
+		require.NoError(t, err)
+		s, err := NewBuildlogger("buildlogger", &BuildloggerConfig{
+			URL:            server.URL,
+			BufferCount:    10,
+			BufferInterval: 10 * time.Millisecond,
+			Local:          local,
+		}, closeConformanceLevel)
+		require.NoError(t, err)
+		return s, server.Close
+	},
+}
+
+// TestSendersCloseConformance checks that, for every sender, Close
+// is idempotent, that sending while the sender closes neither panics
+// nor deadlocks, and that sending after Close reports an error.
+func TestSendersCloseConformance(t *testing.T) {
+	for name, constructor := range closeConformanceSenders {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			dir, err := ioutil.TempDir("", "grip-close")
+			require.NoError(t, err)
+			defer os.RemoveAll(dir)
+
+			sender, cleanup := constructor(t, dir)
+			defer cleanup()
+
+			var closedErrs int64
+			require.NoError(t, sender.SetErrorHandler(func(err error, _ message.Composer) {
+				if err == ErrSenderClosed {
+					atomic.AddInt64(&closedErrs, 1)
+				}
+			}))
+
+			start := make(chan struct{})
+			wg := &sync.WaitGroup{}
+			for i := 0; i < 100; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					<-start
+					for j := 0; j < 10; j++ {
+						sender.Send(message.NewDefaultMessage(level.Info, "hello"))
+					}
+				}()
+			}
+
+			close(start)
+			var closeErr error
+			assert.NotPanics(func() { closeErr = sender.Close() })
+
+			done := make(chan struct{})
+			go func() {
+				wg.Wait()
+				close(done)
+			}()
+
+			select {
+			case <-done:
+			case <-time.After(10 * time.Second):
+				t.Fatal("sending while closing did not complete")
+			}
+
+			assert.NotPanics(func() { assert.Equal(closeErr, sender.Close()) })
+
+			before := atomic.LoadInt64(&closedErrs)
+			assert.NotPanics(func() { sender.Send(message.NewDefaultMessage(level.Info, "closed")) })
+
+			// the internal sender has no error handlers, and drops
+			// messages sent after Close.
+			if name != "internal" {
+				assert.True(atomic.LoadInt64(&closedErrs) > before, "sending after close did not report an error")
+			}
+		})
+	}
+}
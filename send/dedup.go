@@ -0,0 +1,146 @@
+package send
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/mongodb/grip/level"
+	"github.com/mongodb/grip/message"
+)
+
+// maxDedupKeys is the number of distinct messages that a
+// deduplicating sender tracks at once. Messages that arrive while the
+// sender tracks this many are sent without deduplication.
+const maxDedupKeys = 10000
+
+type dedupEntry struct {
+	text     string
+	priority level.Priority
+	repeats  int
+	timer    *time.Timer
+}
+
+type dedupSender struct {
+	window  time.Duration
+	mutex   sync.Mutex
+	entries map[string]*dedupEntry
+	closed  bool
+	Sender
+}
+
+// NewDeduplicatingSender wraps an existing Sender, and collapses
+// repeated messages, with the same priority and text, that it sends
+// within the window: the sender sends the first message immediately,
+// and suppresses its repeats until the window after the first message
+// ends. Then the sender sends a single message, at the same priority,
+// that reports how many times the message repeated, if it did, and
+// forgets the message, so that the next occurrence starts a new
+// window.
+//
+// Messages that the underlying sender would not log are not
+// deduplicated. Close sends the reports for the current windows
+// before it closes the underlying sender.
+func NewDeduplicatingSender(inner Sender, window time.Duration) (Sender, error) {
+	if inner == nil {
+		return nil, errors.New("no underlying sender specified")
+	}
+
+	if window <= 0 {
+		return nil, errors.New("deduplication window must be positive")
+	}
+
+	return &dedupSender{
+		window:  window,
+		entries: map[string]*dedupEntry{},
+		Sender:  inner,
+	}, nil
+}
+
+func (s *dedupSender) Send(m message.Composer) {
+	if !s.Level().ShouldLog(m) {
+		s.Sender.Send(m)
+		return
+	}
+
+	text := m.String()
+	key := fmt.Sprintf("%d:%s", m.Priority(), text)
+
+	s.mutex.Lock()
+
+	// after close, the underlying sender reports the message to
+	// its error handler.
+	if s.closed || len(s.entries) >= maxDedupKeys {
+		s.mutex.Unlock()
+		s.Sender.Send(m)
+		return
+	}
+
+	if entry, ok := s.entries[key]; ok {
+		entry.repeats++
+		s.mutex.Unlock()
+		return
+	}
+
+	entry := &dedupEntry{text: text, priority: m.Priority()}
+	entry.timer = time.AfterFunc(s.window, func() { s.endWindow(key, entry) })
+	s.entries[key] = entry
+	s.mutex.Unlock()
+
+	s.Sender.Send(m)
+}
+
+// endWindow forgets the message, unless the sender has already
+// forgotten it, and sends the report of its repeats, if any.
+func (s *dedupSender) endWindow(key string, entry *dedupEntry) {
+	s.mutex.Lock()
+	if s.entries[key] != entry {
+		s.mutex.Unlock()
+		return
+	}
+	delete(s.entries, key)
+	s.mutex.Unlock()
+
+	if summary := s.summary(entry); summary != nil {
+		s.Sender.Send(summary)
+	}
+}
+
+// summary returns the report of the repeats of the message, or nil if
+// the message did not repeat.
+func (s *dedupSender) summary(entry *dedupEntry) message.Composer {
+	if entry.repeats == 0 {
+		return nil
+	}
+
+	return message.NewFieldsMessage(entry.priority,
+		fmt.Sprintf("repeated %d times in last %s: %s", entry.repeats, s.window, entry.text),
+		message.Fields{
+			"repeats": entry.repeats,
+			"window":  s.window.String(),
+			"message": entry.text,
+		})
+}
+
+func (s *dedupSender) Close() error {
+	s.mutex.Lock()
+	entries := []*dedupEntry{}
+	if !s.closed {
+		s.closed = true
+		for _, entry := range s.entries {
+			entry.timer.Stop()
+			entries = append(entries, entry)
+		}
+		s.entries = map[string]*dedupEntry{}
+	}
+	s.mutex.Unlock()
+
+	for _, entry := range entries {
+		if summary := s.summary(entry); summary != nil {
+			s.Sender.Send(summary)
+		}
+	}
+
+	return s.Sender.Close()
+}
@@ -0,0 +1,107 @@
+package send
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mongodb/grip/level"
+	"github.com/mongodb/grip/message"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeduplicatingSender(t *testing.T) {
+	sink := func(t *testing.T) *InternalSender {
+		s, err := NewInternalLogger("sink", LevelInfo{level.Info, level.Info})
+		require.NoError(t, err)
+		return s
+	}
+
+	t.Run("Validation", func(t *testing.T) {
+		assert := assert.New(t)
+
+		_, err := NewDeduplicatingSender(nil, time.Second)
+		assert.Error(err)
+		_, err = NewDeduplicatingSender(sink(t), 0)
+		assert.Error(err)
+	})
+	t.Run("CollapsesRepeats", func(t *testing.T) {
+		assert := assert.New(t)
+
+		inner := sink(t)
+		s, err := NewDeduplicatingSender(inner, 100*time.Millisecond)
+		require.NoError(t, err)
+
+		m := message.NewDefaultMessage(level.Error, "connection refused")
+		wg := &sync.WaitGroup{}
+		for i := 0; i < 4; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for j := 0; j < 250; j++ {
+					s.Send(m)
+				}
+			}()
+		}
+		wg.Wait()
+
+		assert.Equal(1, inner.Len())
+		assert.Equal("connection refused", inner.GetMessage().Rendered)
+
+		var summary *InternalMessage
+		for deadline := time.Now().Add(time.Second); summary == nil && time.Now().Before(deadline); {
+			if inner.HasMessage() {
+				summary = inner.GetMessage()
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		require.NotNil(t, summary)
+		assert.Equal(level.Error, summary.Priority)
+		assert.Contains(summary.Rendered, "repeated 999 times in last 100ms: connection refused")
+		assert.Equal(999, summary.Message.Raw().(message.Fields)["repeats"])
+
+		time.Sleep(150 * time.Millisecond)
+		assert.Equal(0, inner.Len())
+
+		// the window ended, so the next occurrence is sent.
+		s.Send(m)
+		assert.Equal(1, inner.Len())
+		assert.NoError(s.Close())
+	})
+	t.Run("DistinguishesPriorityAndText", func(t *testing.T) {
+		assert := assert.New(t)
+
+		inner := sink(t)
+		s, err := NewDeduplicatingSender(inner, time.Minute)
+		require.NoError(t, err)
+
+		s.Send(message.NewDefaultMessage(level.Error, "one"))
+		s.Send(message.NewDefaultMessage(level.Warning, "one"))
+		s.Send(message.NewDefaultMessage(level.Error, "two"))
+		s.Send(message.NewDefaultMessage(level.Debug, "quiet"))
+		s.Send(message.NewDefaultMessage(level.Debug, "quiet"))
+		assert.Equal(5, inner.Len())
+	})
+	t.Run("CloseReportsRepeats", func(t *testing.T) {
+		assert := assert.New(t)
+
+		inner := sink(t)
+		s, err := NewDeduplicatingSender(inner, time.Minute)
+		require.NoError(t, err)
+
+		for i := 0; i < 3; i++ {
+			s.Send(message.NewDefaultMessage(level.Error, "retrying"))
+		}
+		s.Send(message.NewDefaultMessage(level.Error, "once"))
+		assert.Equal(2, inner.Len())
+
+		assert.NoError(s.Close())
+		var summaries []string
+		for inner.HasMessage() {
+			summaries = append(summaries, inner.GetMessage().Rendered)
+		}
+		require.Len(t, summaries, 3)
+		assert.Contains(summaries[2], "repeated 2 times in last 1m0s: retrying")
+	})
+}
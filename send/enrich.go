@@ -0,0 +1,117 @@
+package send
+
+import (
+	"errors"
+	"net"
+
+	"github.com/mongodb/grip/message"
+)
+
+// Enricher adds information to a message, typically by annotating it
+// with the result of a lookup keyed on one of its fields, and returns
+// the message to send. Enrichers may return the message unchanged, for
+// example when it does not have the fields that they use, or a
+// different message.
+type Enricher func(message.Composer) message.Composer
+
+type enrichingSender struct {
+	enrichers []Enricher
+	Sender
+}
+
+// NewEnrichingSender wraps an existing Sender, and passes every
+// message that the sender would log through the enrichers, in order,
+// before sending the result to the underlying sender. Enrichers that
+// return nil leave the message unchanged.
+func NewEnrichingSender(underlying Sender, enrichers ...Enricher) (Sender, error) {
+	if underlying == nil {
+		return nil, errors.New("no underlying sender specified")
+	}
+
+	for _, e := range enrichers {
+		if e == nil {
+			return nil, errors.New("cannot use a nil enricher")
+		}
+	}
+
+	return &enrichingSender{
+		enrichers: append([]Enricher{}, enrichers...),
+		Sender:    underlying,
+	}, nil
+}
+
+func (s *enrichingSender) Send(m message.Composer) {
+	if s.Level().ShouldLog(m) {
+		for _, enrich := range s.enrichers {
+			if out := enrich(m); out != nil {
+				m = out
+			}
+		}
+	}
+
+	s.Sender.Send(m)
+}
+
+// GeoIPRecord is the geographic and network information about an IP
+// address.
+type GeoIPRecord struct {
+	// Country is the ISO 3166 code of the country (e.g. "US").
+	Country string
+
+	// ASN is the number of the autonomous system that announces
+	// the address, or 0 if it is not known.
+	ASN uint
+}
+
+// GeoIPLookup looks up IP addresses in a GeoIP database. grip does
+// not include a database; implementations typically wrap a reader for
+// a local database, such as the MaxMind GeoLite2 databases.
+type GeoIPLookup interface {
+	Lookup(net.IP) (GeoIPRecord, error)
+}
+
+// NewGeoIPEnricher returns an Enricher that looks up the IP address
+// in the field of messages, and annotates messages with the
+// "geo_country" and "asn" fields of the result. The enricher looks for
+// the field in messages whose raw form is message.Fields or a map with
+// string keys, and leaves messages that do not have the field, whose
+// field is not an IP address, or whose address the database does not
+// have, unchanged.
+func NewGeoIPEnricher(field string, db GeoIPLookup) Enricher {
+	return func(m message.Composer) message.Composer {
+		var fields map[string]interface{}
+		switch raw := m.Raw().(type) {
+		case message.Fields:
+			fields = raw
+		case map[string]interface{}:
+			fields = raw
+		}
+
+		var ip net.IP
+		switch addr := fields[field].(type) {
+		case string:
+			ip = net.ParseIP(addr)
+		case net.IP:
+			ip = addr
+		}
+
+		if ip == nil {
+			return m
+		}
+
+		record, err := db.Lookup(ip)
+		if err != nil {
+			return m
+		}
+
+		if record.Country != "" {
+			_ = m.Annotate("geo_country", record.Country)
+		}
+
+		if record.ASN != 0 {
+			_ = m.Annotate("asn", record.ASN)
+		}
+
+		return m
+	}
+}
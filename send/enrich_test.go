@@ -0,0 +1,92 @@
+package send
+
+import (
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/mongodb/grip/level"
+	"github.com/mongodb/grip/message"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type mockGeoIPLookup map[string]GeoIPRecord
+
+func (db mockGeoIPLookup) Lookup(ip net.IP) (GeoIPRecord, error) {
+	record, ok := db[ip.String()]
+	if !ok {
+		return GeoIPRecord{}, errors.New("address not found")
+	}
+
+	return record, nil
+}
+
+func TestEnrichingSender(t *testing.T) {
+	assert := assert.New(t)
+
+	_, err := NewEnrichingSender(nil)
+	assert.Error(err)
+
+	sink, err := NewInternalLogger("sink", LevelInfo{level.Info, level.Info})
+	require.NoError(t, err)
+
+	_, err = NewEnrichingSender(sink, nil)
+	assert.Error(err)
+
+	order := []string{}
+	s, err := NewEnrichingSender(sink,
+		func(m message.Composer) message.Composer {
+			order = append(order, "first")
+			return nil
+		},
+		func(m message.Composer) message.Composer {
+			order = append(order, "second")
+			return message.NewFieldsMessage(m.Priority(), "replaced", message.Fields{})
+		},
+	)
+	require.NoError(t, err)
+	assert.Equal("sink", s.Name())
+
+	s.Send(message.NewDefaultMessage(level.Info, "original"))
+	assert.Equal([]string{"first", "second"}, order)
+	assert.Contains(sink.GetMessage().Rendered, "replaced")
+
+	// messages that the sender would not log are not enriched.
+	s.Send(message.NewDefaultMessage(level.Debug, "quiet"))
+	assert.Len(order, 2)
+	assert.Equal("quiet", sink.GetMessage().Rendered)
+}
+
+func TestGeoIPEnricher(t *testing.T) {
+	assert := assert.New(t)
+
+	enrich := NewGeoIPEnricher("ip", mockGeoIPLookup{
+		"192.0.2.1":   {Country: "US", ASN: 64496},
+		"2001:db8::1": {Country: "DE"},
+	})
+
+	m := enrich(message.NewFields(level.Info, message.Fields{"ip": "192.0.2.1"}))
+	fields := m.Raw().(message.Fields)
+	assert.Equal("US", fields["geo_country"])
+	assert.Equal(uint(64496), fields["asn"])
+
+	m = enrich(message.NewFields(level.Info, message.Fields{"ip": net.ParseIP("2001:db8::1")}))
+	fields = m.Raw().(message.Fields)
+	assert.Equal("DE", fields["geo_country"])
+	assert.NotContains(fields, "asn")
+
+	for _, in := range []message.Composer{
+		message.NewFields(level.Info, message.Fields{"ip": "198.51.100.7"}),
+		message.NewFields(level.Info, message.Fields{"ip": "not an address"}),
+		message.NewFields(level.Info, message.Fields{"ip": 42}),
+		message.NewFields(level.Info, message.Fields{"path": "/"}),
+		message.NewDefaultMessage(level.Info, "no fields"),
+	} {
+		out := enrich(in)
+		assert.Equal(in, out)
+		if fields, ok := out.Raw().(message.Fields); ok {
+			assert.NotContains(fields, "geo_country")
+		}
+	}
+}
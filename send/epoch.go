@@ -0,0 +1,59 @@
+package send
+
+import (
+	"crypto/rand"
+	"fmt"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"github.com/mongodb/grip/message"
+)
+
+type epochSender struct {
+	counter int64
+	epoch   string
+	Sender
+}
+
+// NewEpochSender wraps an existing Sender, and annotates every
+// message it sends with an "epoch" key, which holds an identifier
+// that is generated once, when the sender is constructed, and an
+// "epoch_seq" key, which holds a monotonically increasing sequence
+// number. Use this sender to distinguish the output of different
+// instances (e.g. restarts) of a process, even when the hostname,
+// pid, and timestamps of the messages are the same.
+//
+// Messages that already have an epoch annotation, such as messages
+// that are sent more than once, retain their original annotations.
+func NewEpochSender(underlying Sender) Sender {
+	return &epochSender{
+		epoch:  newEpochID(),
+		Sender: underlying,
+	}
+}
+
+func (s *epochSender) Send(m message.Composer) {
+	if s.Level().ShouldLog(m) {
+		if err := m.Annotate("epoch", s.epoch); err == nil {
+			_ = m.Annotate("epoch_seq", atomic.AddInt64(&s.counter, 1))
+		}
+	}
+
+	s.Sender.Send(m)
+}
+
+// newEpochID returns a random (version 4) UUID. If the system's
+// source of randomness is unavailable, the id is derived from the pid
+// and the current time.
+func newEpochID() string {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return fmt.Sprintf("%d-%d", os.Getpid(), time.Now().UnixNano())
+	}
+
+	id[6] = (id[6] & 0x0f) | 0x40
+	id[8] = (id[8] & 0x3f) | 0x80
+
+	return fmt.Sprintf("%x-%x-%x-%x-%x", id[0:4], id[4:6], id[6:8], id[8:10], id[10:])
+}
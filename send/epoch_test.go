@@ -0,0 +1,52 @@
+package send
+
+import (
+	"testing"
+
+	"github.com/mongodb/grip/level"
+	"github.com/mongodb/grip/message"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEpochSenderAnnotatesMessages(t *testing.T) {
+	assert := assert.New(t)
+
+	sink, err := NewInternalLogger("sink", LevelInfo{level.Info, level.Info})
+	assert.NoError(err)
+
+	s := NewEpochSender(sink)
+	assert.Equal("sink", s.Name())
+	assert.Equal(sink.Level(), s.Level())
+
+	var epoch interface{}
+	for i := 1; i <= 3; i++ {
+		s.Send(message.NewFields(level.Info, message.Fields{"msg": "hello"}))
+		fields := sink.GetMessage().Message.Raw().(message.Fields)
+		assert.NotEmpty(fields["epoch"])
+		assert.Equal(int64(i), fields["epoch_seq"])
+
+		if epoch == nil {
+			epoch = fields["epoch"]
+		}
+		assert.Equal(epoch, fields["epoch"])
+	}
+
+	// messages below the threshold are not annotated
+	m := message.NewFields(level.Debug, message.Fields{"msg": "hello"})
+	s.Send(m)
+	_ = sink.GetMessage()
+	assert.NotContains(m.Raw(), "epoch")
+
+	// messages that are sent again retain the original annotations
+	m = message.NewFields(level.Info, message.Fields{"msg": "hello"})
+	s.Send(m)
+	s.Send(m)
+	assert.Equal(int64(4), sink.GetMessage().Message.Raw().(message.Fields)["epoch_seq"])
+	assert.Equal(int64(4), sink.GetMessage().Message.Raw().(message.Fields)["epoch_seq"])
+
+	other := NewEpochSender(sink)
+	other.Send(message.NewFields(level.Info, message.Fields{"msg": "hello"}))
+	fields := sink.GetMessage().Message.Raw().(message.Fields)
+	assert.NotEqual(epoch, fields["epoch"])
+	assert.Equal(int64(1), fields["epoch_seq"])
+}
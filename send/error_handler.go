@@ -1,7 +1,13 @@
 package send
 
 import (
+	"errors"
+	"fmt"
+	"io"
 	"log"
+	"os"
+	"sync"
+	"time"
 
 	"github.com/mongodb/grip/level"
 	"github.com/mongodb/grip/message"
@@ -12,6 +18,117 @@ import (
 // should perform a noop if the err object is nil.
 type ErrorHandler func(error, message.Composer)
 
+// SendErrorHandler is a function that handles the errors that a
+// sender encounters when sending messages. In addition to the error
+// and the message, SendErrorHandlers receive the name of the sender,
+// which makes it possible to, for example, reroute messages to a
+// fallback sender. Senders call SendErrorHandlers in the order that
+// they were added, and only for non-nil errors.
+type SendErrorHandler func(string, error, message.Composer)
+
+// AddErrorHandler registers an additional error handler with the
+// sender, which the sender calls after the handlers it already
+// has. Returns an error if the sender does not support multiple error
+// handlers. All senders that embed Base support multiple handlers.
+func AddErrorHandler(s Sender, h SendErrorHandler) error {
+	sender, ok := s.(interface {
+		AddErrorHandler(SendErrorHandler) error
+	})
+	if !ok {
+		return fmt.Errorf("sender %s does not support multiple error handlers", s.Name())
+	}
+
+	return sender.AddErrorHandler(h)
+}
+
+// ErrorHandlerWithSender is a function that handles the errors that a
+// sender encounters when sending messages, and receives the sender, so
+// that one handler shared by many senders can attribute errors to the
+// sender that encountered them, and, for example, disable a failing
+// sender. Use SetErrorHandlerWithSender or AddErrorHandlerWithSender
+// to register the handler.
+type ErrorHandlerWithSender func(Sender, error, message.Composer)
+
+// AdaptErrorHandler returns an ErrorHandlerWithSender that ignores
+// the sender and calls the ErrorHandler, so that existing handlers can
+// be used where an ErrorHandlerWithSender is required.
+func AdaptErrorHandler(h ErrorHandler) ErrorHandlerWithSender {
+	if h == nil {
+		return nil
+	}
+
+	return func(_ Sender, err error, m message.Composer) { h(err, m) }
+}
+
+// SetErrorHandlerWithSender replaces the error handlers of the sender
+// with the handler, which receives the sender with every error.
+func SetErrorHandlerWithSender(s Sender, h ErrorHandlerWithSender) error {
+	if h == nil {
+		return errors.New("error handler must be non-nil")
+	}
+
+	return s.SetErrorHandler(func(err error, m message.Composer) { h(s, err, m) })
+}
+
+// AddErrorHandlerWithSender registers an additional error handler with
+// the sender, as AddErrorHandler does, which receives the sender with
+// every error.
+func AddErrorHandlerWithSender(s Sender, h ErrorHandlerWithSender) error {
+	if h == nil {
+		return errors.New("error handler must be non-nil")
+	}
+
+	return AddErrorHandler(s, func(_ string, err error, m message.Composer) { h(s, err, m) })
+}
+
+// MakeDefaultErrorHandler returns the error handler that senders use
+// unless configured otherwise, which writes a line to standard error
+// for each error, but no more than one line per second.
+func MakeDefaultErrorHandler() SendErrorHandler {
+	return MakeRateLimitedErrorHandler(os.Stderr, time.Second)
+}
+
+// MakeRateLimitedErrorHandler returns an error handler that writes a
+// line that describes the error, the sender, and the message to the
+// writer. The handler writes at most one line per interval, and
+// reports the number of errors that it suppressed in the next line it
+// writes.
+func MakeRateLimitedErrorHandler(w io.Writer, interval time.Duration) SendErrorHandler {
+	var (
+		last       time.Time
+		suppressed int
+		mutex      sync.Mutex
+	)
+
+	return func(name string, err error, m message.Composer) {
+		if err == nil {
+			return
+		}
+
+		mutex.Lock()
+		defer mutex.Unlock()
+
+		now := time.Now()
+		if !last.IsZero() && now.Sub(last) < interval {
+			suppressed++
+			return
+		}
+		last = now
+
+		line := fmt.Sprintf("[grip] %s sender '%s' encountered error: %s",
+			now.Format(time.RFC3339), name, err.Error())
+		if m != nil {
+			line += fmt.Sprintf(" [message='%s']", m.String())
+		}
+		if suppressed > 0 {
+			line += fmt.Sprintf(" (suppressed %d errors)", suppressed)
+			suppressed = 0
+		}
+
+		_, _ = fmt.Fprintln(w, line)
+	}
+}
+
 func ErrorHandlerFromLogger(l *log.Logger) ErrorHandler {
 	return func(err error, m message.Composer) {
 		if err == nil {
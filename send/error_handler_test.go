@@ -0,0 +1,154 @@
+package send
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mongodb/grip/level"
+	"github.com/mongodb/grip/message"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDefaultErrorHandlerWritesToStandardError(t *testing.T) {
+	assert := assert.New(t)
+
+	r, w, err := os.Pipe()
+	assert.NoError(err)
+
+	stderr := os.Stderr
+	os.Stderr = w
+	base := NewBase("sender-name")
+	os.Stderr = stderr
+
+	base.ErrorHandler(nil, message.NewString("ignored"))
+	base.ErrorHandler(errors.New("failed"), message.NewString("lost message"))
+	base.ErrorHandler(errors.New("failed again"), message.NewString("suppressed message"))
+	assert.NoError(w.Close())
+
+	out, err := ioutil.ReadAll(r)
+	assert.NoError(err)
+
+	lines := strings.Split(strings.TrimSpace(string(out)), "\n")
+	assert.Len(lines, 1)
+	assert.Contains(lines[0], "sender 'sender-name' encountered error: failed")
+	assert.Contains(lines[0], "lost message")
+}
+
+func TestRateLimitedErrorHandler(t *testing.T) {
+	assert := assert.New(t)
+
+	buf := &bytes.Buffer{}
+	handler := MakeRateLimitedErrorHandler(buf, 50*time.Millisecond)
+
+	handler("sender", nil, message.NewString("ignored"))
+	assert.Equal(0, buf.Len())
+
+	for i := 0; i < 5; i++ {
+		handler("sender", errors.New("failed"), message.NewString("lost"))
+	}
+	assert.Equal(1, strings.Count(buf.String(), "\n"))
+
+	time.Sleep(60 * time.Millisecond)
+	handler("sender", errors.New("failed"), nil)
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	assert.Len(lines, 2)
+	assert.Contains(lines[1], "(suppressed 4 errors)")
+}
+
+func TestErrorHandlersAreCalledInOrder(t *testing.T) {
+	assert := assert.New(t)
+
+	calls := []string{}
+	record := func(id string) SendErrorHandler {
+		return func(name string, err error, m message.Composer) {
+			calls = append(calls, strings.Join([]string{id, name, err.Error(), m.String()}, ":"))
+		}
+	}
+
+	sender, err := NewStreamLogger("stream", &failingWriter{}, LevelInfo{level.Info, level.Info})
+	assert.NoError(err)
+	assert.NoError(sender.SetErrorHandler(func(error, message.Composer) { calls = append(calls, "set") }))
+	assert.NoError(AddErrorHandler(sender, record("first")))
+	assert.NoError(AddErrorHandler(sender, record("second")))
+	assert.Error(AddErrorHandler(sender, nil))
+
+	sender.Send(message.NewDefaultMessage(level.Info, "hello"))
+	assert.Equal([]string{"set", "first:stream:write failed:hello", "second:stream:write failed:hello"}, calls)
+
+	// setting an error handler replaces all handlers.
+	calls = []string{}
+	assert.NoError(sender.SetErrorHandler(func(error, message.Composer) { calls = append(calls, "replaced") }))
+	sender.Send(message.NewDefaultMessage(level.Info, "hello"))
+	assert.Equal([]string{"replaced"}, calls)
+
+	internal, err := NewInternalLogger("internal", LevelInfo{level.Info, level.Info})
+	assert.NoError(err)
+	assert.Error(AddErrorHandler(internal, record("internal")))
+}
+
+func TestErrorHandlersCanRerouteMessages(t *testing.T) {
+	assert := assert.New(t)
+
+	fallback, err := NewInternalLogger("fallback", LevelInfo{level.Info, level.Info})
+	assert.NoError(err)
+
+	sender, err := NewStreamLogger("stream", &failingWriter{}, LevelInfo{level.Info, level.Info})
+	assert.NoError(err)
+	assert.NoError(sender.SetErrorHandler(func(error, message.Composer) {}))
+	assert.NoError(AddErrorHandler(sender, func(_ string, _ error, m message.Composer) { fallback.Send(m) }))
+
+	sender.Send(message.NewDefaultMessage(level.Info, "hello"))
+	assert.Equal(1, fallback.Len())
+	assert.Equal("hello", fallback.GetMessage().Rendered)
+}
+
+func TestErrorHandlersWithSenderReceiveTheSender(t *testing.T) {
+	assert := assert.New(t)
+
+	failures := map[Sender][]string{}
+	handler := func(s Sender, err error, m message.Composer) {
+		failures[s] = append(failures[s], s.Name()+":"+m.String())
+	}
+
+	one, err := NewStreamLogger("one", &failingWriter{}, LevelInfo{level.Info, level.Info})
+	assert.NoError(err)
+	two, err := NewStreamLogger("two", &failingWriter{}, LevelInfo{level.Info, level.Info})
+	assert.NoError(err)
+
+	assert.NoError(SetErrorHandlerWithSender(one, handler))
+	assert.NoError(two.SetErrorHandler(func(error, message.Composer) {}))
+	assert.NoError(AddErrorHandlerWithSender(two, handler))
+	assert.Error(SetErrorHandlerWithSender(one, nil))
+	assert.Error(AddErrorHandlerWithSender(two, nil))
+
+	one.Send(message.NewDefaultMessage(level.Info, "first"))
+	two.Send(message.NewDefaultMessage(level.Info, "second"))
+	one.Send(message.NewDefaultMessage(level.Info, "third"))
+
+	assert.Equal([]string{"one:first", "one:third"}, failures[one])
+	assert.Equal([]string{"two:second"}, failures[two])
+
+	// wrapping senders pass themselves, rather than the sender that
+	// they wrap.
+	epoch := NewEpochSender(two)
+	assert.NoError(SetErrorHandlerWithSender(epoch, handler))
+	epoch.Send(message.NewDefaultMessage(level.Info, "fourth"))
+	assert.Len(failures[epoch], 1)
+
+	errs := []error{}
+	adapted := AdaptErrorHandler(func(err error, _ message.Composer) { errs = append(errs, err) })
+	assert.NoError(SetErrorHandlerWithSender(one, adapted))
+	one.Send(message.NewDefaultMessage(level.Info, "adapted"))
+	assert.Len(errs, 1)
+	assert.Nil(AdaptErrorHandler(nil))
+}
+
+type failingWriter struct{}
+
+func (*failingWriter) WriteString(string) (int, error) { return 0, errors.New("write failed") }
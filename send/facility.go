@@ -0,0 +1,103 @@
+package send
+
+import (
+	"fmt"
+
+	"github.com/mongodb/grip/level"
+)
+
+// SyslogFacility identifies the syslog facility, the kind of program
+// that logged a message, which syslog servers use to route messages.
+type SyslogFacility int
+
+// The syslog facilities, as defined in RFC 5424.
+const (
+	FacilityKern SyslogFacility = iota
+	FacilityUser
+	FacilityMail
+	FacilityDaemon
+	FacilityAuth
+	FacilitySyslog
+	FacilityLPR
+	FacilityNews
+	FacilityUUCP
+	FacilityCron
+	FacilityAuthPriv
+	FacilityFTP
+	FacilityNTP
+	FacilityAudit
+	FacilityAlert
+	FacilityClock
+	FacilityLocal0
+	FacilityLocal1
+	FacilityLocal2
+	FacilityLocal3
+	FacilityLocal4
+	FacilityLocal5
+	FacilityLocal6
+	FacilityLocal7
+)
+
+// Validate returns an error if the facility is not one of the
+// facilities defined by RFC 5424.
+func (f SyslogFacility) Validate() error {
+	if f < FacilityKern || f > FacilityLocal7 {
+		return fmt.Errorf("%d is not a valid syslog facility", f)
+	}
+
+	return nil
+}
+
+// SetSyslogFacility sets the facility of the messages that a syslog
+// or Papertrail sender writes. Returns an error if the sender does
+// not write syslog messages, or if the facility is not valid.
+func SetSyslogFacility(s Sender, f SyslogFacility) error {
+	sender, ok := s.(interface {
+		SetFacility(SyslogFacility) error
+	})
+	if !ok {
+		return fmt.Errorf("sender %s does not support syslog facilities", s.Name())
+	}
+
+	return sender.SetFacility(f)
+}
+
+// syslogPriority returns the value of the PRI field of a syslog
+// message with the facility and the severity that corresponds to the
+// priority.
+func syslogPriority(f SyslogFacility, p level.Priority) int {
+	return int(f)*8 + syslogSeverity(p)
+}
+
+// syslogSeverity returns the syslog severity of the priority, or, for
+// priorities that are not one of the levels (including Invalid), of
+// the default priority.
+func (l LevelInfo) syslogSeverity(p level.Priority) int {
+	switch p {
+	case level.Emergency, level.Alert, level.Critical, level.Error, level.Warning, level.Notice, level.Info, level.Debug, level.Trace:
+		return syslogSeverity(p)
+	default:
+		return syslogSeverity(l.Default)
+	}
+}
+
+func syslogSeverity(p level.Priority) int {
+	switch p {
+	case level.Emergency:
+		return 0
+	case level.Alert:
+		return 1
+	case level.Critical:
+		return 2
+	case level.Error:
+		return 3
+	case level.Warning:
+		return 4
+	case level.Notice:
+		return 5
+	case level.Info:
+		return 6
+	default:
+		return 7
+	}
+}
@@ -0,0 +1,71 @@
+package send
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mongodb/grip/level"
+	"github.com/mongodb/grip/message"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSyslogPriorityCombinesFacilityAndSeverity(t *testing.T) {
+	assert := assert.New(t)
+
+	for _, test := range []struct {
+		facility SyslogFacility
+		priority level.Priority
+		pri      int
+	}{
+		{FacilityKern, level.Emergency, 0},
+		{FacilityKern, level.Debug, 7},
+		{FacilityUser, level.Error, 11},
+		{FacilityUser, level.Notice, 13},
+		{FacilityDaemon, level.Warning, 28},
+		{FacilityAuth, level.Critical, 34},
+		{FacilityLocal0, level.Info, 134},
+		{FacilityLocal3, level.Alert, 153},
+		{FacilityLocal7, level.Trace, 191},
+		{FacilityLocal7, level.Emergency, 184},
+	} {
+		assert.Equal(test.pri, syslogPriority(test.facility, test.priority), "%d/%s", test.facility, test.priority)
+	}
+}
+
+func TestSyslogFacilityValidate(t *testing.T) {
+	assert := assert.New(t)
+
+	for f := FacilityKern; f <= FacilityLocal7; f++ {
+		assert.NoError(f.Validate())
+	}
+
+	assert.Error(SyslogFacility(-1).Validate())
+	assert.Error(SyslogFacility(24).Validate())
+}
+
+func TestSetSyslogFacility(t *testing.T) {
+	assert := assert.New(t)
+	server := newPapertrailServer(t)
+	defer server.listener.Close()
+
+	sender, err := NewPapertrailSender("app", PapertrailOptions{
+		Address:   server.listener.Addr().String(),
+		TLSConfig: server.client,
+	}, LevelInfo{level.Info, level.Info})
+	require.NoError(t, err)
+	defer sender.Close()
+
+	sender.Send(message.NewDefaultMessage(level.Info, "user"))
+	assert.Error(SetSyslogFacility(sender, SyslogFacility(24)))
+	require.NoError(t, SetSyslogFacility(sender, FacilityLocal4))
+	sender.Send(message.NewDefaultMessage(level.Warning, "local4"))
+
+	msgs := server.waitForMessages(t, 2)
+	assert.True(strings.HasPrefix(msgs[0], "<14>1 "), msgs[0])
+	assert.True(strings.HasPrefix(msgs[1], "<164>1 "), msgs[1])
+
+	native, err := NewNativeLogger("native", LevelInfo{level.Info, level.Info})
+	require.NoError(t, err)
+	assert.Error(SetSyslogFacility(native, FacilityLocal0))
+}
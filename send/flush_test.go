@@ -0,0 +1,154 @@
+package send
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mongodb/grip/level"
+	"github.com/mongodb/grip/message"
+	"github.com/stretchr/testify/assert"
+)
+
+func (s *SenderSuite) TestFlushSucceedsForAllSenders() {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	for name, sender := range s.functionalMockSenders() {
+		sender.Send(message.NewDefaultMessage(level.Alert, "hello world"))
+		s.NoError(sender.Flush(ctx), name)
+	}
+
+	for name, sender := range s.senders {
+		s.NoError(sender.Flush(ctx), name)
+	}
+}
+
+func TestFlushDeliversPendingMessages(t *testing.T) {
+	assert := assert.New(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	dir, err := ioutil.TempDir("", "grip-flush")
+	assert.NoError(err)
+	defer os.RemoveAll(dir)
+
+	l := LevelInfo{level.Info, level.Info}
+	m := message.NewDefaultMessage(level.Info, "hello world")
+
+	for name, constructor := range map[string]func(string) (Sender, error){
+		"file": func(fn string) (Sender, error) { return NewFileLogger("file", fn, l) },
+		"json": func(fn string) (Sender, error) { return NewJSONFileLogger("json", fn, l) },
+	} {
+		fn := filepath.Join(dir, name)
+		sender, err := constructor(fn)
+		assert.NoError(err)
+
+		sender.Send(m)
+		assert.NoError(sender.Flush(ctx), name)
+		out, err := ioutil.ReadFile(fn)
+		assert.NoError(err)
+		assert.Contains(string(out), "hello world", name)
+		assert.NoError(sender.Close())
+	}
+
+	buf := &bytes.Buffer{}
+	writer := bufio.NewWriter(buf)
+	stream, err := NewStreamLogger("stream", writer, l)
+	assert.NoError(err)
+	stream.Send(m)
+	assert.Equal(0, buf.Len())
+	assert.NoError(stream.Flush(ctx))
+	assert.Equal("hello world\n", buf.String())
+
+	// flushing the epoch wrapper flushes the underlying sender
+	buf.Reset()
+	epoch := NewEpochSender(stream)
+	epoch.Send(message.NewDefaultMessage(level.Info, "hello world"))
+	assert.Equal(0, buf.Len())
+	assert.NoError(epoch.Flush(ctx))
+	assert.Equal("hello world\n", buf.String())
+}
+
+func TestMultiSenderFlushWaitsForBackgroundSends(t *testing.T) {
+	assert := assert.New(t)
+
+	buf := &bytes.Buffer{}
+	stream, err := NewStreamLogger("stream", bufio.NewWriter(buf), LevelInfo{level.Info, level.Info})
+	assert.NoError(err)
+
+	slow := newSlowSender(100 * time.Millisecond)
+	multi, err := NewMultiSender("multi", LevelInfo{level.Info, level.Info}, []Sender{stream, slow})
+	assert.NoError(err)
+	assert.NoError(SetMultiSenderOptions(multi, MultiSenderOptions{
+		Parallel:      true,
+		FireAndForget: []Sender{slow},
+	}))
+
+	multi.Send(message.NewDefaultMessage(level.Info, "hello world"))
+	assert.Equal(int64(0), slow.sent())
+	assert.Equal(0, buf.Len())
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	assert.Error(multi.Flush(ctx))
+	cancel()
+
+	ctx, cancel = context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	assert.NoError(multi.Flush(ctx))
+	assert.Equal(int64(1), slow.sent())
+	assert.Equal("hello world\n", buf.String())
+}
+
+func TestBuildloggerFlushPostsBufferedMessages(t *testing.T) {
+	assert := assert.New(t)
+
+	lines := []interface{}{}
+	mutex := &sync.Mutex{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/build" {
+			_, _ = w.Write([]byte(`{"id": "build0"}`))
+			return
+		}
+
+		batch := []interface{}{}
+		assert.NoError(json.NewDecoder(r.Body).Decode(&batch))
+
+		mutex.Lock()
+		defer mutex.Unlock()
+		lines = append(lines, batch...)
+	}))
+	defer server.Close()
+
+	sender, err := NewBuildlogger("build", &BuildloggerConfig{
+		URL:            server.URL,
+		BufferCount:    1000,
+		BufferInterval: time.Hour,
+		Local:          MakeInternalLogger(),
+	}, LevelInfo{level.Info, level.Info})
+	assert.NoError(err)
+
+	sender.Send(message.NewDefaultMessage(level.Info, "hello world"))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	assert.NoError(sender.Flush(ctx))
+
+	mutex.Lock()
+	assert.Len(lines, 1)
+	assert.True(strings.Contains(lines[0].([]interface{})[1].(string), "hello world"))
+	mutex.Unlock()
+
+	assert.NoError(sender.Close())
+	assert.NoError(sender.Flush(ctx))
+}
@@ -5,6 +5,10 @@ import (
 	"fmt"
 	"path/filepath"
 	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/mongodb/grip/message"
 )
@@ -35,6 +39,301 @@ func MakeJSONFormatter() MessageFormatter {
 	}
 }
 
+// MakeJSONFormatterWithDefaults returns a MessageFormatter that, like
+// the formatter returned by MakeJSONFormatter, renders messages as
+// JSON documents, and adds the default fields to every document. When
+// a message has a field with the same name as a default field, the
+// message's field takes precedence. Use this formatter to add static
+// context (e.g. the data center) to the output of senders that share
+// the same messages.
+//
+// Messages whose Raw form does not render as a JSON object are
+// rendered without the defaults.
+func MakeJSONFormatterWithDefaults(defaults map[string]interface{}) MessageFormatter {
+	fixed := make(map[string]interface{}, len(defaults))
+	for k, v := range defaults {
+		fixed[k] = v
+	}
+
+	return func(m message.Composer) (string, error) {
+		doc := map[string]interface{}{}
+		switch raw := m.Raw().(type) {
+		case message.Fields:
+			doc = raw
+		case map[string]interface{}:
+			doc = raw
+		default:
+			out, err := json.Marshal(raw)
+			if err != nil {
+				return "", err
+			}
+
+			fields := map[string]json.RawMessage{}
+			if err = json.Unmarshal(out, &fields); err != nil {
+				return string(out), nil
+			}
+
+			for k, v := range fields {
+				doc[k] = v
+			}
+		}
+
+		merged := make(map[string]interface{}, len(fixed)+len(doc))
+		for k, v := range fixed {
+			merged[k] = v
+		}
+		for k, v := range doc {
+			merged[k] = v
+		}
+
+		out, err := json.Marshal(merged)
+		if err != nil {
+			return "", err
+		}
+
+		return string(out), nil
+	}
+}
+
+// JSONConfig configures the names of the standard keys of the JSON
+// documents that a formatter renders, and the layout of their
+// timestamps. The zero value renders the same documents as
+// MakeJSONFormatter. Other keys, such as the fields of Fields
+// messages, are never renamed.
+type JSONConfig struct {
+	// LevelKey, if specified, is the key of the name of the
+	// priority of the message (e.g. "severity"), which the
+	// documents do not have otherwise.
+	LevelKey string
+
+	// MessageKey, if specified, replaces the "msg" key of Fields
+	// messages, and the "message" key of other messages.
+	MessageKey string
+
+	// TimeKey, if specified, replaces the "time" key of Fields
+	// messages (e.g. with "@timestamp"), and is the key of the time
+	// that other messages were created, which they otherwise only
+	// record in their metadata.
+	TimeKey string
+
+	// MetadataKey, if specified, replaces the "metadata" key of the
+	// messages that record metadata.
+	MetadataKey string
+
+	// TimeLayout, if specified, is the layout of the times of the
+	// messages, and of the time in their metadata, as for
+	// time.Format (e.g. time.RFC3339Nano). By default, times render
+	// as they do in JSON.
+	TimeLayout string
+}
+
+// MakeJSONFormatterWithConfig returns a MessageFormatter that, like
+// the formatter returned by MakeJSONFormatter, renders messages as
+// JSON documents, with the standard keys and timestamps that the
+// configuration describes. Keys that the configuration renames
+// replace the keys of the message that have the same names.
+//
+// Messages whose Raw form does not render as a JSON object are
+// rendered unchanged.
+func MakeJSONFormatterWithConfig(conf JSONConfig) MessageFormatter {
+	if conf == (JSONConfig{}) {
+		return MakeJSONFormatter()
+	}
+
+	return func(m message.Composer) (string, error) {
+		var doc map[string]interface{}
+		switch raw := m.Raw().(type) {
+		case message.Fields:
+			doc = conf.fieldsDocument(raw)
+		case map[string]interface{}:
+			doc = conf.fieldsDocument(raw)
+		default:
+			out, err := json.Marshal(raw)
+			if err != nil {
+				return "", err
+			}
+
+			fields := map[string]json.RawMessage{}
+			if err = json.Unmarshal(out, &fields); err != nil {
+				return string(out), nil
+			}
+
+			doc = conf.document(fields, messageTime(m))
+		}
+
+		if conf.LevelKey != "" {
+			doc[conf.LevelKey] = m.Priority().String()
+		}
+
+		out, err := json.Marshal(doc)
+		if err != nil {
+			return "", err
+		}
+
+		return string(out), nil
+	}
+}
+
+// fieldsDocument returns a copy of the fields, with the "msg" and
+// "time" keys renamed, and the time in the configured layout.
+func (conf JSONConfig) fieldsDocument(fields map[string]interface{}) map[string]interface{} {
+	doc := make(map[string]interface{}, len(fields)+1)
+	for k, v := range fields {
+		doc[k] = v
+	}
+
+	if t, ok := doc["time"].(time.Time); ok {
+		doc["time"] = conf.formatTime(t)
+	}
+
+	renameKey(doc, "msg", conf.MessageKey)
+	renameKey(doc, "time", conf.TimeKey)
+
+	return doc
+}
+
+// document returns the fields of the JSON document of a message that
+// does not have Fields, with the "message" and "metadata" keys
+// renamed, the time in its metadata in the configured layout, and the
+// time that the message was created, if the configuration has a time
+// key.
+func (conf JSONConfig) document(fields map[string]json.RawMessage, created time.Time) map[string]interface{} {
+	doc := make(map[string]interface{}, len(fields)+1)
+	for k, v := range fields {
+		doc[k] = v
+	}
+
+	if meta, ok := fields["metadata"]; ok && conf.TimeLayout != "" {
+		metadata := map[string]interface{}{}
+		var t struct {
+			Time time.Time `json:"time"`
+		}
+		if json.Unmarshal(meta, &metadata) == nil && json.Unmarshal(meta, &t) == nil && !t.Time.IsZero() {
+			metadata["time"] = conf.formatTime(t.Time)
+			doc["metadata"] = metadata
+		}
+	}
+
+	renameKey(doc, "message", conf.MessageKey)
+	renameKey(doc, "metadata", conf.MetadataKey)
+
+	if conf.TimeKey != "" {
+		doc[conf.TimeKey] = conf.formatTime(created)
+	}
+
+	return doc
+}
+
+// formatTime returns the time in the configured layout, or the time
+// itself, which renders in its JSON form, without a layout.
+func (conf JSONConfig) formatTime(t time.Time) interface{} {
+	if conf.TimeLayout == "" {
+		return t
+	}
+
+	return t.Format(conf.TimeLayout)
+}
+
+// renameKey moves the value of the key in the document to the new
+// key, if the document has the key, and the new key is specified.
+func renameKey(doc map[string]interface{}, from, to string) {
+	if value, ok := doc[from]; ok && to != "" && to != from {
+		delete(doc, from)
+		doc[to] = value
+	}
+}
+
+// MakeLogFmtFormatter returns a MessageFormatter that renders messages
+// as logfmt lines, for systems that parse key=value pairs:
+//
+//     level=<level> msg="<message>" <key>=<value> ...
+//
+// Fields messages render their "msg" field as the message, and the
+// other fields as pairs, in the order of their keys, with nested
+// fields as their own pairs, with dotted keys (e.g. "request.id"),
+// as their String() form does. Nil values, and the "time" field, do
+// not render. Other messages render the level and their String()
+// form. Values that are empty, or that contain spaces, equals signs,
+// quotes, or control characters, are quoted, with quotes and control
+// characters escaped.
+//
+// It can never error.
+func MakeLogFmtFormatter() MessageFormatter {
+	return func(m message.Composer) (string, error) {
+		var fields map[string]interface{}
+		switch raw := m.Raw().(type) {
+		case message.Fields:
+			fields = raw
+		case map[string]interface{}:
+			fields = raw
+		}
+
+		if fields == nil {
+			return fmt.Sprintf("level=%s msg=%s", m.Priority(), strconv.Quote(messageText(m, " "))), nil
+		}
+
+		msg, _ := fields["msg"].(string)
+		pairs := []string{
+			"level=" + m.Priority().String(),
+			"msg=" + strconv.Quote(msg),
+		}
+
+		return strings.Join(logFmtPairs("", fields, pairs), " "), nil
+	}
+}
+
+// logFmtPairs appends the logfmt pairs of the fields to out, in the
+// order of their keys, and returns the extended slice.
+func logFmtPairs(prefix string, fields map[string]interface{}, out []string) []string {
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		if prefix == "" && (k == "msg" || k == "time") {
+			continue
+		}
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		switch v := fields[k].(type) {
+		case nil:
+		case message.Fields:
+			out = logFmtPairs(prefix+k+".", v, out)
+		case map[string]interface{}:
+			out = logFmtPairs(prefix+k+".", v, out)
+		case error:
+			out = append(out, logFmtKey(prefix+k)+"="+logFmtValue(v.Error()))
+		default:
+			out = append(out, logFmtKey(prefix+k)+"="+logFmtValue(fmt.Sprintf("%v", v)))
+		}
+	}
+
+	return out
+}
+
+// logFmtKey replaces the characters that cannot appear in logfmt keys
+// with underscores.
+func logFmtKey(key string) string {
+	return strings.Map(func(r rune) rune {
+		if r <= ' ' || r == '=' || r == '"' || r == 0x7f {
+			return '_'
+		}
+		return r
+	}, key)
+}
+
+// logFmtValue quotes the value if logfmt parsers would not read it as
+// a single value.
+func logFmtValue(value string) string {
+	if value == "" || strings.IndexFunc(value, func(r rune) bool {
+		return r <= ' ' || r == '=' || r == '"' || r == 0x7f
+	}) >= 0 {
+		return strconv.Quote(value)
+	}
+
+	return value
+}
+
 // MakeDefaultFormatter returns a MessageFormatter that will produce a
 // message in the following format:
 //
@@ -43,7 +342,7 @@ func MakeJSONFormatter() MessageFormatter {
 // It can never error.
 func MakeDefaultFormatter() MessageFormatter {
 	return func(m message.Composer) (string, error) {
-		return fmt.Sprintf(defaultFormatTmpl, m.Priority(), m.String()), nil
+		return fmt.Sprintf(defaultFormatTmpl, m.Priority(), messageText(m, " ")), nil
 	}
 }
 
@@ -51,7 +350,7 @@ func MakeDefaultFormatter() MessageFormatter {
 // string format of the log message.
 func MakePlainFormatter() MessageFormatter {
 	return func(m message.Composer) (string, error) {
-		return m.String(), nil
+		return messageText(m, " "), nil
 	}
 }
 
@@ -65,7 +364,7 @@ func MakeCallSiteFormatter(depth int) MessageFormatter {
 	depth++
 	return func(m message.Composer) (string, error) {
 		file, line := callerInfo(depth)
-		return fmt.Sprintf(callSiteTmpl, m.Priority(), file, line, m), nil
+		return fmt.Sprintf(callSiteTmpl, m.Priority(), file, line, messageText(m, " ")), nil
 	}
 }
 
@@ -77,10 +376,52 @@ func MakeCallSiteFormatter(depth int) MessageFormatter {
 // It can never error.
 func MakeXMPPFormatter(name string) MessageFormatter {
 	return func(m message.Composer) (string, error) {
-		return fmt.Sprintf(completeFormatTmpl, name, m.Priority(), m.String()), nil
+		return fmt.Sprintf(completeFormatTmpl, name, m.Priority(), messageText(m, " ")), nil
 	}
 }
 
+// messageText returns the String() form of the message. Loggable
+// messages that have structured content, but whose String() form is
+// empty or only whitespace, are rendered from their Raw form, as
+// key='value' pairs sorted by key and separated by sep. Raw forms
+// that don't render as JSON objects are rendered as JSON.
+func messageText(m message.Composer, sep string) string {
+	msg := m.String()
+	if strings.TrimSpace(msg) != "" || !m.Loggable() {
+		return msg
+	}
+
+	var fields map[string]interface{}
+	switch raw := m.Raw().(type) {
+	case message.Fields:
+		fields = raw
+	case map[string]interface{}:
+		fields = raw
+	default:
+		out, err := json.Marshal(raw)
+		if err != nil {
+			return msg
+		}
+
+		if err = json.Unmarshal(out, &fields); err != nil {
+			return string(out)
+		}
+	}
+
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, 0, len(keys))
+	for _, k := range keys {
+		pairs = append(pairs, fmt.Sprintf("%s='%v'", k, fields[k]))
+	}
+
+	return strings.Join(pairs, sep)
+}
+
 func callerInfo(depth int) (string, int) {
 	// increase depth to account for callerInfo itself.
 	depth++
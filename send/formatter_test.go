@@ -0,0 +1,203 @@
+package send
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mongodb/grip/level"
+	"github.com/mongodb/grip/message"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func decodeJSONOutput(t *testing.T, formatter MessageFormatter, m message.Composer) map[string]interface{} {
+	out, err := formatter(m)
+	require.NoError(t, err)
+
+	doc := map[string]interface{}{}
+	require.NoError(t, json.Unmarshal([]byte(out), &doc))
+
+	return doc
+}
+
+func TestJSONFormatterWithDefaultsPrecedence(t *testing.T) {
+	assert := assert.New(t)
+
+	defaults := map[string]interface{}{"dc": "us-east", "service": "api"}
+	east := MakeJSONFormatterWithDefaults(defaults)
+	west := MakeJSONFormatterWithDefaults(map[string]interface{}{"dc": "eu-west"})
+	defaults["dc"] = "changed"
+
+	m := message.NewFields(level.Info, message.Fields{"service": "worker", "n": 1})
+
+	doc := decodeJSONOutput(t, east, m)
+	assert.Equal("us-east", doc["dc"])
+	assert.Equal("worker", doc["service"])
+	assert.Equal(float64(1), doc["n"])
+
+	doc = decodeJSONOutput(t, west, m)
+	assert.Equal("eu-west", doc["dc"])
+	assert.Equal("worker", doc["service"])
+
+	doc = decodeJSONOutput(t, east, message.NewFields(level.Info, message.Fields{"dc": "local"}))
+	assert.Equal("local", doc["dc"])
+	assert.Equal("api", doc["service"])
+
+	// the fields of messages that aren't maps are merged as well.
+	doc = decodeJSONOutput(t, east, message.NewDefaultMessage(level.Info, "hello"))
+	assert.Equal("us-east", doc["dc"])
+	assert.Equal("hello", doc["message"])
+	assert.Contains(doc, "metadata")
+}
+
+func TestLogFmtFormatter(t *testing.T) {
+	assert := assert.New(t)
+
+	format := MakeLogFmtFormatter()
+	render := func(m message.Composer) string {
+		out, err := format(m)
+		require.NoError(t, err)
+		return out
+	}
+
+	assert.Equal(`level=info msg="hello world"`, render(message.NewDefaultMessage(level.Info, "hello world")))
+	assert.Equal(`level=error msg="say \"hi\""`, render(message.NewDefaultMessage(level.Error, `say "hi"`)))
+
+	assert.Equal(`level=warning msg="request done" a=1 b="two words" c="x=y" d="say \"hi\"" e="" f="line\nbreak" g=plain`,
+		render(message.NewFieldsMessage(level.Warning, "request done", message.Fields{
+			"g": "plain",
+			"f": "line\nbreak",
+			"e": "",
+			"d": `say "hi"`,
+			"c": "x=y",
+			"b": "two words",
+			"a": 1,
+		})))
+
+	// nested values render with dotted keys; nil values do not render.
+	assert.Equal(`level=info msg="" request.duration=1.5 request.ok=true request.status=200 user.id=7 user_name=alice`,
+		render(message.NewFields(level.Info, message.Fields{
+			"request":   message.Fields{"status": 200, "ok": true, "duration": 1.5},
+			"user":      map[string]interface{}{"id": int64(7), "missing": nil},
+			"user name": "alice",
+			"skipped":   nil,
+		})))
+
+	assert.Equal(`level=error msg="boom" err="disk full"`,
+		render(message.NewFieldsMessage(level.Error, "boom", message.Fields{"err": errors.New("disk full")})))
+}
+
+func TestLogFmtFormatterWithFileSender(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logfmt")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "logfmt.log")
+	sender, err := NewFileLogger("logfmt", path, LevelInfo{level.Info, level.Info})
+	require.NoError(t, err)
+	require.NoError(t, sender.SetFormatter(MakeLogFmtFormatter()))
+
+	sender.Send(message.NewFieldsMessage(level.Info, "started", message.Fields{"port": 8080}))
+	require.NoError(t, sender.Close())
+
+	out, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+	assert.True(t, strings.HasSuffix(string(out), " level=info msg=\"started\" port=8080\n"), string(out))
+}
+
+func TestJSONFormatterWithConfig(t *testing.T) {
+	assert := assert.New(t)
+
+	created := time.Date(2020, time.March, 4, 5, 6, 7, 890000000, time.UTC)
+	fields := timestampedComposer{created: created, Composer: message.NewFieldsMessage(level.Warning, "disk full", message.Fields{
+		"time":    created,
+		"message": "user field",
+		"level":   3,
+		"n":       1,
+	})}
+	plain := message.NewDefaultMessage(level.Error, "hello")
+
+	t.Run("DefaultConfigIsUnchanged", func(t *testing.T) {
+		for _, m := range []message.Composer{fields, plain, message.NewString("plain")} {
+			expected, err := MakeJSONFormatter()(m)
+			require.NoError(t, err)
+			out, err := MakeJSONFormatterWithConfig(JSONConfig{})(m)
+			require.NoError(t, err)
+			assert.Equal(expected, out)
+		}
+	})
+
+	conf := JSONConfig{
+		LevelKey:    "severity",
+		MessageKey:  "message",
+		TimeKey:     "@timestamp",
+		MetadataKey: "meta",
+		TimeLayout:  time.RFC3339Nano,
+	}
+	format := MakeJSONFormatterWithConfig(conf)
+
+	t.Run("Fields", func(t *testing.T) {
+		doc := decodeJSONOutput(t, format, fields)
+		assert.Equal("disk full", doc["message"])
+		assert.Equal("2020-03-04T05:06:07.89Z", doc["@timestamp"])
+		assert.Equal("warning", doc["severity"])
+		assert.NotContains(doc, "msg")
+		assert.NotContains(doc, "time")
+
+		// other fields are not renamed, but are replaced by the
+		// configured keys.
+		assert.Equal(float64(3), doc["level"])
+		assert.Equal(float64(1), doc["n"])
+	})
+
+	t.Run("OtherMessages", func(t *testing.T) {
+		doc := decodeJSONOutput(t, format, plain)
+		assert.Equal("hello", doc["message"])
+		assert.Equal("error", doc["severity"])
+		assert.NotContains(doc, "metadata")
+
+		meta, ok := doc["meta"].(map[string]interface{})
+		require.True(t, ok, "%T", doc["meta"])
+		assert.Equal(float64(level.Error), meta["level"])
+
+		timestamp, ok := doc["@timestamp"].(string)
+		require.True(t, ok, "%T", doc["@timestamp"])
+		parsed, err := time.Parse(time.RFC3339Nano, timestamp)
+		require.NoError(t, err)
+		assert.Equal(plain.(message.Timestamped).Timestamp().UnixNano(), parsed.UnixNano())
+		assert.Equal(timestamp, meta["time"])
+	})
+
+	t.Run("Layout", func(t *testing.T) {
+		doc := decodeJSONOutput(t, MakeJSONFormatterWithConfig(JSONConfig{TimeLayout: "2006-01-02"}), fields)
+		assert.Equal("2020-03-04", doc["time"])
+		assert.Equal("disk full", doc["msg"])
+		assert.Equal("user field", doc["message"])
+		assert.NotContains(doc, "severity")
+	})
+
+	t.Run("Sender", func(t *testing.T) {
+		dir, err := ioutil.TempDir("", "json-config")
+		require.NoError(t, err)
+		defer os.RemoveAll(dir)
+
+		path := filepath.Join(dir, "out.json")
+		sender, err := NewJSONFileLoggerWithConfig("json", path, conf, LevelInfo{level.Info, level.Info})
+		require.NoError(t, err)
+		sender.Send(fields)
+		require.NoError(t, sender.Close())
+
+		out, err := ioutil.ReadFile(path)
+		require.NoError(t, err)
+		doc := map[string]interface{}{}
+		require.NoError(t, json.Unmarshal(out, &doc))
+		assert.Equal("disk full", doc["message"])
+		assert.Equal("2020-03-04T05:06:07.89Z", doc["@timestamp"])
+	})
+}
@@ -0,0 +1,366 @@
+package send
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mongodb/grip/message"
+)
+
+const githubEndpoint = "https://api.github.com"
+
+// githubTitleLength is the maximum length of the titles of the issues
+// that the GitHub sender opens.
+const githubTitleLength = 256
+
+// githubFingerprintPrefix precedes the fingerprint in the bodies of
+// the issues that the GitHub sender opens, so that the sender can
+// search for them.
+const githubFingerprintPrefix = "grip-fingerprint:"
+
+// GitHubOptions configures the GitHub sender.
+type GitHubOptions struct {
+	// Account and Repo identify the repository that the sender
+	// opens issues in, and Token is an access token that can open
+	// and comment on issues in the repository.
+	Account string
+	Repo    string
+	Token   string
+
+	// Endpoint is the URL of the GitHub REST API. Defaults to
+	// GitHub's API, and may be the API of a GitHub Enterprise
+	// server.
+	Endpoint string
+
+	// Client is the HTTP client that the sender makes requests
+	// with. Defaults to a client with a 10 second timeout.
+	Client *http.Client
+
+	// Label is the label of the issues that the sender opens.
+	// Defaults to "grip".
+	Label string
+
+	// Deduplicate, if true, makes the sender comment on the open
+	// issue for the fingerprint of each message, if there is one,
+	// instead of opening another issue.
+	Deduplicate bool
+
+	// Fingerprint, if specified, returns the fingerprint of a
+	// message. By default, the fingerprint is the hash of the
+	// title of the issue for the message.
+	Fingerprint func(message.Composer) string
+
+	// Hash constructs the hash of the default fingerprint.
+	// Defaults to SHA-256.
+	Hash func() hash.Hash
+
+	// MaxCommentsPerHour, if positive, limits the number of
+	// comments that the sender adds to each issue in an hour. The
+	// sender drops the messages that exceed the limit.
+	MaxCommentsPerHour int
+}
+
+// Validate checks the options, and sets defaults for unspecified
+// values.
+func (o *GitHubOptions) Validate() error {
+	errs := []string{}
+
+	if o.Account == "" {
+		errs = append(errs, "no github account specified")
+	}
+
+	if o.Repo == "" {
+		errs = append(errs, "no github repository specified")
+	}
+
+	if o.Token == "" {
+		errs = append(errs, "no github token specified")
+	}
+
+	if o.Endpoint == "" {
+		o.Endpoint = githubEndpoint
+	} else if _, err := url.ParseRequestURI(o.Endpoint); err != nil {
+		errs = append(errs, fmt.Sprintf("invalid github endpoint: %s", err.Error()))
+	}
+
+	if o.MaxCommentsPerHour < 0 {
+		errs = append(errs, "max comments per hour cannot be negative")
+	}
+
+	if o.Client == nil {
+		o.Client = &http.Client{Timeout: 10 * time.Second}
+	}
+
+	if o.Label == "" {
+		o.Label = "grip"
+	}
+
+	if o.Hash == nil {
+		o.Hash = sha256.New
+	}
+
+	if len(errs) > 0 {
+		return errors.New(strings.Join(errs, "; "))
+	}
+
+	return nil
+}
+
+// githubIssue is the part of a GitHub issue that the sender uses.
+type githubIssue struct {
+	Number int    `json:"number"`
+	State  string `json:"state"`
+}
+
+// githubCommentWindow counts the comments that the sender added to an
+// issue in the hour that started at the time.
+type githubCommentWindow struct {
+	start time.Time
+	count int
+}
+
+type githubSender struct {
+	opts     GitHubOptions
+	repoPath string
+	mutex    sync.Mutex
+	issues   map[string]int
+	comments map[int]*githubCommentWindow
+	*Base
+}
+
+// NewGitHubIssuesLogger constructs a Sender that opens a GitHub issue
+// for every message, with the first line of the text of the message
+// as the title, and the text as the body. Use the level threshold to
+// limit the issues to messages that someone must act on.
+//
+// With the Deduplicate option, the body of each issue records the
+// fingerprint of its message, and the sender searches the open issues
+// with the label for the fingerprint of each message, and comments on
+// the issue that it finds, instead of opening another. The sender also
+// remembers the issues that it opened, since GitHub may take some time
+// to index new issues for search. When the search fails, the sender
+// reports the error to its error handler, and opens an issue.
+func NewGitHubIssuesLogger(name string, opts GitHubOptions, l LevelInfo) (Sender, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+
+	s := &githubSender{
+		opts:     opts,
+		repoPath: fmt.Sprintf("/repos/%s/%s", url.PathEscape(opts.Account), url.PathEscape(opts.Repo)),
+		issues:   map[string]int{},
+		comments: map[int]*githubCommentWindow{},
+		Base:     NewBase(name),
+	}
+
+	return setup(s, name, l)
+}
+
+func (s *githubSender) Send(m message.Composer) { s.SendContext(context.Background(), m) }
+
+// SendContext opens the issue for the message, or comments on it, and
+// cancels the requests when the context is canceled.
+func (s *githubSender) SendContext(ctx context.Context, m message.Composer) {
+	if !s.Level().ShouldLog(m) || s.reportClosed(m) {
+		return
+	}
+
+	body := messageText(m, " ")
+	title := githubTitle(body)
+
+	if !s.opts.Deduplicate {
+		_, err := s.openIssue(ctx, title, body)
+		s.ErrorHandler(err, m)
+		return
+	}
+
+	fingerprint := s.fingerprint(m, title)
+
+	// hold the mutex while the sender opens the issue, so that
+	// concurrent messages with the same fingerprint do not open
+	// several issues.
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	number, err := s.findIssue(ctx, fingerprint)
+	if err != nil {
+		s.ErrorHandler(fmt.Errorf("searching for github issue: %s", err.Error()), m)
+	}
+
+	if number == 0 {
+		number, err = s.openIssue(ctx, title, fmt.Sprintf("%s\n\n%s %s", body, githubFingerprintPrefix, fingerprint))
+		if err == nil {
+			s.issues[fingerprint] = number
+		}
+		s.ErrorHandler(err, m)
+		return
+	}
+
+	if !s.allowComment(number) {
+		return
+	}
+
+	s.ErrorHandler(s.comment(ctx, number, body), m)
+}
+
+// fingerprint returns the fingerprint of the message, with the
+// Fingerprint option, if specified, and otherwise the hash of the
+// title.
+func (s *githubSender) fingerprint(m message.Composer, title string) string {
+	if s.opts.Fingerprint != nil {
+		return s.opts.Fingerprint(m)
+	}
+
+	h := s.opts.Hash()
+	_, _ = io.WriteString(h, title)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// findIssue returns the number of the open issue with the fingerprint,
+// or 0 if there is none. The caller must hold the mutex.
+func (s *githubSender) findIssue(ctx context.Context, fingerprint string) (int, error) {
+	if number, ok := s.issues[fingerprint]; ok {
+		issue := githubIssue{}
+		err := s.request(ctx, "GET", fmt.Sprintf("%s/issues/%d", s.repoPath, number), nil, &issue)
+		if err == nil && issue.State == "open" {
+			return number, nil
+		}
+		delete(s.issues, fingerprint)
+	}
+
+	query := url.Values{}
+	query.Set("q", fmt.Sprintf(`repo:%s/%s is:issue is:open label:"%s" in:body "%s %s"`,
+		s.opts.Account, s.opts.Repo, s.opts.Label, githubFingerprintPrefix, fingerprint))
+
+	result := struct {
+		Items []githubIssue `json:"items"`
+	}{}
+	if err := s.request(ctx, "GET", "/search/issues?"+query.Encode(), nil, &result); err != nil {
+		return 0, err
+	}
+
+	if len(result.Items) == 0 {
+		return 0, nil
+	}
+
+	s.issues[fingerprint] = result.Items[0].Number
+	return result.Items[0].Number, nil
+}
+
+// allowComment returns true, and counts the comment, if the sender may
+// add another comment to the issue in the current hour. The caller
+// must hold the mutex.
+func (s *githubSender) allowComment(number int) bool {
+	if s.opts.MaxCommentsPerHour == 0 {
+		return true
+	}
+
+	now := time.Now()
+	window, ok := s.comments[number]
+	if !ok || now.Sub(window.start) >= time.Hour {
+		window = &githubCommentWindow{start: now}
+		s.comments[number] = window
+	}
+
+	if window.count >= s.opts.MaxCommentsPerHour {
+		return false
+	}
+
+	window.count++
+	return true
+}
+
+func (s *githubSender) openIssue(ctx context.Context, title, body string) (int, error) {
+	issue := githubIssue{}
+	err := s.request(ctx, "POST", s.repoPath+"/issues", map[string]interface{}{
+		"title":  title,
+		"body":   body,
+		"labels": []string{s.opts.Label},
+	}, &issue)
+
+	return issue.Number, err
+}
+
+func (s *githubSender) comment(ctx context.Context, number int, body string) error {
+	return s.request(ctx, "POST", fmt.Sprintf("%s/issues/%d/comments", s.repoPath, number),
+		map[string]interface{}{"body": body}, nil)
+}
+
+// request makes a request to the path of the API, with the JSON
+// encoding of the payload, if any, as the body, and decodes the
+// response into the result, if any.
+func (s *githubSender) request(ctx context.Context, method, path string, payload, result interface{}) error {
+	var body io.Reader
+	if payload != nil {
+		data, err := json.Marshal(payload)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequest(method, strings.TrimSuffix(s.opts.Endpoint, "/")+path, body)
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("Authorization", "token "+s.opts.Token)
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := s.opts.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		// github describes failed requests in the body of the
+		// response.
+		detail := struct {
+			Message string `json:"message"`
+		}{}
+		text, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 512))
+		if json.Unmarshal(text, &detail) == nil && detail.Message != "" {
+			return fmt.Errorf("github responded with status %s: %s", resp.Status, detail.Message)
+		}
+
+		return fmt.Errorf("github responded with status %s", resp.Status)
+	}
+
+	if result == nil {
+		return nil
+	}
+
+	return json.NewDecoder(resp.Body).Decode(result)
+}
+
+// githubTitle returns the first line of the text, truncated to the
+// maximum length of a title.
+func githubTitle(text string) string {
+	if idx := strings.IndexByte(text, '\n'); idx >= 0 {
+		text = text[:idx]
+	}
+
+	text = strings.TrimSpace(text)
+	if len(text) > githubTitleLength {
+		text = text[:githubTitleLength]
+	}
+
+	return text
+}
@@ -0,0 +1,329 @@
+package send
+
+import (
+	"crypto/md5"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/mongodb/grip/level"
+	"github.com/mongodb/grip/message"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// githubServer implements the parts of the GitHub issues and search
+// APIs that the GitHub sender uses. New issues are not searchable
+// until the test indexes them, as on GitHub.
+type githubServer struct {
+	*httptest.Server
+	mutex      sync.Mutex
+	issues     []githubTestIssue
+	comments   map[int][]string
+	queries    []string
+	searchable int
+	failSearch bool
+}
+
+type githubTestIssue struct {
+	title  string
+	body   string
+	labels []string
+	state  string
+}
+
+func newGitHubServer() *githubServer {
+	s := &githubServer{comments: map[int][]string{}}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mutex.Lock()
+		defer s.mutex.Unlock()
+
+		if r.Header.Get("Authorization") != "token gh-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte(`{"message":"Bad credentials"}`))
+			return
+		}
+
+		payload := map[string]interface{}{}
+		_ = json.NewDecoder(r.Body).Decode(&payload)
+
+		parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/repos/mongodb/grip/issues"), "/")
+		switch {
+		case r.URL.Path == "/search/issues":
+			query := r.URL.Query().Get("q")
+			s.queries = append(s.queries, query)
+			if s.failSearch {
+				w.WriteHeader(http.StatusForbidden)
+				_, _ = w.Write([]byte(`{"message":"API rate limit exceeded"}`))
+				return
+			}
+
+			items := []githubIssue{}
+			for idx, issue := range s.issues[:s.searchable] {
+				fingerprint := query[strings.Index(query, githubFingerprintPrefix):strings.LastIndex(query, `"`)]
+				if issue.state == "open" && strings.Contains(issue.body, fingerprint) {
+					items = append(items, githubIssue{Number: idx + 1, State: issue.state})
+				}
+			}
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"items": items})
+		case r.Method == "POST" && r.URL.Path == "/repos/mongodb/grip/issues":
+			labels := []string{}
+			for _, label := range payload["labels"].([]interface{}) {
+				labels = append(labels, label.(string))
+			}
+			s.issues = append(s.issues, githubTestIssue{
+				title:  payload["title"].(string),
+				body:   payload["body"].(string),
+				labels: labels,
+				state:  "open",
+			})
+			w.WriteHeader(http.StatusCreated)
+			_ = json.NewEncoder(w).Encode(githubIssue{Number: len(s.issues), State: "open"})
+		case len(parts) >= 2:
+			number, err := strconv.Atoi(parts[1])
+			if err != nil || number < 1 || number > len(s.issues) {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+
+			if len(parts) == 3 && r.Method == "POST" {
+				s.comments[number] = append(s.comments[number], payload["body"].(string))
+				w.WriteHeader(http.StatusCreated)
+				_, _ = w.Write([]byte(`{}`))
+				return
+			}
+			_ = json.NewEncoder(w).Encode(githubIssue{Number: number, State: s.issues[number-1].state})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+
+	return s
+}
+
+func (s *githubServer) options() GitHubOptions {
+	return GitHubOptions{
+		Account:  "mongodb",
+		Repo:     "grip",
+		Token:    "gh-token",
+		Endpoint: s.URL,
+		Client:   s.Client(),
+	}
+}
+
+// index makes the issues searchable.
+func (s *githubServer) index() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.searchable = len(s.issues)
+}
+
+func (s *githubServer) close(number int) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.issues[number-1].state = "closed"
+}
+
+func (s *githubServer) received() ([]githubTestIssue, map[int][]string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	comments := map[int][]string{}
+	for number, bodies := range s.comments {
+		comments[number] = append([]string{}, bodies...)
+	}
+	return append([]githubTestIssue{}, s.issues...), comments
+}
+
+func TestGitHubOptionsValidate(t *testing.T) {
+	assert := assert.New(t)
+
+	opts := GitHubOptions{Endpoint: "not a url", MaxCommentsPerHour: -1}
+	err := opts.Validate()
+	assert.Error(err)
+	assert.Len(strings.Split(err.Error(), "; "), 5)
+
+	opts = GitHubOptions{Account: "mongodb", Repo: "grip", Token: "token"}
+	assert.NoError(opts.Validate())
+	assert.Equal("https://api.github.com", opts.Endpoint)
+	assert.Equal("grip", opts.Label)
+	assert.NotNil(opts.Client)
+	assert.NotNil(opts.Hash)
+
+	_, err = NewGitHubIssuesLogger("github", GitHubOptions{}, LevelInfo{level.Info, level.Info})
+	assert.Error(err)
+}
+
+func TestGitHubLoggerOpensIssues(t *testing.T) {
+	assert := assert.New(t)
+
+	srv := newGitHubServer()
+	defer srv.Close()
+
+	sender, err := NewGitHubIssuesLogger("github", srv.options(), LevelInfo{level.Info, level.Error})
+	require.NoError(t, err)
+
+	sender.Send(message.NewDefaultMessage(level.Info, "below threshold"))
+	sender.Send(message.NewDefaultMessage(level.Critical, "disk full\non /data"))
+	sender.Send(message.NewDefaultMessage(level.Critical, "disk full\non /data"))
+
+	issues, comments := srv.received()
+	require.Len(t, issues, 2, "issues are not deduplicated by default")
+	assert.Equal("disk full", issues[0].title)
+	assert.Equal("disk full\non /data", issues[0].body)
+	assert.Equal([]string{"grip"}, issues[0].labels)
+	assert.Len(comments, 0)
+
+	errs := &errorCollector{}
+	opts := srv.options()
+	opts.Token = "wrong"
+	sender, err = NewGitHubIssuesLogger("github", opts, LevelInfo{level.Info, level.Error})
+	require.NoError(t, err)
+	require.NoError(t, sender.SetErrorHandler(errs.handler))
+	sender.Send(message.NewDefaultMessage(level.Critical, "unauthorized"))
+	require.Len(t, errs.get(), 1)
+	assert.Contains(errs.get()[0].Error(), "Bad credentials")
+}
+
+func TestGitHubLoggerDeduplicatesIssues(t *testing.T) {
+	assert := assert.New(t)
+
+	srv := newGitHubServer()
+	defer srv.Close()
+
+	opts := srv.options()
+	opts.Deduplicate = true
+	opts.Label = "incident"
+	sender, err := NewGitHubIssuesLogger("github", opts, LevelInfo{level.Info, level.Info})
+	require.NoError(t, err)
+
+	errs := &errorCollector{}
+	require.NoError(t, sender.SetErrorHandler(errs.handler))
+
+	sender.Send(message.NewDefaultMessage(level.Critical, "disk full"))
+	issues, _ := srv.received()
+	require.Len(t, issues, 1)
+	assert.Equal([]string{"incident"}, issues[0].labels)
+	assert.Contains(issues[0].body, githubFingerprintPrefix)
+
+	// the sender remembers the issue before github indexes it.
+	sender.Send(message.NewDefaultMessage(level.Critical, "disk full"))
+	srv.index()
+
+	// another sender finds the issue with a search.
+	other, err := NewGitHubIssuesLogger("github", opts, LevelInfo{level.Info, level.Info})
+	require.NoError(t, err)
+	other.Send(message.NewDefaultMessage(level.Critical, "disk full"))
+	other.Send(message.NewDefaultMessage(level.Critical, "out of memory"))
+
+	issues, comments := srv.received()
+	require.Len(t, issues, 2)
+	assert.Equal("out of memory", issues[1].title)
+	assert.Equal(map[int][]string{1: {"disk full", "disk full"}}, comments)
+
+	srv.mutex.Lock()
+	query := srv.queries[0]
+	srv.mutex.Unlock()
+	assert.Contains(query, "repo:mongodb/grip")
+	assert.Contains(query, "is:open")
+	assert.Contains(query, `label:"incident"`)
+
+	// closed issues are not reused.
+	srv.index()
+	srv.close(1)
+	sender.Send(message.NewDefaultMessage(level.Critical, "disk full"))
+	issues, _ = srv.received()
+	require.Len(t, issues, 3)
+	assert.Equal("disk full", issues[2].title)
+	assert.Len(errs.get(), 0)
+}
+
+func TestGitHubLoggerFingerprints(t *testing.T) {
+	assert := assert.New(t)
+
+	srv := newGitHubServer()
+	defer srv.Close()
+
+	opts := srv.options()
+	opts.Deduplicate = true
+	opts.Fingerprint = func(m message.Composer) string {
+		return fmt.Sprintf("%v", m.Raw().(message.Fields)["service"])
+	}
+	sender, err := NewGitHubIssuesLogger("github", opts, LevelInfo{level.Info, level.Info})
+	require.NoError(t, err)
+
+	sender.Send(message.NewFieldsMessage(level.Critical, "timeout", message.Fields{"service": "payments"}))
+	sender.Send(message.NewFieldsMessage(level.Critical, "connection refused", message.Fields{"service": "payments"}))
+	sender.Send(message.NewFieldsMessage(level.Critical, "timeout", message.Fields{"service": "search"}))
+
+	issues, comments := srv.received()
+	require.Len(t, issues, 2)
+	assert.Contains(issues[0].body, githubFingerprintPrefix+" payments")
+	assert.Contains(issues[1].body, githubFingerprintPrefix+" search")
+	require.Len(t, comments[1], 1)
+	assert.Contains(comments[1][0], "connection refused")
+
+	opts = srv.options()
+	opts.Deduplicate = true
+	opts.Hash = md5.New
+	sender, err = NewGitHubIssuesLogger("github", opts, LevelInfo{level.Info, level.Info})
+	require.NoError(t, err)
+	sender.Send(message.NewDefaultMessage(level.Critical, "hashed"))
+
+	issues, _ = srv.received()
+	require.Len(t, issues, 3)
+	assert.True(strings.HasSuffix(issues[2].body, fmt.Sprintf("%s %x", githubFingerprintPrefix, md5.Sum([]byte("hashed")))), issues[2].body)
+}
+
+func TestGitHubLoggerSearchFailures(t *testing.T) {
+	assert := assert.New(t)
+
+	srv := newGitHubServer()
+	defer srv.Close()
+	srv.failSearch = true
+
+	opts := srv.options()
+	opts.Deduplicate = true
+	sender, err := NewGitHubIssuesLogger("github", opts, LevelInfo{level.Info, level.Info})
+	require.NoError(t, err)
+
+	errs := &errorCollector{}
+	require.NoError(t, sender.SetErrorHandler(errs.handler))
+
+	sender.Send(message.NewDefaultMessage(level.Critical, "disk full"))
+
+	issues, _ := srv.received()
+	assert.Len(issues, 1, "the sender opens an issue when the search fails")
+	require.Len(t, errs.get(), 1)
+	assert.Contains(errs.get()[0].Error(), "searching for github issue")
+	assert.Contains(errs.get()[0].Error(), "API rate limit exceeded")
+}
+
+func TestGitHubLoggerLimitsComments(t *testing.T) {
+	assert := assert.New(t)
+
+	srv := newGitHubServer()
+	defer srv.Close()
+
+	opts := srv.options()
+	opts.Deduplicate = true
+	opts.MaxCommentsPerHour = 2
+	sender, err := NewGitHubIssuesLogger("github", opts, LevelInfo{level.Info, level.Info})
+	require.NoError(t, err)
+
+	for i := 0; i < 5; i++ {
+		sender.Send(message.NewDefaultMessage(level.Critical, "disk full"))
+	}
+	sender.Send(message.NewDefaultMessage(level.Critical, "out of memory"))
+	sender.Send(message.NewDefaultMessage(level.Critical, "out of memory"))
+
+	issues, comments := srv.received()
+	assert.Len(issues, 2)
+	assert.Len(comments[1], 2)
+	assert.Len(comments[2], 1)
+}
@@ -0,0 +1,106 @@
+package send
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/mongodb/grip/message"
+)
+
+// defaultHashChainGenesis is the hash that precedes the first message
+// of a hash chain sender, unless the sender has a different genesis
+// hash.
+var defaultHashChainGenesis = strings.Repeat("0", sha256.Size*2)
+
+type hashChainSender struct {
+	head  string
+	mutex sync.Mutex
+	Sender
+}
+
+// NewHashChainSender wraps an existing Sender, and makes the messages
+// that it sends tamper evident, by annotating every message with a
+// "prev_hash" field, the hash of the previous message, and a "hash"
+// field: the hex-encoded SHA-256 of the previous hash followed by the
+// JSON encoding of the raw form of the message, before the
+// annotations. Altering, reordering, or removing a message changes
+// the hashes of all of the messages that follow it, so auditors can
+// verify the log by recomputing the chain. The hash that precedes the
+// first message is 64 zeros; use NewHashChainSenderWithGenesis to
+// configure it.
+//
+// The sender sends messages to the underlying sender in the order of
+// the chain. Messages that the sender would not log, and messages
+// that cannot be annotated or encoded, are sent unchanged and are not
+// part of the chain. Use HashChainHead to get the hash of the most
+// recent message, for example to anchor the chain periodically in
+// another system.
+func NewHashChainSender(underlying Sender) (Sender, error) {
+	return NewHashChainSenderWithGenesis(underlying, defaultHashChainGenesis)
+}
+
+// NewHashChainSenderWithGenesis is the same as NewHashChainSender,
+// except that the first message of the chain follows the genesis hash,
+// for example, the head of a previous chain, so that a new process can
+// continue the chain of the process that it replaces.
+func NewHashChainSenderWithGenesis(underlying Sender, genesis string) (Sender, error) {
+	if underlying == nil {
+		return nil, errors.New("no underlying sender specified")
+	}
+
+	if genesis == "" {
+		return nil, errors.New("no genesis hash specified")
+	}
+
+	return &hashChainSender{
+		head:   genesis,
+		Sender: underlying,
+	}, nil
+}
+
+// HashChainHead returns the hash of the most recent message that a
+// hash chain sender sent, or the genesis hash, if the sender has not
+// sent any messages.
+func HashChainHead(s Sender) (string, error) {
+	sender, ok := s.(*hashChainSender)
+	if !ok {
+		return "", fmt.Errorf("%s is not a hash chain sender", s.Name())
+	}
+
+	sender.mutex.Lock()
+	defer sender.mutex.Unlock()
+
+	return sender.head, nil
+}
+
+func (s *hashChainSender) Send(m message.Composer) {
+	if !s.Level().ShouldLog(m) {
+		s.Sender.Send(m)
+		return
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	payload, err := json.Marshal(m.Raw())
+	if err != nil || m.Annotate("prev_hash", s.head) != nil {
+		s.Sender.Send(m)
+		return
+	}
+
+	hash := sha256.New()
+	_, _ = hash.Write([]byte(s.head))
+	_, _ = hash.Write(payload)
+	head := hex.EncodeToString(hash.Sum(nil))
+
+	if m.Annotate("hash", head) == nil {
+		s.head = head
+	}
+
+	s.Sender.Send(m)
+}
@@ -0,0 +1,89 @@
+package send
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"testing"
+
+	"github.com/mongodb/grip/level"
+	"github.com/mongodb/grip/message"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHashChainSender(t *testing.T) {
+	assert := assert.New(t)
+
+	_, err := NewHashChainSender(nil)
+	assert.Error(err)
+
+	sink, err := NewInternalLogger("sink", LevelInfo{level.Info, level.Info})
+	require.NoError(t, err)
+
+	_, err = NewHashChainSenderWithGenesis(sink, "")
+	assert.Error(err)
+
+	_, err = HashChainHead(sink)
+	assert.Error(err)
+
+	s, err := NewHashChainSender(sink)
+	require.NoError(t, err)
+	assert.Equal("sink", s.Name())
+
+	head, err := HashChainHead(s)
+	assert.NoError(err)
+	assert.Equal(defaultHashChainGenesis, head)
+
+	prev := head
+	for _, m := range []message.Composer{
+		message.NewFields(level.Info, message.Fields{"user": "alice", "action": "login"}),
+		message.NewDefaultMessage(level.Warning, "disk almost full"),
+		message.NewFields(level.Error, message.Fields{"user": "bob", "action": "delete"}),
+	} {
+		s.Send(m)
+		fields := sink.GetMessage().Message.Raw()
+
+		// recompute the hash from the message without the annotations.
+		var original interface{}
+		if f, ok := fields.(message.Fields); ok {
+			assert.Equal(prev, f["prev_hash"])
+			hash := f["hash"]
+			delete(f, "prev_hash")
+			delete(f, "hash")
+			original = f
+
+			payload, err := json.Marshal(original)
+			require.NoError(t, err)
+			sum := sha256.Sum256(append([]byte(prev), payload...))
+			assert.Equal(hex.EncodeToString(sum[:]), hash)
+		}
+
+		head, err = HashChainHead(s)
+		assert.NoError(err)
+		assert.NotEqual(prev, head)
+		assert.Len(head, 64)
+		prev = head
+	}
+
+	// messages below the threshold are not part of the chain.
+	s.Send(message.NewDefaultMessage(level.Debug, "quiet"))
+	assert.Equal(1, sink.Len())
+	_ = sink.GetMessage()
+	head, err = HashChainHead(s)
+	assert.NoError(err)
+	assert.Equal(prev, head)
+
+	// the genesis hash determines the chain.
+	first, err := NewHashChainSenderWithGenesis(sink, "abc")
+	require.NoError(t, err)
+	second, err := NewHashChainSenderWithGenesis(sink, "def")
+	require.NoError(t, err)
+	first.Send(message.NewFields(level.Info, message.Fields{"n": 1}))
+	second.Send(message.NewFields(level.Info, message.Fields{"n": 1}))
+	assert.Equal("abc", sink.GetMessage().Message.Raw().(message.Fields)["prev_hash"])
+	assert.Equal("def", sink.GetMessage().Message.Raw().(message.Fields)["prev_hash"])
+	firstHead, _ := HashChainHead(first)
+	secondHead, _ := HashChainHead(second)
+	assert.NotEqual(firstHead, secondHead)
+}
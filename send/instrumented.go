@@ -0,0 +1,185 @@
+package send
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/mongodb/grip/level"
+	"github.com/mongodb/grip/message"
+)
+
+// sendLatencyBuckets are the upper bounds of the buckets of the send
+// latency histogram of instrumented senders.
+var sendLatencyBuckets = []time.Duration{
+	time.Millisecond,
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	5 * time.Second,
+}
+
+// SendMetrics describes the messages that an instrumented sender has
+// processed, by the name of their priority (e.g. "info").
+type SendMetrics struct {
+	Levels map[string]LevelSendMetrics `json:"levels"`
+
+	// Buckets are the upper bounds of the buckets of the latency
+	// histograms.
+	Buckets []time.Duration `json:"buckets_ns"`
+}
+
+// LevelSendMetrics describes the messages of one priority that an
+// instrumented sender has processed.
+type LevelSendMetrics struct {
+	// Sent is the number of messages that the sender passed to the
+	// underlying sender, and Dropped is the number of messages
+	// below the threshold of the underlying sender, or not
+	// loggable, which it did not send.
+	Sent    int64 `json:"sent_total"`
+	Dropped int64 `json:"dropped_total"`
+
+	// Errors is the number of errors that the underlying sender
+	// reported to its error handlers.
+	Errors int64 `json:"errors_total"`
+
+	// Latency has the cumulative number of sends that took at most
+	// the upper bound of each bucket, and LatencySum is the total
+	// time of the sends.
+	Latency    []int64       `json:"latency_buckets"`
+	LatencySum time.Duration `json:"latency_sum_ns"`
+}
+
+// InstrumentedSender is implemented by senders that count the
+// messages that they process, such as the senders that
+// NewInstrumented returns.
+type InstrumentedSender interface {
+	Sender
+	SendMetrics() SendMetrics
+}
+
+type instrumentedSender struct {
+	mutex  sync.Mutex
+	levels map[level.Priority]*LevelSendMetrics
+	Sender
+}
+
+// NewInstrumented wraps an existing Sender, and counts the messages
+// that it sends, the messages that it drops because they are below
+// the threshold of the underlying sender, and the errors that the
+// underlying sender reports, and records the latency of each send,
+// by the priority of the messages. For senders that deliver messages
+// in the background, the latency is the time to enqueue the message.
+//
+// The sender counts errors with an error handler that it adds to the
+// underlying sender, which must support AddErrorHandler. The
+// SetErrorHandler and AddErrorHandler methods of the instrumented
+// sender configure the handlers of the underlying sender, and keep
+// the handler that counts errors; replacing the handlers of the
+// underlying sender directly stops the counting of errors. Use
+// RegisterMetrics or WriteMetrics to publish the metrics, labeled by
+// the name of the sender and the priority.
+func NewInstrumented(underlying Sender) (Sender, error) {
+	if underlying == nil {
+		return nil, errors.New("no underlying sender specified")
+	}
+
+	s := &instrumentedSender{
+		levels: map[level.Priority]*LevelSendMetrics{},
+		Sender: underlying,
+	}
+
+	if err := AddErrorHandler(underlying, s.recordError); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// SetErrorHandler replaces the error handlers of the underlying
+// sender, and adds the handler that counts errors after the new
+// handler.
+func (s *instrumentedSender) SetErrorHandler(eh ErrorHandler) error {
+	if err := s.Sender.SetErrorHandler(eh); err != nil {
+		return err
+	}
+
+	return AddErrorHandler(s.Sender, s.recordError)
+}
+
+// AddErrorHandler registers an additional error handler with the
+// underlying sender.
+func (s *instrumentedSender) AddErrorHandler(eh SendErrorHandler) error {
+	return AddErrorHandler(s.Sender, eh)
+}
+
+func (s *instrumentedSender) Send(m message.Composer) {
+	if message.IsNil(m) {
+		s.Sender.Send(m)
+		return
+	}
+
+	if !s.Level().ShouldLog(m) {
+		s.record(m.Priority(), func(l *LevelSendMetrics) { l.Dropped++ })
+
+		// the underlying sender does not log the message, and
+		// reports nothing.
+		s.Sender.Send(m)
+		return
+	}
+
+	start := time.Now()
+	s.Sender.Send(m)
+	latency := time.Since(start)
+
+	s.record(m.Priority(), func(l *LevelSendMetrics) {
+		l.Sent++
+		l.LatencySum += latency
+		for idx, bound := range sendLatencyBuckets {
+			if latency <= bound {
+				l.Latency[idx]++
+			}
+		}
+	})
+}
+
+func (s *instrumentedSender) recordError(_ string, err error, m message.Composer) {
+	if err == nil || m == nil {
+		return
+	}
+
+	s.record(m.Priority(), func(l *LevelSendMetrics) { l.Errors++ })
+}
+
+func (s *instrumentedSender) record(p level.Priority, update func(*LevelSendMetrics)) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	l, ok := s.levels[p]
+	if !ok {
+		l = &LevelSendMetrics{Latency: make([]int64, len(sendLatencyBuckets))}
+		s.levels[p] = l
+	}
+
+	update(l)
+}
+
+func (s *instrumentedSender) SendMetrics() SendMetrics {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	m := SendMetrics{
+		Levels:  make(map[string]LevelSendMetrics, len(s.levels)),
+		Buckets: append([]time.Duration{}, sendLatencyBuckets...),
+	}
+	for p, l := range s.levels {
+		out := *l
+		out.Latency = append([]int64{}, l.Latency...)
+		m.Levels[p.String()] = out
+	}
+
+	return m
+}
@@ -6,6 +6,8 @@
 package send
 
 import (
+	"context"
+
 	"github.com/mongodb/grip/level"
 	"github.com/mongodb/grip/message"
 )
@@ -45,6 +47,15 @@ type Sender interface {
 	// that takes a message and returns string and error.
 	SetFormatter(MessageFormatter) error
 
+	// Flush ensures that all messages that the sender has
+	// accepted have reached the logging backend, or returns
+	// when the context is canceled. Senders that buffer
+	// messages, or perform work in the background, must flush
+	// their pending messages, and senders that wrap other
+	// senders must flush the wrapped senders. Flush is a no-op
+	// for senders that deliver messages synchronously.
+	Flush(context.Context) error
+
 	// If the logging sender holds any resources that require
 	// desecration, they should be cleaned up tin the Close()
 	// method. Close() is called by the SetSender() method before
@@ -52,6 +63,53 @@ type Sender interface {
 	Close() error
 }
 
+// ContextSender is implemented by senders that can stop waiting for
+// the delivery of a message when a context is canceled, such as the
+// senders that deliver messages over the network. SendContext, like
+// Send, reports errors, including the error of the context, to the
+// sender's error handler. Use the SendContext function to send
+// messages with a context to any Sender.
+type ContextSender interface {
+	Sender
+	SendContext(context.Context, message.Composer)
+}
+
+// SendContext sends the message with the context, if the Sender is a
+// ContextSender, and otherwise sends the message with Send, which
+// ignores the context.
+func SendContext(ctx context.Context, s Sender, m message.Composer) {
+	if sender, ok := s.(ContextSender); ok {
+		sender.SendContext(ctx, m)
+		return
+	}
+
+	s.Send(m)
+}
+
+// sendUntilDone calls send in the background, for senders whose
+// clients cannot be canceled, and returns when send returns, or
+// reports the error of the context to the error handler when the
+// context is done first. In that case, send continues in the
+// background.
+func sendUntilDone(ctx context.Context, send func(message.Composer), handler ErrorHandler, m message.Composer) {
+	if ctx.Done() == nil {
+		send(m)
+		return
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		send(m)
+	}()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+		handler(ctx.Err(), m)
+	}
+}
+
 // LevelInfo provides a sender-independent structure for storing
 // information about a sender's configured log levels.
 type LevelInfo struct {
@@ -65,11 +123,17 @@ func (l LevelInfo) Valid() bool {
 }
 
 // ShouldLog checks to see if the log message should be logged, and
-// returns false if there is no message or if the message's priority
-// is below the logging threshold.
+// returns false if there is no message, including nil Composers (see
+// message.IsNil), or if the message's priority is below the logging
+// threshold.
 func (l LevelInfo) ShouldLog(m message.Composer) bool {
-	// priorities are 0 = Emergency; 7 = debug
-	return m.Loggable() && (m.Priority() >= l.Threshold)
+	if message.IsNil(m) {
+		return false
+	}
+
+	// check the priority first, so that lazy messages below the
+	// threshold never produce their content.
+	return m.Priority() >= l.Threshold && m.Loggable()
 }
 
 func setup(s Sender, name string, l LevelInfo) (Sender, error) {
@@ -1,7 +1,9 @@
 package send
 
 import (
+	"context"
 	"errors"
+	"sync"
 
 	"github.com/mongodb/grip/level"
 	"github.com/mongodb/grip/message"
@@ -13,9 +15,12 @@ import (
 // under-priority and unloggable messages. Used  for testing
 // purposes.
 type InternalSender struct {
-	name   string
-	level  LevelInfo
-	output chan *InternalMessage
+	name       string
+	level      LevelInfo
+	levelMutex sync.RWMutex
+	output     chan *InternalMessage
+	closed     bool
+	mutex      sync.RWMutex
 }
 
 // InternalMessage provides a complete representation of all
@@ -55,15 +60,25 @@ func MakeInternalLogger() *InternalSender {
 
 func (s *InternalSender) Name() string                          { return s.name }
 func (s *InternalSender) SetName(n string)                      { s.name = n }
-func (s *InternalSender) Close() error                          { close(s.output); return nil }
-func (s *InternalSender) Level() LevelInfo                      { return s.level }
+func (s *InternalSender) Flush(_ context.Context) error         { return nil }
 func (s *InternalSender) SetErrorHandler(_ ErrorHandler) error  { return nil }
 func (s *InternalSender) SetFormatter(_ MessageFormatter) error { return nil }
+
+func (s *InternalSender) Level() LevelInfo {
+	s.levelMutex.RLock()
+	defer s.levelMutex.RUnlock()
+
+	return s.level
+}
+
 func (s *InternalSender) SetLevel(l LevelInfo) error {
 	if !l.Valid() {
 		return errors.New("invalid level")
 	}
 
+	s.levelMutex.Lock()
+	defer s.levelMutex.Unlock()
+
 	s.level = l
 	return nil
 }
@@ -82,14 +97,41 @@ func (s *InternalSender) HasMessage() bool {
 // Len returns the number of sent messages that have not been retrieved.
 func (s *InternalSender) Len() int { return len(s.output) }
 
+// Close closes the channel of messages. Close is idempotent.
+func (s *InternalSender) Close() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if !s.closed {
+		s.closed = true
+		close(s.output)
+	}
+
+	return nil
+}
+
 // Send sends a message. Unlike all other sender implementations, all
 // messages are sent, but the InternalMessage format tracks
-// "loggability" for testing purposes.
+// "loggability" for testing purposes. Nil messages, and messages sent
+// after Close, are dropped.
 func (s *InternalSender) Send(m message.Composer) {
+	if message.IsNil(m) {
+		return
+	}
+
+	logged := s.Level().ShouldLog(m)
+
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	if s.closed {
+		return
+	}
+
 	s.output <- &InternalMessage{
 		Message:  m,
 		Priority: m.Priority(),
-		Rendered: m.String(),
-		Logged:   s.level.ShouldLog(m),
+		Rendered: messageText(m, " "),
+		Logged:   logged,
 	}
 }
@@ -14,16 +14,28 @@ func NewJSONConsoleLogger(name string, l LevelInfo) (Sender, error) {
 	return setup(MakeJSONConsoleLogger(), name, l)
 }
 
+// NewJSONConsoleLoggerWithConfig is the same as NewJSONConsoleLogger,
+// but renders the JSON documents with the keys and timestamp layout
+// of the configuration.
+func NewJSONConsoleLoggerWithConfig(name string, conf JSONConfig, l LevelInfo) (Sender, error) {
+	s := MakeJSONConsoleLogger()
+	if err := s.SetFormatter(MakeJSONFormatterWithConfig(conf)); err != nil {
+		return nil, err
+	}
+
+	return setup(s, name, l)
+}
+
 // MakeJSONConsoleLogger returns an un-configured JSON console logging
 // instance.
 func MakeJSONConsoleLogger() Sender {
 	s := &nativeLogger{
 		Base:   NewBase(""),
 		logger: log.New(os.Stdout, "", 0),
+		json:   true,
 	}
 
 	_ = s.SetFormatter(MakeJSONFormatter())
-	_ = s.SetErrorHandler(ErrorHandlerFromLogger(s.logger))
 
 	return s
 }
@@ -41,10 +53,26 @@ func NewJSONFileLogger(name, file string, l LevelInfo) (Sender, error) {
 	return setup(s, name, l)
 }
 
+// NewJSONFileLoggerWithConfig is the same as NewJSONFileLogger, but
+// renders the JSON documents with the keys and timestamp layout of the
+// configuration.
+func NewJSONFileLoggerWithConfig(name, file string, conf JSONConfig, l LevelInfo) (Sender, error) {
+	s, err := MakeJSONFileLogger(file)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = s.SetFormatter(MakeJSONFormatterWithConfig(conf)); err != nil {
+		return nil, err
+	}
+
+	return setup(s, name, l)
+}
+
 // MakeJSONFileLogger creates an un-configured JSON logger that writes
 // output to the specified file.
 func MakeJSONFileLogger(file string) (Sender, error) {
-	s := &nativeLogger{Base: NewBase("")}
+	s := &nativeLogger{Base: NewBase(""), json: true}
 
 	if err := s.SetFormatter(MakeJSONFormatter()); err != nil {
 		return nil, err
@@ -56,6 +84,7 @@ func MakeJSONFileLogger(file string) (Sender, error) {
 	}
 
 	s.logger = log.New(f, "", 0)
+	s.file = f
 
 	s.closer = func() error {
 		return f.Close()
@@ -0,0 +1,160 @@
+package send
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"sort"
+
+	"github.com/mongodb/grip/message"
+)
+
+// JSONTruncatedKey is the key that the streaming JSON formatter adds
+// to a document when the encoded message exceeds the size limit.
+const JSONTruncatedKey = "grip.truncated"
+
+var errJSONLimit = errors.New("json document exceeds size limit")
+
+// MakeStreamingJSONFormatter returns a MessageFormatter that, like
+// the formatter returned by MakeJSONFormatter, renders the Raw form
+// of messages as JSON documents. Rather than marshaling the entire
+// document at once, the streaming formatter encodes maps (e.g.
+// message.Fields) one value at a time, directly into the output, and
+// writes the output of values that implement json.Marshaler, and the
+// content of values that implement io.Reader, which must be valid
+// JSON, without additional copies. Readers are consumed by the
+// formatter.
+//
+// If limit is greater than zero, the formatter stops encoding a
+// document when the output reaches the limit, omits the remaining
+// values, and marks the document with a JSONTruncatedKey field. The
+// keys of maps are sorted, as with encoding/json.
+func MakeStreamingJSONFormatter(limit int) MessageFormatter {
+	return func(m message.Composer) (string, error) {
+		enc := &jsonStreamer{limit: limit}
+		if err := enc.encode(m.Raw()); err != nil {
+			return "", err
+		}
+
+		return enc.buf.String(), nil
+	}
+}
+
+type jsonStreamer struct {
+	buf       bytes.Buffer
+	limit     int
+	truncated bool
+}
+
+func (s *jsonStreamer) encode(v interface{}) error {
+	err := s.writeValue(v)
+	if err == errJSONLimit {
+		s.buf.Reset()
+		s.buf.WriteString(`{"` + JSONTruncatedKey + `":true}`)
+		return nil
+	}
+
+	return err
+}
+
+func (s *jsonStreamer) remaining() int { return s.limit - s.buf.Len() }
+
+func (s *jsonStreamer) write(b []byte) error {
+	if s.limit > 0 && len(b) > s.remaining() {
+		return errJSONLimit
+	}
+
+	_, _ = s.buf.Write(b)
+	return nil
+}
+
+func (s *jsonStreamer) writeValue(v interface{}) error {
+	switch v := v.(type) {
+	case message.Fields:
+		return s.writeMap(v)
+	case map[string]interface{}:
+		return s.writeMap(v)
+	case json.Marshaler:
+		out, err := v.MarshalJSON()
+		if err != nil {
+			return err
+		}
+		return s.write(out)
+	case io.Reader:
+		if s.limit <= 0 {
+			_, err := s.buf.ReadFrom(v)
+			return err
+		}
+
+		rem := s.remaining()
+		if rem < 0 {
+			return errJSONLimit
+		}
+
+		n, err := io.CopyN(&s.buf, v, int64(rem+1))
+		if err != nil && err != io.EOF {
+			return err
+		}
+		if n > int64(rem) {
+			return errJSONLimit
+		}
+		return nil
+	default:
+		out, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		return s.write(out)
+	}
+}
+
+// writeMap writes the map as a JSON object, stopping when the output
+// reaches the limit. Once the limit is reached, the object that
+// reached the limit is marked as truncated, and all enclosing objects
+// are closed without adding more values.
+func (s *jsonStreamer) writeMap(m map[string]interface{}) error {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	s.buf.WriteByte('{')
+	for idx, k := range keys {
+		if s.truncated {
+			break
+		}
+
+		mark := s.buf.Len()
+		if idx > 0 {
+			s.buf.WriteByte(',')
+		}
+
+		key, err := json.Marshal(k)
+		if err != nil {
+			return err
+		}
+
+		err = s.write(key)
+		if err == nil {
+			s.buf.WriteByte(':')
+			err = s.writeValue(m[k])
+		}
+
+		if err == errJSONLimit {
+			s.buf.Truncate(mark)
+			if idx > 0 {
+				s.buf.WriteByte(',')
+			}
+			s.buf.WriteString(`"` + JSONTruncatedKey + `":true`)
+			s.truncated = true
+			break
+		} else if err != nil {
+			return err
+		}
+	}
+	s.buf.WriteByte('}')
+
+	return nil
+}
@@ -0,0 +1,121 @@
+package send
+
+import (
+	"bytes"
+	"encoding/json"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mongodb/grip/level"
+	"github.com/mongodb/grip/message"
+	"github.com/stretchr/testify/assert"
+)
+
+type marshalerValue struct{}
+
+func (marshalerValue) MarshalJSON() ([]byte, error) { return []byte(`{"custom":true}`), nil }
+
+func TestStreamingJSONFormatterMatchesJSONFormatter(t *testing.T) {
+	assert := assert.New(t)
+
+	fields := message.Fields{
+		"b":      "two",
+		"a":      1,
+		"nested": map[string]interface{}{"z": []int{1, 2}, "y": message.Fields{"x": nil}},
+		"custom": marshalerValue{},
+	}
+
+	fm := message.MakeFields(fields)
+	expected, err := MakeJSONFormatter()(fm)
+	assert.NoError(err)
+	streamed, err := MakeStreamingJSONFormatter(0)(fm)
+	assert.NoError(err)
+	assert.Equal(expected, streamed)
+
+	// structures which aren't maps are marshaled directly.
+	m := message.NewJobRun("job", time.Time{}, 0, nil)
+	expected, err = MakeJSONFormatter()(m)
+	assert.NoError(err)
+	streamed, err = MakeStreamingJSONFormatter(0)(m)
+	assert.NoError(err)
+	assert.Equal(expected, streamed)
+}
+
+func TestStreamingJSONFormatterWritesReaders(t *testing.T) {
+	assert := assert.New(t)
+
+	out, err := MakeStreamingJSONFormatter(0)(message.MakeFields(message.Fields{
+		"payload": strings.NewReader(`{"response":[1,2,3]}`),
+	}))
+	assert.NoError(err)
+	assert.Contains(out, `"payload":{"response":[1,2,3]}`)
+}
+
+func TestStreamingJSONFormatterTruncates(t *testing.T) {
+	assert := assert.New(t)
+
+	fields := message.Fields{
+		"a":      "small",
+		"b":      strings.Repeat("x", 1024),
+		"c":      "never written",
+		"nested": message.Fields{"a": "small", "b": strings.Repeat("x", 1024)},
+	}
+
+	out, err := MakeStreamingJSONFormatter(100)(message.MakeFields(fields))
+	assert.NoError(err)
+	assert.Equal(`{"a":"small","grip.truncated":true}`, out)
+
+	delete(fields, "b")
+	out, err = MakeStreamingJSONFormatter(100)(message.MakeFields(fields))
+	assert.NoError(err)
+	assert.Equal(`{"a":"small","c":"never written","msg":"","nested":{"a":"small","grip.truncated":true}}`, out)
+
+	doc := map[string]interface{}{}
+	assert.NoError(json.Unmarshal([]byte(out), &doc))
+
+	out, err = MakeStreamingJSONFormatter(10)(message.NewString(strings.Repeat("x", 1024)))
+	assert.NoError(err)
+	assert.Equal(`{"grip.truncated":true}`, out)
+}
+
+func TestStreamingJSONFormatterBoundsMemoryForLargePayloads(t *testing.T) {
+	assert := assert.New(t)
+
+	const payloadSize = 10 * 1024 * 1024
+	const limit = 64 * 1024
+
+	var payload bytes.Buffer
+	payload.WriteString(`{"items":[`)
+	for payload.Len() < payloadSize {
+		payload.WriteString(`{"id":1,"nested":{"name":"value"}},`)
+	}
+	payload.WriteString(`{}]}`)
+	data := payload.Bytes()
+
+	formatter := MakeStreamingJSONFormatter(limit)
+	stats := runtime.MemStats{}
+
+	runtime.GC()
+	runtime.ReadMemStats(&stats)
+	before := stats.TotalAlloc
+
+	out, err := formatter(message.NewFields(level.Info, message.Fields{
+		"msg":      "api response",
+		"response": bytes.NewReader(data),
+	}))
+
+	runtime.ReadMemStats(&stats)
+	allocated := stats.TotalAlloc - before
+
+	assert.NoError(err)
+	assert.True(len(out) <= limit)
+	assert.Contains(out, JSONTruncatedKey)
+	assert.True(allocated < 1024*1024, "allocated %d bytes", allocated)
+
+	// without a limit, the reader is copied into the output in full.
+	out, err = MakeStreamingJSONFormatter(0)(message.MakeFields(message.Fields{"response": bytes.NewReader(data)}))
+	assert.NoError(err)
+	assert.True(strings.Contains(out, `"response":`+string(data)+`,`))
+}
@@ -0,0 +1,335 @@
+package send
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mongodb/grip/message"
+)
+
+const lokiPushEndpoint = "/loki/api/v1/push"
+
+// lokiLabelName matches the label names that Loki accepts.
+var lokiLabelName = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// LokiOptions configures the Loki sender.
+type LokiOptions struct {
+	// Labels are the static labels of every stream that the sender
+	// pushes, in addition to the "level" label, which is the
+	// priority of the message, and which takes precedence over a
+	// static "level" label.
+	Labels map[string]string
+
+	// BatchSize is the number of messages at which the sender
+	// pushes the buffered messages. Defaults to 100.
+	BatchSize int
+
+	// FlushInterval is how often the sender pushes the buffered
+	// messages. Defaults to 5 seconds.
+	FlushInterval time.Duration
+
+	// Client is the HTTP client that the sender pushes messages
+	// with. Defaults to a client with a 10 second timeout.
+	Client *http.Client
+}
+
+// Validate checks the options, and sets defaults for unspecified
+// values.
+func (o *LokiOptions) Validate() error {
+	errs := []string{}
+
+	for name := range o.Labels {
+		if !lokiLabelName.MatchString(name) {
+			errs = append(errs, fmt.Sprintf("invalid loki label name '%s'", name))
+		}
+	}
+	sort.Strings(errs)
+
+	if o.BatchSize < 0 {
+		errs = append(errs, "batch size cannot be negative")
+	}
+
+	if o.FlushInterval < 0 {
+		errs = append(errs, "flush interval cannot be negative")
+	}
+
+	if o.BatchSize == 0 {
+		o.BatchSize = 100
+	}
+
+	if o.FlushInterval == 0 {
+		o.FlushInterval = 5 * time.Second
+	}
+
+	if o.Client == nil {
+		o.Client = &http.Client{Timeout: 10 * time.Second}
+	}
+
+	if len(errs) > 0 {
+		return errors.New(strings.Join(errs, "; "))
+	}
+
+	return nil
+}
+
+// lokiEntry is the level label of a buffered message, and the
+// timestamp and the line of its entry.
+type lokiEntry struct {
+	level string
+	ts    time.Time
+	line  string
+}
+
+// lokiStream is a stream of the push request: the labels of the
+// stream, and its entries, as pairs of the timestamp, in nanoseconds
+// since the epoch, and the line.
+type lokiStream struct {
+	Stream map[string]string `json:"stream"`
+	Values [][2]string       `json:"values"`
+}
+
+type lokiSender struct {
+	endpoint  string
+	opts      LokiOptions
+	entries   []lokiEntry
+	closed    bool
+	mutex     sync.Mutex
+	postMutex sync.Mutex
+	stop      chan struct{}
+	done      chan struct{}
+	*Base
+}
+
+// MakeLokiLogger constructs a Sender that pushes messages to the Loki
+// server at the endpoint (e.g. "http://loki:3100"), with the static
+// labels, and the default options.
+func MakeLokiLogger(name, endpoint string, labels map[string]string, l LevelInfo) (Sender, error) {
+	return MakeLokiLoggerWithOptions(name, endpoint, LokiOptions{Labels: labels}, l)
+}
+
+// MakeLokiLoggerWithOptions constructs a Sender that pushes messages
+// to the push API of the Loki server at the endpoint. The sender
+// buffers messages, and pushes them in a single gzipped request when
+// the buffer holds BatchSize messages, every FlushInterval, on Flush,
+// and on Close.
+//
+// The messages of each priority are a stream, with the static labels
+// and a "level" label, such as "error". Each entry has the text of its
+// message, and the time that its message was created, in order within
+// the stream. The sender reports requests that fail to its error
+// handler.
+func MakeLokiLoggerWithOptions(name, endpoint string, opts LokiOptions, l LevelInfo) (Sender, error) {
+	if endpoint == "" {
+		return nil, errors.New("no loki endpoint specified")
+	}
+
+	if _, err := url.ParseRequestURI(endpoint); err != nil {
+		return nil, fmt.Errorf("invalid loki endpoint: %s", err.Error())
+	}
+
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+
+	labels := make(map[string]string, len(opts.Labels))
+	for k, v := range opts.Labels {
+		labels[k] = v
+	}
+	opts.Labels = labels
+
+	endpoint = strings.TrimSuffix(endpoint, "/")
+	if !strings.HasSuffix(endpoint, lokiPushEndpoint) {
+		endpoint += lokiPushEndpoint
+	}
+
+	s := &lokiSender{
+		endpoint: endpoint,
+		opts:     opts,
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+		Base:     NewBase(name),
+	}
+
+	s.closer = func() error {
+		close(s.stop)
+		<-s.done
+
+		s.mutex.Lock()
+		s.closed = true
+		s.mutex.Unlock()
+
+		return s.flush(context.Background())
+	}
+
+	go s.flushPeriodically()
+
+	return setup(s, name, l)
+}
+
+func (s *lokiSender) Send(m message.Composer) {
+	if !s.Level().ShouldLog(m) || s.reportClosed(m) {
+		return
+	}
+
+	entry := lokiEntry{
+		level: m.Priority().String(),
+		ts:    s.timestamp(m),
+		line:  messageText(m, " "),
+	}
+
+	s.mutex.Lock()
+
+	// the sender may have closed since the check.
+	if s.closed {
+		s.mutex.Unlock()
+		s.ErrorHandler(ErrSenderClosed, m)
+		return
+	}
+
+	s.entries = append(s.entries, entry)
+	if len(s.entries) < s.opts.BatchSize {
+		s.mutex.Unlock()
+		return
+	}
+
+	entries := s.entries
+	s.entries = nil
+
+	// hold the post mutex before releasing the mutex, so that
+	// batches are pushed in the order that they were taken from the
+	// buffer.
+	s.postMutex.Lock()
+	defer s.postMutex.Unlock()
+	s.mutex.Unlock()
+
+	s.ErrorHandler(s.push(context.Background(), entries), m)
+}
+
+// Flush pushes the buffered messages.
+func (s *lokiSender) Flush(ctx context.Context) error { return s.flush(ctx) }
+
+func (s *lokiSender) flush(ctx context.Context) error {
+	s.mutex.Lock()
+	entries := s.entries
+	s.entries = nil
+	s.postMutex.Lock()
+	defer s.postMutex.Unlock()
+	s.mutex.Unlock()
+
+	return s.push(ctx, entries)
+}
+
+func (s *lokiSender) flushPeriodically() {
+	defer close(s.done)
+
+	ticker := time.NewTicker(s.opts.FlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+			s.ErrorHandler(s.flush(context.Background()), message.NewString(s.endpoint))
+		}
+	}
+}
+
+// streams groups the entries into streams by their level, in order of
+// the levels, with the entries of each stream in order of their
+// timestamps, since Loki may reject entries that are out of order.
+func (s *lokiSender) streams(entries []lokiEntry) []lokiStream {
+	byLevel := map[string][]lokiEntry{}
+	levels := []string{}
+	for _, entry := range entries {
+		if _, ok := byLevel[entry.level]; !ok {
+			levels = append(levels, entry.level)
+		}
+		byLevel[entry.level] = append(byLevel[entry.level], entry)
+	}
+	sort.Strings(levels)
+
+	streams := make([]lokiStream, 0, len(levels))
+	for _, lvl := range levels {
+		labels := make(map[string]string, len(s.opts.Labels)+1)
+		for k, v := range s.opts.Labels {
+			labels[k] = v
+		}
+		labels["level"] = lvl
+
+		stream := byLevel[lvl]
+		sort.SliceStable(stream, func(i, j int) bool { return stream[i].ts.Before(stream[j].ts) })
+
+		values := make([][2]string, 0, len(stream))
+		for _, entry := range stream {
+			values = append(values, [2]string{strconv.FormatInt(entry.ts.UnixNano(), 10), entry.line})
+		}
+
+		streams = append(streams, lokiStream{Stream: labels, Values: values})
+	}
+
+	return streams
+}
+
+// push pushes the entries in a single request. The caller must hold
+// the post mutex.
+func (s *lokiSender) push(ctx context.Context, entries []lokiEntry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+
+	payload, err := json.Marshal(map[string]interface{}{"streams": s.streams(entries)})
+	if err != nil {
+		return err
+	}
+
+	body := &bytes.Buffer{}
+	zw := gzip.NewWriter(body)
+	if _, err = zw.Write(payload); err != nil {
+		return err
+	}
+	if err = zw.Close(); err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest("POST", s.endpoint, body)
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Content-Encoding", "gzip")
+
+	resp, err := s.opts.Client.Do(req)
+	if err != nil {
+		return fmt.Errorf("loki push of %d entries failed: %s", len(entries), err.Error())
+	}
+	defer resp.Body.Close()
+
+	// loki describes rejected pushes in the body of the response.
+	text, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 512))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		if detail := strings.TrimSpace(string(text)); detail != "" {
+			return fmt.Errorf("loki push of %d entries failed with status %s: %s", len(entries), resp.Status, detail)
+		}
+
+		return fmt.Errorf("loki push of %d entries failed with status %s", len(entries), resp.Status)
+	}
+
+	return nil
+}
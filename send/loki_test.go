@@ -0,0 +1,185 @@
+package send
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mongodb/grip/level"
+	"github.com/mongodb/grip/message"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// lokiServer records the streams of the pushes to it, and responds
+// with the configured status.
+type lokiServer struct {
+	*httptest.Server
+	mutex    sync.Mutex
+	requests [][]lokiStream
+	status   int
+}
+
+func newLokiServer() *lokiServer {
+	s := &lokiServer{status: http.StatusNoContent}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != lokiPushEndpoint || r.Header.Get("Content-Encoding") != "gzip" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		zr, err := gzip.NewReader(r.Body)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		payload := struct {
+			Streams []lokiStream `json:"streams"`
+		}{}
+		if err = json.NewDecoder(zr).Decode(&payload); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		s.mutex.Lock()
+		defer s.mutex.Unlock()
+		s.requests = append(s.requests, payload.Streams)
+		w.WriteHeader(s.status)
+		if s.status == http.StatusBadRequest {
+			_, _ = w.Write([]byte("entry out of order"))
+		}
+	}))
+
+	return s
+}
+
+func (s *lokiServer) received() [][]lokiStream {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return append([][]lokiStream{}, s.requests...)
+}
+
+func TestLokiOptionsValidate(t *testing.T) {
+	assert := assert.New(t)
+
+	opts := LokiOptions{Labels: map[string]string{"app": "grip", "bad-name": "x", "9lives": "y"}, BatchSize: -1, FlushInterval: -1}
+	err := opts.Validate()
+	assert.Error(err)
+	assert.Len(strings.Split(err.Error(), "; "), 4)
+
+	opts = LokiOptions{}
+	assert.NoError(opts.Validate())
+	assert.Equal(100, opts.BatchSize)
+	assert.Equal(5*time.Second, opts.FlushInterval)
+	assert.NotNil(opts.Client)
+
+	_, err = MakeLokiLogger("loki", "", nil, LevelInfo{level.Info, level.Info})
+	assert.Error(err)
+	_, err = MakeLokiLogger("loki", "not a url", nil, LevelInfo{level.Info, level.Info})
+	assert.Error(err)
+}
+
+func TestLokiSenderPushesStreams(t *testing.T) {
+	assert := assert.New(t)
+
+	srv := newLokiServer()
+	defer srv.Close()
+
+	sender, err := MakeLokiLoggerWithOptions("loki", srv.URL+"/", LokiOptions{
+		Labels:        map[string]string{"app": "checkout", "level": "ignored"},
+		BatchSize:     4,
+		FlushInterval: time.Hour,
+		Client:        srv.Client(),
+	}, LevelInfo{level.Info, level.Info})
+	require.NoError(t, err)
+	defer sender.Close()
+
+	first := time.Unix(1600000000, 123456789)
+	sender.Send(timestampedComposer{created: first.Add(time.Second), Composer: message.NewDefaultMessage(level.Info, "second")})
+	sender.Send(timestampedComposer{created: first, Composer: message.NewDefaultMessage(level.Info, "first")})
+	sender.Send(message.NewDefaultMessage(level.Debug, "not logged"))
+	sender.Send(timestampedComposer{created: first.Add(time.Nanosecond), Composer: message.NewFieldsMessage(level.Error, "failed", message.Fields{"code": 500})})
+	assert.Len(srv.received(), 0, "messages are buffered until the batch is full")
+
+	sender.Send(timestampedComposer{created: time.Unix(0, 5), Composer: message.NewDefaultMessage(level.Error, "early")})
+	requests := srv.received()
+	require.Len(t, requests, 1)
+	streams := requests[0]
+	require.Len(t, streams, 2)
+
+	assert.Equal(map[string]string{"app": "checkout", "level": "error"}, streams[0].Stream)
+	assert.Equal([][2]string{
+		{"5", "early"},
+		{"1600000000123456790", "[msg='failed' code='500']"},
+	}, streams[0].Values)
+
+	assert.Equal(map[string]string{"app": "checkout", "level": "info"}, streams[1].Stream)
+	assert.Equal([][2]string{
+		{"1600000000123456789", "first"},
+		{"1600000001123456789", "second"},
+	}, streams[1].Values)
+
+	// flush and close push the partial batches.
+	sender.Send(message.NewDefaultMessage(level.Warning, "flushed"))
+	require.NoError(t, sender.Flush(context.Background()))
+	sender.Send(message.NewDefaultMessage(level.Warning, "closed"))
+	require.NoError(t, sender.Close())
+
+	requests = srv.received()
+	require.Len(t, requests, 3)
+	assert.Equal("flushed", requests[1][0].Values[0][1])
+	assert.Equal("closed", requests[2][0].Values[0][1])
+}
+
+func TestLokiSenderFlushesOnInterval(t *testing.T) {
+	srv := newLokiServer()
+	defer srv.Close()
+
+	sender, err := MakeLokiLoggerWithOptions("loki", srv.URL, LokiOptions{
+		FlushInterval: 10 * time.Millisecond,
+		Client:        srv.Client(),
+	}, LevelInfo{level.Info, level.Info})
+	require.NoError(t, err)
+	defer sender.Close()
+
+	sender.Send(message.NewDefaultMessage(level.Info, "eventually"))
+	deadline := time.Now().Add(5 * time.Second)
+	for len(srv.received()) == 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	requests := srv.received()
+	require.Len(t, requests, 1)
+	assert.Equal(t, "eventually", requests[0][0].Values[0][1])
+}
+
+func TestLokiSenderReportsErrors(t *testing.T) {
+	assert := assert.New(t)
+
+	srv := newLokiServer()
+	defer srv.Close()
+	srv.status = http.StatusBadRequest
+
+	sender, err := MakeLokiLoggerWithOptions("loki", srv.URL, LokiOptions{
+		BatchSize:     1,
+		FlushInterval: time.Hour,
+		Client:        srv.Client(),
+	}, LevelInfo{level.Info, level.Info})
+	require.NoError(t, err)
+	defer sender.Close()
+
+	errs := &errorCollector{}
+	require.NoError(t, sender.SetErrorHandler(errs.handler))
+
+	sender.Send(message.NewDefaultMessage(level.Info, "rejected"))
+	require.Len(t, errs.get(), 1)
+	assert.Contains(errs.get()[0].Error(), "400")
+	assert.Contains(errs.get()[0].Error(), "entry out of order")
+}
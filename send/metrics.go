@@ -0,0 +1,234 @@
+package send
+
+import (
+	"bytes"
+	"expvar"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// BufferMetrics describes the backlog of a sender that buffers
+// messages before delivering them.
+type BufferMetrics struct {
+	// QueueDepth is the number of messages that the sender holds
+	// and has not yet delivered.
+	QueueDepth int64 `json:"queue_depth"`
+
+	// Enqueued and Dropped are the total number of messages
+	// that the sender has added to its buffer, and that it has
+	// discarded without delivering them.
+	Enqueued int64 `json:"enqueued_total"`
+	Dropped  int64 `json:"dropped_total"`
+
+	// Flushes and FlushTime are the number of completed calls
+	// to Flush, and the total time spent in those calls.
+	Flushes   int64         `json:"flushes_total"`
+	FlushTime time.Duration `json:"flush_time_ns"`
+}
+
+// MetricsSender is implemented by senders that buffer messages, such
+// as the buffered, channel and spooling senders, and report metrics
+// about their buffers.
+type MetricsSender interface {
+	Sender
+	BufferMetrics() BufferMetrics
+}
+
+// flushTimer records the number and duration of calls to Flush.
+type flushTimer struct {
+	count int64
+	nanos int64
+}
+
+func (t *flushTimer) observe(start time.Time) {
+	atomic.AddInt64(&t.nanos, int64(time.Since(start)))
+	atomic.AddInt64(&t.count, 1)
+}
+
+func (t *flushTimer) load(m *BufferMetrics) {
+	m.Flushes = atomic.LoadInt64(&t.count)
+	m.FlushTime = time.Duration(atomic.LoadInt64(&t.nanos))
+}
+
+var (
+	metricNamespace = regexp.MustCompile(`^[a-zA-Z_:][a-zA-Z0-9_:]*$`)
+
+	registeredMetrics      = map[string]Sender{}
+	registeredMetricsMutex sync.RWMutex
+)
+
+// publishedMetrics returns the function that publishes the metrics of
+// the sender with expvar, or an error if the sender reports neither
+// buffer metrics nor send metrics.
+func publishedMetrics(s Sender) (func() interface{}, error) {
+	buffered, isBuffered := s.(MetricsSender)
+	instrumented, isInstrumented := s.(InstrumentedSender)
+
+	switch {
+	case isBuffered && isInstrumented:
+		return func() interface{} {
+			return map[string]interface{}{"buffer": buffered.BufferMetrics(), "send": instrumented.SendMetrics()}
+		}, nil
+	case isBuffered:
+		return func() interface{} { return buffered.BufferMetrics() }, nil
+	case isInstrumented:
+		return func() interface{} { return instrumented.SendMetrics() }, nil
+	default:
+		return nil, fmt.Errorf("%s does not report metrics", s.Name())
+	}
+}
+
+// RegisterMetrics publishes the buffer metrics of the sender, if it
+// implements MetricsSender, and its send metrics, if it implements
+// InstrumentedSender, with the expvar package under the namespace,
+// and adds the sender to those that MetricsHandler reports. The
+// namespace must be a valid Prometheus metric name prefix, and may
+// only be registered once.
+func RegisterMetrics(s Sender, namespace string) error {
+	published, err := publishedMetrics(s)
+	if err != nil {
+		return err
+	}
+
+	if !metricNamespace.MatchString(namespace) {
+		return fmt.Errorf("'%s' is not a valid metric namespace", namespace)
+	}
+
+	registeredMetricsMutex.Lock()
+	defer registeredMetricsMutex.Unlock()
+
+	if _, ok := registeredMetrics[namespace]; ok || expvar.Get(namespace) != nil {
+		return fmt.Errorf("metrics for '%s' are already registered", namespace)
+	}
+
+	expvar.Publish(namespace, expvar.Func(published))
+	registeredMetrics[namespace] = s
+
+	return nil
+}
+
+// WriteMetrics writes the buffer metrics of the sender, if it
+// implements MetricsSender, and its send metrics, if it implements
+// InstrumentedSender, in the Prometheus text exposition format, with
+// metric names prefixed by the namespace. Send metrics are labeled by
+// the name of the sender and the priority of the messages.
+func WriteMetrics(w io.Writer, s Sender, namespace string) error {
+	if _, err := publishedMetrics(s); err != nil {
+		return err
+	}
+
+	if !metricNamespace.MatchString(namespace) {
+		return fmt.Errorf("'%s' is not a valid metric namespace", namespace)
+	}
+
+	buf := &bytes.Buffer{}
+	if sender, ok := s.(MetricsSender); ok {
+		writeBufferMetrics(buf, sender.BufferMetrics(), namespace)
+	}
+	if sender, ok := s.(InstrumentedSender); ok {
+		writeSendMetrics(buf, sender.SendMetrics(), s.Name(), namespace)
+	}
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+func writeBufferMetrics(buf *bytes.Buffer, m BufferMetrics, namespace string) {
+	fmt.Fprintf(buf, "# HELP %s_queue_depth Messages buffered and not yet delivered.\n", namespace)
+	fmt.Fprintf(buf, "# TYPE %s_queue_depth gauge\n", namespace)
+	fmt.Fprintf(buf, "%s_queue_depth %d\n", namespace, m.QueueDepth)
+	fmt.Fprintf(buf, "# HELP %s_enqueued_total Messages added to the buffer.\n", namespace)
+	fmt.Fprintf(buf, "# TYPE %s_enqueued_total counter\n", namespace)
+	fmt.Fprintf(buf, "%s_enqueued_total %d\n", namespace, m.Enqueued)
+	fmt.Fprintf(buf, "# HELP %s_dropped_total Messages discarded without delivery.\n", namespace)
+	fmt.Fprintf(buf, "# TYPE %s_dropped_total counter\n", namespace)
+	fmt.Fprintf(buf, "%s_dropped_total %d\n", namespace, m.Dropped)
+	fmt.Fprintf(buf, "# HELP %s_flush_duration_seconds Time spent flushing the sender.\n", namespace)
+	fmt.Fprintf(buf, "# TYPE %s_flush_duration_seconds summary\n", namespace)
+	fmt.Fprintf(buf, "%s_flush_duration_seconds_sum %g\n", namespace, m.FlushTime.Seconds())
+	fmt.Fprintf(buf, "%s_flush_duration_seconds_count %d\n", namespace, m.Flushes)
+}
+
+// writeSendMetrics writes the series of every priority that the
+// sender has processed messages at, in order of the names of the
+// priorities.
+func writeSendMetrics(buf *bytes.Buffer, m SendMetrics, name, namespace string) {
+	levels := make([]string, 0, len(m.Levels))
+	for l := range m.Levels {
+		levels = append(levels, l)
+	}
+	sort.Strings(levels)
+
+	labels := func(l string) string {
+		return fmt.Sprintf(`sender="%s",level="%s"`, escapeLabelValue(name), escapeLabelValue(l))
+	}
+
+	counters := []struct {
+		name, help string
+		value      func(LevelSendMetrics) int64
+	}{
+		{"messages_sent_total", "Messages passed to the sender.", func(l LevelSendMetrics) int64 { return l.Sent }},
+		{"messages_dropped_total", "Messages below the threshold of the sender.", func(l LevelSendMetrics) int64 { return l.Dropped }},
+		{"send_errors_total", "Errors reported by the sender.", func(l LevelSendMetrics) int64 { return l.Errors }},
+	}
+	for _, counter := range counters {
+		fmt.Fprintf(buf, "# HELP %s_%s %s\n", namespace, counter.name, counter.help)
+		fmt.Fprintf(buf, "# TYPE %s_%s counter\n", namespace, counter.name)
+		for _, l := range levels {
+			fmt.Fprintf(buf, "%s_%s{%s} %d\n", namespace, counter.name, labels(l), counter.value(m.Levels[l]))
+		}
+	}
+
+	fmt.Fprintf(buf, "# HELP %s_send_duration_seconds Time spent sending messages.\n", namespace)
+	fmt.Fprintf(buf, "# TYPE %s_send_duration_seconds histogram\n", namespace)
+	for _, l := range levels {
+		metrics := m.Levels[l]
+		for idx, bound := range m.Buckets {
+			if idx < len(metrics.Latency) {
+				fmt.Fprintf(buf, "%s_send_duration_seconds_bucket{%s,le=\"%g\"} %d\n", namespace, labels(l), bound.Seconds(), metrics.Latency[idx])
+			}
+		}
+		fmt.Fprintf(buf, "%s_send_duration_seconds_bucket{%s,le=\"+Inf\"} %d\n", namespace, labels(l), metrics.Sent)
+		fmt.Fprintf(buf, "%s_send_duration_seconds_sum{%s} %g\n", namespace, labels(l), metrics.LatencySum.Seconds())
+		fmt.Fprintf(buf, "%s_send_duration_seconds_count{%s} %d\n", namespace, labels(l), metrics.Sent)
+	}
+}
+
+// escapeLabelValue escapes the backslashes, double quotes, and line
+// feeds of a label value, as the text exposition format requires.
+func escapeLabelValue(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value)
+}
+
+// MetricsHandler returns an http.Handler that serves the metrics of
+// every sender registered with RegisterMetrics in the Prometheus text
+// exposition format, so that Prometheus can scrape the metrics
+// without a dependency on the Prometheus client library.
+func MetricsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		registeredMetricsMutex.RLock()
+		namespaces := make([]string, 0, len(registeredMetrics))
+		for ns := range registeredMetrics {
+			namespaces = append(namespaces, ns)
+		}
+		sort.Strings(namespaces)
+
+		// registered senders and namespaces are valid, so writing
+		// to the buffer cannot fail.
+		buf := &bytes.Buffer{}
+		for _, ns := range namespaces {
+			_ = WriteMetrics(buf, registeredMetrics[ns], ns)
+		}
+		registeredMetricsMutex.RUnlock()
+
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		_, _ = w.Write(buf.Bytes())
+	})
+}
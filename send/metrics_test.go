@@ -0,0 +1,261 @@
+package send
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"expvar"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mongodb/grip/level"
+	"github.com/mongodb/grip/message"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChannelSenderBufferMetrics(t *testing.T) {
+	assert := assert.New(t)
+
+	s, output := NewChannelSender("channel", LevelInfo{level.Info, level.Info}, 2)
+	defer s.Close()
+
+	for i := 0; i < 3; i++ {
+		s.Send(message.NewDefaultMessage(level.Info, "hello"))
+	}
+
+	m := s.(MetricsSender).BufferMetrics()
+	assert.Equal(int64(2), m.QueueDepth)
+	assert.Equal(int64(2), m.Enqueued)
+	assert.Equal(int64(1), m.Dropped)
+	assert.Equal(int64(0), m.Flushes)
+
+	<-output
+	<-output
+	require.NoError(t, s.Flush(context.Background()))
+
+	m = s.(MetricsSender).BufferMetrics()
+	assert.Equal(int64(0), m.QueueDepth)
+	assert.Equal(int64(2), m.Enqueued)
+	assert.Equal(int64(1), m.Flushes)
+}
+
+func TestSpoolingSenderBufferMetrics(t *testing.T) {
+	assert := assert.New(t)
+	dir := newSpoolDir(t)
+	defer os.RemoveAll(dir)
+
+	underlying := newFlakySender(t)
+	underlying.setFailing(true)
+
+	s, err := NewSpoolingSender(underlying, dir, SpoolOptions{ProbeInterval: 10 * time.Millisecond})
+	require.NoError(t, err)
+	defer s.Close()
+
+	for i := 0; i < 3; i++ {
+		s.Send(message.NewDefaultMessage(level.Info, "spooled"))
+	}
+
+	m := s.(MetricsSender).BufferMetrics()
+	assert.Equal(int64(3), m.QueueDepth)
+	assert.Equal(int64(3), m.Enqueued)
+
+	underlying.setFailing(false)
+	underlying.waitForMessages(t, 3)
+	require.NoError(t, s.Flush(context.Background()))
+
+	m = s.(MetricsSender).BufferMetrics()
+	assert.Equal(int64(0), m.QueueDepth)
+	assert.Equal(int64(3), m.Enqueued)
+	assert.Equal(int64(0), m.Dropped)
+	assert.Equal(int64(1), m.Flushes)
+}
+
+func TestBufferedSenderBufferMetrics(t *testing.T) {
+	assert := assert.New(t)
+
+	out := &bytes.Buffer{}
+	underlying, err := NewStreamLogger("buffered", out, LevelInfo{level.Info, level.Info})
+	require.NoError(t, err)
+	require.NoError(t, underlying.SetErrorHandler(func(error, message.Composer) {}))
+
+	s, err := NewBufferedSenderWithOptions(underlying, BufferedSenderOptions{FlushInterval: time.Hour, BufferSize: 3})
+	require.NoError(t, err)
+
+	for i := 0; i < 4; i++ {
+		s.Send(message.NewDefaultMessage(level.Info, "buffered"))
+	}
+	s.Send(message.NewDefaultMessage(level.Debug, "below threshold"))
+
+	m := s.(MetricsSender).BufferMetrics()
+	assert.Equal(int64(1), m.QueueDepth)
+	assert.Equal(int64(4), m.Enqueued)
+	assert.Equal(int64(0), m.Flushes)
+
+	require.NoError(t, s.Flush(context.Background()))
+	require.NoError(t, s.Close())
+	s.Send(message.NewDefaultMessage(level.Info, "closed"))
+
+	m = s.(MetricsSender).BufferMetrics()
+	assert.Equal(int64(0), m.QueueDepth)
+	assert.Equal(int64(4), m.Enqueued)
+	assert.Equal(int64(1), m.Dropped)
+	assert.Equal(int64(1), m.Flushes)
+	assert.True(m.FlushTime > 0)
+	assert.Equal(4, strings.Count(out.String(), "buffered"))
+}
+
+func TestRegisterMetrics(t *testing.T) {
+	assert := assert.New(t)
+
+	s, _ := NewChannelSender("channel", LevelInfo{level.Info, level.Info}, 10)
+	defer s.Close()
+	s.Send(message.NewDefaultMessage(level.Info, "hello"))
+
+	native, err := NewNativeLogger("native", LevelInfo{level.Info, level.Info})
+	require.NoError(t, err)
+	assert.Error(RegisterMetrics(native, "grip_native"))
+	assert.Error(RegisterMetrics(s, "grip channel"))
+
+	require.NoError(t, RegisterMetrics(s, "grip_test_channel"))
+	assert.Error(RegisterMetrics(s, "grip_test_channel"))
+
+	published := BufferMetrics{}
+	require.NoError(t, json.Unmarshal([]byte(expvar.Get("grip_test_channel").String()), &published))
+	assert.Equal(int64(1), published.QueueDepth)
+	assert.Equal(int64(1), published.Enqueued)
+
+	rec := httptest.NewRecorder()
+	MetricsHandler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	assert.Equal(200, rec.Code)
+	assert.Contains(rec.Body.String(), "# TYPE grip_test_channel_queue_depth gauge\ngrip_test_channel_queue_depth 1\n")
+	assert.Contains(rec.Body.String(), "grip_test_channel_enqueued_total 1\n")
+	assert.Contains(rec.Body.String(), "grip_test_channel_dropped_total 0\n")
+	assert.Contains(rec.Body.String(), "grip_test_channel_flush_duration_seconds_count 0\n")
+}
+
+func TestWriteMetrics(t *testing.T) {
+	assert := assert.New(t)
+
+	s, _ := NewChannelSender("channel", LevelInfo{level.Info, level.Info}, 10)
+	defer s.Close()
+
+	buf := &bytes.Buffer{}
+	require.NoError(t, WriteMetrics(buf, s, "app"))
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if !strings.HasPrefix(line, "#") {
+			assert.True(strings.HasPrefix(line, "app_"), line)
+		}
+	}
+
+	assert.Error(WriteMetrics(buf, s, "0app"))
+}
+
+func TestInstrumentedSenderCountsMessages(t *testing.T) {
+	assert := assert.New(t)
+
+	out := &bytes.Buffer{}
+	inner, err := NewStreamLogger("inner", out, LevelInfo{level.Info, level.Warning})
+	require.NoError(t, err)
+	require.NoError(t, inner.SetErrorHandler(func(error, message.Composer) {}))
+
+	_, err = NewInstrumented(nil)
+	assert.Error(err)
+	_, err = NewInstrumented(MakeInternalLogger())
+	assert.Error(err, "the sender must support multiple error handlers")
+
+	s, err := NewInstrumented(inner)
+	require.NoError(t, err)
+	assert.Equal("inner", s.Name())
+
+	s.Send(message.NewDefaultMessage(level.Info, "below threshold"))
+	s.Send(message.NewDefaultMessage(level.Debug, "below threshold"))
+	s.Send(message.NewDefaultMessage(level.Error, ""))
+	s.Send(message.NewDefaultMessage(level.Warning, "sent"))
+	s.Send(message.NewDefaultMessage(level.Error, "sent"))
+	s.Send(message.NewDefaultMessage(level.Error, "sent"))
+	assert.Equal(3, strings.Count(out.String(), "sent"), "below threshold messages are not sent")
+
+	// errors that the underlying sender reports count once.
+	inner.(*streamLogger).ErrorHandler(errors.New("failed"), message.NewDefaultMessage(level.Error, "failed"))
+
+	metrics := s.(InstrumentedSender).SendMetrics()
+	require.Len(t, metrics.Levels, 4)
+	assert.Equal(LevelSendMetrics{Dropped: 1, Latency: []int64{}}, withoutLatency(metrics.Levels["info"]))
+	assert.Equal(LevelSendMetrics{Dropped: 1, Latency: []int64{}}, withoutLatency(metrics.Levels["debug"]))
+	assert.Equal(LevelSendMetrics{Sent: 1, Latency: []int64{}}, withoutLatency(metrics.Levels["warning"]))
+	assert.Equal(LevelSendMetrics{Sent: 2, Dropped: 1, Errors: 1, Latency: []int64{}}, withoutLatency(metrics.Levels["error"]))
+
+	errorLatency := metrics.Levels["error"].Latency
+	require.Len(t, errorLatency, len(metrics.Buckets))
+	assert.Equal(int64(2), errorLatency[len(errorLatency)-1], "sends take less than the largest bucket")
+	for idx := 1; idx < len(errorLatency); idx++ {
+		assert.True(errorLatency[idx] >= errorLatency[idx-1], "buckets are cumulative")
+	}
+
+	// replacing the error handlers of the instrumented sender keeps
+	// the counting of errors.
+	errs := &errorCollector{}
+	require.NoError(t, s.SetErrorHandler(errs.handler))
+	require.NoError(t, AddErrorHandler(s, func(string, error, message.Composer) {}))
+	inner.(*streamLogger).ErrorHandler(errors.New("failed"), message.NewDefaultMessage(level.Error, "failed"))
+	assert.Len(errs.get(), 1)
+	assert.Equal(int64(2), s.(InstrumentedSender).SendMetrics().Levels["error"].Errors)
+
+	// the metrics are snapshots.
+	metrics.Levels["error"].Latency[0] = 100
+	assert.NotEqual(int64(100), s.(InstrumentedSender).SendMetrics().Levels["error"].Latency[0])
+}
+
+// withoutLatency returns the counters of the metrics.
+func withoutLatency(m LevelSendMetrics) LevelSendMetrics {
+	m.Latency = []int64{}
+	m.LatencySum = 0
+	return m
+}
+
+func TestWriteInstrumentedMetrics(t *testing.T) {
+	assert := assert.New(t)
+
+	inner, err := NewStreamLogger("inner", &bytes.Buffer{}, LevelInfo{level.Info, level.Info})
+	require.NoError(t, err)
+	s, err := NewInstrumented(inner)
+	require.NoError(t, err)
+	s.SetName(`app "one"`)
+
+	s.Send(message.NewDefaultMessage(level.Debug, "dropped"))
+	s.Send(message.NewDefaultMessage(level.Info, "sent"))
+	s.Send(message.NewDefaultMessage(level.Info, "sent"))
+
+	buf := &bytes.Buffer{}
+	require.NoError(t, WriteMetrics(buf, s, "grip"))
+	out := buf.String()
+
+	assert.Contains(out, "# TYPE grip_messages_sent_total counter\n")
+	assert.Contains(out, "grip_messages_sent_total{sender=\"app \\\"one\\\"\",level=\"info\"} 2\n")
+	assert.Contains(out, "grip_messages_sent_total{sender=\"app \\\"one\\\"\",level=\"debug\"} 0\n")
+	assert.Contains(out, "grip_messages_dropped_total{sender=\"app \\\"one\\\"\",level=\"debug\"} 1\n")
+	assert.Contains(out, "grip_send_errors_total{sender=\"app \\\"one\\\"\",level=\"info\"} 0\n")
+	assert.Contains(out, "# TYPE grip_send_duration_seconds histogram\n")
+	assert.Contains(out, "grip_send_duration_seconds_bucket{sender=\"app \\\"one\\\"\",level=\"info\",le=\"5\"} 2\n")
+	assert.Contains(out, "grip_send_duration_seconds_bucket{sender=\"app \\\"one\\\"\",level=\"info\",le=\"+Inf\"} 2\n")
+	assert.Contains(out, "grip_send_duration_seconds_count{sender=\"app \\\"one\\\"\",level=\"info\"} 2\n")
+	assert.NotContains(out, "queue_depth", "the sender does not buffer messages")
+
+	// every family has a single type line.
+	assert.Equal(1, strings.Count(out, "# TYPE grip_send_duration_seconds "))
+
+	require.NoError(t, RegisterMetrics(s, "grip_test_instrumented"))
+	published := SendMetrics{}
+	require.NoError(t, json.Unmarshal([]byte(expvar.Get("grip_test_instrumented").String()), &published))
+	assert.Equal(int64(2), published.Levels["info"].Sent)
+	assert.Equal(int64(1), published.Levels["debug"].Dropped)
+
+	rec := httptest.NewRecorder()
+	MetricsHandler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	assert.Contains(rec.Body.String(), "grip_test_instrumented_messages_sent_total{sender=\"app \\\"one\\\"\",level=\"info\"} 2\n")
+}
@@ -1,19 +1,295 @@
 package send
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/mongodb/grip/level"
 	"github.com/mongodb/grip/message"
 )
 
+// RouteToAnnotation is the annotation that routes a message that a
+// multi sender sends to a single member Sender: the value of the
+// annotation is the name of the member, as it was before the member
+// was added to the multi sender.
+const RouteToAnnotation = "_route_to"
+
 type multiSender struct {
 	senders []Sender
+	names   []string
+	opts    MultiSenderOptions
+	pending pendingSends
+	errors  memberErrors
+	sends   sendErrors
 	*Base
 }
 
+// sendErrors collects the errors that member Senders report while the
+// multi sender sends a message to them, so that Send reports the
+// failures of its members in a single error.
+type sendErrors struct {
+	sends []*sendErrorList
+	mutex sync.Mutex
+}
+
+type sendErrorList struct {
+	m    message.Composer
+	errs []string
+}
+
+func (e *sendErrors) start(m message.Composer) *sendErrorList {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	list := &sendErrorList{m: m}
+	e.sends = append(e.sends, list)
+
+	return list
+}
+
+// add adds the error to the errors of a Send of the message, and
+// reports whether there is such a Send in progress.
+func (e *sendErrors) add(m message.Composer, err error) bool {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	for _, list := range e.sends {
+		if sameMessage(list.m, m) {
+			list.errs = append(list.errs, err.Error())
+			return true
+		}
+	}
+
+	return false
+}
+
+// finish stops collecting errors for the Send, and returns the
+// errors that the members reported, combined, if there are any.
+func (e *sendErrors) finish(list *sendErrorList) error {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	for idx, l := range e.sends {
+		if l == list {
+			e.sends = append(e.sends[:idx:idx], e.sends[idx+1:]...)
+			break
+		}
+	}
+
+	if len(list.errs) == 0 {
+		return nil
+	}
+
+	return errors.New(strings.Join(list.errs, "; "))
+}
+
+// sameMessage reports whether the composers are the same message.
+// Comparing composers of types that are not comparable, such as those
+// with map fields, panics, and they are never the same message.
+func sameMessage(a, b message.Composer) (same bool) {
+	defer func() {
+		if recover() != nil {
+			same = false
+		}
+	}()
+
+	return a == b
+}
+
+// memberErrors records the most recent error of each member Sender,
+// by the name of the member.
+type memberErrors struct {
+	errs  map[string]error
+	mutex sync.Mutex
+}
+
+func (e *memberErrors) record(name string, err error) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	if e.errs == nil {
+		e.errs = map[string]error{}
+	}
+	e.errs[name] = err
+}
+
+func (e *memberErrors) forget(name string) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	delete(e.errs, name)
+}
+
+func (e *memberErrors) snapshot() map[string]error {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	out := make(map[string]error, len(e.errs))
+	for name, err := range e.errs {
+		out[name] = err
+	}
+
+	return out
+}
+
+// pendingSends tracks the sends to member Senders that continue in
+// the background, so that Flush can wait for them.
+type pendingSends struct {
+	count int
+	idle  chan struct{}
+	mutex sync.Mutex
+}
+
+func (p *pendingSends) add() {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if p.count == 0 {
+		p.idle = make(chan struct{})
+	}
+	p.count++
+}
+
+func (p *pendingSends) done() {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.count--
+	if p.count == 0 {
+		close(p.idle)
+	}
+}
+
+func (p *pendingSends) wait(ctx context.Context) error {
+	p.mutex.Lock()
+	if p.count == 0 {
+		p.mutex.Unlock()
+		return nil
+	}
+	idle := p.idle
+	p.mutex.Unlock()
+
+	select {
+	case <-idle:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// MultiSenderOptions configures how a multi sender dispatches
+// messages to its member Senders. By default, multi senders send
+// each message to each member Sender in turn, so the latency of Send
+// is the sum of the latency of every member. In either mode, a member
+// that panics does not prevent the other members from receiving the
+// message, and Send reports the errors that members encounter while
+// it sends a message to the multi sender's error handler, once, in a
+// single error.
+//
+// With Parallel set, Send dispatches the message to the member
+// Senders concurrently, using at most Workers goroutines (all
+// members, at once, when Workers is 0), and waits for the members to
+// finish, or for the Timeout (if set) to elapse for each member. Send
+// does not wait for the members listed in FireAndForget. Timeouts and
+// panics in member Senders are reported to the multi sender's error
+// handler, and identify the member by its position and name.
+//
+// Duplicates controls how AddToMulti handles a Sender with the same
+// name as an existing member. Names are compared as they were before
+// the Sender was added, because multi senders rename their members.
+type MultiSenderOptions struct {
+	Parallel      bool
+	Workers       int
+	Timeout       time.Duration
+	FireAndForget []Sender
+	Duplicates    DuplicatePolicy
+}
+
+// DuplicatePolicy controls how a multi sender handles the addition of
+// a Sender with the same name as one of its members.
+type DuplicatePolicy int
+
+const (
+	// AllowDuplicates adds the Sender, and is the default.
+	AllowDuplicates DuplicatePolicy = iota
+
+	// RejectDuplicates returns an error rather than adding the
+	// Sender.
+	RejectDuplicates
+
+	// IgnoreDuplicates does not add the Sender, and does not
+	// return an error.
+	IgnoreDuplicates
+)
+
+// Validate checks the options and returns an error for impossible
+// values.
+func (o *MultiSenderOptions) Validate() error {
+	errs := []string{}
+	if o.Workers < 0 {
+		errs = append(errs, "workers must not be negative")
+	}
+
+	if o.Timeout < 0 {
+		errs = append(errs, "timeout must not be negative")
+	}
+
+	if o.Duplicates < AllowDuplicates || o.Duplicates > IgnoreDuplicates {
+		errs = append(errs, "invalid duplicate policy")
+	}
+
+	if !o.Parallel && (len(o.FireAndForget) > 0 || o.Timeout > 0) {
+		errs = append(errs, "timeouts and fire and forget senders require parallel dispatch")
+	}
+
+	if len(errs) > 0 {
+		return errors.New(strings.Join(errs, "; "))
+	}
+
+	return nil
+}
+
+func (o MultiSenderOptions) isFireAndForget(s Sender) bool {
+	for _, sender := range o.FireAndForget {
+		if sender == s {
+			return true
+		}
+	}
+
+	return false
+}
+
+func makeMultiSender(name string, senders []Sender, names []string) *multiSender {
+	s := &multiSender{senders: senders, names: names, Base: NewBase(name)}
+	s.closer = s.closeSenders
+
+	for _, sender := range senders {
+		s.watch(sender)
+	}
+
+	return s
+}
+
+// NewMulti returns a multi sender with the name, which sends every
+// message to each of the member Senders. Unlike NewMultiSender, the
+// members keep their own names and levels, so that each member
+// decides which messages to log. A member that panics does not
+// prevent the other members from receiving the message, and the
+// errors that members report while the multi sender sends a message
+// are combined, and reported to the multi sender's error handler in a
+// single call. Close closes every member, and returns their errors,
+// combined.
+func NewMulti(name string, senders ...Sender) Sender {
+	s := NewConfiguredMultiSender(senders...)
+	s.(*multiSender).Base.SetName(name)
+
+	return s
+}
+
 // NewMultiSender configures a new sender implementation that takes a
 // slice of Sender implementations that dispatches all messages to all
 // implementations. This constructor forces all member Senders to have
@@ -31,12 +307,17 @@ func NewMultiSender(name string, l LevelInfo, senders []Sender) (Sender, error)
 		return nil, errors.New("must specify at least one sender when creating a multi sender")
 	}
 
+	names := make([]string, 0, len(senders))
 	for _, sender := range senders {
+		names = append(names, sender.Name())
 		sender.SetName(name)
 		_ = sender.SetLevel(l)
 	}
 
-	return &multiSender{senders: senders, Base: NewBase(name)}, nil
+	s := makeMultiSender(name, senders, names)
+	_ = s.Base.SetLevel(l)
+
+	return s, nil
 }
 
 // NewConfiguredMultiSender returns a multi sender implementation with
@@ -47,7 +328,12 @@ func NewMultiSender(name string, l LevelInfo, senders []Sender) (Sender, error)
 // Use the AddToMulti helper to add additioanl senders to one of these
 // multi Sender implementations after construction.
 func NewConfiguredMultiSender(senders ...Sender) Sender {
-	s := &multiSender{senders: senders, Base: NewBase("")}
+	names := make([]string, 0, len(senders))
+	for _, sender := range senders {
+		names = append(names, sender.Name())
+	}
+
+	s := makeMultiSender("", senders, names)
 	_ = s.Base.SetLevel(LevelInfo{Default: level.Invalid, Threshold: level.Invalid})
 
 	return s
@@ -58,7 +344,9 @@ func NewConfiguredMultiSender(senders ...Sender) Sender {
 // AddToMulti adds the second Sender to the first Sender's list of
 // Senders.
 //
-// Returns an error if the first instance is not a multi sender.
+// Returns an error if the first instance is not a multi sender, or
+// if the second has the same name as a member and the multi sender
+// rejects duplicates (see MultiSenderOptions).
 func AddToMulti(multi Sender, s Sender) error {
 	sender, ok := multi.(*multiSender)
 	if !ok {
@@ -68,9 +356,71 @@ func AddToMulti(multi Sender, s Sender) error {
 	return sender.add(s)
 }
 
-func (s *multiSender) Close() error {
+// RemoveFromMulti removes the member Senders with the name, as it was
+// before they were added, from a multi sender, and restores their
+// names. The multi sender does not close the removed Senders, and
+// stops reporting their errors. Returns an error if the Sender is not
+// a multi sender, or if it has no member with the name.
+func RemoveFromMulti(multi Sender, name string) error {
+	sender, ok := multi.(*multiSender)
+	if !ok {
+		return fmt.Errorf("%s is not a multi sender", multi.Name())
+	}
+
+	return sender.remove(name)
+}
+
+// MultiSenderLastErrors returns the most recent error of each member
+// Sender of a multi sender that has encountered an error, by the name
+// of the member as it was before it was added. Members report their
+// errors to the multi sender's error handler as well, with an error
+// that identifies the member by its position and name, which Send
+// combines with the errors of the other members. Returns an error if
+// the Sender is not a multi sender.
+func MultiSenderLastErrors(multi Sender) (map[string]error, error) {
+	sender, ok := multi.(*multiSender)
+	if !ok {
+		return nil, fmt.Errorf("%s is not a multi sender", multi.Name())
+	}
+
+	return sender.errors.snapshot(), nil
+}
+
+// MultiSenderMembers returns the member Senders of a multi sender.
+// Returns an error if the Sender is not a multi sender.
+func MultiSenderMembers(multi Sender) ([]Sender, error) {
+	sender, ok := multi.(*multiSender)
+	if !ok {
+		return nil, fmt.Errorf("%s is not a multi sender", multi.Name())
+	}
+
+	return sender.Senders(), nil
+}
+
+// SetMultiSenderOptions configures the dispatch of messages for a
+// multi sender. Returns an error if the Sender is not a multi sender,
+// or if the options are not valid.
+func SetMultiSenderOptions(multi Sender, opts MultiSenderOptions) error {
+	sender, ok := multi.(*multiSender)
+	if !ok {
+		return fmt.Errorf("%s is not a multi sender", multi.Name())
+	}
+
+	if err := opts.Validate(); err != nil {
+		return err
+	}
+
+	sender.mutex.Lock()
+	defer sender.mutex.Unlock()
+	sender.opts = opts
+
+	return nil
+}
+
+// closeSenders closes all member Senders.
+func (s *multiSender) closeSenders() error {
 	errs := []string{}
-	for _, sender := range s.senders {
+	for _, sender := range s.Senders() {
 		if err := sender.Close(); err != nil {
 			errs = append(errs, err.Error())
 		}
@@ -82,22 +432,167 @@ func (s *multiSender) Close() error {
 	return nil
 }
 
+// Flush waits for sends to member Senders that continue in the
+// background (e.g. fire and forget members, and members that timed
+// out) to complete, and then flushes all member Senders.
+func (s *multiSender) Flush(ctx context.Context) error {
+	if err := s.pending.wait(ctx); err != nil {
+		return err
+	}
+
+	errs := []string{}
+	for idx, sender := range s.Senders() {
+		if err := sender.Flush(ctx); err != nil {
+			errs = append(errs, fmt.Sprintf("sender %d (%s): %s", idx, sender.Name(), err.Error()))
+		}
+	}
+	if len(errs) > 0 {
+		return errors.New(strings.Join(errs, "\n"))
+	}
+
+	return nil
+}
+
 func (s *multiSender) add(sender Sender) error {
-	sender.SetName(s.Base.Name())
+	name := sender.Name()
+	multiName := s.Base.Name()
+	multiLevel := s.Base.Level()
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.opts.Duplicates != AllowDuplicates {
+		for _, existing := range s.names {
+			if existing != name {
+				continue
+			}
+
+			if s.opts.Duplicates == IgnoreDuplicates {
+				return nil
+			}
+
+			return fmt.Errorf("multi sender %s already has a sender named '%s'", multiName, name)
+		}
+	}
+
+	sender.SetName(multiName)
 
 	// ignore the error here; if the Base value on the multiSender
 	// is not set, then senders should just have their own level values.
-	_ = sender.SetLevel(s.Base.Level())
+	_ = sender.SetLevel(multiLevel)
 
 	s.senders = append(s.senders, sender)
+	s.names = append(s.names, name)
+	s.watch(sender)
+	return nil
+}
+
+// remove removes the members with the name, which is the name of the
+// member before it was added, and restores their names.
+func (s *multiSender) remove(name string) error {
+	multiName := s.Base.Name()
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	// build new slices, rather than modifying the existing ones,
+	// because Send uses them without holding the mutex.
+	senders := make([]Sender, 0, len(s.senders))
+	names := make([]string, 0, len(s.names))
+	removed := []Sender{}
+	for idx, sender := range s.senders {
+		if s.names[idx] == name {
+			removed = append(removed, sender)
+			continue
+		}
+		senders = append(senders, sender)
+		names = append(names, s.names[idx])
+	}
+
+	if len(removed) == 0 {
+		return fmt.Errorf("multi sender %s has no sender named '%s'", multiName, name)
+	}
+
+	s.senders = senders
+	s.names = names
+	s.errors.forget(name)
+
+	for _, sender := range removed {
+		sender.SetName(name)
+	}
+
 	return nil
 }
 
+// watch adds an error handler to the member Sender that records its
+// errors, and reports them to the multi sender's error handler. The
+// handler ignores the errors of Senders that are no longer members.
+// Members that do not support multiple error handlers only report
+// panics and timeouts to the multi sender.
+func (s *multiSender) watch(sender Sender) {
+	_ = AddErrorHandler(sender, func(_ string, err error, m message.Composer) {
+		idx, name, ok := s.member(sender)
+		if !ok {
+			return
+		}
+
+		s.errors.record(name, err)
+		s.report(fmt.Errorf("sender %d (%s): %s", idx, name, err.Error()), m)
+	})
+}
+
+// report adds the error of a member to the errors of the Send of the
+// message, or, if the member reported the error after Send returned,
+// reports it to the multi sender's error handler.
+func (s *multiSender) report(err error, m message.Composer) {
+	if !s.sends.add(m, err) {
+		s.ErrorHandler(err, m)
+	}
+}
+
+// member returns the position and name of the member Sender, and
+// whether the Sender is a member.
+func (s *multiSender) member(sender Sender) (int, string, bool) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	for idx, member := range s.senders {
+		if member == sender {
+			return idx, s.names[idx], true
+		}
+	}
+
+	return 0, "", false
+}
+
+// reportMemberError records an error, such as a panic or a timeout,
+// that the multi sender encountered sending to a member, and reports
+// it to the multi sender's error handler.
+func (s *multiSender) reportMemberError(sender Sender, err error, m message.Composer) {
+	if err == nil {
+		return
+	}
+
+	if _, name, ok := s.member(sender); ok {
+		s.errors.record(name, err)
+	}
+
+	s.report(err, m)
+}
+
+// Senders returns a copy of the list of member Senders.
+func (s *multiSender) Senders() []Sender {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	return append([]Sender{}, s.senders...)
+}
+
 func (s *multiSender) Name() string { return s.Base.Name() }
 func (s *multiSender) SetName(n string) {
 	s.Base.SetName(n)
 
-	for _, sender := range s.senders {
+	for _, sender := range s.Senders() {
 		sender.SetName(n)
 	}
 }
@@ -108,7 +603,7 @@ func (s *multiSender) SetLevel(l LevelInfo) error {
 		return err
 	}
 
-	for _, sender := range s.senders {
+	for _, sender := range s.Senders() {
 		_ = sender.SetLevel(l)
 	}
 
@@ -123,7 +618,121 @@ func (s *multiSender) Send(m message.Composer) {
 		return
 	}
 
-	for _, sender := range s.senders {
-		sender.Send(m)
+	if s.reportClosed(m) {
+		return
+	}
+
+	s.mutex.RLock()
+	opts := s.opts
+	senders := s.senders
+	names := s.names
+	s.mutex.RUnlock()
+
+	sendErrs := s.sends.start(m)
+	defer func() { s.ErrorHandler(s.sends.finish(sendErrs), m) }()
+
+	if idx, ok := s.route(m, names); ok {
+		s.sendToMember(idx, senders[idx], m, opts.Timeout)
+		return
+	}
+
+	// a member that panics does not prevent the members that
+	// follow it from receiving the message.
+	if !opts.Parallel {
+		for idx, sender := range senders {
+			s.sendToMember(idx, sender, m, 0)
+		}
+		return
 	}
+
+	workers := opts.Workers
+	if workers == 0 || workers > len(senders) {
+		workers = len(senders)
+	}
+
+	pool := make(chan struct{}, workers)
+	wg := &sync.WaitGroup{}
+	for idx, sender := range senders {
+		if opts.isFireAndForget(sender) {
+			s.pending.add()
+			go func(idx int, sender Sender) {
+				defer s.pending.done()
+				s.sendToMember(idx, sender, m, opts.Timeout)
+			}(idx, sender)
+			continue
+		}
+
+		pool <- struct{}{}
+		wg.Add(1)
+		go func(idx int, sender Sender) {
+			defer func() { <-pool; wg.Done() }()
+			s.sendToMember(idx, sender, m, opts.Timeout)
+		}(idx, sender)
+	}
+	wg.Wait()
+}
+
+// route returns the position of the member Sender that the message's
+// RouteToAnnotation names, if it has one. Messages that name a Sender
+// that is not a member are reported to the multi sender's error
+// handler, and sent to all members.
+func (s *multiSender) route(m message.Composer, names []string) (int, bool) {
+	annotated, ok := m.(message.Annotated)
+	if !ok {
+		return 0, false
+	}
+
+	target, ok := annotated.Annotation(RouteToAnnotation)
+	if !ok {
+		return 0, false
+	}
+
+	for idx, name := range names {
+		if name == target {
+			return idx, true
+		}
+	}
+
+	s.ErrorHandler(fmt.Errorf("multi sender %s has no sender named '%v', sending to all senders", s.Base.Name(), target), m)
+	return 0, false
+}
+
+// sendToMember sends the message to a single member Sender, and
+// reports panics and, when the timeout is non-zero, timeouts to the
+// multi sender's error handler. If the member times out, sendToMember
+// returns, but the member's Send continues in the background.
+func (s *multiSender) sendToMember(idx int, sender Sender, m message.Composer, timeout time.Duration) {
+	if timeout == 0 {
+		s.reportMemberError(sender, safeSend(idx, sender, m), m)
+		return
+	}
+
+	errs := make(chan error, 1)
+	s.pending.add()
+	go func() {
+		defer s.pending.done()
+		errs <- safeSend(idx, sender, m)
+	}()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case err := <-errs:
+		s.reportMemberError(sender, err, m)
+	case <-timer.C:
+		s.reportMemberError(sender, fmt.Errorf("sender %d (%s) timed out after %s", idx, sender.Name(), timeout), m)
+	}
+}
+
+func safeSend(idx int, sender Sender, m message.Composer) (err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("sender %d (%s) panicked: %v", idx, sender.Name(), p)
+		}
+	}()
+
+	sender.Send(m)
+
+	return nil
 }
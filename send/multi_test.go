@@ -0,0 +1,628 @@
+package send
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mongodb/grip/level"
+	"github.com/mongodb/grip/message"
+	"github.com/stretchr/testify/assert"
+)
+
+// slowSender is a sender that takes a fixed amount of time, and
+// optionally panics, when sending a message.
+type slowSender struct {
+	delay  time.Duration
+	panics bool
+	count  int64
+	*Base
+}
+
+func newSlowSender(delay time.Duration) *slowSender {
+	return &slowSender{delay: delay, Base: NewBase("slow")}
+}
+
+func (s *slowSender) sent() int64 { return atomic.LoadInt64(&s.count) }
+
+func (s *slowSender) Send(m message.Composer) {
+	if !s.Level().ShouldLog(m) {
+		return
+	}
+
+	time.Sleep(s.delay)
+	if s.panics {
+		panic("slow sender failed")
+	}
+
+	atomic.AddInt64(&s.count, 1)
+}
+
+type errorCollector struct {
+	errs  []error
+	mutex sync.Mutex
+}
+
+func (c *errorCollector) handler(err error, _ message.Composer) {
+	if err == nil {
+		return
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.errs = append(c.errs, err)
+}
+
+func (c *errorCollector) get() []error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.errs
+}
+
+func makeSlowMultiSender(t assert.TestingT, delays ...time.Duration) (Sender, []*slowSender) {
+	members := []*slowSender{}
+	senders := []Sender{}
+	for _, d := range delays {
+		member := newSlowSender(d)
+		members = append(members, member)
+		senders = append(senders, member)
+	}
+
+	multi, err := NewMultiSender("multi", LevelInfo{level.Info, level.Info}, senders)
+	assert.NoError(t, err)
+
+	return multi, members
+}
+
+func TestMultiSenderOptionsValidate(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.NoError((&MultiSenderOptions{}).Validate())
+	assert.NoError((&MultiSenderOptions{Parallel: true, Workers: 2, Timeout: time.Second}).Validate())
+	assert.Error((&MultiSenderOptions{Parallel: true, Workers: -1}).Validate())
+	assert.Error((&MultiSenderOptions{Parallel: true, Timeout: -time.Second}).Validate())
+	assert.Error((&MultiSenderOptions{Timeout: time.Second}).Validate())
+	assert.NoError((&MultiSenderOptions{Duplicates: IgnoreDuplicates}).Validate())
+	assert.Error((&MultiSenderOptions{Duplicates: DuplicatePolicy(-1)}).Validate())
+
+	sink, err := NewInternalLogger("sink", LevelInfo{level.Info, level.Info})
+	assert.NoError(err)
+	assert.Error(SetMultiSenderOptions(sink, MultiSenderOptions{Parallel: true}))
+}
+
+func TestMultiSenderDeduplicatesMembersByName(t *testing.T) {
+	assert := assert.New(t)
+
+	named := func(name string) Sender {
+		s := newSlowSender(0)
+		s.SetName(name)
+		return s
+	}
+
+	first, second := named("one"), named("two")
+	multi, err := NewMultiSender("multi", LevelInfo{level.Info, level.Info}, []Sender{first, second})
+	assert.NoError(err)
+
+	// duplicates are allowed by default.
+	duplicate := named("one")
+	assert.NoError(AddToMulti(multi, duplicate))
+	members, err := MultiSenderMembers(multi)
+	assert.NoError(err)
+	assert.Equal([]Sender{first, second, duplicate}, members)
+
+	assert.NoError(SetMultiSenderOptions(multi, MultiSenderOptions{Duplicates: RejectDuplicates}))
+	assert.Error(AddToMulti(multi, named("two")))
+	third := named("three")
+	assert.NoError(AddToMulti(multi, third))
+	members, err = MultiSenderMembers(multi)
+	assert.NoError(err)
+	assert.Equal([]Sender{first, second, duplicate, third}, members)
+
+	assert.NoError(SetMultiSenderOptions(multi, MultiSenderOptions{Duplicates: IgnoreDuplicates}))
+	assert.NoError(AddToMulti(multi, named("three")))
+	members, err = MultiSenderMembers(multi)
+	assert.NoError(err)
+	assert.Len(members, 4)
+
+	// modifying the returned list does not modify the members.
+	members[0] = nil
+	members, err = MultiSenderMembers(multi)
+	assert.NoError(err)
+	assert.Equal(first, members[0])
+
+	sink, err := NewInternalLogger("sink", LevelInfo{level.Info, level.Info})
+	assert.NoError(err)
+	_, err = MultiSenderMembers(sink)
+	assert.Error(err)
+}
+
+func TestMultiSenderRoutesAnnotatedMessages(t *testing.T) {
+	for _, parallel := range []bool{false, true} {
+		t.Run(fmt.Sprintf("Parallel=%t", parallel), func(t *testing.T) {
+			assert := assert.New(t)
+
+			named := func(name string) *slowSender {
+				s := newSlowSender(0)
+				s.SetName(name)
+				return s
+			}
+
+			audit, app := named("audit"), named("app")
+			multi, err := NewMultiSender("multi", LevelInfo{level.Info, level.Info}, []Sender{audit, app})
+			assert.NoError(err)
+			assert.NoError(SetMultiSenderOptions(multi, MultiSenderOptions{Parallel: parallel}))
+			errs := &errorCollector{}
+			assert.NoError(multi.SetErrorHandler(errs.handler))
+
+			multi.Send(message.NewDefaultMessage(level.Info, "everyone"))
+			assert.Equal(int64(1), audit.sent())
+			assert.Equal(int64(1), app.sent())
+
+			for _, m := range []message.Composer{
+				message.NewDefaultMessage(level.Info, "audit only"),
+				message.NewFields(level.Info, message.Fields{"msg": "audit only"}),
+			} {
+				assert.NoError(m.Annotate(RouteToAnnotation, "audit"))
+				multi.Send(m)
+			}
+			assert.Equal(int64(3), audit.sent())
+			assert.Equal(int64(1), app.sent())
+			assert.Empty(errs.get())
+
+			// unknown targets go to all members, and are reported.
+			m := message.NewDefaultMessage(level.Info, "nowhere")
+			assert.NoError(m.Annotate(RouteToAnnotation, "missing"))
+			multi.Send(m)
+			assert.Equal(int64(4), audit.sent())
+			assert.Equal(int64(2), app.sent())
+			if assert.Len(errs.get(), 1) {
+				assert.Contains(errs.get()[0].Error(), "no sender named 'missing'")
+			}
+		})
+	}
+}
+
+func TestMultiSenderParallelDispatch(t *testing.T) {
+	assert := assert.New(t)
+
+	multi, members := makeSlowMultiSender(t, 50*time.Millisecond, 50*time.Millisecond, 50*time.Millisecond)
+	assert.NoError(SetMultiSenderOptions(multi, MultiSenderOptions{Parallel: true}))
+
+	start := time.Now()
+	multi.Send(message.NewDefaultMessage(level.Info, "hello"))
+	assert.True(time.Since(start) < 140*time.Millisecond)
+
+	for _, member := range members {
+		assert.Equal(int64(1), member.sent())
+	}
+
+	// messages below the threshold are not dispatched
+	multi.Send(message.NewDefaultMessage(level.Debug, "hello"))
+	for _, member := range members {
+		assert.Equal(int64(1), member.sent())
+	}
+}
+
+func TestMultiSenderParallelDispatchWithBoundedWorkers(t *testing.T) {
+	assert := assert.New(t)
+
+	multi, members := makeSlowMultiSender(t, 20*time.Millisecond, 20*time.Millisecond, 20*time.Millisecond, 20*time.Millisecond)
+	assert.NoError(SetMultiSenderOptions(multi, MultiSenderOptions{Parallel: true, Workers: 2}))
+
+	start := time.Now()
+	multi.Send(message.NewDefaultMessage(level.Info, "hello"))
+	assert.True(time.Since(start) >= 40*time.Millisecond)
+
+	for _, member := range members {
+		assert.Equal(int64(1), member.sent())
+	}
+}
+
+func TestMultiSenderParallelDispatchTimeout(t *testing.T) {
+	assert := assert.New(t)
+
+	multi, members := makeSlowMultiSender(t, 0, time.Second, 0)
+	collector := &errorCollector{}
+	assert.NoError(multi.SetErrorHandler(collector.handler))
+	assert.NoError(SetMultiSenderOptions(multi, MultiSenderOptions{Parallel: true, Timeout: 20 * time.Millisecond}))
+
+	start := time.Now()
+	multi.Send(message.NewDefaultMessage(level.Info, "hello"))
+	assert.True(time.Since(start) < 500*time.Millisecond)
+
+	assert.Equal(int64(1), members[0].sent())
+	assert.Equal(int64(0), members[1].sent())
+	assert.Equal(int64(1), members[2].sent())
+
+	errs := collector.get()
+	if assert.Len(errs, 1) {
+		assert.True(strings.HasPrefix(errs[0].Error(), "sender 1 (multi) timed out"), errs[0].Error())
+	}
+}
+
+func TestMultiSenderParallelDispatchRecoversPanics(t *testing.T) {
+	assert := assert.New(t)
+
+	multi, members := makeSlowMultiSender(t, 0, 0)
+	members[0].panics = true
+	collector := &errorCollector{}
+	assert.NoError(multi.SetErrorHandler(collector.handler))
+	assert.NoError(SetMultiSenderOptions(multi, MultiSenderOptions{Parallel: true}))
+
+	assert.NotPanics(func() { multi.Send(message.NewDefaultMessage(level.Info, "hello")) })
+	assert.Equal(int64(0), members[0].sent())
+	assert.Equal(int64(1), members[1].sent())
+
+	errs := collector.get()
+	if assert.Len(errs, 1) {
+		assert.Equal(errors.New("sender 0 (multi) panicked: slow sender failed"), errs[0])
+	}
+}
+
+func TestMultiSenderFireAndForget(t *testing.T) {
+	assert := assert.New(t)
+
+	multi, members := makeSlowMultiSender(t, 0, 100*time.Millisecond)
+	assert.NoError(SetMultiSenderOptions(multi, MultiSenderOptions{
+		Parallel:      true,
+		FireAndForget: []Sender{members[1]},
+	}))
+
+	start := time.Now()
+	multi.Send(message.NewDefaultMessage(level.Info, "hello"))
+	assert.True(time.Since(start) < 100*time.Millisecond)
+	assert.Equal(int64(1), members[0].sent())
+	assert.Equal(int64(0), members[1].sent())
+
+	time.Sleep(200 * time.Millisecond)
+	assert.Equal(int64(1), members[1].sent())
+}
+
+func benchmarkMultiSender(b *testing.B, opts MultiSenderOptions) {
+	delays := []time.Duration{}
+	for i := 0; i < 5; i++ {
+		delays = append(delays, 0)
+	}
+	delays = append(delays, time.Millisecond)
+
+	multi, _ := makeSlowMultiSender(b, delays...)
+	if err := SetMultiSenderOptions(multi, opts); err != nil {
+		b.Fatal(err)
+	}
+
+	// timeouts are expected in some cases.
+	_ = multi.SetErrorHandler(func(error, message.Composer) {})
+
+	m := message.NewDefaultMessage(level.Info, "hello")
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		multi.Send(m)
+	}
+}
+
+// renderSender renders every message it receives, in the same ways
+// that senders that format messages do.
+type renderSender struct {
+	count int64
+	*Base
+}
+
+func (s *renderSender) Send(m message.Composer) {
+	if !s.Level().ShouldLog(m) {
+		return
+	}
+
+	_ = m.String()
+	_ = m.Priority()
+	if _, err := json.Marshal(m.Raw()); err != nil {
+		s.ErrorHandler(err, m)
+		return
+	}
+
+	atomic.AddInt64(&s.count, 1)
+}
+
+func TestMultiSenderAnnotateWhileRendering(t *testing.T) {
+	assert := assert.New(t)
+
+	renderers := []*renderSender{}
+	senders := []Sender{}
+	for i := 0; i < 3; i++ {
+		r := &renderSender{Base: NewBase(fmt.Sprintf("render-%d", i))}
+		assert.NoError(r.SetLevel(LevelInfo{level.Info, level.Info}))
+		renderers = append(renderers, r)
+		senders = append(senders, r)
+	}
+	sink := &renderSender{Base: NewBase("epoch")}
+	assert.NoError(sink.SetLevel(LevelInfo{level.Info, level.Info}))
+	senders = append(senders, NewEpochSender(sink))
+
+	multi, err := NewMultiSender("multi", LevelInfo{level.Info, level.Info}, senders)
+	assert.NoError(err)
+	assert.NoError(SetMultiSenderOptions(multi, MultiSenderOptions{Parallel: true}))
+
+	composers := []func() message.Composer{
+		func() message.Composer { return message.NewDefaultMessage(level.Info, "hello") },
+		func() message.Composer { return message.NewFields(level.Info, message.Fields{"a": 1}) },
+		func() message.Composer { return message.NewJobRun("job", time.Now(), time.Second, nil) },
+		func() message.Composer { return message.NewWebhookReceived("github", "push", true, 42) },
+	}
+
+	const iterations = 50
+	for _, makeComposer := range composers {
+		for i := 0; i < iterations; i++ {
+			m := makeComposer()
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				for j := 0; j < 10; j++ {
+					_ = m.Annotate(fmt.Sprintf("key-%d", j), j)
+					_ = m.SetPriority(level.Warning)
+				}
+			}()
+
+			multi.Send(m)
+			<-done
+		}
+	}
+	assert.NoError(multi.Flush(context.Background()))
+
+	expected := int64(len(composers) * iterations)
+	for _, r := range renderers {
+		assert.Equal(expected, atomic.LoadInt64(&r.count), r.Name())
+	}
+	assert.Equal(expected, atomic.LoadInt64(&sink.count))
+}
+
+func BenchmarkMultiSender(b *testing.B) {
+	cases := map[string]MultiSenderOptions{
+		"Sequential":        {},
+		"Parallel":          {Parallel: true},
+		"ParallelTwoWorker": {Parallel: true, Workers: 2},
+		"ParallelTimeout":   {Parallel: true, Timeout: 100 * time.Microsecond},
+	}
+
+	for name, opts := range cases {
+		b.Run(name, func(b *testing.B) { benchmarkMultiSender(b, opts) })
+	}
+}
+
+func BenchmarkMultiSenderSlowMembers(b *testing.B) {
+	for _, parallel := range []bool{false, true} {
+		b.Run(fmt.Sprintf("Parallel=%t", parallel), func(b *testing.B) {
+			multi, _ := makeSlowMultiSender(b, time.Millisecond, time.Millisecond, time.Millisecond)
+			if err := SetMultiSenderOptions(multi, MultiSenderOptions{Parallel: parallel}); err != nil {
+				b.Fatal(err)
+			}
+
+			m := message.NewDefaultMessage(level.Info, "hello")
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				multi.Send(m)
+			}
+		})
+	}
+}
+
+// failingSender is a sender that reports an error for every message.
+type failingSender struct{ *Base }
+
+func (s *failingSender) Send(m message.Composer) {
+	if s.Level().ShouldLog(m) {
+		s.ErrorHandler(errors.New("delivery failed"), m)
+	}
+}
+
+func TestMultiSenderReportsMemberErrors(t *testing.T) {
+	assert := assert.New(t)
+
+	first, second := newSlowSender(0), newSlowSender(0)
+	first.SetName("first")
+	second.SetName("second")
+	failing := &failingSender{Base: NewBase("failing")}
+	assert.NoError(failing.SetErrorHandler(func(error, message.Composer) {}))
+
+	multi, err := NewMultiSender("multi", LevelInfo{level.Info, level.Info}, []Sender{first, failing, second})
+	assert.NoError(err)
+	collector := &errorCollector{}
+	assert.NoError(multi.SetErrorHandler(collector.handler))
+
+	errs, err := MultiSenderLastErrors(multi)
+	assert.NoError(err)
+	assert.Empty(errs)
+
+	multi.Send(message.NewDefaultMessage(level.Info, "hello"))
+	assert.EqualValues(1, first.sent())
+	assert.EqualValues(1, second.sent())
+
+	errs, err = MultiSenderLastErrors(multi)
+	assert.NoError(err)
+	assert.Len(errs, 1)
+	assert.EqualError(errs["failing"], "delivery failed")
+
+	collector.mutex.Lock()
+	assert.Len(collector.errs, 1)
+	assert.EqualError(collector.errs[0], "sender 1 (failing): delivery failed")
+	collector.mutex.Unlock()
+
+	_, err = MultiSenderLastErrors(first)
+	assert.Error(err)
+}
+
+func TestMultiSenderAddAndRemoveMembers(t *testing.T) {
+	assert := assert.New(t)
+
+	first, second := newSlowSender(0), newSlowSender(0)
+	first.SetName("first")
+	second.SetName("second")
+	failing := &failingSender{Base: NewBase("failing")}
+	assert.NoError(failing.SetErrorHandler(func(error, message.Composer) {}))
+
+	multi, err := NewMultiSender("multi", LevelInfo{level.Info, level.Info}, []Sender{first, failing})
+	assert.NoError(err)
+	collector := &errorCollector{}
+	assert.NoError(multi.SetErrorHandler(collector.handler))
+
+	multi.Send(message.NewDefaultMessage(level.Info, "one"))
+	assert.NoError(AddToMulti(multi, second))
+	multi.Send(message.NewDefaultMessage(level.Info, "two"))
+
+	assert.NoError(RemoveFromMulti(multi, "failing"))
+	assert.Equal("failing", failing.Name())
+	assert.Error(RemoveFromMulti(multi, "failing"))
+	assert.Error(RemoveFromMulti(first, "failing"))
+
+	errs, err := MultiSenderLastErrors(multi)
+	assert.NoError(err)
+	assert.Empty(errs)
+
+	multi.Send(message.NewDefaultMessage(level.Info, "three"))
+	// the removed sender's errors no longer reach the multi sender.
+	failing.Send(message.NewDefaultMessage(level.Info, "direct"))
+
+	assert.EqualValues(3, first.sent())
+	assert.EqualValues(2, second.sent())
+
+	members, err := MultiSenderMembers(multi)
+	assert.NoError(err)
+	assert.Equal([]Sender{first, second}, members)
+
+	collector.mutex.Lock()
+	assert.Len(collector.errs, 2)
+	collector.mutex.Unlock()
+}
+
+func TestMultiSenderIsolatesMembers(t *testing.T) {
+	assert := assert.New(t)
+
+	first, panicking, last := newSlowSender(0), newSlowSender(0), newSlowSender(0)
+	panicking.panics = true
+	assert.NoError(first.SetLevel(LevelInfo{level.Info, level.Info}))
+	assert.NoError(panicking.SetLevel(LevelInfo{level.Info, level.Info}))
+	assert.NoError(last.SetLevel(LevelInfo{level.Info, level.Error}))
+
+	multi := NewConfiguredMultiSender(first, panicking, last)
+	collector := &errorCollector{}
+	assert.NoError(multi.SetErrorHandler(collector.handler))
+
+	assert.NotPanics(func() { multi.Send(message.NewDefaultMessage(level.Info, "info")) })
+	assert.NotPanics(func() { multi.Send(message.NewDefaultMessage(level.Error, "error")) })
+	assert.EqualValues(2, first.sent())
+	assert.EqualValues(0, panicking.sent())
+	// members keep their own thresholds.
+	assert.EqualValues(1, last.sent())
+
+	errs := collector.get()
+	if assert.Len(errs, 2) {
+		assert.Equal(errors.New("sender 1 (slow) panicked: slow sender failed"), errs[0])
+	}
+
+	first.closer = func() error { return errors.New("first failed") }
+	last.closer = func() error { return errors.New("last failed") }
+	err := multi.Close()
+	assert.EqualError(err, "first failed\nlast failed")
+	assert.EqualValues(1, atomic.LoadInt32(&panicking.closed))
+}
+
+func TestNewMulti(t *testing.T) {
+	assert := assert.New(t)
+
+	first, panicking, last := newSlowSender(0), newSlowSender(0), newSlowSender(0)
+	first.SetName("first")
+	panicking.panics = true
+	assert.NoError(first.SetLevel(LevelInfo{level.Info, level.Info}))
+	assert.NoError(panicking.SetLevel(LevelInfo{level.Info, level.Info}))
+	assert.NoError(last.SetLevel(LevelInfo{level.Info, level.Error}))
+
+	multi := NewMulti("multi", first, panicking, last)
+	assert.Equal("multi", multi.Name())
+	assert.Equal("first", first.Name(), "members keep their names")
+
+	collector := &errorCollector{}
+	assert.NoError(multi.SetErrorHandler(collector.handler))
+
+	assert.NotPanics(func() { multi.Send(message.NewDefaultMessage(level.Info, "info")) })
+	assert.NotPanics(func() { multi.Send(message.NewDefaultMessage(level.Error, "error")) })
+	assert.EqualValues(2, first.sent())
+	assert.EqualValues(1, last.sent(), "members keep their own thresholds")
+	assert.Len(collector.get(), 2)
+
+	first.closer = func() error { return errors.New("first failed") }
+	assert.EqualError(multi.Close(), "first failed")
+	assert.EqualValues(1, atomic.LoadInt32(&panicking.closed))
+	assert.EqualValues(1, atomic.LoadInt32(&last.closed))
+}
+
+func TestMultiSenderCombinesMemberErrors(t *testing.T) {
+	assert := assert.New(t)
+
+	first := &failingSender{Base: NewBase("first")}
+	second := &failingSender{Base: NewBase("second")}
+	panicking := newSlowSender(0)
+	panicking.panics = true
+	for _, sender := range []Sender{first, second, panicking} {
+		assert.NoError(sender.SetLevel(LevelInfo{level.Info, level.Info}))
+		assert.NoError(sender.SetErrorHandler(func(error, message.Composer) {}))
+	}
+
+	for _, parallel := range []bool{false, true} {
+		multi := NewMulti("multi", first, panicking, second)
+		assert.NoError(SetMultiSenderOptions(multi, MultiSenderOptions{Parallel: parallel}))
+		collector := &errorCollector{}
+		assert.NoError(multi.SetErrorHandler(collector.handler))
+
+		multi.Send(message.NewDefaultMessage(level.Info, "hello"))
+		errs := collector.get()
+		if assert.Len(errs, 1, "parallel=%t", parallel) {
+			assert.Contains(errs[0].Error(), "sender 0 (first): delivery failed")
+			assert.Contains(errs[0].Error(), "sender 1 (slow) panicked: slow sender failed")
+			assert.Contains(errs[0].Error(), "sender 2 (second): delivery failed")
+			assert.Len(strings.Split(errs[0].Error(), "; "), 3)
+		}
+
+		// errors that members report after Send returns are
+		// reported on their own.
+		m := message.NewDefaultMessage(level.Info, "later")
+		first.ErrorHandler(errors.New("delivery failed"), m)
+		assert.Len(collector.get(), 2)
+		assert.EqualError(collector.get()[1], "sender 0 (first): delivery failed")
+
+		_ = RemoveFromMulti(multi, "first")
+		_ = RemoveFromMulti(multi, "second")
+		_ = RemoveFromMulti(multi, "slow")
+	}
+}
+
+func TestMultiSenderConcurrentAddAndFlush(t *testing.T) {
+	multi := NewConfiguredMultiSender(newSlowSender(0))
+
+	wg := &sync.WaitGroup{}
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 25; j++ {
+				assert.NoError(t, AddToMulti(multi, newSlowSender(0)))
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 25; j++ {
+				assert.NoError(t, multi.Flush(context.Background()))
+				multi.SetName("multi")
+			}
+		}()
+	}
+	wg.Wait()
+
+	members, err := MultiSenderMembers(multi)
+	assert.NoError(t, err)
+	assert.Len(t, members, 101)
+	assert.NoError(t, multi.Close())
+}
@@ -1,19 +1,77 @@
 package send
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
 	"log"
 	"os"
+	"strings"
 
 	"github.com/mongodb/grip/level"
 	"github.com/mongodb/grip/message"
 )
 
+// nativeTimeFormat matches the timestamps that the standard
+// library's logger writes with the log.LstdFlags flags.
+const nativeTimeFormat = "2006/01/02 15:04:05 "
+
+// defaultNamePrefix is the prefix of the lines that native senders,
+// other than the JSON senders, write.
+const defaultNamePrefix = "[{name}] "
+
 type nativeLogger struct {
-	logger *log.Logger
+	logger     *log.Logger
+	file       interface{ Sync() error }
+	timestamps bool
+	json       bool
+	namePrefix string
 	*Base
 }
 
+// SetNamePrefix configures the prefix of the lines that a native,
+// file, or call site sender writes, where "{name}" in the template
+// is replaced with the name of the sender. The prefix defaults to
+// "[{name}] ", and an empty template removes the prefix.
+//
+// JSON senders, which write no prefix by default, add the name of
+// the sender to each document as the "sender" field when the template
+// is not empty, so that their output remains valid JSON. Returns an
+// error if the sender is not a native sender.
+func SetNamePrefix(s Sender, template string) error {
+	sender, ok := s.(interface {
+		SetNamePrefix(string) error
+	})
+	if !ok {
+		return fmt.Errorf("sender %s does not support name prefixes", s.Name())
+	}
+
+	return sender.SetNamePrefix(template)
+}
+
+// SetNamePrefix sets the template of the prefix of each line. See the
+// SetNamePrefix function.
+func (s *nativeLogger) SetNamePrefix(template string) error {
+	s.mutex.Lock()
+	s.namePrefix = template
+	s.mutex.Unlock()
+
+	if !s.json {
+		s.reset()
+	}
+
+	return nil
+}
+
+// prefix renders the name prefix of the sender.
+func (s *nativeLogger) prefix() string {
+	s.mutex.RLock()
+	template := s.namePrefix
+	s.mutex.RUnlock()
+
+	return strings.Replace(template, "{name}", s.Name(), -1)
+}
+
 // NewFileLogger creates a Sender implementation that writes log
 // output to a file. Returns an error but falls back to a standard
 // output logger if there's problems with the file. Internally using
@@ -43,9 +101,12 @@ func MakeFileLogger(filePath string) (Sender, error) {
 	}
 
 	s.level = LevelInfo{level.Trace, level.Trace}
+	s.file = f
+	s.timestamps = true
+	s.namePrefix = defaultNamePrefix
 
 	s.reset = func() {
-		s.logger = log.New(f, fmt.Sprintf("[%s] ", s.Name()), log.LstdFlags)
+		s.logger = log.New(f, s.prefix(), 0)
 	}
 
 	s.closer = func() error {
@@ -67,7 +128,9 @@ func NewNativeLogger(name string, l LevelInfo) (Sender, error) {
 // SetSender will typically do this.)
 func MakeNative() Sender {
 	s := &nativeLogger{
-		Base: NewBase(""),
+		Base:       NewBase(""),
+		timestamps: true,
+		namePrefix: defaultNamePrefix,
 	}
 
 	_ = s.SetFormatter(MakeDefaultFormatter())
@@ -75,9 +138,7 @@ func MakeNative() Sender {
 	s.level = LevelInfo{level.Trace, level.Trace}
 
 	s.reset = func() {
-		prefix := fmt.Sprintf("[%s] ", s.Name())
-		s.logger = log.New(os.Stdout, prefix, log.LstdFlags)
-		_ = s.SetErrorHandler(ErrorHandlerFromLogger(s.logger))
+		s.logger = log.New(os.Stdout, s.prefix(), 0)
 	}
 
 	// we don't call reset here because name isn't set yet, and
@@ -91,15 +152,16 @@ func MakeNative() Sender {
 // writes all logging output to standard error.
 func MakeErrorLogger() Sender {
 	s := &nativeLogger{
-		Base: NewBase(""),
+		Base:       NewBase(""),
+		timestamps: true,
+		namePrefix: defaultNamePrefix,
 	}
 	_ = s.SetFormatter(MakeDefaultFormatter())
 
 	s.level = LevelInfo{level.Trace, level.Trace}
 
 	s.reset = func() {
-		prefix := fmt.Sprintf("[%s] ", s.Name())
-		s.logger = log.New(os.Stderr, prefix, log.LstdFlags)
+		s.logger = log.New(os.Stderr, s.prefix(), 0)
 	}
 
 	return s
@@ -112,14 +174,62 @@ func NewErrorLogger(name string, l LevelInfo) (Sender, error) {
 }
 
 func (s *nativeLogger) Send(m message.Composer) {
-	if s.level.ShouldLog(m) {
+	if s.Level().ShouldLog(m) && !s.reportClosed(m) {
 		out, err := s.formatter(m)
 
 		if err != nil {
-			s.errHandler(err, m)
+			s.ErrorHandler(err, m)
 			return
 		}
 
-		s.logger.Printf(out)
+		if s.timestamps {
+			out = s.timestamp(m).Format(nativeTimeFormat) + out
+		}
+
+		if s.json && s.prefix() != "" {
+			out = withSenderField(out, s.Name())
+		}
+
+		if err = s.logger.Output(2, out); err != nil {
+			s.ErrorHandler(err, m)
+		}
+	}
+}
+
+// withSenderField adds the name of the sender to the JSON document
+// as the "sender" field, unless the document already has the field,
+// or the output is not a JSON object.
+func withSenderField(out, name string) string {
+	doc := map[string]json.RawMessage{}
+	if err := json.Unmarshal([]byte(out), &doc); err != nil {
+		return out
+	}
+
+	if _, ok := doc["sender"]; ok {
+		return out
+	}
+
+	encoded, err := json.Marshal(name)
+	if err != nil {
+		return out
 	}
+	doc["sender"] = encoded
+
+	merged, err := json.Marshal(doc)
+	if err != nil {
+		return out
+	}
+
+	return string(merged)
+}
+
+// Flush commits the contents of the file to stable storage for file
+// loggers, and is a no-op for loggers that write to standard output
+// or standard error.
+func (s *nativeLogger) Flush(_ context.Context) error {
+	if s.file == nil {
+		return nil
+	}
+
+	return s.file.Sync()
 }
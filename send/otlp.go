@@ -0,0 +1,434 @@
+package send
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mongodb/grip/level"
+	"github.com/mongodb/grip/message"
+)
+
+// OTLPFileOptions configures the OTLP file sender.
+type OTLPFileOptions struct {
+	// ResourceAttributes describe the source of the logs (e.g.
+	// "service.name" and "host.name"), following the OpenTelemetry
+	// semantic conventions. The "service.name" attribute defaults
+	// to the name of the sender.
+	ResourceAttributes map[string]string
+
+	// MaxFileSize is the size, in bytes, at which the sender
+	// rotates the file. Defaults to 10MB.
+	MaxFileSize int64
+
+	// MaxFiles is the number of rotated files that the sender
+	// keeps, in addition to the file that it writes. Defaults to
+	// 5.
+	MaxFiles int
+
+	// BufferSize is the number of log records that the sender
+	// buffers before it writes them to the file. Defaults to 100.
+	BufferSize int
+
+	// FlushInterval is how often the sender writes buffered log
+	// records to the file. Defaults to 10 seconds.
+	FlushInterval time.Duration
+}
+
+// Validate checks the options, and sets defaults for unspecified
+// values.
+func (o *OTLPFileOptions) Validate() error {
+	errs := []string{}
+
+	if o.MaxFileSize < 0 {
+		errs = append(errs, "max file size cannot be negative")
+	}
+
+	if o.MaxFiles < 0 {
+		errs = append(errs, "max files cannot be negative")
+	}
+
+	if o.BufferSize < 0 {
+		errs = append(errs, "buffer size cannot be negative")
+	}
+
+	if o.FlushInterval < 0 {
+		errs = append(errs, "flush interval cannot be negative")
+	}
+
+	if o.MaxFileSize == 0 {
+		o.MaxFileSize = 10 * 1024 * 1024
+	}
+
+	if o.MaxFiles == 0 {
+		o.MaxFiles = 5
+	}
+
+	if o.BufferSize == 0 {
+		o.BufferSize = 100
+	}
+
+	if o.FlushInterval == 0 {
+		o.FlushInterval = 10 * time.Second
+	}
+
+	if len(errs) > 0 {
+		return errors.New(strings.Join(errs, "; "))
+	}
+
+	return nil
+}
+
+// The types that follow are the subset of the OTLP logs data model
+// that the sender writes, in the OTLP JSON encoding: field names are
+// lowerCamelCase, 64-bit integers are strings, and enums are numbers.
+
+type otlpLogsData struct {
+	ResourceLogs []otlpResourceLogs `json:"resourceLogs"`
+}
+
+type otlpResourceLogs struct {
+	Resource  otlpResource    `json:"resource"`
+	ScopeLogs []otlpScopeLogs `json:"scopeLogs"`
+}
+
+type otlpResource struct {
+	Attributes []otlpKeyValue `json:"attributes"`
+}
+
+type otlpScopeLogs struct {
+	Scope      otlpScope       `json:"scope"`
+	LogRecords []otlpLogRecord `json:"logRecords"`
+}
+
+type otlpScope struct {
+	Name string `json:"name"`
+}
+
+type otlpLogRecord struct {
+	TimeUnixNano         string         `json:"timeUnixNano"`
+	ObservedTimeUnixNano string         `json:"observedTimeUnixNano"`
+	SeverityNumber       int            `json:"severityNumber"`
+	SeverityText         string         `json:"severityText"`
+	Body                 otlpAnyValue   `json:"body"`
+	Attributes           []otlpKeyValue `json:"attributes,omitempty"`
+}
+
+type otlpKeyValue struct {
+	Key   string       `json:"key"`
+	Value otlpAnyValue `json:"value"`
+}
+
+// otlpAnyValue holds exactly one of the "stringValue", "boolValue",
+// "intValue", or "doubleValue" keys.
+type otlpAnyValue map[string]interface{}
+
+type otlpFileSender struct {
+	path    string
+	opts    OTLPFileOptions
+	file    *os.File
+	size    int64
+	records []otlpLogRecord
+	closed  bool
+	mutex   sync.Mutex
+	stop    chan struct{}
+	done    chan struct{}
+	*Base
+}
+
+// NewOTLPFileSender constructs a Sender that writes messages to the
+// file as OpenTelemetry (OTLP) log records, so that an OpenTelemetry
+// collector can ingest the file later, for example with the
+// "otlpjsonfile" receiver. Each line of the file is an OTLP JSON
+// encoded logs request, with one ResourceLogs that has the resource
+// attributes from the options, and holds the records that the sender
+// buffered since the last line.
+//
+// Log records have the severity that corresponds to the priority of
+// the message, and the text of the message as the body. Fields
+// messages have their fields as attributes, and their "msg" field as
+// the body. The sender writes buffered records when the buffer is
+// full, every FlushInterval, on Flush, and on Close, and rotates the
+// file when it reaches the MaxFileSize, renaming the old files to
+// "<path>.1", "<path>.2", and so on.
+func NewOTLPFileSender(name, path string, opts OTLPFileOptions, l LevelInfo) (Sender, error) {
+	if path == "" {
+		return nil, errors.New("no otlp file path specified")
+	}
+
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+
+	s := &otlpFileSender{
+		path: path,
+		opts: opts,
+		stop: make(chan struct{}),
+		done: make(chan struct{}),
+		Base: NewBase(name),
+	}
+
+	if err := s.open(); err != nil {
+		return nil, err
+	}
+
+	s.closer = func() error {
+		close(s.stop)
+		<-s.done
+
+		s.mutex.Lock()
+		defer s.mutex.Unlock()
+
+		s.closed = true
+		err := s.write()
+		if cerr := s.file.Close(); err == nil {
+			err = cerr
+		}
+
+		return err
+	}
+
+	go s.flushPeriodically()
+
+	return setup(s, name, l)
+}
+
+func (s *otlpFileSender) Send(m message.Composer) {
+	if !s.Level().ShouldLog(m) || s.reportClosed(m) {
+		return
+	}
+
+	record := s.record(m)
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	// the sender may have closed while building the record.
+	if s.closed {
+		s.ErrorHandler(ErrSenderClosed, m)
+		return
+	}
+
+	s.records = append(s.records, record)
+	if len(s.records) >= s.opts.BufferSize {
+		s.ErrorHandler(s.write(), m)
+	}
+}
+
+// Flush writes the buffered log records to the file, and syncs the
+// file to disk.
+func (s *otlpFileSender) Flush(_ context.Context) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.closed {
+		return nil
+	}
+
+	if err := s.write(); err != nil {
+		return err
+	}
+
+	return s.file.Sync()
+}
+
+func (s *otlpFileSender) flushPeriodically() {
+	defer close(s.done)
+
+	ticker := time.NewTicker(s.opts.FlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+			s.mutex.Lock()
+			err := s.write()
+			s.mutex.Unlock()
+
+			s.ErrorHandler(err, message.NewString(s.path))
+		}
+	}
+}
+
+// write appends the buffered log records to the file, as a single
+// line, rotating the file as needed. The records are discarded even
+// if the write fails, so that a failing file does not grow the
+// buffer. The caller must hold the mutex.
+func (s *otlpFileSender) write() error {
+	if len(s.records) == 0 {
+		return nil
+	}
+
+	records := s.records
+	s.records = nil
+
+	line, err := json.Marshal(otlpLogsData{ResourceLogs: []otlpResourceLogs{{
+		Resource:  otlpResource{Attributes: s.resourceAttributes()},
+		ScopeLogs: []otlpScopeLogs{{Scope: otlpScope{Name: "grip"}, LogRecords: records}},
+	}}})
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	if s.size > 0 && s.size+int64(len(line)) > s.opts.MaxFileSize {
+		if err = s.rotate(); err != nil {
+			return err
+		}
+	}
+
+	n, err := s.file.Write(line)
+	s.size += int64(n)
+	return err
+}
+
+func (s *otlpFileSender) open() error {
+	f, err := os.OpenFile(s.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return err
+	}
+
+	s.file = f
+	s.size = info.Size()
+	return nil
+}
+
+// rotate renames the file to "<path>.1", after renaming the older
+// rotated files to make room and removing the oldest, and opens a new
+// file. The caller must hold the mutex.
+func (s *otlpFileSender) rotate() error {
+	if err := s.file.Close(); err != nil {
+		return err
+	}
+
+	rotated := func(n int) string { return fmt.Sprintf("%s.%d", s.path, n) }
+
+	if err := os.Remove(rotated(s.opts.MaxFiles)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	for n := s.opts.MaxFiles - 1; n >= 1; n-- {
+		if err := os.Rename(rotated(n), rotated(n+1)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+
+	if err := os.Rename(s.path, rotated(1)); err != nil {
+		return err
+	}
+
+	return s.open()
+}
+
+func (s *otlpFileSender) resourceAttributes() []otlpKeyValue {
+	attrs := map[string]interface{}{"service.name": s.Name()}
+	for k, v := range s.opts.ResourceAttributes {
+		attrs[k] = v
+	}
+
+	return otlpAttributes(attrs)
+}
+
+func (s *otlpFileSender) record(m message.Composer) otlpLogRecord {
+	record := otlpLogRecord{
+		TimeUnixNano:         strconv.FormatInt(s.timestamp(m).UnixNano(), 10),
+		ObservedTimeUnixNano: strconv.FormatInt(time.Now().UnixNano(), 10),
+		SeverityNumber:       otlpSeverity(m.Priority()),
+		SeverityText:         m.Priority().String(),
+	}
+
+	body := messageText(m, " ")
+	if fields, ok := m.Raw().(message.Fields); ok {
+		attrs := map[string]interface{}{}
+		for k, v := range fields {
+			if k == "msg" || k == "time" {
+				continue
+			}
+			attrs[k] = v
+		}
+		record.Attributes = otlpAttributes(attrs)
+
+		if msg, ok := fields["msg"].(string); ok && msg != "" {
+			body = msg
+		}
+	}
+
+	record.Body = otlpValue(body)
+	return record
+}
+
+// otlpAttributes converts the attributes to OTLP key-value pairs,
+// sorted by key.
+func otlpAttributes(attrs map[string]interface{}) []otlpKeyValue {
+	keys := make([]string, 0, len(attrs))
+	for k := range attrs {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	out := make([]otlpKeyValue, 0, len(keys))
+	for _, k := range keys {
+		out = append(out, otlpKeyValue{Key: k, Value: otlpValue(attrs[k])})
+	}
+
+	return out
+}
+
+// otlpValue converts strings, booleans, and numbers to the
+// corresponding OTLP values, and other values to strings.
+func otlpValue(v interface{}) otlpAnyValue {
+	switch val := v.(type) {
+	case string:
+		return otlpAnyValue{"stringValue": val}
+	case bool:
+		return otlpAnyValue{"boolValue": val}
+	case int, int8, int16, int32, int64, uint8, uint16, uint32:
+		return otlpAnyValue{"intValue": fmt.Sprintf("%d", val)}
+	case float32, float64:
+		return otlpAnyValue{"doubleValue": val}
+	case error:
+		return otlpAnyValue{"stringValue": val.Error()}
+	default:
+		return otlpAnyValue{"stringValue": fmt.Sprintf("%v", val)}
+	}
+}
+
+// otlpSeverity returns the OTLP severity number that corresponds to
+// the priority.
+func otlpSeverity(p level.Priority) int {
+	switch {
+	case p >= level.Emergency:
+		return 24 // FATAL4
+	case p >= level.Alert:
+		return 23 // FATAL3
+	case p >= level.Critical:
+		return 21 // FATAL
+	case p >= level.Error:
+		return 17 // ERROR
+	case p >= level.Warning:
+		return 13 // WARN
+	case p >= level.Notice:
+		return 10 // INFO2
+	case p >= level.Info:
+		return 9 // INFO
+	case p >= level.Debug:
+		return 5 // DEBUG
+	default:
+		return 1 // TRACE
+	}
+}
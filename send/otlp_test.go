@@ -0,0 +1,170 @@
+package send
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mongodb/grip/level"
+	"github.com/mongodb/grip/message"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// readOTLPFile parses each line of the file as a logs request.
+func readOTLPFile(t *testing.T, path string) []otlpLogsData {
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer f.Close()
+
+	out := []otlpLogsData{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		data := otlpLogsData{}
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &data))
+		out = append(out, data)
+	}
+	require.NoError(t, scanner.Err())
+
+	return out
+}
+
+func TestOTLPFileSenderWritesLogRecords(t *testing.T) {
+	assert := assert.New(t)
+
+	dir, err := ioutil.TempDir("", "grip-otlp")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "logs.json")
+
+	s, err := NewOTLPFileSender("edge", path, OTLPFileOptions{
+		ResourceAttributes: map[string]string{"host.name": "device-1"},
+	}, LevelInfo{level.Info, level.Info})
+	require.NoError(t, err)
+
+	s.Send(message.NewDefaultMessage(level.Warning, "disk almost full"))
+	s.Send(message.NewFieldsMessage(level.Info, "uploaded", message.Fields{"bytes": 42, "ok": true, "rate": 0.5}))
+	s.Send(message.NewDefaultMessage(level.Debug, "below threshold"))
+	require.NoError(t, s.Flush(context.Background()))
+
+	lines := readOTLPFile(t, path)
+	require.Len(t, lines, 1)
+	require.Len(t, lines[0].ResourceLogs, 1)
+
+	resource := lines[0].ResourceLogs[0]
+	assert.Equal([]otlpKeyValue{
+		{Key: "host.name", Value: otlpAnyValue{"stringValue": "device-1"}},
+		{Key: "service.name", Value: otlpAnyValue{"stringValue": "edge"}},
+	}, resource.Resource.Attributes)
+	require.Len(t, resource.ScopeLogs, 1)
+	assert.Equal("grip", resource.ScopeLogs[0].Scope.Name)
+
+	records := resource.ScopeLogs[0].LogRecords
+	require.Len(t, records, 2)
+	assert.Equal(13, records[0].SeverityNumber)
+	assert.Equal("warning", records[0].SeverityText)
+	assert.Equal(otlpAnyValue{"stringValue": "disk almost full"}, records[0].Body)
+	assert.Empty(records[0].Attributes)
+	assert.NotEmpty(records[0].TimeUnixNano)
+	assert.NotEmpty(records[0].ObservedTimeUnixNano)
+
+	assert.Equal(9, records[1].SeverityNumber)
+	assert.Equal(otlpAnyValue{"stringValue": "uploaded"}, records[1].Body)
+	assert.Equal([]otlpKeyValue{
+		{Key: "bytes", Value: otlpAnyValue{"intValue": "42"}},
+		{Key: "ok", Value: otlpAnyValue{"boolValue": true}},
+		{Key: "rate", Value: otlpAnyValue{"doubleValue": 0.5}},
+	}, records[1].Attributes)
+
+	// each flush writes a separate line, and empty flushes write
+	// nothing.
+	require.NoError(t, s.Flush(context.Background()))
+	s.Send(message.NewDefaultMessage(level.Error, "again"))
+	require.NoError(t, s.Close())
+	assert.Len(readOTLPFile(t, path), 2)
+}
+
+func TestOTLPFileSenderWritesFullBuffers(t *testing.T) {
+	dir, err := ioutil.TempDir("", "grip-otlp")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "logs.json")
+
+	s, err := NewOTLPFileSender("edge", path, OTLPFileOptions{BufferSize: 2, FlushInterval: time.Hour}, LevelInfo{level.Info, level.Info})
+	require.NoError(t, err)
+	defer s.Close()
+
+	s.Send(message.NewDefaultMessage(level.Info, "one"))
+	assert.Len(t, readOTLPFile(t, path), 0)
+	s.Send(message.NewDefaultMessage(level.Info, "two"))
+
+	lines := readOTLPFile(t, path)
+	require.Len(t, lines, 1)
+	assert.Len(t, lines[0].ResourceLogs[0].ScopeLogs[0].LogRecords, 2)
+}
+
+func TestOTLPFileSenderWritesPeriodically(t *testing.T) {
+	dir, err := ioutil.TempDir("", "grip-otlp")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "logs.json")
+
+	s, err := NewOTLPFileSender("edge", path, OTLPFileOptions{FlushInterval: 10 * time.Millisecond}, LevelInfo{level.Info, level.Info})
+	require.NoError(t, err)
+	defer s.Close()
+
+	s.Send(message.NewDefaultMessage(level.Info, "one"))
+	time.Sleep(100 * time.Millisecond)
+	assert.Len(t, readOTLPFile(t, path), 1)
+}
+
+func TestOTLPFileSenderRotatesFiles(t *testing.T) {
+	assert := assert.New(t)
+
+	dir, err := ioutil.TempDir("", "grip-otlp")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "logs.json")
+
+	s, err := NewOTLPFileSender("edge", path, OTLPFileOptions{MaxFileSize: 1, MaxFiles: 2}, LevelInfo{level.Info, level.Info})
+	require.NoError(t, err)
+
+	for _, msg := range []string{"one", "two", "three", "four"} {
+		s.Send(message.NewDefaultMessage(level.Info, msg))
+		require.NoError(t, s.Flush(context.Background()))
+	}
+	require.NoError(t, s.Close())
+
+	body := func(path string) interface{} {
+		lines := readOTLPFile(t, path)
+		require.Len(t, lines, 1)
+		return lines[0].ResourceLogs[0].ScopeLogs[0].LogRecords[0].Body["stringValue"]
+	}
+	assert.Equal("four", body(path))
+	assert.Equal("three", body(path+".1"))
+	assert.Equal("two", body(path+".2"))
+	_, err = os.Stat(path + ".3")
+	assert.True(os.IsNotExist(err))
+}
+
+func TestOTLPFileSenderOptions(t *testing.T) {
+	assert := assert.New(t)
+
+	opts := OTLPFileOptions{}
+	assert.NoError(opts.Validate())
+	assert.Equal(int64(10*1024*1024), opts.MaxFileSize)
+	assert.Equal(5, opts.MaxFiles)
+	assert.Equal(100, opts.BufferSize)
+	assert.Equal(10*time.Second, opts.FlushInterval)
+
+	opts = OTLPFileOptions{MaxFileSize: -1, BufferSize: -1}
+	assert.Error(opts.Validate())
+
+	_, err := NewOTLPFileSender("edge", "", OTLPFileOptions{}, LevelInfo{level.Info, level.Info})
+	assert.Error(err)
+}
@@ -0,0 +1,207 @@
+package send
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/mongodb/grip/level"
+	"github.com/mongodb/grip/message"
+)
+
+const pagerDutyEndpoint = "https://events.pagerduty.com/v2/enqueue"
+
+// pagerDutySummaryLength is the maximum length of the summary of an
+// event that PagerDuty accepts.
+const pagerDutySummaryLength = 1024
+
+// DedupKeyAnnotation is the annotation that holds the deduplication
+// key of a PagerDuty event: PagerDuty groups the events that a
+// PagerDuty sender triggers with the same key into a single incident.
+const DedupKeyAnnotation = "_dedup_key"
+
+// PagerDutyOptions configures the PagerDuty sender.
+type PagerDutyOptions struct {
+	// Source identifies the system that triggered the event.
+	// Defaults to the hostname of the system.
+	Source string
+
+	// Endpoint is the URL of the PagerDuty Events API. Defaults to
+	// PagerDuty's API.
+	Endpoint string
+
+	// Client is the HTTP client that the sender posts events with.
+	// Defaults to a client with a 10 second timeout.
+	Client *http.Client
+}
+
+// Validate sets defaults for unspecified values.
+func (o *PagerDutyOptions) Validate() error {
+	if o.Source == "" {
+		o.Source, _ = os.Hostname()
+	}
+
+	if o.Endpoint == "" {
+		o.Endpoint = pagerDutyEndpoint
+	}
+
+	if o.Client == nil {
+		o.Client = &http.Client{Timeout: 10 * time.Second}
+	}
+
+	return nil
+}
+
+type pagerDutySender struct {
+	routingKey string
+	opts       PagerDutyOptions
+	*Base
+}
+
+// NewPagerDutyLogger constructs a Sender that triggers a PagerDuty
+// event, with the Events API v2, for every message, using the routing
+// key of a PagerDuty integration. Use the level threshold to limit the
+// events to messages that should page someone, such as Alert and
+// Emergency messages.
+func NewPagerDutyLogger(name, routingKey string, l LevelInfo) (Sender, error) {
+	return NewPagerDutyLoggerWithOptions(name, routingKey, PagerDutyOptions{}, l)
+}
+
+// NewPagerDutyLoggerWithOptions is the same as NewPagerDutyLogger, but
+// configures the sender with the options.
+//
+// Events have the severity that corresponds to the priority of the
+// message, and the text of the message as the summary. The fields of
+// Fields messages are the custom details of the event. Messages with
+// a DedupKeyAnnotation trigger events with that deduplication key, so
+// that repeated messages update a single incident. The sender posts
+// each event as it is sent, and reports errors to its error handler.
+func NewPagerDutyLoggerWithOptions(name, routingKey string, opts PagerDutyOptions, l LevelInfo) (Sender, error) {
+	if routingKey == "" {
+		return nil, errors.New("no pagerduty routing key specified")
+	}
+
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+
+	s := &pagerDutySender{
+		routingKey: routingKey,
+		opts:       opts,
+		Base:       NewBase(name),
+	}
+
+	return setup(s, name, l)
+}
+
+func (s *pagerDutySender) Send(m message.Composer) { s.SendContext(context.Background(), m) }
+
+// SendContext triggers the event for the message, and cancels the
+// request when the context is canceled.
+func (s *pagerDutySender) SendContext(ctx context.Context, m message.Composer) {
+	if !s.Level().ShouldLog(m) || s.reportClosed(m) {
+		return
+	}
+
+	body, err := json.Marshal(s.event(m))
+	if err != nil {
+		s.ErrorHandler(err, m)
+		return
+	}
+
+	s.ErrorHandler(s.post(ctx, body), m)
+}
+
+func (s *pagerDutySender) post(ctx context.Context, body []byte) error {
+	req, err := http.NewRequest("POST", s.opts.Endpoint, bytes.NewBuffer(body))
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.opts.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	// pagerduty describes invalid events in the body of the
+	// response.
+	text, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 512))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		if detail := strings.TrimSpace(string(text)); detail != "" {
+			return fmt.Errorf("pagerduty responded with status %s: %s", resp.Status, detail)
+		}
+
+		return fmt.Errorf("pagerduty responded with status %s", resp.Status)
+	}
+
+	return nil
+}
+
+func (s *pagerDutySender) event(m message.Composer) map[string]interface{} {
+	summary := messageText(m, " ")
+	if len(summary) > pagerDutySummaryLength {
+		summary = summary[:pagerDutySummaryLength]
+	}
+
+	payload := map[string]interface{}{
+		"summary":   summary,
+		"source":    s.opts.Source,
+		"severity":  pagerDutySeverity(m.Priority()),
+		"timestamp": s.timestamp(m).Format(time.RFC3339Nano),
+		"component": s.Name(),
+	}
+
+	if fields, ok := m.Raw().(message.Fields); ok {
+		details := map[string]interface{}{}
+		for k, v := range fields {
+			if k == DedupKeyAnnotation {
+				continue
+			}
+			details[k] = v
+		}
+		payload["custom_details"] = details
+	}
+
+	event := map[string]interface{}{
+		"routing_key":  s.routingKey,
+		"event_action": "trigger",
+		"payload":      payload,
+	}
+
+	if annotated, ok := m.(message.Annotated); ok {
+		if key, ok := annotated.Annotation(DedupKeyAnnotation); ok {
+			event["dedup_key"] = fmt.Sprintf("%v", key)
+		}
+	}
+
+	return event
+}
+
+// pagerDutySeverity returns the PagerDuty severity that corresponds
+// to the priority: Emergency messages are critical, Alert, Critical,
+// and Error messages are errors, and Notice and lower priority
+// messages are informational.
+func pagerDutySeverity(p level.Priority) string {
+	switch {
+	case p >= level.Emergency:
+		return "critical"
+	case p >= level.Error:
+		return "error"
+	case p >= level.Warning:
+		return "warning"
+	default:
+		return "info"
+	}
+}
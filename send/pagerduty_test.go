@@ -0,0 +1,142 @@
+package send
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/mongodb/grip/level"
+	"github.com/mongodb/grip/message"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// pagerDutyServer records the events posted to it, and responds with
+// the configured status.
+type pagerDutyServer struct {
+	*httptest.Server
+	mutex  sync.Mutex
+	events []map[string]interface{}
+	status int
+}
+
+func newPagerDutyServer() *pagerDutyServer {
+	s := &pagerDutyServer{status: http.StatusAccepted}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		event := map[string]interface{}{}
+		_ = json.NewDecoder(r.Body).Decode(&event)
+
+		s.mutex.Lock()
+		defer s.mutex.Unlock()
+		s.events = append(s.events, event)
+		w.WriteHeader(s.status)
+		if s.status == http.StatusBadRequest {
+			_, _ = w.Write([]byte(`{"status":"invalid event","message":"Event object is invalid"}`))
+		}
+	}))
+
+	return s
+}
+
+func (s *pagerDutyServer) received() []map[string]interface{} {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return append([]map[string]interface{}{}, s.events...)
+}
+
+func (s *pagerDutyServer) options() PagerDutyOptions {
+	return PagerDutyOptions{Source: "web-1", Endpoint: s.URL, Client: s.Client()}
+}
+
+func TestPagerDutyLoggerTriggersEvents(t *testing.T) {
+	assert := assert.New(t)
+
+	srv := newPagerDutyServer()
+	defer srv.Close()
+
+	s, err := NewPagerDutyLoggerWithOptions("checkout", "routing-key", srv.options(), LevelInfo{level.Info, level.Warning})
+	require.NoError(t, err)
+
+	s.Send(message.NewDefaultMessage(level.Info, "below threshold"))
+	s.Send(message.NewDefaultMessage(level.Emergency, "database down"))
+
+	m := message.NewFieldsMessage(level.Alert, "payments failing", message.Fields{"region": "us-east-1", "errors": 42})
+	require.NoError(t, m.Annotate(DedupKeyAnnotation, "payments"))
+	s.Send(m)
+
+	events := srv.received()
+	require.Len(t, events, 2)
+
+	assert.Equal("routing-key", events[0]["routing_key"])
+	assert.Equal("trigger", events[0]["event_action"])
+	assert.Nil(events[0]["dedup_key"])
+	payload := events[0]["payload"].(map[string]interface{})
+	assert.Equal("database down", payload["summary"])
+	assert.Equal("web-1", payload["source"])
+	assert.Equal("critical", payload["severity"])
+	assert.Equal("checkout", payload["component"])
+	assert.NotEmpty(payload["timestamp"])
+	assert.Nil(payload["custom_details"])
+
+	assert.Equal("payments", events[1]["dedup_key"])
+	payload = events[1]["payload"].(map[string]interface{})
+	assert.Equal("error", payload["severity"])
+	details := payload["custom_details"].(map[string]interface{})
+	assert.Equal("us-east-1", details["region"])
+	assert.Equal(float64(42), details["errors"])
+	assert.Equal("payments failing", details["msg"])
+	assert.NotContains(details, DedupKeyAnnotation)
+}
+
+func TestPagerDutySeverity(t *testing.T) {
+	for p, severity := range map[level.Priority]string{
+		level.Emergency: "critical",
+		level.Alert:     "error",
+		level.Critical:  "error",
+		level.Error:     "error",
+		level.Warning:   "warning",
+		level.Notice:    "info",
+		level.Info:      "info",
+		level.Debug:     "info",
+	} {
+		assert.Equal(t, severity, pagerDutySeverity(p), p.String())
+	}
+}
+
+func TestPagerDutyLoggerReportsHTTPErrors(t *testing.T) {
+	srv := newPagerDutyServer()
+	defer srv.Close()
+	srv.status = http.StatusBadRequest
+
+	s, err := NewPagerDutyLoggerWithOptions("checkout", "routing-key", srv.options(), LevelInfo{level.Info, level.Info})
+	require.NoError(t, err)
+
+	var failures []error
+	var failed []message.Composer
+	require.NoError(t, s.SetErrorHandler(func(err error, m message.Composer) {
+		if err != nil {
+			failures = append(failures, err)
+			failed = append(failed, m)
+		}
+	}))
+
+	m := message.NewDefaultMessage(level.Alert, "failed")
+	s.Send(m)
+
+	require.Len(t, failures, 1)
+	assert.Contains(t, failures[0].Error(), "pagerduty responded with status 400 Bad Request: ")
+	assert.Contains(t, failures[0].Error(), "Event object is invalid")
+	assert.True(t, m == failed[0])
+}
+
+func TestPagerDutyLoggerDefaults(t *testing.T) {
+	_, err := NewPagerDutyLogger("checkout", "", LevelInfo{level.Info, level.Info})
+	assert.Error(t, err)
+
+	opts := PagerDutyOptions{}
+	require.NoError(t, opts.Validate())
+	assert.Equal(t, pagerDutyEndpoint, opts.Endpoint)
+	assert.NotNil(t, opts.Client)
+}
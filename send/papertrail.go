@@ -0,0 +1,251 @@
+package send
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mongodb/grip/message"
+)
+
+// PapertrailOptions configures the Papertrail sender.
+type PapertrailOptions struct {
+	// Address is the host:port of the Papertrail log destination
+	// (e.g. "logs.papertrailapp.com:12345").
+	Address string
+
+	// AppName identifies the application in Papertrail. Defaults
+	// to the name of the sender.
+	AppName string
+
+	// Hostname identifies the system in Papertrail. Defaults to
+	// the hostname of the system.
+	Hostname string
+
+	// TLSConfig, if specified, configures the TLS connection to
+	// Papertrail. By default, the sender verifies Papertrail's
+	// certificate using the system's root certificates.
+	TLSConfig *tls.Config
+
+	// DialTimeout limits the time the sender waits to connect to
+	// Papertrail. Defaults to 10 seconds.
+	DialTimeout time.Duration
+
+	// MinBackoff and MaxBackoff control how long the sender waits
+	// before reconnecting after a failure. The wait starts at
+	// MinBackoff and doubles after every failed attempt, up to
+	// MaxBackoff. Default to 100 milliseconds and 30 seconds.
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+}
+
+// Validate checks the options, and sets defaults for unspecified
+// values.
+func (o *PapertrailOptions) Validate() error {
+	errs := []string{}
+
+	if o.Address == "" {
+		errs = append(errs, "no papertrail address specified")
+	} else if _, _, err := net.SplitHostPort(o.Address); err != nil {
+		errs = append(errs, fmt.Sprintf("invalid papertrail address: %s", err.Error()))
+	}
+
+	if o.DialTimeout < 0 {
+		errs = append(errs, "dial timeout cannot be negative")
+	}
+
+	if o.MinBackoff < 0 || o.MaxBackoff < 0 {
+		errs = append(errs, "backoff cannot be negative")
+	}
+
+	if o.Hostname == "" {
+		o.Hostname, _ = os.Hostname()
+	}
+
+	if o.DialTimeout == 0 {
+		o.DialTimeout = 10 * time.Second
+	}
+
+	if o.MinBackoff == 0 {
+		o.MinBackoff = 100 * time.Millisecond
+	}
+
+	if o.MaxBackoff == 0 {
+		o.MaxBackoff = 30 * time.Second
+	}
+
+	if o.MaxBackoff < o.MinBackoff {
+		errs = append(errs, "max backoff cannot be less than min backoff")
+	}
+
+	if len(errs) > 0 {
+		return errors.New(strings.Join(errs, "; "))
+	}
+
+	return nil
+}
+
+type papertrailSender struct {
+	opts      PapertrailOptions
+	facility  SyslogFacility
+	conn      net.Conn
+	backoff   time.Duration
+	nextDial  time.Time
+	closed    bool
+	connMutex sync.Mutex
+	*Base
+}
+
+// NewPapertrailSender constructs a Sender that writes RFC 5424 syslog
+// messages over TLS to Papertrail, with octet-counted framing (RFC
+// 5425), so that messages may contain newlines. The syslog severity
+// of each message follows its priority, and every message includes
+// the app name, hostname, pid, and the time the message was created.
+// Messages use the "user" facility; use SetSyslogFacility to change
+// it.
+//
+// When the connection fails, the sender reports the error to its
+// error handler, and reconnects on a later Send, waiting longer after
+// each consecutive failure; messages sent before the sender may
+// reconnect are reported to the error handler without an attempt to
+// send them. Wrap the sender with NewSpoolingSender to deliver those
+// messages after Papertrail recovers.
+func NewPapertrailSender(name string, opts PapertrailOptions, l LevelInfo) (Sender, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+
+	s := &papertrailSender{
+		opts:     opts,
+		facility: FacilityUser,
+		backoff:  opts.MinBackoff,
+		Base:     NewBase(name),
+	}
+
+	s.closer = func() error {
+		s.connMutex.Lock()
+		defer s.connMutex.Unlock()
+
+		s.closed = true
+		if s.conn == nil {
+			return nil
+		}
+
+		err := s.conn.Close()
+		s.conn = nil
+		return err
+	}
+
+	return setup(s, name, l)
+}
+
+func (s *papertrailSender) Send(m message.Composer) {
+	if !s.Level().ShouldLog(m) {
+		return
+	}
+
+	if err := s.write(s.format(m)); err != nil {
+		s.ErrorHandler(err, m)
+	}
+}
+
+func (s *papertrailSender) format(m message.Composer) []byte {
+	appName := s.opts.AppName
+	if appName == "" {
+		appName = s.Name()
+	}
+
+	s.connMutex.Lock()
+	facility := s.facility
+	s.connMutex.Unlock()
+
+	// messages have no message id or structured data.
+	header := rfc5424Header{
+		facility: facility,
+		hostname: s.opts.Hostname,
+		appName:  appName,
+		procID:   strconv.Itoa(os.Getpid()),
+	}
+	msg := header.format(syslogSeverity(m.Priority()), s.timestamp(m), "-", messageText(m, " "))
+
+	return []byte(fmt.Sprintf("%d %s", len(msg), msg))
+}
+
+func (s *papertrailSender) write(frame []byte) error {
+	s.connMutex.Lock()
+	defer s.connMutex.Unlock()
+
+	if s.closed {
+		return ErrSenderClosed
+	}
+
+	if s.conn == nil {
+		if err := s.dial(); err != nil {
+			return err
+		}
+	}
+
+	if _, err := s.conn.Write(frame); err != nil {
+		_ = s.conn.Close()
+		s.conn = nil
+		s.failed()
+		return err
+	}
+
+	return nil
+}
+
+// dial connects to Papertrail, unless the sender must wait before
+// reconnecting. The caller must hold the connection mutex.
+func (s *papertrailSender) dial() error {
+	if time.Now().Before(s.nextDial) {
+		return fmt.Errorf("waiting to reconnect to papertrail at %s", s.opts.Address)
+	}
+
+	dialer := &net.Dialer{Timeout: s.opts.DialTimeout}
+	conn, err := tls.DialWithDialer(dialer, "tcp", s.opts.Address, s.opts.TLSConfig)
+	if err != nil {
+		s.failed()
+		return err
+	}
+
+	s.conn = conn
+	s.backoff = s.opts.MinBackoff
+	return nil
+}
+
+// failed schedules the next connection attempt. The caller must hold
+// the connection mutex.
+func (s *papertrailSender) failed() {
+	s.nextDial = time.Now().Add(s.backoff)
+
+	s.backoff *= 2
+	if s.backoff > s.opts.MaxBackoff {
+		s.backoff = s.opts.MaxBackoff
+	}
+}
+
+// SetFacility sets the syslog facility of the messages that the
+// sender writes, which defaults to the "user" facility.
+func (s *papertrailSender) SetFacility(f SyslogFacility) error {
+	if err := f.Validate(); err != nil {
+		return err
+	}
+
+	s.connMutex.Lock()
+	defer s.connMutex.Unlock()
+
+	s.facility = f
+	return nil
+}
+
+// Flush is a no-op, because the sender writes every message to the
+// connection as it is sent.
+func (s *papertrailSender) Flush(_ context.Context) error { return nil }
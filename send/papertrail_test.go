@@ -0,0 +1,227 @@
+package send
+
+import (
+	"bufio"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mongodb/grip/level"
+	"github.com/mongodb/grip/message"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// papertrailServer is a TLS server that records the octet-counted
+// syslog messages that it receives.
+type papertrailServer struct {
+	listener net.Listener
+	client   *tls.Config
+	mutex    sync.Mutex
+	conns    []net.Conn
+	messages []string
+}
+
+func newPapertrailServer(t *testing.T) *papertrailServer {
+	// borrow the certificate of the httptest package's TLS server.
+	ts := httptest.NewTLSServer(http.NotFoundHandler())
+	certs := ts.TLS.Certificates
+	pool := x509.NewCertPool()
+	pool.AddCert(ts.Certificate())
+	ts.Close()
+
+	listener, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{Certificates: certs})
+	require.NoError(t, err)
+
+	s := &papertrailServer{
+		listener: listener,
+		client:   &tls.Config{RootCAs: pool},
+	}
+	go s.serve()
+
+	return s
+}
+
+func (s *papertrailServer) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+
+		s.mutex.Lock()
+		s.conns = append(s.conns, conn)
+		s.mutex.Unlock()
+
+		go s.read(conn)
+	}
+}
+
+func (s *papertrailServer) read(conn net.Conn) {
+	r := bufio.NewReader(conn)
+	for {
+		length, err := r.ReadString(' ')
+		if err != nil {
+			return
+		}
+
+		n, err := strconv.Atoi(strings.TrimSpace(length))
+		if err != nil {
+			return
+		}
+
+		msg := make([]byte, n)
+		if _, err = io.ReadFull(r, msg); err != nil {
+			return
+		}
+
+		s.mutex.Lock()
+		s.messages = append(s.messages, string(msg))
+		s.mutex.Unlock()
+	}
+}
+
+// disconnect closes the connections that the server has accepted.
+func (s *papertrailServer) disconnect() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for _, conn := range s.conns {
+		_ = conn.Close()
+	}
+	s.conns = nil
+}
+
+func (s *papertrailServer) waitForMessages(t *testing.T, n int) []string {
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		s.mutex.Lock()
+		msgs := append([]string{}, s.messages...)
+		s.mutex.Unlock()
+
+		if len(msgs) >= n {
+			return msgs
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	t.Fatalf("expected %d messages", n)
+	return nil
+}
+
+func TestPapertrailOptionsValidate(t *testing.T) {
+	assert := assert.New(t)
+
+	opts := PapertrailOptions{Address: "logs.papertrailapp.com:12345"}
+	assert.NoError(opts.Validate())
+	assert.NotEqual("", opts.Hostname)
+	assert.Equal(10*time.Second, opts.DialTimeout)
+	assert.Equal(100*time.Millisecond, opts.MinBackoff)
+	assert.Equal(30*time.Second, opts.MaxBackoff)
+
+	for _, opts := range []PapertrailOptions{
+		{},
+		{Address: "logs.papertrailapp.com"},
+		{Address: "logs.papertrailapp.com:12345", DialTimeout: -1},
+		{Address: "logs.papertrailapp.com:12345", MinBackoff: -1},
+		{Address: "logs.papertrailapp.com:12345", MinBackoff: time.Minute, MaxBackoff: time.Second},
+	} {
+		assert.Error(opts.Validate())
+	}
+}
+
+func TestPapertrailSenderWritesFramedMessages(t *testing.T) {
+	assert := assert.New(t)
+	server := newPapertrailServer(t)
+	defer server.listener.Close()
+
+	sender, err := NewPapertrailSender("my app", PapertrailOptions{
+		Address:   server.listener.Addr().String(),
+		Hostname:  "web-1",
+		TLSConfig: server.client,
+	}, LevelInfo{level.Info, level.Info})
+	require.NoError(t, err)
+	defer sender.Close()
+
+	m := message.NewDefaultMessage(level.Error, "first line\nsecond line")
+	created := m.(message.Timestamped).Timestamp()
+	sender.Send(m)
+	sender.Send(message.NewDefaultMessage(level.Debug, "below threshold"))
+	sender.Send(message.NewDefaultMessage(level.Notice, "hello"))
+
+	msgs := server.waitForMessages(t, 2)
+	require.Len(t, msgs, 2)
+	assert.Equal(fmt.Sprintf("<11>1 %s web-1 my_app %d - - first line\nsecond line",
+		created.Format(rfc5424TimeFormat), os.Getpid()), msgs[0])
+	assert.True(strings.HasPrefix(msgs[1], "<13>1 "), msgs[1])
+	assert.True(strings.HasSuffix(msgs[1], " web-1 my_app "+strconv.Itoa(os.Getpid())+" - - hello"), msgs[1])
+}
+
+func TestPapertrailSenderReconnectsAfterFailure(t *testing.T) {
+	assert := assert.New(t)
+	server := newPapertrailServer(t)
+	defer server.listener.Close()
+
+	sender, err := NewPapertrailSender("app", PapertrailOptions{
+		Address:    server.listener.Addr().String(),
+		TLSConfig:  server.client,
+		MinBackoff: 10 * time.Millisecond,
+		MaxBackoff: 20 * time.Millisecond,
+	}, LevelInfo{level.Info, level.Info})
+	require.NoError(t, err)
+	defer sender.Close()
+
+	errs := 0
+	var mutex sync.Mutex
+	require.NoError(t, sender.SetErrorHandler(func(error, message.Composer) {
+		mutex.Lock()
+		errs++
+		mutex.Unlock()
+	}))
+
+	sender.Send(message.NewDefaultMessage(level.Info, "before"))
+	server.waitForMessages(t, 1)
+	server.disconnect()
+
+	// the sender notices the broken connection on a later write,
+	// then waits before reconnecting.
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		mutex.Lock()
+		failed := errs > 0
+		mutex.Unlock()
+		if failed {
+			break
+		}
+
+		sender.Send(message.NewDefaultMessage(level.Info, "during"))
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	mutex.Lock()
+	assert.True(errs > 0)
+	mutex.Unlock()
+
+	time.Sleep(30 * time.Millisecond)
+	sender.Send(message.NewDefaultMessage(level.Info, "after"))
+	msgs := server.waitForMessages(t, 2)
+	for !strings.HasSuffix(msgs[len(msgs)-1], " after") {
+		msgs = server.waitForMessages(t, len(msgs)+1)
+	}
+
+	assert.NoError(sender.Close())
+	sender.Send(message.NewDefaultMessage(level.Info, "closed"))
+	mutex.Lock()
+	assert.True(errs > 1)
+	mutex.Unlock()
+}
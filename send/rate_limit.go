@@ -0,0 +1,182 @@
+package send
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/mongodb/grip/level"
+	"github.com/mongodb/grip/message"
+)
+
+type rateLimitedSender struct {
+	interval time.Duration
+	max      int
+
+	mutex       sync.Mutex
+	windowStart time.Time
+	sent        int
+	suppressed  int
+	priority    level.Priority
+	first       message.Composer
+	last        message.Composer
+	firstTime   time.Time
+	lastTime    time.Time
+	timer       *time.Timer
+	closed      bool
+	Sender
+}
+
+// NewRateLimitedSender wraps an existing Sender, and limits the
+// messages that it sends to maxPerInterval messages in every interval,
+// so that a burst of messages (e.g. from a crash loop) does not flood
+// senders that notify people, such as the SMTP, Slack, and XMPP
+// senders. The interval starts with the first message that the
+// sender sends after the previous interval ended.
+//
+// The sender suppresses the messages that exceed the limit, and at the
+// end of the interval sends a single message that summarizes them,
+// with the number of messages, the times of the first and last
+// messages, and their text, at the highest priority of the suppressed
+// messages. Messages that the underlying sender would not log do not
+// count toward the limit. Close sends the summary of the current
+// interval, if any, before it closes the underlying sender.
+func NewRateLimitedSender(inner Sender, interval time.Duration, maxPerInterval int) (Sender, error) {
+	if inner == nil {
+		return nil, errors.New("no underlying sender specified")
+	}
+
+	if interval <= 0 {
+		return nil, errors.New("rate limit interval must be positive")
+	}
+
+	if maxPerInterval <= 0 {
+		return nil, errors.New("rate limit must allow at least one message per interval")
+	}
+
+	return &rateLimitedSender{
+		interval: interval,
+		max:      maxPerInterval,
+		Sender:   inner,
+	}, nil
+}
+
+func (s *rateLimitedSender) Send(m message.Composer) {
+	if !s.Level().ShouldLog(m) {
+		s.Sender.Send(m)
+		return
+	}
+
+	s.mutex.Lock()
+	now := time.Now()
+
+	// after close, the underlying sender reports the message to
+	// its error handler.
+	if s.closed {
+		s.mutex.Unlock()
+		s.Sender.Send(m)
+		return
+	}
+
+	var summary message.Composer
+	if !s.windowStart.IsZero() && now.Sub(s.windowStart) >= s.interval {
+		summary = s.endWindow()
+	}
+
+	if s.windowStart.IsZero() {
+		s.windowStart = now
+	}
+
+	if s.sent < s.max {
+		s.sent++
+		s.mutex.Unlock()
+
+		if summary != nil {
+			s.Sender.Send(summary)
+		}
+		s.Sender.Send(m)
+		return
+	}
+
+	if s.suppressed == 0 {
+		s.first, s.firstTime = m, now
+		s.priority = m.Priority()
+		start := s.windowStart
+		s.timer = time.AfterFunc(start.Add(s.interval).Sub(now), func() { s.sendSummary(start) })
+	}
+	s.suppressed++
+	s.last, s.lastTime = m, now
+	if m.Priority() > s.priority {
+		s.priority = m.Priority()
+	}
+	s.mutex.Unlock()
+
+	if summary != nil {
+		s.Sender.Send(summary)
+	}
+}
+
+// sendSummary ends the interval that started at the time, unless it
+// has already ended, and sends the summary of the messages that the
+// sender suppressed in it, if any.
+func (s *rateLimitedSender) sendSummary(start time.Time) {
+	s.mutex.Lock()
+	var summary message.Composer
+	if s.windowStart.Equal(start) {
+		summary = s.endWindow()
+	}
+	s.mutex.Unlock()
+
+	if summary != nil {
+		s.Sender.Send(summary)
+	}
+}
+
+// endWindow resets the interval, and returns the summary of the
+// messages that the sender suppressed in it, or nil if it did not
+// suppress any. The caller must hold the mutex.
+func (s *rateLimitedSender) endWindow() message.Composer {
+	defer func() {
+		s.windowStart = time.Time{}
+		s.sent = 0
+		s.suppressed = 0
+		s.first, s.last = nil, nil
+		if s.timer != nil {
+			s.timer.Stop()
+			s.timer = nil
+		}
+	}()
+
+	if s.suppressed == 0 {
+		return nil
+	}
+
+	return message.NewFieldsMessage(s.priority,
+		fmt.Sprintf("suppressed %d messages between %s and %s, first: %s, last: %s",
+			s.suppressed, s.firstTime.Format(time.RFC3339), s.lastTime.Format(time.RFC3339),
+			s.first.String(), s.last.String()),
+		message.Fields{
+			"suppressed": s.suppressed,
+			"first_time": s.firstTime,
+			"last_time":  s.lastTime,
+			"first":      s.first.String(),
+			"last":       s.last.String(),
+		})
+}
+
+func (s *rateLimitedSender) Close() error {
+	s.mutex.Lock()
+	var summary message.Composer
+	if !s.closed {
+		s.closed = true
+		summary = s.endWindow()
+	}
+	s.mutex.Unlock()
+
+	if summary != nil {
+		s.Sender.Send(summary)
+	}
+
+	return s.Sender.Close()
+}
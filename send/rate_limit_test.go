@@ -0,0 +1,129 @@
+package send
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mongodb/grip/level"
+	"github.com/mongodb/grip/message"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRateLimitedSender(t *testing.T) {
+	sink := func(t *testing.T) *InternalSender {
+		s, err := NewInternalLogger("sink", LevelInfo{level.Info, level.Info})
+		require.NoError(t, err)
+		return s
+	}
+
+	t.Run("Validation", func(t *testing.T) {
+		assert := assert.New(t)
+
+		_, err := NewRateLimitedSender(nil, time.Second, 1)
+		assert.Error(err)
+		_, err = NewRateLimitedSender(sink(t), 0, 1)
+		assert.Error(err)
+		_, err = NewRateLimitedSender(sink(t), time.Second, 0)
+		assert.Error(err)
+	})
+	t.Run("SummarizesSuppressedMessages", func(t *testing.T) {
+		assert := assert.New(t)
+
+		inner := sink(t)
+		s, err := NewRateLimitedSender(inner, 100*time.Millisecond, 2)
+		require.NoError(t, err)
+		assert.Equal("sink", s.Name())
+
+		for i := 0; i < 5; i++ {
+			s.Send(message.NewDefaultMessage(level.Alert, fmt.Sprintf("crash %d", i)))
+		}
+		s.Send(message.NewDefaultMessage(level.Emergency, "crash 5"))
+		assert.Equal("crash 0", inner.GetMessage().Rendered)
+		assert.Equal("crash 1", inner.GetMessage().Rendered)
+		assert.Equal(0, inner.Len())
+
+		// messages that the underlying sender would not log do
+		// not count.
+		s.Send(message.NewDefaultMessage(level.Debug, "quiet"))
+		assert.False(inner.GetMessage().Logged)
+
+		var summary *InternalMessage
+		for deadline := time.Now().Add(time.Second); summary == nil && time.Now().Before(deadline); {
+			if inner.HasMessage() {
+				summary = inner.GetMessage()
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		require.NotNil(t, summary)
+		assert.Equal(level.Emergency, summary.Priority)
+		assert.Contains(summary.Rendered, "suppressed 4 messages between")
+		assert.Contains(summary.Rendered, "first: crash 2, last: crash 5")
+		fields := summary.Message.Raw().(message.Fields)
+		assert.Equal(4, fields["suppressed"])
+
+		// the next interval starts with the next message.
+		s.Send(message.NewDefaultMessage(level.Alert, "recovered"))
+		assert.Equal("recovered", inner.GetMessage().Rendered)
+	})
+	t.Run("EndsIntervalOnNextMessage", func(t *testing.T) {
+		assert := assert.New(t)
+
+		inner := sink(t)
+		s, err := NewRateLimitedSender(inner, time.Hour, 1)
+		require.NoError(t, err)
+		limited := s.(*rateLimitedSender)
+
+		s.Send(message.NewDefaultMessage(level.Error, "one"))
+		s.Send(message.NewDefaultMessage(level.Error, "two"))
+		assert.Equal(1, inner.Len())
+		_ = inner.GetMessage()
+
+		limited.mutex.Lock()
+		limited.windowStart = limited.windowStart.Add(-2 * time.Hour)
+		limited.mutex.Unlock()
+
+		s.Send(message.NewDefaultMessage(level.Error, "three"))
+		assert.Contains(inner.GetMessage().Rendered, "suppressed 1 messages")
+		assert.Equal("three", inner.GetMessage().Rendered)
+	})
+	t.Run("CloseSendsSummary", func(t *testing.T) {
+		assert := assert.New(t)
+
+		inner := sink(t)
+		s, err := NewRateLimitedSender(inner, time.Hour, 1)
+		require.NoError(t, err)
+
+		s.Send(message.NewDefaultMessage(level.Error, "one"))
+		s.Send(message.NewDefaultMessage(level.Error, "two"))
+		assert.NoError(s.Close())
+		assert.Equal("one", inner.GetMessage().Rendered)
+		assert.Contains(inner.GetMessage().Rendered, "suppressed 1 messages")
+		assert.NoError(s.Close())
+	})
+	t.Run("ConcurrentSends", func(t *testing.T) {
+		assert := assert.New(t)
+
+		inner := sink(t)
+		s, err := NewRateLimitedSender(inner, time.Hour, 10)
+		require.NoError(t, err)
+
+		wg := &sync.WaitGroup{}
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for j := 0; j < 10; j++ {
+					s.Send(message.NewDefaultMessage(level.Error, "burst"))
+				}
+			}()
+		}
+		wg.Wait()
+
+		assert.Equal(10, inner.Len())
+		assert.NoError(s.Close())
+		assert.Equal(11, inner.Len())
+	})
+}
@@ -0,0 +1,219 @@
+package send
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/mongodb/grip/level"
+	"github.com/mongodb/grip/message"
+)
+
+// schemaViolationSummaryInterval is how often a require fields sender
+// summarizes the messages that were missing required fields.
+const schemaViolationSummaryInterval = time.Minute
+
+// MissingPolicy controls how a require fields sender handles messages
+// that are missing required fields.
+type MissingPolicy int
+
+const (
+	// DropMissing discards the message, and is the default.
+	DropMissing MissingPolicy = iota
+
+	// DowngradeMissing lowers the priority of the message to Debug,
+	// so that the underlying sender only logs the message if it logs
+	// debug messages.
+	DowngradeMissing
+
+	// AnnotateMissing sends the message, annotated with a
+	// "_schema_violation" key that holds the missing fields.
+	AnnotateMissing
+)
+
+// Validate returns an error if the policy is not one of the defined
+// policies.
+func (p MissingPolicy) Validate() error {
+	if p < DropMissing || p > AnnotateMissing {
+		return fmt.Errorf("%d is not a valid missing field policy", p)
+	}
+
+	return nil
+}
+
+func (p MissingPolicy) String() string {
+	switch p {
+	case DropMissing:
+		return "drop"
+	case DowngradeMissing:
+		return "downgrade"
+	case AnnotateMissing:
+		return "annotate"
+	default:
+		return fmt.Sprintf("MissingPolicy(%d)", int(p))
+	}
+}
+
+type requireFieldsSender struct {
+	violations int64
+	summarized int64
+	closed     int32
+	required   []string
+	policy     MissingPolicy
+	interval   time.Duration
+	stop       chan struct{}
+	done       chan struct{}
+	closeOnce  sync.Once
+	Sender
+}
+
+// NewRequireFieldsSender wraps an existing Sender, and checks that
+// every message it sends has all of the required fields, handling
+// messages that do not according to the policy. The sender looks for
+// the fields in the raw form of messages that are message.Fields or
+// maps with string keys, such as the messages that the
+// message.NewFields constructors produce; other messages have no
+// fields.
+//
+// The sender counts the messages that are missing fields, which
+// SchemaViolations reports, and every minute that there were any,
+// sends a Warning message to the underlying sender that summarizes
+// them. Close sends a final summary before it closes the underlying
+// sender.
+func NewRequireFieldsSender(underlying Sender, required []string, policy MissingPolicy) (Sender, error) {
+	return newRequireFieldsSender(underlying, required, policy, schemaViolationSummaryInterval)
+}
+
+func newRequireFieldsSender(underlying Sender, required []string, policy MissingPolicy, interval time.Duration) (*requireFieldsSender, error) {
+	if underlying == nil {
+		return nil, errors.New("no underlying sender specified")
+	}
+
+	if len(required) == 0 {
+		return nil, errors.New("no required fields specified")
+	}
+
+	if err := policy.Validate(); err != nil {
+		return nil, err
+	}
+
+	s := &requireFieldsSender{
+		required: append([]string{}, required...),
+		policy:   policy,
+		interval: interval,
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+		Sender:   underlying,
+	}
+
+	go s.summarize()
+
+	return s, nil
+}
+
+// SchemaViolations returns the number of messages that a require
+// fields sender has handled that were missing required fields.
+func SchemaViolations(s Sender) (int64, error) {
+	sender, ok := s.(*requireFieldsSender)
+	if !ok {
+		return 0, fmt.Errorf("%s is not a require fields sender", s.Name())
+	}
+
+	return atomic.LoadInt64(&sender.violations), nil
+}
+
+func (s *requireFieldsSender) Send(m message.Composer) {
+	// after close, the underlying sender reports the message to
+	// its error handler.
+	if atomic.LoadInt32(&s.closed) != 0 || !s.Level().ShouldLog(m) {
+		s.Sender.Send(m)
+		return
+	}
+
+	missing := s.missing(m)
+	if len(missing) == 0 {
+		s.Sender.Send(m)
+		return
+	}
+
+	atomic.AddInt64(&s.violations, 1)
+
+	switch s.policy {
+	case DropMissing:
+		return
+	case DowngradeMissing:
+		_ = m.SetPriority(level.Debug)
+	case AnnotateMissing:
+		_ = m.Annotate("_schema_violation", missing)
+	}
+
+	s.Sender.Send(m)
+}
+
+// missing returns the required fields that the message does not have.
+func (s *requireFieldsSender) missing(m message.Composer) []string {
+	var fields map[string]interface{}
+	switch raw := m.Raw().(type) {
+	case message.Fields:
+		fields = raw
+	case map[string]interface{}:
+		fields = raw
+	}
+
+	missing := []string{}
+	for _, key := range s.required {
+		if _, ok := fields[key]; !ok {
+			missing = append(missing, key)
+		}
+	}
+
+	return missing
+}
+
+func (s *requireFieldsSender) summarize() {
+	defer close(s.done)
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			s.sendSummary()
+		case <-s.stop:
+			return
+		}
+	}
+}
+
+// sendSummary sends a message that summarizes the messages that were
+// missing required fields since the last summary, if there were any.
+func (s *requireFieldsSender) sendSummary() {
+	total := atomic.LoadInt64(&s.violations)
+	count := total - atomic.SwapInt64(&s.summarized, total)
+	if count <= 0 {
+		return
+	}
+
+	s.Sender.Send(message.NewFieldsMessage(level.Warning,
+		fmt.Sprintf("%d messages missing required fields (%s)", count, strings.Join(s.required, ", ")),
+		message.Fields{
+			"violations": count,
+			"required":   s.required,
+			"policy":     s.policy.String(),
+		}))
+}
+
+func (s *requireFieldsSender) Close() error {
+	s.closeOnce.Do(func() {
+		close(s.stop)
+		<-s.done
+		s.sendSummary()
+		atomic.StoreInt32(&s.closed, 1)
+	})
+
+	return s.Sender.Close()
+}
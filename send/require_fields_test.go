@@ -0,0 +1,132 @@
+package send
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mongodb/grip/level"
+	"github.com/mongodb/grip/message"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequireFieldsSenderPolicies(t *testing.T) {
+	required := []string{"service", "env"}
+	complete := func() message.Composer {
+		return message.NewFields(level.Info, message.Fields{"service": "api", "env": "prod"})
+	}
+	incomplete := func() message.Composer {
+		return message.NewFields(level.Info, message.Fields{"service": "api"})
+	}
+
+	t.Run("Drop", func(t *testing.T) {
+		assert := assert.New(t)
+
+		sink, err := NewInternalLogger("sink", LevelInfo{level.Info, level.Info})
+		require.NoError(t, err)
+		s, err := NewRequireFieldsSender(sink, required, DropMissing)
+		require.NoError(t, err)
+		assert.Equal("sink", s.Name())
+
+		s.Send(complete())
+		assert.Equal(1, sink.Len())
+		_ = sink.GetMessage()
+
+		s.Send(incomplete())
+		s.Send(message.NewDefaultMessage(level.Info, "no fields"))
+		assert.Equal(0, sink.Len())
+
+		count, err := SchemaViolations(s)
+		assert.NoError(err)
+		assert.Equal(int64(2), count)
+	})
+	t.Run("Downgrade", func(t *testing.T) {
+		assert := assert.New(t)
+
+		sink, err := NewInternalLogger("sink", LevelInfo{level.Info, level.Info})
+		require.NoError(t, err)
+		s, err := NewRequireFieldsSender(sink, required, DowngradeMissing)
+		require.NoError(t, err)
+
+		s.Send(incomplete())
+		msg := sink.GetMessage()
+		assert.Equal(level.Debug, msg.Priority)
+		assert.False(msg.Logged)
+
+		s.Send(complete())
+		assert.Equal(level.Info, sink.GetMessage().Priority)
+	})
+	t.Run("Annotate", func(t *testing.T) {
+		assert := assert.New(t)
+
+		sink, err := NewInternalLogger("sink", LevelInfo{level.Info, level.Info})
+		require.NoError(t, err)
+		s, err := NewRequireFieldsSender(sink, required, AnnotateMissing)
+		require.NoError(t, err)
+
+		s.Send(incomplete())
+		fields := sink.GetMessage().Message.Raw().(message.Fields)
+		assert.Equal([]string{"env"}, fields["_schema_violation"])
+
+		s.Send(complete())
+		assert.NotContains(sink.GetMessage().Message.Raw(), "_schema_violation")
+	})
+	t.Run("BelowThreshold", func(t *testing.T) {
+		assert := assert.New(t)
+
+		sink, err := NewInternalLogger("sink", LevelInfo{level.Info, level.Info})
+		require.NoError(t, err)
+		s, err := NewRequireFieldsSender(sink, required, AnnotateMissing)
+		require.NoError(t, err)
+
+		s.Send(message.NewFields(level.Debug, message.Fields{"service": "api"}))
+		assert.NotContains(sink.GetMessage().Message.Raw(), "_schema_violation")
+
+		count, err := SchemaViolations(s)
+		assert.NoError(err)
+		assert.Equal(int64(0), count)
+	})
+}
+
+func TestRequireFieldsSenderSummarizesViolations(t *testing.T) {
+	assert := assert.New(t)
+
+	sink, err := NewInternalLogger("sink", LevelInfo{level.Info, level.Info})
+	require.NoError(t, err)
+	s, err := newRequireFieldsSender(sink, []string{"service"}, DropMissing, 10*time.Millisecond)
+	require.NoError(t, err)
+
+	s.Send(message.NewFields(level.Info, message.Fields{"env": "prod"}))
+	s.Send(message.NewFields(level.Info, message.Fields{"env": "prod"}))
+
+	summary := sink.GetMessage()
+	assert.Equal(level.Warning, summary.Priority)
+	assert.Contains(summary.Rendered, "2 messages missing required fields (service)")
+	assert.Equal(int64(2), summary.Message.Raw().(message.Fields)["violations"])
+
+	// intervals without violations have no summary.
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(0, sink.Len())
+
+	s.Send(message.NewFields(level.Info, message.Fields{"env": "prod"}))
+	require.NoError(t, s.Close())
+	assert.Equal(int64(1), sink.GetMessage().Message.Raw().(message.Fields)["violations"])
+	assert.NoError(s.Close())
+}
+
+func TestRequireFieldsSenderConstructor(t *testing.T) {
+	assert := assert.New(t)
+
+	sink, err := NewInternalLogger("sink", LevelInfo{level.Info, level.Info})
+	require.NoError(t, err)
+
+	_, err = NewRequireFieldsSender(nil, []string{"service"}, DropMissing)
+	assert.Error(err)
+	_, err = NewRequireFieldsSender(sink, nil, DropMissing)
+	assert.Error(err)
+	_, err = NewRequireFieldsSender(sink, []string{"service"}, MissingPolicy(42))
+	assert.Error(err)
+
+	_, err = SchemaViolations(sink)
+	assert.Error(err)
+}
@@ -0,0 +1,142 @@
+package send
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mongodb/grip/message"
+)
+
+// rfc5424TimeFormat is the timestamp format of RFC 5424 syslog
+// messages, which permits at most microsecond precision.
+const rfc5424TimeFormat = "2006-01-02T15:04:05.000000Z07:00"
+
+// rfc5424Header holds the header fields of RFC 5424 syslog messages
+// that do not change from message to message.
+type rfc5424Header struct {
+	facility SyslogFacility
+	hostname string
+	appName  string
+	procID   string
+	msgID    string
+}
+
+// format renders an RFC 5424 syslog message with the severity (0, for
+// emergencies, to 7, for debugging), and the structured data, which
+// must already be rendered, or "-" if the message has none.
+func (h rfc5424Header) format(severity int, ts time.Time, structuredData, msg string) string {
+	out := fmt.Sprintf("<%d>1 %s %s %s %s %s %s",
+		int(h.facility)*8+severity,
+		ts.Format(rfc5424TimeFormat),
+		syslogHeaderField(h.hostname, 255),
+		syslogHeaderField(h.appName, 48),
+		syslogHeaderField(h.procID, 128),
+		syslogHeaderField(h.msgID, 32),
+		structuredData)
+
+	if msg != "" {
+		out += " " + msg
+	}
+
+	return out
+}
+
+// syslogHeaderField renders a value for an RFC 5424 header field,
+// which may only contain printable ASCII characters other than
+// spaces, and has a maximum length. Empty values are rendered as
+// the nil value, "-".
+func syslogHeaderField(value string, max int) string {
+	out := []byte{}
+	for i := 0; i < len(value) && len(out) < max; i++ {
+		if value[i] < 33 || value[i] > 126 {
+			out = append(out, '_')
+		} else {
+			out = append(out, value[i])
+		}
+	}
+
+	if len(out) == 0 {
+		return "-"
+	}
+
+	return string(out)
+}
+
+// rfc5424StructuredData renders the fields of Fields messages as an
+// RFC 5424 SD-ELEMENT with the id, with one SD-PARAM for every field,
+// in the order of their keys, other than the "msg" and "time" fields.
+// Nested fields render as their own params, with dotted names (e.g.
+// "request.id"), and nil values do not render. Returns the nil value,
+// "-", for other messages, and for messages without fields to render.
+func rfc5424StructuredData(id string, m message.Composer) string {
+	var fields map[string]interface{}
+	switch raw := m.Raw().(type) {
+	case message.Fields:
+		fields = raw
+	case map[string]interface{}:
+		fields = raw
+	}
+
+	params := rfc5424Params("", fields, nil)
+	if len(params) == 0 {
+		return "-"
+	}
+
+	return fmt.Sprintf("[%s %s]", syslogSDName(id), strings.Join(params, " "))
+}
+
+func rfc5424Params(prefix string, fields map[string]interface{}, out []string) []string {
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		if prefix == "" && (k == "msg" || k == "time") {
+			continue
+		}
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		switch v := fields[k].(type) {
+		case nil:
+		case message.Fields:
+			out = rfc5424Params(prefix+k+".", v, out)
+		case map[string]interface{}:
+			out = rfc5424Params(prefix+k+".", v, out)
+		case error:
+			out = append(out, fmt.Sprintf(`%s="%s"`, syslogSDName(prefix+k), escapeSDParamValue(v.Error())))
+		default:
+			out = append(out, fmt.Sprintf(`%s="%s"`, syslogSDName(prefix+k), escapeSDParamValue(fmt.Sprintf("%v", v))))
+		}
+	}
+
+	return out
+}
+
+// syslogSDName renders an RFC 5424 SD-NAME, which may only contain
+// printable ASCII characters other than spaces, '=', ']', and '"', and
+// has at most 32 characters.
+func syslogSDName(name string) string {
+	out := []byte{}
+	for i := 0; i < len(name) && len(out) < 32; i++ {
+		switch c := name[i]; {
+		case c < 33 || c > 126 || c == '=' || c == ']' || c == '"':
+			out = append(out, '_')
+		default:
+			out = append(out, c)
+		}
+	}
+
+	if len(out) == 0 {
+		return "_"
+	}
+
+	return string(out)
+}
+
+// escapeSDParamValue escapes the characters that RFC 5424 requires to
+// be escaped in SD-PARAM values: '"', '\', and ']'.
+func escapeSDParamValue(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, `]`, `\]`).Replace(value)
+}
@@ -0,0 +1,355 @@
+package send
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/mongodb/grip/level"
+	"github.com/mongodb/grip/message"
+)
+
+const rollbarEndpoint = "https://api.rollbar.com/api/1/item/"
+
+// RollbarOptions configures the Rollbar sender.
+type RollbarOptions struct {
+	// Environment is the name of the environment (e.g.
+	// "staging") in Rollbar. Defaults to "production".
+	Environment string
+
+	// CodeVersion, if specified, identifies the version of the
+	// application (e.g. a git revision).
+	CodeVersion string
+
+	// Hostname identifies the system in Rollbar. Defaults to the
+	// hostname of the system.
+	Hostname string
+
+	// Endpoint is the URL of the Rollbar item API. Defaults to
+	// Rollbar's API.
+	Endpoint string
+
+	// BufferSize is the number of items that the sender holds
+	// while it posts items to Rollbar. The sender drops items
+	// when the buffer is full. Defaults to 100.
+	BufferSize int
+
+	// Timeout limits the time the sender waits for each request
+	// to Rollbar, and for Close to post the buffered items.
+	// Defaults to 10 seconds.
+	Timeout time.Duration
+
+	// RateLimitBackoff is the time that the sender waits after
+	// Rollbar rate limits the sender, when Rollbar does not
+	// report when the limit resets. Defaults to a minute.
+	RateLimitBackoff time.Duration
+}
+
+// Validate checks the options, and sets defaults for unspecified
+// values.
+func (o *RollbarOptions) Validate() error {
+	errs := []string{}
+
+	if o.BufferSize < 0 {
+		errs = append(errs, "buffer size cannot be negative")
+	}
+
+	if o.Timeout < 0 {
+		errs = append(errs, "timeout cannot be negative")
+	}
+
+	if o.RateLimitBackoff < 0 {
+		errs = append(errs, "rate limit backoff cannot be negative")
+	}
+
+	if o.Environment == "" {
+		o.Environment = "production"
+	}
+
+	if o.Hostname == "" {
+		o.Hostname, _ = os.Hostname()
+	}
+
+	if o.Endpoint == "" {
+		o.Endpoint = rollbarEndpoint
+	}
+
+	if o.BufferSize == 0 {
+		o.BufferSize = 100
+	}
+
+	if o.Timeout == 0 {
+		o.Timeout = 10 * time.Second
+	}
+
+	if o.RateLimitBackoff == 0 {
+		o.RateLimitBackoff = time.Minute
+	}
+
+	if len(errs) > 0 {
+		return errors.New(strings.Join(errs, "; "))
+	}
+
+	return nil
+}
+
+type rollbarItem struct {
+	body []byte
+	m    message.Composer
+}
+
+type rollbarSender struct {
+	pending      int64
+	opts         RollbarOptions
+	token        string
+	client       *http.Client
+	queue        chan rollbarItem
+	done         chan struct{}
+	limitedUntil time.Time
+	closed       bool
+	mutex        sync.RWMutex
+	*Base
+}
+
+// NewRollbarSender constructs a Sender that posts messages at Error
+// priority and above to Rollbar as items, using the access token.
+// Items include the Rollbar level that corresponds to the priority of
+// the message, and the environment, code version and hostname from the
+// options. Stack messages become Rollbar traces with the stack frames
+// of the message; error messages become traces with the type and text
+// of the error. The fields of Fields messages become custom data.
+//
+// The sender posts items in the background, and reports errors to its
+// error handler. When Rollbar rate limits the sender, the sender
+// drops items, and reports an error for each item, until the limit
+// resets. Flush waits for the sender to post the buffered items, and
+// Close posts the buffered items before it returns, waiting at most
+// the Timeout.
+func NewRollbarSender(name, accessToken string, opts RollbarOptions, l LevelInfo) (Sender, error) {
+	if accessToken == "" {
+		return nil, errors.New("no rollbar access token specified")
+	}
+
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+
+	s := &rollbarSender{
+		opts:   opts,
+		token:  accessToken,
+		client: &http.Client{Timeout: opts.Timeout},
+		queue:  make(chan rollbarItem, opts.BufferSize),
+		done:   make(chan struct{}),
+		Base:   NewBase(name),
+	}
+
+	s.closer = func() error {
+		s.mutex.Lock()
+		s.closed = true
+		close(s.queue)
+		s.mutex.Unlock()
+
+		select {
+		case <-s.done:
+			return nil
+		case <-time.After(s.opts.Timeout):
+			return fmt.Errorf("timed out posting %d items to rollbar", atomic.LoadInt64(&s.pending))
+		}
+	}
+
+	go s.post()
+
+	return setup(s, name, l)
+}
+
+func (s *rollbarSender) Send(m message.Composer) {
+	if !s.Level().ShouldLog(m) || s.reportClosed(m) || m.Priority() < level.Error {
+		return
+	}
+
+	body, err := json.Marshal(map[string]interface{}{"data": s.item(m)})
+	if err != nil {
+		s.ErrorHandler(err, m)
+		return
+	}
+
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	// the sender may have closed while encoding the item.
+	if s.closed {
+		s.ErrorHandler(ErrSenderClosed, m)
+		return
+	}
+
+	atomic.AddInt64(&s.pending, 1)
+	select {
+	case s.queue <- rollbarItem{body: body, m: m}:
+	default:
+		atomic.AddInt64(&s.pending, -1)
+		s.ErrorHandler(errors.New("rollbar buffer is full"), m)
+	}
+}
+
+// Flush waits until the sender has posted all of the buffered items,
+// or until the context is canceled.
+func (s *rollbarSender) Flush(ctx context.Context) error {
+	ticker := time.NewTicker(10 * time.Millisecond)
+	defer ticker.Stop()
+
+	for atomic.LoadInt64(&s.pending) > 0 {
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	return nil
+}
+
+func (s *rollbarSender) post() {
+	defer close(s.done)
+
+	for item := range s.queue {
+		if err := s.postItem(item.body); err != nil {
+			s.ErrorHandler(err, item.m)
+		}
+		atomic.AddInt64(&s.pending, -1)
+	}
+}
+
+func (s *rollbarSender) postItem(body []byte) error {
+	if time.Now().Before(s.limitedUntil) {
+		return fmt.Errorf("rollbar rate limit exceeded until %s", s.limitedUntil.Format(time.RFC3339))
+	}
+
+	req, err := http.NewRequest("POST", s.opts.Endpoint, bytes.NewBuffer(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Rollbar-Access-Token", s.token)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(ioutil.Discard, resp.Body)
+
+	if resp.StatusCode == http.StatusTooManyRequests {
+		s.limitedUntil = rollbarRateLimitReset(resp.Header, s.opts.RateLimitBackoff)
+		return fmt.Errorf("rollbar rate limit exceeded until %s", s.limitedUntil.Format(time.RFC3339))
+	}
+
+	if resp.Header.Get("X-Rate-Limit-Remaining") == "0" {
+		s.limitedUntil = rollbarRateLimitReset(resp.Header, s.opts.RateLimitBackoff)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("rollbar responded with status %s", resp.Status)
+	}
+
+	return nil
+}
+
+// rollbarRateLimitReset returns the time that Rollbar's rate limit
+// resets, from the headers of a response, or after the backoff if the
+// headers do not report it.
+func rollbarRateLimitReset(h http.Header, backoff time.Duration) time.Time {
+	if secs, err := strconv.ParseInt(h.Get("X-Rate-Limit-Remaining-Seconds"), 10, 64); err == nil && secs >= 0 {
+		return time.Now().Add(time.Duration(secs) * time.Second)
+	}
+
+	if epoch, err := strconv.ParseInt(h.Get("X-Rate-Limit-Reset"), 10, 64); err == nil && epoch > 0 {
+		return time.Unix(epoch, 0)
+	}
+
+	return time.Now().Add(backoff)
+}
+
+func (s *rollbarSender) item(m message.Composer) map[string]interface{} {
+	item := map[string]interface{}{
+		"environment": s.opts.Environment,
+		"level":       rollbarLevel(m.Priority()),
+		"timestamp":   s.timestamp(m).Unix(),
+		"language":    "go",
+		"notifier":    map[string]string{"name": "grip"},
+		"server":      map[string]string{"host": s.opts.Hostname},
+		"body":        rollbarBody(m),
+	}
+
+	if s.opts.CodeVersion != "" {
+		item["code_version"] = s.opts.CodeVersion
+	}
+
+	if fields, ok := m.Raw().(message.Fields); ok {
+		custom := map[string]interface{}{}
+		for k, v := range fields {
+			custom[k] = v
+		}
+		item["custom"] = custom
+	}
+
+	return item
+}
+
+// rollbarBody returns a trace for stack and error messages, and a
+// text message otherwise.
+func rollbarBody(m message.Composer) map[string]interface{} {
+	if stack, ok := m.Raw().(message.StackTrace); ok && len(stack.Frames) > 0 {
+		// rollbar expects the most recent call last.
+		frames := make([]map[string]interface{}, 0, len(stack.Frames))
+		for i := len(stack.Frames) - 1; i >= 0; i-- {
+			frames = append(frames, map[string]interface{}{
+				"filename": stack.Frames[i].File,
+				"lineno":   stack.Frames[i].Line,
+				"method":   stack.Frames[i].Function,
+			})
+		}
+
+		return map[string]interface{}{"trace": map[string]interface{}{
+			"frames":    frames,
+			"exception": map[string]string{"class": "Stack", "message": stack.Message},
+		}}
+	}
+
+	if wrapped, ok := m.(interface{ Unwrap() error }); ok && wrapped.Unwrap() != nil {
+		return map[string]interface{}{"trace": map[string]interface{}{
+			"frames": []interface{}{},
+			"exception": map[string]string{
+				"class":   fmt.Sprintf("%T", wrapped.Unwrap()),
+				"message": m.String(),
+			},
+		}}
+	}
+
+	return map[string]interface{}{"message": map[string]string{"body": messageText(m, "\n")}}
+}
+
+func rollbarLevel(p level.Priority) string {
+	switch {
+	case p >= level.Critical:
+		return "critical"
+	case p >= level.Error:
+		return "error"
+	case p >= level.Warning:
+		return "warning"
+	case p >= level.Info:
+		return "info"
+	default:
+		return "debug"
+	}
+}
@@ -0,0 +1,183 @@
+package send
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mongodb/grip/level"
+	"github.com/mongodb/grip/message"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// rollbarServer records the items posted to it, and responds with
+// the configured status and headers.
+type rollbarServer struct {
+	*httptest.Server
+	mutex   sync.Mutex
+	items   []map[string]interface{}
+	tokens  []string
+	status  int
+	headers map[string]string
+}
+
+func newRollbarServer() *rollbarServer {
+	s := &rollbarServer{status: http.StatusOK}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		payload := struct {
+			Data map[string]interface{} `json:"data"`
+		}{}
+		_ = json.NewDecoder(r.Body).Decode(&payload)
+
+		s.mutex.Lock()
+		defer s.mutex.Unlock()
+		s.items = append(s.items, payload.Data)
+		s.tokens = append(s.tokens, r.Header.Get("X-Rollbar-Access-Token"))
+		for k, v := range s.headers {
+			w.Header().Set(k, v)
+		}
+		w.WriteHeader(s.status)
+	}))
+
+	return s
+}
+
+func (s *rollbarServer) received() []map[string]interface{} {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return append([]map[string]interface{}{}, s.items...)
+}
+
+func TestRollbarOptionsValidate(t *testing.T) {
+	assert := assert.New(t)
+
+	opts := RollbarOptions{}
+	assert.NoError(opts.Validate())
+	assert.Equal("production", opts.Environment)
+	assert.Equal(rollbarEndpoint, opts.Endpoint)
+	assert.NotEqual("", opts.Hostname)
+	assert.Equal(100, opts.BufferSize)
+	assert.Equal(10*time.Second, opts.Timeout)
+	assert.Equal(time.Minute, opts.RateLimitBackoff)
+
+	for _, opts := range []RollbarOptions{
+		{BufferSize: -1},
+		{Timeout: -1},
+		{RateLimitBackoff: -1},
+	} {
+		assert.Error(opts.Validate())
+	}
+
+	_, err := NewRollbarSender("app", "", RollbarOptions{}, LevelInfo{level.Info, level.Info})
+	assert.Error(err)
+}
+
+func TestRollbarSenderPostsItems(t *testing.T) {
+	assert := assert.New(t)
+	server := newRollbarServer()
+	defer server.Close()
+
+	sender, err := NewRollbarSender("app", "token", RollbarOptions{
+		Environment: "staging",
+		CodeVersion: "abc123",
+		Hostname:    "web-1",
+		Endpoint:    server.URL,
+	}, LevelInfo{level.Info, level.Info})
+	require.NoError(t, err)
+
+	sender.Send(message.NewDefaultMessage(level.Warning, "below error"))
+	sender.Send(message.NewDefaultMessage(level.Error, "failed"))
+	sender.Send(message.NewFieldsMessage(level.Critical, "fields", message.Fields{"user": "alice"}))
+	stack := message.NewStack(1, "stack")
+	require.NoError(t, stack.SetPriority(level.Critical))
+	sender.Send(stack)
+	sender.Send(message.NewErrorMessage(level.Alert, errors.New("boom")))
+	require.NoError(t, sender.Close())
+
+	items := server.received()
+	require.Len(t, items, 4)
+	for idx, item := range items {
+		assert.Equal("staging", item["environment"])
+		assert.Equal("abc123", item["code_version"])
+		assert.Equal(map[string]interface{}{"host": "web-1"}, item["server"])
+		assert.Equal("token", server.tokens[idx])
+	}
+
+	assert.Equal("error", items[0]["level"])
+	assert.Equal(map[string]interface{}{"message": map[string]interface{}{"body": "failed"}}, items[0]["body"])
+	assert.Nil(items[0]["custom"])
+
+	assert.Equal("critical", items[1]["level"])
+	custom := items[1]["custom"].(map[string]interface{})
+	assert.Equal("alice", custom["user"])
+
+	assert.Equal("critical", items[2]["level"])
+	trace := items[2]["body"].(map[string]interface{})["trace"].(map[string]interface{})
+	frames := trace["frames"].([]interface{})
+	require.NotEmpty(t, frames)
+	last := frames[len(frames)-1].(map[string]interface{})
+	assert.Contains(last["filename"], "rollbar_test.go")
+	assert.Contains(last["method"], "TestRollbarSenderPostsItems")
+	assert.Equal("Stack", trace["exception"].(map[string]interface{})["class"])
+
+	trace = items[3]["body"].(map[string]interface{})["trace"].(map[string]interface{})
+	assert.Equal(map[string]interface{}{"class": "*errors.errorString", "message": "boom"}, trace["exception"])
+}
+
+func TestRollbarSenderRespectsRateLimits(t *testing.T) {
+	assert := assert.New(t)
+	server := newRollbarServer()
+	defer server.Close()
+	server.status = http.StatusTooManyRequests
+	server.headers = map[string]string{"X-Rate-Limit-Remaining-Seconds": "60"}
+
+	sender, err := NewRollbarSender("app", "token", RollbarOptions{Endpoint: server.URL}, LevelInfo{level.Info, level.Info})
+	require.NoError(t, err)
+
+	var mutex sync.Mutex
+	errs := 0
+	require.NoError(t, sender.SetErrorHandler(func(error, message.Composer) {
+		mutex.Lock()
+		errs++
+		mutex.Unlock()
+	}))
+
+	for i := 0; i < 5; i++ {
+		sender.Send(message.NewDefaultMessage(level.Error, "limited"))
+	}
+	require.NoError(t, sender.Close())
+
+	assert.Len(server.received(), 1)
+	mutex.Lock()
+	assert.Equal(5, errs)
+	mutex.Unlock()
+}
+
+func TestRollbarRateLimitReset(t *testing.T) {
+	assert := assert.New(t)
+
+	reset := rollbarRateLimitReset(http.Header{"X-Rate-Limit-Remaining-Seconds": {"30"}}, time.Hour)
+	assert.WithinDuration(time.Now().Add(30*time.Second), reset, time.Second)
+
+	epoch := time.Now().Add(time.Minute).Unix()
+	reset = rollbarRateLimitReset(http.Header{"X-Rate-Limit-Reset": {strconv.FormatInt(epoch, 10)}}, time.Hour)
+	assert.Equal(time.Unix(epoch, 0), reset)
+
+	reset = rollbarRateLimitReset(http.Header{}, time.Hour)
+	assert.WithinDuration(time.Now().Add(time.Hour), reset, time.Second)
+}
+
+func init() {
+	closeConformanceSenders["rollbar"] = func(t *testing.T, _ string) (Sender, func()) {
+		server := newRollbarServer()
+		s, err := NewRollbarSender("rollbar", "token", RollbarOptions{Endpoint: server.URL}, closeConformanceLevel)
+		require.NoError(t, err)
+		return s, server.Close
+	}
+}
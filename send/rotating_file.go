@@ -0,0 +1,461 @@
+package send
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mongodb/grip/level"
+	"github.com/mongodb/grip/message"
+)
+
+// rotatedFileTimeFormat is the format of the timestamp suffix of
+// rotated files, which sorts in the order that the files rotated.
+const rotatedFileTimeFormat = "20060102T150405.000000000"
+
+// NewRotatingFileLogger constructs a Sender that writes log output to
+// a file, like the sender that NewFileLogger constructs, and rotates
+// the file when it reaches the maximum size, in bytes, or when the
+// sender has written to it for the maximum age. Rotation renames the
+// file with a suffix of the time of the rotation (e.g.
+// "app.log.20240102T150405.000000000"), and opens a new file, so that
+// the sender does not need an external log rotation tool. The sender
+// keeps the maxRetainedFiles most recently rotated files, and removes
+// the older files.
+//
+// A zero maximum size, age, or number of retained files disables that
+// limit. The sender reports errors that rotating the file produces to
+// its error handler, and continues to write to the current file.
+func NewRotatingFileLogger(name, baseFileName string, maxSizeBytes int64, maxAgeDuration time.Duration, maxRetainedFiles int, l LevelInfo) (Sender, error) {
+	if baseFileName == "" {
+		return nil, errors.New("no file name specified")
+	}
+
+	errs := []string{}
+	if maxSizeBytes < 0 {
+		errs = append(errs, "max size cannot be negative")
+	}
+
+	if maxAgeDuration < 0 {
+		errs = append(errs, "max age cannot be negative")
+	}
+
+	if maxRetainedFiles < 0 {
+		errs = append(errs, "max retained files cannot be negative")
+	}
+
+	if len(errs) > 0 {
+		return nil, errors.New(strings.Join(errs, "; "))
+	}
+
+	return newRotatingFileLogger(name, &rotatingFile{
+		path:     baseFileName,
+		maxSize:  maxSizeBytes,
+		maxAge:   maxAgeDuration,
+		maxFiles: maxRetainedFiles,
+	}, l)
+}
+
+// FileOptions configures the rotation of the file that a file sender
+// writes to.
+type FileOptions struct {
+	// MaxSizeBytes is the size at which the sender rotates the
+	// file. By default, the sender does not rotate the file
+	// because of its size.
+	MaxSizeBytes int64
+
+	// MaxAge is how long the sender writes to a file before it
+	// rotates the file. By default, the sender does not rotate the
+	// file because of its age.
+	MaxAge time.Duration
+
+	// MaxBackups is the number of rotated files that the sender
+	// keeps. Defaults to 5.
+	MaxBackups int
+
+	// Compress, if set, compresses rotated files with gzip, in the
+	// background, renaming "<path>.1" to "<path>.1.gz". The sender
+	// never compresses the file that it writes to. With
+	// compression, the sender renames the backups in the background
+	// as well, after it finishes compressing the previous backup,
+	// so that writes never wait for compression.
+	Compress bool
+}
+
+// Validate checks the options, and sets defaults for unspecified
+// values.
+func (o *FileOptions) Validate() error {
+	errs := []string{}
+
+	if o.MaxSizeBytes < 0 {
+		errs = append(errs, "max size cannot be negative")
+	}
+
+	if o.MaxAge < 0 {
+		errs = append(errs, "max age cannot be negative")
+	}
+
+	if o.MaxBackups < 0 {
+		errs = append(errs, "max backups cannot be negative")
+	}
+
+	if o.MaxBackups == 0 {
+		o.MaxBackups = 5
+	}
+
+	if len(errs) > 0 {
+		return errors.New(strings.Join(errs, "; "))
+	}
+
+	return nil
+}
+
+// NewFileLoggerWithOptions constructs a Sender that writes log output
+// to a file, like the sender that NewFileLogger constructs, and
+// rotates the file when it reaches the MaxSizeBytes, or when the
+// sender has written to it for the MaxAge. Rotation renames the file
+// to "<path>.1", after renaming the older backups to "<path>.2",
+// "<path>.3", and so on, and removing the backups beyond the
+// MaxBackups, and opens a new file. The sender reports errors that
+// rotating or compressing the file produces to its error handler, and
+// continues to write to the current file. Close waits for the sender
+// to finish compressing rotated files.
+func NewFileLoggerWithOptions(name, filePath string, opts FileOptions, l LevelInfo) (Sender, error) {
+	if filePath == "" {
+		return nil, errors.New("no file name specified")
+	}
+
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+
+	return newRotatingFileLogger(name, &rotatingFile{
+		path:     filePath,
+		maxSize:  opts.MaxSizeBytes,
+		maxAge:   opts.MaxAge,
+		maxFiles: opts.MaxBackups,
+		numbered: true,
+		compress: opts.Compress,
+	}, l)
+}
+
+func newRotatingFileLogger(name string, f *rotatingFile, l LevelInfo) (Sender, error) {
+	if err := f.open(); err != nil {
+		return nil, fmt.Errorf("error opening logging file, %s", err.Error())
+	}
+
+	s := &nativeLogger{
+		Base:       NewBase(""),
+		file:       f,
+		timestamps: true,
+		namePrefix: defaultNamePrefix,
+	}
+
+	if err := s.SetFormatter(MakeDefaultFormatter()); err != nil {
+		return nil, err
+	}
+
+	s.level = LevelInfo{level.Trace, level.Trace}
+
+	s.reset = func() {
+		s.logger = log.New(f, s.prefix(), 0)
+	}
+
+	s.closer = f.Close
+	f.report = func(err error) { s.ErrorHandler(err, message.NewString(f.path)) }
+
+	return setup(s, name, l)
+}
+
+// rotatingFile is a writer that rotates the file that it writes to.
+// Each call to Write is a single line of log output, which the file
+// writes entirely before or after rotating. Rotated files have a
+// timestamp suffix, or, if numbered, a number suffix that increases
+// with their age, and which the file can compress in the background.
+type rotatingFile struct {
+	path     string
+	maxSize  int64
+	maxAge   time.Duration
+	maxFiles int
+	numbered bool
+	compress bool
+
+	// report handles the errors of background compression.
+	report      func(error)
+	compressing sync.WaitGroup
+
+	// shifted is closed when the most recent background rotation
+	// has renamed and compressed the backups.
+	shifted chan struct{}
+
+	file   *os.File
+	size   int64
+	opened time.Time
+	mutex  sync.Mutex
+}
+
+func (f *rotatingFile) Write(p []byte) (int, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	var rerr error
+	if f.shouldRotate(len(p)) {
+		rerr = f.rotate()
+	}
+
+	n, err := f.file.Write(p)
+	f.size += int64(n)
+	if err != nil {
+		return n, err
+	}
+
+	// the line is written, so report rotation errors without
+	// losing the message.
+	return n, rerr
+}
+
+// shouldRotate returns true if writing the number of bytes would make
+// the file too large, or the file is too old. Empty files never
+// rotate. The caller must hold the mutex.
+func (f *rotatingFile) shouldRotate(n int) bool {
+	if f.size == 0 {
+		return false
+	}
+
+	if f.maxSize > 0 && f.size+int64(n) > f.maxSize {
+		return true
+	}
+
+	return f.maxAge > 0 && time.Since(f.opened) >= f.maxAge
+}
+
+// open opens the file for appending. The caller must hold the mutex.
+func (f *rotatingFile) open() error {
+	file, err := os.OpenFile(f.path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0666)
+	if err != nil {
+		return err
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		return err
+	}
+
+	f.file = file
+	f.size = info.Size()
+	f.opened = time.Now()
+	return nil
+}
+
+// rotate renames the file, opens a new file, and removes the oldest
+// rotated files. If the file cannot be closed or renamed, the file
+// still reopens the current path, so that writes continue, and
+// returns the errors. The caller must hold the mutex.
+func (f *rotatingFile) rotate() error {
+	errs := []string{}
+	if err := f.file.Close(); err != nil {
+		errs = append(errs, fmt.Sprintf("closing %s for rotation: %s", f.path, err.Error()))
+	}
+
+	var rerr error
+	if f.numbered {
+		rerr = f.shift()
+	} else {
+		rerr = os.Rename(f.path, f.path+"."+time.Now().UTC().Format(rotatedFileTimeFormat))
+	}
+
+	if err := f.open(); err != nil {
+		errs = append(errs, fmt.Sprintf("reopening %s after rotation: %s", f.path, err.Error()))
+		return errors.New(strings.Join(errs, "; "))
+	}
+
+	if rerr != nil {
+		// retry the rotation after the next interval, rather
+		// than before every write.
+		f.opened = time.Now()
+		errs = append(errs, fmt.Sprintf("rotating %s: %s", f.path, rerr.Error()))
+	} else if !f.numbered {
+		if err := f.prune(); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+
+	if len(errs) > 0 {
+		return errors.New(strings.Join(errs, "; "))
+	}
+
+	return nil
+}
+
+// shift renames the file to "<path>.1", after renaming the older
+// numbered files, compressed or not, to make room and removing the
+// oldest. If compression is enabled, shift renames the file aside,
+// and renames the backups and compresses the file in the background,
+// after the previous rotation has finished, so that writes do not
+// wait for compression. The caller must hold the mutex.
+func (f *rotatingFile) shift() error {
+	if !f.compress {
+		return f.shiftBackups(f.path)
+	}
+
+	rotated := f.path + ".rotating." + time.Now().UTC().Format(rotatedFileTimeFormat)
+	if err := os.Rename(f.path, rotated); err != nil {
+		return err
+	}
+
+	previous := f.shifted
+	done := make(chan struct{})
+	f.shifted = done
+
+	f.compressing.Add(1)
+	go func() {
+		defer f.compressing.Done()
+		defer close(done)
+
+		// the previous rotated file must be compressed before
+		// it is renamed.
+		if previous != nil {
+			<-previous
+		}
+
+		backup := fmt.Sprintf("%s.1", f.path)
+		var err error
+		if err = f.shiftBackups(rotated); err != nil {
+			err = fmt.Errorf("rotating %s: %s", f.path, err.Error())
+		} else if err = compressFile(backup); err != nil {
+			err = fmt.Errorf("compressing %s: %s", backup, err.Error())
+		}
+
+		if err != nil && f.report != nil {
+			f.report(err)
+		}
+	}()
+
+	return nil
+}
+
+// shiftBackups renames the numbered files to make room for the
+// rotated file, removing the oldest, and renames the rotated file to
+// "<path>.1".
+func (f *rotatingFile) shiftBackups(rotated string) error {
+	numbered := func(n int) string { return fmt.Sprintf("%s.%d", f.path, n) }
+
+	for _, suffix := range []string{"", ".gz"} {
+		if err := os.Remove(numbered(f.maxFiles) + suffix); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+
+		for n := f.maxFiles - 1; n >= 1; n-- {
+			if err := os.Rename(numbered(n)+suffix, numbered(n+1)+suffix); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+		}
+	}
+
+	return os.Rename(rotated, numbered(1))
+}
+
+// compressFile replaces the file with a gzip compressed copy, with a
+// ".gz" suffix. If compression fails, the file remains uncompressed.
+func compressFile(path string) error {
+	in, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	tmp := path + ".gz.tmp"
+	out, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0666)
+	if err != nil {
+		return err
+	}
+
+	zw := gzip.NewWriter(out)
+	_, err = io.Copy(zw, in)
+	if cerr := zw.Close(); err == nil {
+		err = cerr
+	}
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp, path+".gz")
+	}
+	if err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+
+	return os.Remove(path)
+}
+
+// prune removes the oldest rotated files, so that at most maxFiles
+// rotated files remain. The caller must hold the mutex.
+func (f *rotatingFile) prune() error {
+	if f.maxFiles == 0 {
+		return nil
+	}
+
+	matches, err := filepath.Glob(f.path + ".*")
+	if err != nil {
+		return err
+	}
+
+	rotated := []string{}
+	for _, match := range matches {
+		suffix := strings.TrimPrefix(match, f.path+".")
+		if _, err := time.Parse(rotatedFileTimeFormat, suffix); err == nil {
+			rotated = append(rotated, match)
+		}
+	}
+
+	if len(rotated) <= f.maxFiles {
+		return nil
+	}
+	sort.Strings(rotated)
+
+	errs := []string{}
+	for _, old := range rotated[:len(rotated)-f.maxFiles] {
+		if err := os.Remove(old); err != nil && !os.IsNotExist(err) {
+			errs = append(errs, err.Error())
+		}
+	}
+
+	if len(errs) > 0 {
+		return errors.New(strings.Join(errs, "; "))
+	}
+
+	return nil
+}
+
+// Sync commits the contents of the current file to stable storage.
+func (f *rotatingFile) Sync() error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	return f.file.Sync()
+}
+
+func (f *rotatingFile) Close() error {
+	f.mutex.Lock()
+	err := f.file.Sync()
+	if cerr := f.file.Close(); err == nil {
+		err = cerr
+	}
+	f.mutex.Unlock()
+
+	// wait without the mutex, in case handling a compression error
+	// writes to this file.
+	f.compressing.Wait()
+
+	return err
+}
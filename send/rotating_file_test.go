@@ -0,0 +1,359 @@
+package send
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mongodb/grip/level"
+	"github.com/mongodb/grip/message"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// rotatedFiles returns the rotated files of the path, oldest first.
+func rotatedFiles(t *testing.T, path string) []string {
+	matches, err := filepath.Glob(path + ".*")
+	require.NoError(t, err)
+	sort.Strings(matches)
+
+	return matches
+}
+
+func readLines(t *testing.T, path string) []string {
+	data, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+
+	return strings.Split(strings.TrimSpace(string(data)), "\n")
+}
+
+func TestRotatingFileLoggerRotatesBySize(t *testing.T) {
+	dir, err := ioutil.TempDir("", "grip-rotating")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "app.log")
+
+	s, err := NewRotatingFileLogger("app", path, 200, 0, 0, LevelInfo{level.Info, level.Info})
+	require.NoError(t, err)
+
+	for i := 0; i < 10; i++ {
+		s.Send(message.NewDefaultMessage(level.Info, fmt.Sprintf("message number %d", i)))
+	}
+	require.NoError(t, s.Close())
+
+	rotated := rotatedFiles(t, path)
+	require.NotEmpty(t, rotated)
+
+	lines := []string{}
+	for _, fn := range append(rotated, path) {
+		info, err := os.Stat(fn)
+		require.NoError(t, err)
+		assert.True(t, info.Size() <= 200, "%s is %d bytes", fn, info.Size())
+		lines = append(lines, readLines(t, fn)...)
+	}
+
+	require.Len(t, lines, 10)
+	for i, line := range lines {
+		assert.Contains(t, line, fmt.Sprintf("message number %d", i))
+	}
+}
+
+func TestRotatingFileLoggerRotatesByAge(t *testing.T) {
+	dir, err := ioutil.TempDir("", "grip-rotating")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "app.log")
+
+	s, err := NewRotatingFileLogger("app", path, 0, 50*time.Millisecond, 0, LevelInfo{level.Info, level.Info})
+	require.NoError(t, err)
+
+	s.Send(message.NewDefaultMessage(level.Info, "first"))
+	s.Send(message.NewDefaultMessage(level.Info, "second"))
+	assert.Empty(t, rotatedFiles(t, path))
+
+	time.Sleep(60 * time.Millisecond)
+	s.Send(message.NewDefaultMessage(level.Info, "third"))
+	require.NoError(t, s.Close())
+
+	rotated := rotatedFiles(t, path)
+	require.Len(t, rotated, 1)
+	assert.Len(t, readLines(t, rotated[0]), 2)
+
+	lines := readLines(t, path)
+	require.Len(t, lines, 1)
+	assert.Contains(t, lines[0], "third")
+}
+
+func TestRotatingFileLoggerPrunesRetainedFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "grip-rotating")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "app.log")
+
+	// files that are not rotated files are not pruned.
+	require.NoError(t, ioutil.WriteFile(path+".bak", []byte("keep"), 0644))
+
+	s, err := NewRotatingFileLogger("app", path, 1, 0, 2, LevelInfo{level.Info, level.Info})
+	require.NoError(t, err)
+
+	for i := 0; i < 6; i++ {
+		s.Send(message.NewDefaultMessage(level.Info, fmt.Sprintf("message number %d", i)))
+	}
+	require.NoError(t, s.Close())
+
+	rotated := rotatedFiles(t, path)
+	require.Len(t, rotated, 3)
+	assert.Equal(t, path+".bak", rotated[2])
+	assert.Contains(t, readLines(t, rotated[0])[0], "message number 3")
+	assert.Contains(t, readLines(t, rotated[1])[0], "message number 4")
+	assert.Contains(t, readLines(t, path)[0], "message number 5")
+}
+
+func TestRotatingFileLoggerReportsFailedRotation(t *testing.T) {
+	dir, err := ioutil.TempDir("", "grip-rotating")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "app.log")
+
+	s, err := NewRotatingFileLogger("app", path, 1, 0, 0, LevelInfo{level.Info, level.Info})
+	require.NoError(t, err)
+
+	var handled []error
+	require.NoError(t, s.SetErrorHandler(func(err error, _ message.Composer) {
+		if err != nil {
+			handled = append(handled, err)
+		}
+	}))
+
+	s.Send(message.NewDefaultMessage(level.Info, "before"))
+
+	// removing the file makes the rename fail.
+	require.NoError(t, os.Remove(path))
+	s.Send(message.NewDefaultMessage(level.Info, "after"))
+	require.NoError(t, s.Close())
+
+	require.Len(t, handled, 1)
+	assert.Contains(t, handled[0].Error(), "rotating "+path)
+	assert.Empty(t, rotatedFiles(t, path))
+
+	lines := readLines(t, path)
+	require.Len(t, lines, 1)
+	assert.Contains(t, lines[0], "after")
+}
+
+func TestRotatingFileLoggerRotatesAfterCloseErrors(t *testing.T) {
+	dir, err := ioutil.TempDir("", "grip-rotating")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "app.log")
+
+	s, err := NewRotatingFileLogger("app", path, 1, 0, 0, LevelInfo{level.Info, level.Info})
+	require.NoError(t, err)
+
+	var handled []error
+	require.NoError(t, s.SetErrorHandler(func(err error, _ message.Composer) {
+		if err != nil {
+			handled = append(handled, err)
+		}
+	}))
+
+	s.Send(message.NewDefaultMessage(level.Info, "before"))
+
+	// closing the file makes closing it for rotation fail.
+	require.NoError(t, s.(*nativeLogger).file.(*rotatingFile).file.Close())
+	s.Send(message.NewDefaultMessage(level.Info, "after"))
+	s.Send(message.NewDefaultMessage(level.Info, "later"))
+	require.NoError(t, s.Close())
+
+	require.Len(t, handled, 1)
+	assert.Contains(t, handled[0].Error(), "closing "+path+" for rotation")
+
+	rotated := rotatedFiles(t, path)
+	require.Len(t, rotated, 2)
+	assert.Contains(t, readLines(t, rotated[0])[0], "before")
+	assert.Contains(t, readLines(t, rotated[1])[0], "after")
+	assert.Contains(t, readLines(t, path)[0], "later")
+}
+
+func TestRotatingFileLoggerConcurrentSends(t *testing.T) {
+	dir, err := ioutil.TempDir("", "grip-rotating")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "app.log")
+
+	s, err := NewRotatingFileLogger("app", path, 512, 0, 0, LevelInfo{level.Info, level.Info})
+	require.NoError(t, err)
+
+	wg := &sync.WaitGroup{}
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 25; j++ {
+				s.Send(message.NewDefaultMessage(level.Info, fmt.Sprintf("worker %d message %d", i, j)))
+			}
+		}(i)
+	}
+	wg.Wait()
+	require.NoError(t, s.Close())
+
+	count := 0
+	for _, fn := range append(rotatedFiles(t, path), path) {
+		for _, line := range readLines(t, fn) {
+			assert.Contains(t, line, "worker")
+			count++
+		}
+	}
+	assert.Equal(t, 200, count)
+}
+
+func TestRotatingFileLoggerValidatesLimits(t *testing.T) {
+	_, err := NewRotatingFileLogger("app", "", 0, 0, 0, LevelInfo{level.Info, level.Info})
+	assert.Error(t, err)
+
+	_, err = NewRotatingFileLogger("app", "app.log", -1, -time.Second, -1, LevelInfo{level.Info, level.Info})
+	require.Error(t, err)
+	assert.Equal(t, "max size cannot be negative; max age cannot be negative; max retained files cannot be negative", err.Error())
+}
+
+func TestFileLoggerWithOptionsRotatesNumberedBackups(t *testing.T) {
+	dir, err := ioutil.TempDir("", "grip-rotating")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "app.log")
+
+	s, err := NewFileLoggerWithOptions("app", path, FileOptions{MaxSizeBytes: 1, MaxBackups: 2}, LevelInfo{level.Info, level.Info})
+	require.NoError(t, err)
+
+	for i := 0; i < 4; i++ {
+		s.Send(message.NewDefaultMessage(level.Info, fmt.Sprintf("message number %d", i)))
+	}
+	require.NoError(t, s.Close())
+
+	assert.Equal(t, []string{path + ".1", path + ".2"}, rotatedFiles(t, path))
+	assert.Contains(t, readLines(t, path+".2")[0], "message number 1")
+	assert.Contains(t, readLines(t, path+".1")[0], "message number 2")
+	assert.Contains(t, readLines(t, path)[0], "message number 3")
+}
+
+func TestFileLoggerWithOptionsRotatesByAge(t *testing.T) {
+	dir, err := ioutil.TempDir("", "grip-rotating")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "app.log")
+
+	s, err := NewFileLoggerWithOptions("app", path, FileOptions{MaxAge: 50 * time.Millisecond}, LevelInfo{level.Info, level.Info})
+	require.NoError(t, err)
+
+	s.Send(message.NewDefaultMessage(level.Info, "first"))
+	time.Sleep(60 * time.Millisecond)
+	s.Send(message.NewDefaultMessage(level.Info, "second"))
+	time.Sleep(60 * time.Millisecond)
+	s.Send(message.NewDefaultMessage(level.Info, "third"))
+	require.NoError(t, s.Close())
+
+	assert.Equal(t, []string{path + ".1", path + ".2"}, rotatedFiles(t, path))
+	assert.Contains(t, readLines(t, path+".2")[0], "first")
+	assert.Contains(t, readLines(t, path+".1")[0], "second")
+	assert.Contains(t, readLines(t, path)[0], "third")
+}
+
+func TestFileLoggerWithOptionsCompressesBackups(t *testing.T) {
+	dir, err := ioutil.TempDir("", "grip-rotating")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "app.log")
+
+	s, err := NewFileLoggerWithOptions("app", path, FileOptions{MaxSizeBytes: 1, MaxBackups: 2, Compress: true}, LevelInfo{level.Info, level.Info})
+	require.NoError(t, err)
+
+	for i := 0; i < 4; i++ {
+		s.Send(message.NewDefaultMessage(level.Info, fmt.Sprintf("message number %d", i)))
+	}
+	require.NoError(t, s.Close())
+
+	assert.Equal(t, []string{path + ".1.gz", path + ".2.gz"}, rotatedFiles(t, path))
+	for n, expected := range map[int]string{1: "message number 2", 2: "message number 1"} {
+		f, err := os.Open(fmt.Sprintf("%s.%d.gz", path, n))
+		require.NoError(t, err)
+		zr, err := gzip.NewReader(f)
+		require.NoError(t, err)
+		data, err := ioutil.ReadAll(zr)
+		require.NoError(t, err)
+		require.NoError(t, f.Close())
+
+		lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+		require.Len(t, lines, 1)
+		assert.Contains(t, lines[0], expected)
+	}
+
+	// the active file is never compressed.
+	assert.Contains(t, readLines(t, path)[0], "message number 3")
+}
+
+func TestFileLoggerWithOptionsReportsCompressionErrors(t *testing.T) {
+	dir, err := ioutil.TempDir("", "grip-rotating")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "app.log")
+
+	// a directory in the place of the temporary file makes
+	// compression fail.
+	require.NoError(t, os.Mkdir(path+".1.gz.tmp", 0700))
+
+	s, err := NewFileLoggerWithOptions("app", path, FileOptions{MaxSizeBytes: 1, MaxBackups: 2, Compress: true}, LevelInfo{level.Info, level.Info})
+	require.NoError(t, err)
+
+	// the handler writes to the file, which rotates it while the
+	// previous rotated file is compressing.
+	once := &sync.Once{}
+	reported := make(chan error, 1)
+	require.NoError(t, s.SetErrorHandler(func(err error, _ message.Composer) {
+		once.Do(func() {
+			s.Send(message.NewDefaultMessage(level.Info, "compression failed"))
+			reported <- err
+		})
+	}))
+
+	s.Send(message.NewDefaultMessage(level.Info, "first"))
+	s.Send(message.NewDefaultMessage(level.Info, "second"))
+
+	select {
+	case err := <-reported:
+		assert.Contains(t, err.Error(), "compressing "+path+".1")
+	case <-time.After(5 * time.Second):
+		t.Fatal("compression error was not reported")
+	}
+
+	closed := make(chan error, 1)
+	go func() { closed <- s.Close() }()
+	select {
+	case err := <-closed:
+		assert.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("close did not return")
+	}
+
+	assert.Contains(t, readLines(t, path)[0], "compression failed")
+}
+
+func TestFileOptionsValidate(t *testing.T) {
+	opts := FileOptions{}
+	require.NoError(t, opts.Validate())
+	assert.Equal(t, 5, opts.MaxBackups)
+
+	opts = FileOptions{MaxSizeBytes: -1, MaxAge: -time.Second, MaxBackups: -1}
+	err := opts.Validate()
+	require.Error(t, err)
+	assert.Equal(t, "max size cannot be negative; max age cannot be negative; max backups cannot be negative", err.Error())
+
+	_, err = NewFileLoggerWithOptions("app", "", FileOptions{}, LevelInfo{level.Info, level.Info})
+	assert.Error(t, err)
+}
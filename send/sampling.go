@@ -0,0 +1,213 @@
+package send
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/mongodb/grip/level"
+	"github.com/mongodb/grip/message"
+)
+
+// SamplingOptions configures a sampling sender.
+type SamplingOptions struct {
+	// Rate is the fraction, between 0 and 1, of the sampled
+	// messages that the sender forwards to the underlying sender.
+	Rate float64
+
+	// Rates, if specified, are the fractions of the sampled
+	// messages, by priority, that the sender forwards, for the
+	// priorities that do not use the Rate (e.g. to sample Debug
+	// messages more than Info messages).
+	Rates map[level.Priority]float64
+
+	// Threshold is the highest priority of the messages that the
+	// sender samples; it forwards all messages with a higher
+	// priority. Defaults to Notice, so that warnings and more
+	// severe messages are always forwarded. The sender never
+	// samples Critical, Alert, or Emergency messages, regardless
+	// of the threshold.
+	Threshold level.Priority
+
+	// Random, if specified, returns the random values, between 0
+	// and 1, that decide whether the sender forwards each sampled
+	// message, for deterministic tests. It must be safe for
+	// concurrent use. By default, the sender uses its own
+	// generator.
+	Random func() float64
+}
+
+// Validate checks the options, and sets defaults for unspecified
+// values.
+func (o *SamplingOptions) Validate() error {
+	errs := []string{}
+
+	if math.IsNaN(o.Rate) || o.Rate < 0 || o.Rate > 1 {
+		errs = append(errs, "sampling rate must be between 0 and 1")
+	}
+
+	if o.Threshold == level.Invalid {
+		o.Threshold = level.Notice
+	} else if !level.IsValidPriority(o.Threshold) {
+		errs = append(errs, fmt.Sprintf("%d is not a valid sampling threshold", o.Threshold))
+	}
+
+	for p, rate := range o.Rates {
+		if math.IsNaN(rate) || rate < 0 || rate > 1 {
+			errs = append(errs, fmt.Sprintf("sampling rate for %s must be between 0 and 1", p))
+		} else if !level.IsValidPriority(p) || p > o.Threshold || p >= level.Critical {
+			errs = append(errs, fmt.Sprintf("%d is not a sampled priority", p))
+		}
+	}
+
+	if len(errs) > 0 {
+		return errors.New(strings.Join(errs, "; "))
+	}
+
+	return nil
+}
+
+// SamplingSenderStats counts the messages that a sampling sender has
+// sampled.
+type SamplingSenderStats struct {
+	// SampledIn is the number of sampled messages that the sender
+	// forwarded to the underlying sender.
+	SampledIn int64 `json:"sampled_in"`
+
+	// SampledOut is the number of sampled messages that the sender
+	// discarded.
+	SampledOut int64 `json:"sampled_out"`
+}
+
+type samplingSender struct {
+	sampledIn  int64
+	sampledOut int64
+	closed     int32
+	state      uint64
+	rates      map[level.Priority]float64
+	opts       SamplingOptions
+	Sender
+}
+
+// NewSamplingSender wraps an existing Sender, and forwards only the
+// fraction of Notice and lower priority messages that the rate
+// specifies, chosen at random, to reduce the volume of verbose
+// logging. Messages with a higher priority are always forwarded.
+func NewSamplingSender(inner Sender, rate float64) (Sender, error) {
+	return NewSamplingSenderWithOptions(inner, SamplingOptions{Rate: rate})
+}
+
+// NewSamplingSenderWithOptions wraps an existing Sender, and forwards
+// only the fraction of messages at or below the threshold priority
+// that the rate specifies, chosen at random. Messages with a higher
+// priority, and all Critical, Alert, and Emergency messages, are
+// always forwarded, as are messages that the underlying sender would
+// not log. Use SamplingStats to count the sampled messages.
+//
+// The sender annotates the Fields messages that it samples and
+// forwards with the "sampled" field, set to true, and the
+// "sample_rate" field, so that the count of the messages can be
+// corrected downstream. Discarding a message does not render it.
+func NewSamplingSenderWithOptions(inner Sender, opts SamplingOptions) (Sender, error) {
+	if inner == nil {
+		return nil, errors.New("no underlying sender specified")
+	}
+
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+
+	s := &samplingSender{
+		state:  uint64(time.Now().UnixNano()),
+		rates:  make(map[level.Priority]float64, len(opts.Rates)),
+		opts:   opts,
+		Sender: inner,
+	}
+
+	for p, rate := range opts.Rates {
+		s.rates[p] = rate
+	}
+
+	if s.opts.Random == nil {
+		s.opts.Random = s.random
+	}
+
+	return s, nil
+}
+
+// SamplingStats returns the number of messages that a sampling sender
+// has forwarded and discarded, among the messages that it sampled.
+// Returns an error if the Sender is not a sampling sender.
+func SamplingStats(s Sender) (SamplingSenderStats, error) {
+	sender, ok := s.(*samplingSender)
+	if !ok {
+		return SamplingSenderStats{}, fmt.Errorf("%s is not a sampling sender", s.Name())
+	}
+
+	return SamplingSenderStats{
+		SampledIn:  atomic.LoadInt64(&sender.sampledIn),
+		SampledOut: atomic.LoadInt64(&sender.sampledOut),
+	}, nil
+}
+
+func (s *samplingSender) Send(m message.Composer) {
+	// after close, the underlying sender reports the message to
+	// its error handler.
+	if message.IsNil(m) || atomic.LoadInt32(&s.closed) != 0 {
+		s.Sender.Send(m)
+		return
+	}
+
+	p := m.Priority()
+	if p > s.opts.Threshold || p >= level.Critical || p < s.Level().Threshold {
+		s.Sender.Send(m)
+		return
+	}
+
+	rate, ok := s.rates[p]
+	if !ok {
+		rate = s.opts.Rate
+	}
+
+	// decide before checking whether the message is loggable, so
+	// that discarding lazy messages does not produce their content.
+	if rate < 1 && s.opts.Random() >= rate {
+		atomic.AddInt64(&s.sampledOut, 1)
+		return
+	}
+
+	if !m.Loggable() {
+		s.Sender.Send(m)
+		return
+	}
+
+	atomic.AddInt64(&s.sampledIn, 1)
+	if _, ok := m.Raw().(message.Fields); ok {
+		_ = m.Annotate("sampled", true)
+		_ = m.Annotate("sample_rate", rate)
+	}
+
+	s.Sender.Send(m)
+}
+
+// random returns the next value of a splitmix64 generator, as a value
+// between 0 and 1. Advancing the state atomically makes the generator
+// safe for concurrent use without a lock.
+func (s *samplingSender) random() float64 {
+	z := atomic.AddUint64(&s.state, 0x9e3779b97f4a7c15)
+	z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+	z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+	z ^= z >> 31
+
+	// the top 53 bits fill the mantissa of the value.
+	return float64(z>>11) / (1 << 53)
+}
+
+func (s *samplingSender) Close() error {
+	atomic.StoreInt32(&s.closed, 1)
+
+	return s.Sender.Close()
+}
@@ -0,0 +1,172 @@
+package send
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/mongodb/grip/level"
+	"github.com/mongodb/grip/message"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSamplingOptionsValidate(t *testing.T) {
+	assert := assert.New(t)
+
+	opts := SamplingOptions{Rate: 0.5}
+	assert.NoError(opts.Validate())
+	assert.Equal(level.Notice, opts.Threshold)
+
+	for _, opts := range []SamplingOptions{
+		{Rate: -0.1},
+		{Rate: 1.1},
+		{Rate: 0.5, Threshold: level.Priority(200)},
+		{Rate: 0.5, Rates: map[level.Priority]float64{level.Debug: 2}},
+		{Rate: 0.5, Rates: map[level.Priority]float64{level.Warning: 0.5}},
+	} {
+		assert.Error(opts.Validate())
+	}
+
+	_, err := NewSamplingSender(nil, 0.5)
+	assert.Error(err)
+}
+
+func TestSamplingSenderForwardsFractionOfMessages(t *testing.T) {
+	assert := assert.New(t)
+
+	inner := newSlowSender(0)
+	require.NoError(t, inner.SetLevel(LevelInfo{level.Trace, level.Trace}))
+	s, err := NewSamplingSender(inner, 0.1)
+	require.NoError(t, err)
+
+	wg := &sync.WaitGroup{}
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 2500; j++ {
+				s.Send(message.NewDefaultMessage(level.Debug, "debug"))
+			}
+		}()
+	}
+	wg.Wait()
+
+	forwarded := inner.sent()
+	assert.InDelta(1000, forwarded, 150)
+
+	stats, err := SamplingStats(s)
+	assert.NoError(err)
+	assert.Equal(forwarded, stats.SampledIn)
+	assert.Equal(10000-forwarded, stats.SampledOut)
+
+	_, err = SamplingStats(inner)
+	assert.Error(err)
+}
+
+func TestSamplingSenderAlwaysForwardsSevereMessages(t *testing.T) {
+	assert := assert.New(t)
+
+	inner := newSlowSender(0)
+	require.NoError(t, inner.SetLevel(LevelInfo{level.Trace, level.Trace}))
+	s, err := NewSamplingSenderWithOptions(inner, SamplingOptions{Threshold: level.Emergency})
+	require.NoError(t, err)
+
+	for _, p := range []level.Priority{level.Critical, level.Alert, level.Emergency} {
+		for i := 0; i < 100; i++ {
+			s.Send(message.NewDefaultMessage(p, "severe"))
+		}
+	}
+	assert.EqualValues(300, inner.sent())
+
+	s.Send(message.NewDefaultMessage(level.Error, "sampled"))
+	assert.EqualValues(300, inner.sent())
+
+	stats, err := SamplingStats(s)
+	assert.NoError(err)
+	assert.Equal(SamplingSenderStats{SampledOut: 1}, stats)
+
+	s, err = NewSamplingSender(inner, 0)
+	require.NoError(t, err)
+	s.Send(message.NewDefaultMessage(level.Warning, "warning"))
+	s.Send(message.NewDefaultMessage(level.Info, "info"))
+	assert.EqualValues(301, inner.sent())
+}
+
+func TestSamplingSenderUsesRatesByPriority(t *testing.T) {
+	assert := assert.New(t)
+
+	inner, err := NewInternalLogger("sampled", LevelInfo{level.Trace, level.Trace})
+	require.NoError(t, err)
+
+	// the values cycle through 0.05, 0.15, ..., 0.95.
+	var n int64
+	random := func() float64 {
+		n++
+		return float64((n-1)%10)/10 + 0.05
+	}
+
+	s, err := NewSamplingSenderWithOptions(inner, SamplingOptions{
+		Rate:   0.5,
+		Rates:  map[level.Priority]float64{level.Debug: 0.2, level.Trace: 0},
+		Random: random,
+	})
+	require.NoError(t, err)
+
+	for i := 0; i < 10; i++ {
+		s.Send(message.NewDefaultMessage(level.Info, "info"))
+	}
+	for i := 0; i < 10; i++ {
+		s.Send(message.NewDefaultMessage(level.Debug, "debug"))
+	}
+	for i := 0; i < 10; i++ {
+		s.Send(message.NewDefaultMessage(level.Trace, "trace"))
+	}
+	s.Send(message.NewDefaultMessage(level.Warning, "warning"))
+
+	counts := map[level.Priority]int{}
+	for inner.HasMessage() {
+		counts[inner.GetMessage().Priority]++
+	}
+	assert.Equal(map[level.Priority]int{level.Info: 5, level.Debug: 2, level.Warning: 1}, counts)
+
+	stats, err := SamplingStats(s)
+	assert.NoError(err)
+	assert.Equal(SamplingSenderStats{SampledIn: 7, SampledOut: 23}, stats)
+}
+
+func TestSamplingSenderAnnotatesSampledFields(t *testing.T) {
+	assert := assert.New(t)
+
+	inner, err := NewInternalLogger("sampled", LevelInfo{level.Trace, level.Trace})
+	require.NoError(t, err)
+
+	s, err := NewSamplingSenderWithOptions(inner, SamplingOptions{Rate: 0.5, Random: func() float64 { return 0 }})
+	require.NoError(t, err)
+
+	s.Send(message.NewFields(level.Info, message.Fields{"op": "read"}))
+	s.Send(message.NewFields(level.Warning, message.Fields{"op": "write"}))
+
+	sampled := inner.GetMessage().Message.Raw().(message.Fields)
+	assert.Equal(true, sampled["sampled"])
+	assert.Equal(0.5, sampled["sample_rate"])
+
+	forwarded := inner.GetMessage().Message.Raw().(message.Fields)
+	assert.NotContains(forwarded, "sampled")
+	assert.NotContains(forwarded, "sample_rate")
+}
+
+func TestSamplingSenderDoesNotRenderDiscardedMessages(t *testing.T) {
+	inner := newSlowSender(0)
+	require.NoError(t, inner.SetLevel(LevelInfo{level.Trace, level.Trace}))
+	s, err := NewSamplingSenderWithOptions(inner, SamplingOptions{Rate: 0.5, Random: func() float64 { return 0.9 }})
+	require.NoError(t, err)
+
+	rendered := false
+	s.Send(message.NewLazy(level.Debug, func() message.Composer {
+		rendered = true
+		return message.NewDefaultMessage(level.Debug, "expensive")
+	}))
+
+	assert.False(t, rendered)
+	assert.EqualValues(t, 0, inner.sent())
+}
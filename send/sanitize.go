@@ -0,0 +1,23 @@
+package send
+
+import (
+	"strings"
+	"unicode"
+)
+
+// sanitizeHeader returns a copy of the string that is safe to
+// interpolate into a protocol header (e.g. an SMTP header or a syslog
+// tag). Carriage returns, line feeds, other control characters, and
+// the unicode line and paragraph separators become spaces, so that
+// the content of messages and the names of senders cannot end the
+// header and inject additional headers or records. Use it only for
+// header contexts; message bodies keep their newlines.
+func sanitizeHeader(s string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) || r == '\u2028' || r == '\u2029' {
+			return ' '
+		}
+
+		return r
+	}, s)
+}
@@ -0,0 +1,22 @@
+package send
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSanitizeHeader(t *testing.T) {
+	assert := assert.New(t)
+
+	for in, out := range map[string]string{
+		"":                               "",
+		"plain subject":                  "plain subject",
+		"a\r\nBcc: attacker@example.com": "a  Bcc: attacker@example.com",
+		"tab\tand\x00null\x7f":           "tab and null ",
+		"sep\u2028line\u2029para":        "sep line para",
+		"unicode ✓ stays":                "unicode ✓ stays",
+	} {
+		assert.Equal(out, sanitizeHeader(in), "%q", in)
+	}
+}
@@ -2,18 +2,27 @@ package send
 
 import (
 	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io/ioutil"
 	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"net/mail"
 	"os"
 	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/mongodb/grip/level"
 	"github.com/mongodb/grip/message"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"github.com/stretchr/testify/suite"
 )
 
@@ -200,6 +209,14 @@ func (s *SenderSuite) TestBasicNoopSendTest() {
 	}
 }
 
+func (s *SenderSuite) TestSendNilMessagesDoesNotPanic() {
+	var m *nilMessage
+	for t, sender := range s.senders {
+		s.NotPanics(func() { sender.Send(nil) }, t)
+		s.NotPanics(func() { sender.Send(m) }, t)
+	}
+}
+
 func TestBaseConstructor(t *testing.T) {
 	assert := assert.New(t)
 
@@ -247,3 +264,422 @@ func TestBaseConstructor(t *testing.T) {
 		}
 	}
 }
+
+// structuredComposer is a loggable message that has structured
+// content, but no text.
+type structuredComposer struct {
+	message.Composer
+}
+
+func (structuredComposer) String() string   { return "" }
+func (structuredComposer) Loggable() bool   { return true }
+func (structuredComposer) Raw() interface{} { return message.Fields{"key": "value"} }
+
+func TestEmptyAndStructuredMessagesAcrossSenders(t *testing.T) {
+	assert := assert.New(t)
+	l := LevelInfo{level.Info, level.Info}
+
+	// each sender has a function that returns the output of the
+	// last message that the sender sent, if any.
+	senders := map[string]func() (Sender, func() (string, bool)){
+		"stream": func() (Sender, func() (string, bool)) {
+			buf := &bytes.Buffer{}
+			sender, err := NewStreamLogger("stream", buf, l)
+			assert.NoError(err)
+			return sender, func() (string, bool) {
+				out := buf.String()
+				buf.Reset()
+				return out, out != ""
+			}
+		},
+		"internal": func() (Sender, func() (string, bool)) {
+			sender, err := NewInternalLogger("internal", l)
+			assert.NoError(err)
+			return sender, func() (string, bool) {
+				msg := sender.GetMessage()
+				return msg.Rendered, msg.Logged
+			}
+		},
+		"channel": func() (Sender, func() (string, bool)) {
+			sender, out := NewChannelSender("channel", l, 1)
+			return sender, func() (string, bool) {
+				select {
+				case m := <-out:
+					return messageText(m, " "), true
+				default:
+					return "", false
+				}
+			}
+		},
+		"slack": func() (Sender, func() (string, bool)) {
+			mock := &slackClientMock{}
+			sender, err := NewSlackLogger(&SlackOptions{client: mock, Hostname: "testhost", Channel: "#test", Name: "slack"}, "token", l)
+			assert.NoError(err)
+			return sender, func() (string, bool) {
+				out, sent := mock.lastText, mock.numSent > 0
+				mock.lastText, mock.numSent = "", 0
+				return out, sent
+			}
+		},
+		"xmpp": func() (Sender, func() (string, bool)) {
+			mock := &xmppClientMock{}
+			sender, err := NewXMPPLogger("xmpp", "target", XMPPConnectionInfo{client: mock}, l)
+			assert.NoError(err)
+			return sender, func() (string, bool) {
+				out, sent := mock.lastText, mock.numSent > 0
+				mock.lastText, mock.numSent = "", 0
+				return out, sent
+			}
+		},
+		"smtp": func() (Sender, func() (string, bool)) {
+			mock := &smtpClientMock{}
+			opts := &SMTPOptions{client: mock, Name: "smtp", NameAsSubject: true, toAddrs: []*mail.Address{{Name: "one", Address: "two"}}}
+			sender, err := NewSMTPLogger(opts, l)
+			assert.NoError(err)
+			return sender, func() (string, bool) {
+				if mock.numMsgs == 0 {
+					return "", false
+				}
+				mock.numMsgs = 0
+
+				lines := strings.Split(mock.message.String(), "\r\n")
+				body, err := base64.StdEncoding.DecodeString(lines[len(lines)-1])
+				assert.NoError(err)
+				return string(body), true
+			}
+		},
+	}
+
+	cases := []struct {
+		name     string
+		message  func() message.Composer
+		expected []string
+	}{
+		{name: "Empty", message: func() message.Composer { return message.NewDefaultMessage(level.Info, "") }},
+		{name: "Whitespace", message: func() message.Composer { return message.NewDefaultMessage(level.Info, " \n\t") }},
+		{name: "WhitespaceLines", message: func() message.Composer { return message.NewLineMessage(level.Info, " ", "\t") }},
+		{name: "WhitespaceBytes", message: func() message.Composer { return message.NewBytesMessage(level.Info, []byte("  ")) }},
+		{name: "EmptyFields", message: func() message.Composer { return message.NewFieldsMessage(level.Info, " ", message.Fields{}) }},
+		{
+			name:     "Text",
+			message:  func() message.Composer { return message.NewDefaultMessage(level.Info, "hello") },
+			expected: []string{"hello"},
+		},
+		{
+			name:     "FieldsOnly",
+			message:  func() message.Composer { return message.NewFields(level.Info, message.Fields{"a": 1}) },
+			expected: []string{"a='1'"},
+		},
+		{
+			name: "StructuredWithoutText",
+			message: func() message.Composer {
+				return structuredComposer{message.NewDefaultMessage(level.Info, "")}
+			},
+			expected: []string{"key='value'"},
+		},
+	}
+
+	for name, makeSender := range senders {
+		sender, output := makeSender()
+		for _, c := range cases {
+			sender.Send(c.message())
+			out, sent := output()
+			if len(c.expected) == 0 {
+				assert.False(sent, "%s/%s", name, c.name)
+				continue
+			}
+
+			assert.True(sent, "%s/%s", name, c.name)
+			for _, e := range c.expected {
+				assert.Contains(out, e, "%s/%s", name, c.name)
+			}
+		}
+	}
+}
+
+// nilMessage panics when its methods are called on a nil pointer.
+type nilMessage struct {
+	message.Composer
+}
+
+func (m *nilMessage) Loggable() bool           { return m.Composer.Loggable() }
+func (m *nilMessage) Priority() level.Priority { return m.Composer.Priority() }
+
+// timestampedComposer is a message with a fixed creation time.
+type timestampedComposer struct {
+	created time.Time
+	message.Composer
+}
+
+func (m timestampedComposer) Timestamp() time.Time { return m.created }
+
+func TestNativeLoggerUsesMessageTimestamp(t *testing.T) {
+	assert := assert.New(t)
+
+	dir, err := ioutil.TempDir("", "grip-native")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	fn := filepath.Join(dir, "out.log")
+	sender, err := NewFileLogger("native", fn, LevelInfo{level.Info, level.Info})
+	require.NoError(t, err)
+	defer sender.Close()
+
+	created := time.Date(2001, 2, 3, 4, 5, 6, 0, time.Local)
+	sender.Send(timestampedComposer{created, message.NewDefaultMessage(level.Info, "created")})
+
+	assert.NoError(SetUseSendTime(sender, true))
+	sender.Send(timestampedComposer{created, message.NewDefaultMessage(level.Info, "sent")})
+
+	out, err := ioutil.ReadFile(fn)
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(out)), "\n")
+	require.Len(t, lines, 2)
+	assert.Equal("[native] 2001/02/03 04:05:06 [p=info]: created", lines[0])
+	assert.NotContains(lines[1], "2001/02/03")
+	assert.Contains(lines[1], "[p=info]: sent")
+
+	internal, err := NewInternalLogger("internal", LevelInfo{level.Info, level.Info})
+	require.NoError(t, err)
+	assert.Error(SetUseSendTime(internal, true))
+}
+
+func TestNativeLoggerNamePrefix(t *testing.T) {
+	assert := assert.New(t)
+
+	dir, err := ioutil.TempDir("", "grip-native")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	readLines := func(fn string) []string {
+		out, err := ioutil.ReadFile(fn)
+		require.NoError(t, err)
+		return strings.Split(strings.TrimSpace(string(out)), "\n")
+	}
+
+	fn := filepath.Join(dir, "out.log")
+	sender, err := NewFileLogger("db", fn, LevelInfo{level.Info, level.Info})
+	require.NoError(t, err)
+	defer sender.Close()
+	require.NoError(t, SetUseSendTime(sender, false))
+
+	sender.Send(message.NewDefaultMessage(level.Info, "default"))
+	require.NoError(t, SetNamePrefix(sender, "<{name}> "))
+	sender.Send(message.NewDefaultMessage(level.Info, "custom"))
+	sender.SetName("cache")
+	sender.Send(message.NewDefaultMessage(level.Info, "renamed"))
+	require.NoError(t, SetNamePrefix(sender, ""))
+	sender.Send(message.NewDefaultMessage(level.Info, "none"))
+
+	lines := readLines(fn)
+	require.Len(t, lines, 4)
+	assert.True(strings.HasPrefix(lines[0], "[db] "), lines[0])
+	assert.True(strings.HasPrefix(lines[1], "<db> "), lines[1])
+	assert.True(strings.HasPrefix(lines[2], "<cache> "), lines[2])
+	assert.False(strings.HasPrefix(lines[3], "<"), lines[3])
+	assert.False(strings.HasPrefix(lines[3], "["), lines[3])
+
+	// JSON senders add the name as a field, rather than a prefix.
+	jfn := filepath.Join(dir, "out.json")
+	jsonSender, err := NewJSONFileLogger("db", jfn, LevelInfo{level.Info, level.Info})
+	require.NoError(t, err)
+	defer jsonSender.Close()
+
+	jsonSender.Send(message.NewFields(level.Info, message.Fields{"msg": "default"}))
+	require.NoError(t, SetNamePrefix(jsonSender, "[{name}] "))
+	jsonSender.Send(message.NewFields(level.Info, message.Fields{"msg": "named"}))
+	jsonSender.Send(message.NewFields(level.Info, message.Fields{"msg": "own", "sender": "other"}))
+
+	lines = readLines(jfn)
+	require.Len(t, lines, 3)
+	for idx, sender := range []interface{}{nil, "db", "other"} {
+		doc := map[string]interface{}{}
+		require.NoError(t, json.Unmarshal([]byte(lines[idx]), &doc), lines[idx])
+		assert.Equal(sender, doc["sender"])
+	}
+
+	internal, err := NewInternalLogger("internal", LevelInfo{level.Info, level.Info})
+	require.NoError(t, err)
+	assert.Error(SetNamePrefix(internal, "[{name}] "))
+}
+
+func TestSetLevelConcurrentWithSend(t *testing.T) {
+	dir, err := ioutil.TempDir("", "grip-level")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	debug := LevelInfo{level.Info, level.Debug}
+	info := LevelInfo{level.Info, level.Info}
+
+	fileSender, err := NewFileLogger("file", filepath.Join(dir, "out.log"), info)
+	require.NoError(t, err)
+
+	opts := &SMTPOptions{client: &smtpClientMock{}, Name: "smtp", NameAsSubject: true, toAddrs: []*mail.Address{{Name: "one", Address: "two"}}}
+	smtpSender, err := NewSMTPLogger(opts, info)
+	require.NoError(t, err)
+
+	internal, err := NewInternalLogger("internal", info)
+	require.NoError(t, err)
+	go func() {
+		for range internal.output {
+		}
+	}()
+
+	member, err := NewInternalLogger("member", info)
+	require.NoError(t, err)
+	go func() {
+		for range member.output {
+		}
+	}()
+	multi, err := NewMultiSender("multi", info, []Sender{member})
+	require.NoError(t, err)
+
+	for name, sender := range map[string]Sender{
+		"file":     fileSender,
+		"smtp":     smtpSender,
+		"internal": internal,
+		"multi":    multi,
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert.Error(t, sender.SetLevel(LevelInfo{level.Info, level.Invalid}))
+			assert.Equal(t, info, sender.Level())
+
+			wg := &sync.WaitGroup{}
+			for i := 0; i < 4; i++ {
+				wg.Add(2)
+				go func() {
+					defer wg.Done()
+					for j := 0; j < 100; j++ {
+						sender.Send(message.NewDefaultMessage(level.Debug, "debug"))
+					}
+				}()
+				go func() {
+					defer wg.Done()
+					for j := 0; j < 100; j++ {
+						l := info
+						if j%2 == 0 {
+							l = debug
+						}
+						assert.NoError(t, sender.SetLevel(l))
+						got := sender.Level()
+						assert.True(t, got == info || got == debug, "torn level %+v", got)
+					}
+				}()
+			}
+			wg.Wait()
+
+			assert.NoError(t, sender.Close())
+		})
+	}
+}
+
+func TestLazyMessagesBelowThresholdAreNotEvaluated(t *testing.T) {
+	assert := assert.New(t)
+
+	buf := &bytes.Buffer{}
+	sender, err := NewStreamLogger("lazy", buf, LevelInfo{level.Info, level.Info})
+	require.NoError(t, err)
+
+	calls := 0
+	fn := func() message.Composer {
+		calls++
+		return message.NewString("expensive")
+	}
+
+	sender.Send(message.NewLazy(level.Debug, fn))
+	sender.Send(message.ConvertToComposer(level.Trace, fn))
+	assert.Equal(0, calls)
+	assert.Equal(0, buf.Len())
+
+	sender.Send(message.NewLazy(level.Info, fn))
+	assert.Equal(1, calls)
+	assert.Contains(buf.String(), "expensive")
+}
+
+func TestSendContextAbandonsHangingDeliveries(t *testing.T) {
+	l := LevelInfo{level.Info, level.Info}
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-release:
+		}
+	}))
+	defer srv.Close()
+	defer close(release)
+
+	for name, constructor := range map[string]func(t *testing.T) Sender{
+		"webhook": func(t *testing.T) Sender {
+			s, err := NewWebhookSenderWithOptions("webhook", srv.URL, WebhookOptions{}, l)
+			require.NoError(t, err)
+			return s
+		},
+		"teams": func(t *testing.T) Sender {
+			s, err := NewTeamsLoggerWithClient("teams", srv.URL, srv.Client(), l)
+			require.NoError(t, err)
+			return s
+		},
+		"pagerduty": func(t *testing.T) Sender {
+			s, err := NewPagerDutyLoggerWithOptions("pagerduty", "key", PagerDutyOptions{Endpoint: srv.URL, Client: srv.Client()}, l)
+			require.NoError(t, err)
+			return s
+		},
+		"smtp": func(t *testing.T) Sender {
+			mock := &smtpClientMock{hang: release}
+			opts := &SMTPOptions{client: mock, Name: "smtp", NameAsSubject: true, toAddrs: []*mail.Address{{Name: "one", Address: "two"}}}
+			s, err := NewSMTPLogger(opts, l)
+			require.NoError(t, err)
+			return s
+		},
+		"slack": func(t *testing.T) Sender {
+			mock := &slackClientMock{hang: release}
+			s, err := NewSlackLogger(&SlackOptions{client: mock, Hostname: "testhost", Channel: "#test", Name: "slack"}, "token", l)
+			require.NoError(t, err)
+			return s
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			sender := constructor(t)
+			_, ok := sender.(ContextSender)
+			assert.True(ok)
+
+			errs := make(chan error, 1)
+			require.NoError(t, sender.SetErrorHandler(func(err error, _ message.Composer) {
+				select {
+				case errs <- err:
+				default:
+				}
+			}))
+
+			ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+			defer cancel()
+
+			start := time.Now()
+			SendContext(ctx, sender, message.NewDefaultMessage(level.Info, "hello"))
+			assert.True(time.Since(start) < time.Second, "took %s", time.Since(start))
+
+			select {
+			case err := <-errs:
+				assert.Contains(err.Error(), context.DeadlineExceeded.Error())
+			default:
+				assert.Fail("no error reported")
+			}
+		})
+	}
+}
+
+func TestSendContextFallsBackToSend(t *testing.T) {
+	assert := assert.New(t)
+
+	sender, err := NewInternalLogger("internal", LevelInfo{level.Info, level.Info})
+	require.NoError(t, err)
+	_, ok := Sender(sender).(ContextSender)
+	assert.False(ok)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	SendContext(ctx, sender, message.NewDefaultMessage(level.Info, "hello"))
+	assert.Equal("hello", sender.GetMessage().Rendered)
+}
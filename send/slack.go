@@ -1,9 +1,10 @@
 package send
 
 import (
+	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
-	"log"
 	"os"
 	"strings"
 	"sync"
@@ -14,7 +15,8 @@ import (
 )
 
 const (
-	slackClientToken = "GRIP_SLACK_CLIENT_TOKEN"
+	slackClientToken   = "GRIP_SLACK_CLIENT_TOKEN"
+	slackSummaryLength = 100
 )
 
 type slackJournal struct {
@@ -46,15 +48,6 @@ func NewSlackLogger(opts *SlackOptions, token string, l LevelInfo) (Sender, erro
 		return nil, fmt.Errorf("slack authentication error: %v", err)
 	}
 
-	fallback := log.New(os.Stdout, "", log.LstdFlags)
-	if err := s.SetErrorHandler(ErrorHandlerFromLogger(fallback)); err != nil {
-		return nil, err
-	}
-
-	s.reset = func() {
-		fallback.SetPrefix(fmt.Sprintf("[%s] ", s.Name()))
-	}
-
 	s.SetName(opts.Name)
 
 	return s, nil
@@ -74,19 +67,76 @@ func MakeSlackLogger(opts *SlackOptions) (Sender, error) {
 }
 
 func (s *slackJournal) Send(m message.Composer) {
-	if !s.level.ShouldLog(m) {
+	if !s.Level().ShouldLog(m) || s.reportClosed(m) {
 		return
 	}
 
-	msg := m.String()
+	msg := messageText(m, "\n")
 
-	s.Base.mutex.RLock()
-	defer s.Base.mutex.RUnlock()
+	if s.opts.SnippetLength > 0 && len(msg) > s.opts.SnippetLength {
+		s.upload(m, msg)
+		return
+	}
 
 	params := s.opts.getParams(m)
-	if err := s.client.ChatPostMessage(s.opts.Channel, msg, params); err != nil {
-		s.errHandler(err, message.NewFormattedMessage(m.Priority(),
-			"%s: %s\n", params.Attachments[0].Fallback, msg))
+	if err := s.client.ChatPostMessage(s.opts.getChannel(m.Priority()), msg, params); err != nil {
+		s.ErrorHandler(err, m)
+	}
+}
+
+// SendContext posts the message, and returns when the context is
+// canceled, reporting the error of the context, even if Slack has not
+// responded. The Slack client cannot cancel requests, so the sender
+// finishes posting the message in the background.
+func (s *slackJournal) SendContext(ctx context.Context, m message.Composer) {
+	if s.Level().ShouldLog(m) {
+		sendUntilDone(ctx, s.Send, s.ErrorHandler, m)
+	}
+}
+
+// upload posts long messages as a file, using the sender's formatter,
+// if set, to produce the content of the file, along with a short
+// summary of the message.
+func (s *slackJournal) upload(m message.Composer, msg string) {
+	s.mutex.RLock()
+	name := s.name
+	formatter := s.formatter
+	s.mutex.RUnlock()
+
+	content := msg
+	if formatter != nil {
+		out, err := formatter(m)
+		if err != nil {
+			s.ErrorHandler(err, m)
+			return
+		}
+		content = out
+	}
+
+	filename, filetype := name+".log", "text"
+	if json.Valid([]byte(content)) {
+		filename, filetype = name+".json", "javascript"
+	}
+
+	summary := msg
+	if idx := strings.Index(summary, "\n"); idx >= 0 {
+		summary = summary[:idx]
+	}
+	if len(summary) > slackSummaryLength {
+		summary = summary[:slackSummaryLength]
+	}
+
+	err := s.client.FilesUpload(&slack.FilesUploadOpt{
+		Content:        content,
+		Filename:       filename,
+		Filetype:       filetype,
+		Title:          filename,
+		InitialComment: fmt.Sprintf("%s... (%d characters, see %s)", summary, len(msg), filename),
+		Channels:       []string{s.opts.getChannel(m.Priority())},
+	})
+
+	if err != nil {
+		s.ErrorHandler(fmt.Errorf("uploading %s: %s", filename, err.Error()), m)
 	}
 }
 
@@ -112,10 +162,76 @@ type SlackOptions struct {
 	Fields        bool
 	FieldsSet     map[string]struct{}
 
+	// Slack truncates long messages. When SnippetLength is
+	// greater than zero, messages that are longer than
+	// SnippetLength are uploaded to the channel as a file,
+	// named for the sender, with a short summary of the
+	// message. If the sender has a formatter, the formatter
+	// produces the content of the file, and the file has a
+	// ".json" extension if the content is JSON, and a ".log"
+	// extension otherwise.
+	SnippetLength int
+
+	// PriorityChannels routes messages to channels other than
+	// Channel, by priority: a message goes to the channel for the
+	// highest priority in the map that is at or below the priority
+	// of the message, or to Channel, if there is none. For example,
+	// {level.Warning: "#incidents"} posts warnings and more
+	// severe messages to "#incidents", and other messages to
+	// Channel. Slack rejects messages to channels that do not
+	// exist, which the sender reports to its error handler.
+	PriorityChannels map[level.Priority]string
+
 	client slackClient
 	mutex  sync.RWMutex
 }
 
+// SetChannel changes the channel that the sender posts messages
+// to. Unlike modifying the Channel field directly, it is safe to call
+// SetChannel while the sender is sending messages.
+func (o *SlackOptions) SetChannel(channel string) {
+	if !strings.HasPrefix(channel, "#") {
+		channel = "#" + channel
+	}
+
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	o.Channel = channel
+}
+
+// SetFieldsSet replaces the set of fields that the sender attaches to
+// messages, when the Fields option is set. Unlike modifying the
+// FieldsSet map directly, it is safe to call SetFieldsSet while the
+// sender is sending messages.
+func (o *SlackOptions) SetFieldsSet(fields ...string) {
+	set := make(map[string]struct{}, len(fields))
+	for _, f := range fields {
+		set[f] = struct{}{}
+	}
+
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	o.FieldsSet = set
+}
+
+// getChannel returns the channel for messages with the priority.
+func (o *SlackOptions) getChannel(p level.Priority) string {
+	o.mutex.RLock()
+	defer o.mutex.RUnlock()
+
+	channel := o.Channel
+	selected := level.Invalid
+	for threshold, c := range o.PriorityChannels {
+		if threshold <= p && threshold > selected {
+			channel, selected = c, threshold
+		}
+	}
+
+	return channel
+}
+
 func (o *SlackOptions) fieldSetShouldInclude(name string) bool {
 	if name == "time" || name == "msg" {
 		return false
@@ -153,6 +269,22 @@ func (o *SlackOptions) Validate() error {
 		errs = append(errs, "no logger/journal name specified")
 	}
 
+	if o.SnippetLength < 0 {
+		errs = append(errs, "snippet length cannot be negative")
+	}
+
+	for p, channel := range o.PriorityChannels {
+		if !level.IsValidPriority(p) {
+			errs = append(errs, fmt.Sprintf("%d is not a valid priority for a channel", p))
+		}
+
+		if channel == "" {
+			errs = append(errs, fmt.Sprintf("no channel specified for priority %s", p))
+		} else if !strings.HasPrefix(channel, "#") {
+			o.PriorityChannels[p] = "#" + channel
+		}
+	}
+
 	if o.FieldsSet == nil {
 		o.FieldsSet = map[string]struct{}{}
 	}
@@ -271,6 +403,7 @@ type slackClient interface {
 	Create(string)
 	AuthTest() (*slack.AuthTestApiResponse, error)
 	ChatPostMessage(string, string, *slack.ChatPostMessageOpt) error
+	FilesUpload(*slack.FilesUploadOpt) error
 }
 
 type slackClientImpl struct {
@@ -2,6 +2,7 @@ package send
 
 import (
 	"errors"
+	"sync"
 
 	"github.com/bluele/slack"
 )
@@ -10,7 +11,16 @@ import (
 type slackClientMock struct {
 	failAuthTest       bool
 	failSendingMessage bool
+	failUpload         bool
 	numSent            int
+	lastChannel        string
+	lastText           string
+	lastUpload         *slack.FilesUploadOpt
+	mutex              sync.Mutex
+
+	// hang, if set, makes ChatPostMessage wait until it is closed,
+	// like a server that does not respond.
+	hang chan struct{}
 }
 
 func (c *slackClientMock) Create(_ string) { return }
@@ -21,12 +31,34 @@ func (c *slackClientMock) AuthTest() (*slack.AuthTestApiResponse, error) {
 	return nil, nil
 }
 
-func (c *slackClientMock) ChatPostMessage(_, _ string, _ *slack.ChatPostMessageOpt) error {
+func (c *slackClientMock) ChatPostMessage(channel, text string, _ *slack.ChatPostMessageOpt) error {
+	if c.hang != nil {
+		<-c.hang
+	}
+
 	if c.failSendingMessage {
 		return errors.New("mock failed auth test")
 	}
 
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
 	c.numSent++
+	c.lastChannel = channel
+	c.lastText = text
+
+	return nil
+}
+
+func (c *slackClientMock) FilesUpload(opt *slack.FilesUploadOpt) error {
+	if c.failUpload {
+		return errors.New("mock failed upload")
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.lastUpload = opt
 
 	return nil
 }
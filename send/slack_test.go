@@ -3,6 +3,7 @@ package send
 import (
 	"os"
 	"strings"
+	"sync"
 	"testing"
 
 	"github.com/mongodb/grip/level"
@@ -267,6 +268,133 @@ func (s *SlackSuite) TestSendMethodWithError() {
 	s.Equal(mock.numSent, 1)
 }
 
+func (s *SlackSuite) TestErrorHandlerReceivesFailedMessages() {
+	sender, err := NewSlackLogger(s.opts, "foo", LevelInfo{level.Trace, level.Info})
+	s.Require().NoError(err)
+
+	var failures []error
+	var failed []message.Composer
+	s.NoError(sender.SetErrorHandler(func(err error, m message.Composer) {
+		if err != nil {
+			failures = append(failures, err)
+			failed = append(failed, m)
+		}
+	}))
+
+	mock, ok := s.opts.client.(*slackClientMock)
+	s.Require().True(ok)
+	mock.failSendingMessage = true
+	mock.failUpload = true
+
+	m := message.NewDefaultMessage(level.Alert, "world")
+	sender.Send(m)
+
+	s.opts.SnippetLength = 2
+	long := message.NewDefaultMessage(level.Alert, "a longer message")
+	sender.Send(long)
+
+	s.Require().Len(failures, 2)
+	s.True(m == failed[0])
+	s.True(long == failed[1])
+	s.Contains(failures[1].Error(), "uploading bot.log")
+}
+
+func (s *SlackSuite) TestSendMethodUploadsLongMessages() {
+	s.opts.SnippetLength = 10
+	sender, err := NewSlackLogger(s.opts, "foo", LevelInfo{level.Trace, level.Info})
+	s.NoError(err)
+
+	mock, ok := s.opts.client.(*slackClientMock)
+	s.True(ok)
+
+	sender.Send(message.NewDefaultMessage(level.Alert, "world"))
+	s.Equal(1, mock.numSent)
+	s.Nil(mock.lastUpload)
+
+	sender.Send(message.NewDefaultMessage(level.Alert, "hello world\nat main.go:42"))
+	s.Equal(1, mock.numSent)
+	s.Require().NotNil(mock.lastUpload)
+	s.Equal("bot.log", mock.lastUpload.Filename)
+	s.Equal("hello world\nat main.go:42", mock.lastUpload.Content)
+	s.Equal([]string{"#test"}, mock.lastUpload.Channels)
+	s.Equal("hello world... (25 characters, see bot.log)", mock.lastUpload.InitialComment)
+
+	s.NoError(sender.SetFormatter(MakeJSONFormatter()))
+	sender.Send(message.NewDefaultMessage(level.Alert, "hello world\nat main.go:42"))
+	s.Equal("bot.json", mock.lastUpload.Filename)
+	s.True(strings.HasPrefix(mock.lastUpload.Content, "{"))
+
+	mock.lastUpload = nil
+	mock.failUpload = true
+	sender.Send(message.NewDefaultMessage(level.Alert, "hello world\nat main.go:42"))
+	s.Nil(mock.lastUpload)
+	s.Equal(1, mock.numSent)
+}
+
+func (s *SlackSuite) TestSetChannelAndFieldsWhileSending() {
+	s.opts.Fields = true
+	sender, err := NewSlackLogger(s.opts, "foo", LevelInfo{level.Trace, level.Info})
+	s.Require().NoError(err)
+
+	wg := &sync.WaitGroup{}
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				sender.Send(message.NewFields(level.Alert, message.Fields{"a": j, "b": "two"}))
+			}
+		}()
+	}
+
+	for i := 0; i < 50; i++ {
+		s.opts.SetChannel("other")
+		s.opts.SetFieldsSet("a")
+	}
+	wg.Wait()
+
+	mock, ok := s.opts.client.(*slackClientMock)
+	s.Require().True(ok)
+	s.Equal(200, mock.numSent)
+	s.Equal("#other", mock.lastChannel)
+	s.True(s.opts.fieldSetShouldInclude("a"))
+	s.False(s.opts.fieldSetShouldInclude("b"))
+}
+
+func (s *SlackSuite) TestPriorityChannels() {
+	s.opts.Channel = "general"
+	s.opts.PriorityChannels = map[level.Priority]string{
+		level.Warning:  "incidents",
+		level.Critical: "#pager",
+	}
+	sender, err := NewSlackLogger(s.opts, "foo", LevelInfo{level.Trace, level.Trace})
+	s.Require().NoError(err)
+	mock, ok := s.opts.client.(*slackClientMock)
+	s.Require().True(ok)
+
+	for p, channel := range map[level.Priority]string{
+		level.Debug:     "#general",
+		level.Notice:    "#general",
+		level.Warning:   "#incidents",
+		level.Error:     "#incidents",
+		level.Critical:  "#pager",
+		level.Emergency: "#pager",
+	} {
+		sender.Send(message.NewDefaultMessage(p, "hello"))
+		s.Equal(channel, mock.lastChannel, "%s", p)
+	}
+
+	s.opts.PriorityChannels = map[level.Priority]string{level.Info: ""}
+	s.Error(s.opts.Validate())
+	s.opts.PriorityChannels = map[level.Priority]string{level.Priority(500): "#other"}
+	s.Error(s.opts.Validate())
+}
+
+func (s *SlackSuite) TestValidateRejectsNegativeSnippetLength() {
+	s.opts.SnippetLength = -1
+	s.Error(s.opts.Validate())
+}
+
 func (s *SlackSuite) TestCreateMethodChangesClientState() {
 	base := &slackClientImpl{}
 	new := &slackClientImpl{}
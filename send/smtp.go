@@ -2,21 +2,40 @@ package send
 
 import (
 	"bytes"
+	"context"
 	"crypto/tls"
 	"encoding/base64"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
-	"log"
+	"io/ioutil"
+	"math/rand"
+	"mime"
+	"mime/multipart"
 	"net/mail"
 	"net/smtp"
-	"os"
+	"net/textproto"
+	"sort"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/mongodb/grip/message"
 )
 
+const (
+	// SMTPRecipientsKey is the key of the Fields of a message that
+	// overrides the recipients of the email for that message: a
+	// string slice, or a string, of comma separated email
+	// addresses. The Cc and Bcc recipients do not change.
+	SMTPRecipientsKey = "grip-smtp-recipients"
+
+	// SMTPSubjectKey is the key of the Fields of a message that
+	// overrides the subject of the email for that message.
+	SMTPSubjectKey = "grip-smtp-subject"
+)
+
 type smtpLogger struct {
 	opts *SMTPOptions
 	*Base
@@ -57,15 +76,7 @@ func MakeSMTPLogger(opts *SMTPOptions) (Sender, error) {
 		Base: NewBase(opts.Name),
 		opts: opts,
 	}
-
-	fallback := log.New(os.Stdout, "", log.LstdFlags)
-	if err := s.SetErrorHandler(ErrorHandlerFromLogger(fallback)); err != nil {
-		return nil, err
-	}
-
-	s.reset = func() {
-		fallback.SetPrefix(fmt.Sprintf("[%s] ", s.Name()))
-	}
+	s.closer = opts.close
 
 	s.SetName(opts.Name)
 
@@ -73,13 +84,24 @@ func MakeSMTPLogger(opts *SMTPOptions) (Sender, error) {
 }
 
 func (s *smtpLogger) Send(m message.Composer) {
-	if s.level.ShouldLog(m) {
+	if s.Level().ShouldLog(m) && !s.reportClosed(m) {
 		if err := s.opts.sendMail(m); err != nil {
-			s.errHandler(err, m)
+			s.ErrorHandler(err, m)
 		}
 	}
 }
 
+// SendContext sends the email, and returns when the context is
+// canceled, reporting the error of the context, even if the server
+// has not responded. The SMTP client cannot cancel an email in
+// progress, so the sender finishes sending it in the background, and
+// sends the emails that follow after it.
+func (s *smtpLogger) SendContext(ctx context.Context, m message.Composer) {
+	if s.Level().ShouldLog(m) {
+		sendUntilDone(ctx, s.Send, s.ErrorHandler, m)
+	}
+}
+
 ///////////////////////////////////////////////////////////////////////////
 //
 // Implemntation of the Configuration Object
@@ -96,8 +118,10 @@ func (s *smtpLogger) Send(m message.Composer) {
 //
 // In additional to constructing this object with the necessary
 // options. You must also set at least one recipient address using the
-// AddRecipient or AddRecipients functions. You can add or reset the
-// recipients after configuring the options or the sender.
+// AddRecipient or AddRecipients functions; Cc and Bcc recipients,
+// which the AddCC, AddBCC, AddCCRecipients, and AddBCCRecipients
+// functions add, are in addition to these recipients. You can add or
+// reset the recipients after configuring the options or the sender.
 type SMTPOptions struct {
 	// Name controls both the name of the logger, and the name on
 	// the from header field.
@@ -106,7 +130,9 @@ type SMTPOptions struct {
 	From string
 	// Server, Port, and UseSSL control how we connect to the SMTP
 	// server. If unspecified, these options default to
-	// "localhost", port, and false.
+	// "localhost", port 25 (587 for STARTTLS, and 465 for
+	// implicit TLS), and false. UseSSL is equivalent to a TLSMode
+	// of SMTPImplicitTLS.
 	Server string
 	Port   int
 	UseSSL bool
@@ -116,6 +142,14 @@ type SMTPOptions struct {
 	Username string
 	Password string
 
+	// TLSMode controls whether the connection to the server uses
+	// TLS, and TLSConfig, if specified, configures the TLS
+	// connection, for example to trust a private certificate
+	// authority with RootCAs. The ServerName of the TLS
+	// configuration defaults to the Server.
+	TLSMode   SMTPTLSMode
+	TLSConfig *tls.Config
+
 	// These options control the output behavior. You must specify
 	// a subject for the emails, *or* one of the bool options that
 	// specify how to generate the subject (e.g. NameAsSubject,
@@ -141,14 +175,226 @@ type SMTPOptions struct {
 	MessageAsSubject              bool
 	PlainTextContents             bool
 
+	// Attachments are files to attach to every email, and
+	// GetAttachments, if specified, returns the files to attach to
+	// the email for a message, such as the structured content of
+	// a report, which follow the Attachments. Emails with
+	// attachments are multipart/mixed, with the body from
+	// GetContents as the first part; HTML bodies (when
+	// PlainTextContents is false) are a multipart/alternative part
+	// with a plain text version of the message. All parts are base64
+	// encoded, so the content of the body and the attachments
+	// cannot contain the boundaries between the parts. Emails
+	// without attachments have a single part.
+	Attachments    []SMTPAttachment
+	GetAttachments func(*SMTPOptions, message.Composer) []SMTPAttachment
+
+	// KeepAlive, if specified, is how long the sender keeps its
+	// connection to the server open without sending a message.
+	// After that, the sender closes the connection, and reconnects
+	// when it sends the next message. With KeepAlive, the sender
+	// also reconnects when the server or the network drops the
+	// connection: immediately, if the connection dropped before the
+	// sender started to send a message, and otherwise on the next
+	// message. By default, the sender uses the connection that it
+	// opens when it is constructed for every message, and does not
+	// reconnect.
+	KeepAlive time.Duration
+
+	// RetryPolicy controls whether, and how, the sender retries
+	// emails that the server rejects with transient failures. By
+	// default, the sender does not retry.
+	RetryPolicy SMTPRetryPolicy
+
+	// BodyFormat controls the body of the emails. By default, the
+	// body is the body that GetContents returns, as plain text or
+	// HTML according to PlainTextContents. The JSON formats replace
+	// the body with the raw form of the message, as indented JSON,
+	// for systems that parse the emails; GetContents still
+	// provides the subject.
+	BodyFormat SMTPBodyFormat
+
+	// Headers are additional headers (e.g. "Reply-To" and
+	// "List-Unsubscribe") to write to every email, after the
+	// Subject and before the MIME headers. The sender writes the
+	// headers in the order of their names, and generates the From,
+	// To, Cc, Subject, and MIME headers itself, so Headers cannot
+	// set them. Values cannot contain carriage returns or line
+	// feeds.
+	Headers map[string]string
+
 	client   smtpClient
 	fromAddr *mail.Address
 	toAddrs  []*mail.Address
+	ccAddrs  []*mail.Address
+	bccAddrs []*mail.Address
 	mutex    sync.Mutex
+
+	// sendMutex serializes the use of the client, which holds a
+	// single connection to the server, and protects the state of
+	// the connection, while mutex protects the configuration.
+	sendMutex    sync.Mutex
+	disconnected bool
+	lastSend     time.Time
+	idleTimer    *time.Timer
+}
+
+// SMTPBodyFormat controls the format of the body of the emails that the
+// SMTP sender sends.
+type SMTPBodyFormat int
+
+const (
+	// SMTPBodyContents is the body that the GetContents function
+	// returns, and is the default.
+	SMTPBodyContents SMTPBodyFormat = iota
+
+	// SMTPBodyJSON is the raw form of the message, as JSON, with
+	// the application/json content type.
+	SMTPBodyJSON
+
+	// SMTPBodyJSONText is the raw form of the message, as JSON,
+	// with the text/plain content type, for mail systems that do
+	// not display application/json bodies.
+	SMTPBodyJSONText
+)
+
+// Validate returns an error if the format is not one of the defined
+// formats.
+func (f SMTPBodyFormat) Validate() error {
+	if f < SMTPBodyContents || f > SMTPBodyJSONText {
+		return fmt.Errorf("%d is not a valid smtp body format", f)
+	}
+
+	return nil
+}
+
+// SMTPTLSMode controls whether the SMTP sender uses TLS to connect to
+// the server.
+type SMTPTLSMode int
+
+const (
+	// SMTPTLSNone connects without TLS, and is the default, unless
+	// UseSSL is set.
+	SMTPTLSNone SMTPTLSMode = iota
+
+	// SMTPStartTLS connects without TLS, and upgrades the
+	// connection with the STARTTLS command before it authenticates
+	// or sends email. Connecting fails if the server does not
+	// support STARTTLS.
+	SMTPStartTLS
+
+	// SMTPImplicitTLS connects with TLS from the start (e.g. on
+	// port 465).
+	SMTPImplicitTLS
+)
+
+// Validate returns an error if the mode is not one of the defined
+// modes.
+func (m SMTPTLSMode) Validate() error {
+	if m < SMTPTLSNone || m > SMTPImplicitTLS {
+		return fmt.Errorf("%d is not a valid smtp tls mode", m)
+	}
+
+	return nil
+}
+
+// SMTPRetryPolicy configures the retries of emails that fail, which
+// wait for exponentially increasing delays, with jitter, between
+// attempts. The sender does not retry permanent (5xx) failures.
+type SMTPRetryPolicy struct {
+	// MaxAttempts is the number of times that the sender attempts
+	// to send an email. Values less than 2 disable retries.
+	MaxAttempts int
+
+	// BaseDelay is the delay before the first retry, which doubles
+	// for every retry that follows, up to the MaxDelay. The delays
+	// default to 500 milliseconds and 30 seconds.
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+}
+
+// Validate checks the policy, and sets defaults for unspecified
+// values.
+func (p *SMTPRetryPolicy) Validate() error {
+	errs := []string{}
+
+	if p.MaxAttempts < 0 {
+		errs = append(errs, "max attempts cannot be negative")
+	}
+
+	if p.BaseDelay < 0 {
+		errs = append(errs, "base delay cannot be negative")
+	}
+
+	if p.MaxDelay < 0 {
+		errs = append(errs, "max delay cannot be negative")
+	}
+
+	if p.BaseDelay == 0 {
+		p.BaseDelay = 500 * time.Millisecond
+	}
+
+	if p.MaxDelay == 0 {
+		p.MaxDelay = 30 * time.Second
+	}
+
+	if p.MaxDelay < p.BaseDelay {
+		errs = append(errs, "max delay cannot be less than the base delay")
+	}
+
+	if len(errs) > 0 {
+		return errors.New(strings.Join(errs, "; "))
+	}
+
+	return nil
 }
 
-// ResetRecipients removes all recipients from the configuration
-// object. You can reset the recipients at any time, but you must have
+// delay returns the delay before the retry, the first of which is 1.
+func (p SMTPRetryPolicy) delay(retry int) time.Duration {
+	return backoffDelay(p.BaseDelay, p.MaxDelay, retry)
+}
+
+// backoffDelay returns the delay before the retry, the first of which
+// is 1: the base delay doubled for every previous retry, up to the max
+// delay, less up to half of the delay at random, so that senders that
+// fail at the same time do not retry at the same time.
+func backoffDelay(base, max time.Duration, retry int) time.Duration {
+	d := base
+	for i := 1; i < retry && d < max; i++ {
+		d *= 2
+	}
+
+	if d > max {
+		d = max
+	}
+
+	if d <= 1 {
+		return d
+	}
+
+	return d - time.Duration(rand.Int63n(int64(d/2)+1))
+}
+
+// SMTPAttachment is a file attached to an email that the SMTP sender
+// sends.
+type SMTPAttachment struct {
+	// Filename is the name of the file, as the recipient sees it.
+	Filename string
+	// ContentType is the MIME type of the file (e.g.
+	// "application/json"), and defaults to
+	// "application/octet-stream".
+	ContentType string
+
+	// Content is the content of the file. Attachments that
+	// GetAttachments returns may instead have a Reader, which the
+	// sender reads when it sends the email; readers can only be
+	// read once, so do not use them for the Attachments option.
+	Content []byte
+	Reader  io.Reader
+}
+
+// ResetRecipients removes all recipients, including the Cc and Bcc
+// recipients, from the configuration object. You can reset the recipients at any time, but you must have
 // at least one recipient configured when you use this options object to
 // configure a sender *or* attempt to send a message.
 func (o *SMTPOptions) ResetRecipients() {
@@ -156,6 +402,8 @@ func (o *SMTPOptions) ResetRecipients() {
 	defer o.mutex.Unlock()
 
 	o.toAddrs = []*mail.Address{}
+	o.ccAddrs = []*mail.Address{}
+	o.bccAddrs = []*mail.Address{}
 }
 
 // AddRecipient takes a name and email address as an argument and
@@ -196,6 +444,103 @@ func (o *SMTPOptions) AddRecipients(addresses ...string) error {
 	return nil
 }
 
+// AddCC takes a name and email address as an argument and attempts
+// to parse a valid email address from this data, and if valid adds
+// this email address to the carbon copy (Cc) recipients.
+func (o *SMTPOptions) AddCC(name, address string) error {
+	addr, err := mail.ParseAddress(fmt.Sprintf("%s <%s>", name, address))
+	if err != nil {
+		return err
+	}
+
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	o.ccAddrs = append(o.ccAddrs, addr)
+	return nil
+}
+
+// AddBCC takes a name and email address as an argument and attempts
+// to parse a valid email address from this data, and if valid adds
+// this email address to the blind carbon copy (Bcc) recipients.
+func (o *SMTPOptions) AddBCC(name, address string) error {
+	addr, err := mail.ParseAddress(fmt.Sprintf("%s <%s>", name, address))
+	if err != nil {
+		return err
+	}
+
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	o.bccAddrs = append(o.bccAddrs, addr)
+	return nil
+}
+
+// ResetCCRecipients removes all carbon copy (Cc) recipients from the
+// configuration object.
+func (o *SMTPOptions) ResetCCRecipients() {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	o.ccAddrs = []*mail.Address{}
+}
+
+// AddCCRecipients accepts one or more strings that can be, themselves,
+// comma separated lists of email addresses, which are then added to
+// the carbon copy (Cc) recipients for the logger. Cc recipients
+// receive the email, and appear in its Cc header.
+func (o *SMTPOptions) AddCCRecipients(addresses ...string) error {
+	addrs, err := parseRecipients(addresses)
+	if err != nil {
+		return err
+	}
+
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	o.ccAddrs = append(o.ccAddrs, addrs...)
+
+	return nil
+}
+
+// ResetBCCRecipients removes all blind carbon copy (Bcc) recipients
+// from the configuration object.
+func (o *SMTPOptions) ResetBCCRecipients() {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	o.bccAddrs = []*mail.Address{}
+}
+
+// AddBCCRecipients accepts one or more strings that can be,
+// themselves, comma separated lists of email addresses, which are then
+// added to the blind carbon copy (Bcc) recipients for the logger. Bcc
+// recipients receive the email, but do not appear in its headers.
+func (o *SMTPOptions) AddBCCRecipients(addresses ...string) error {
+	addrs, err := parseRecipients(addresses)
+	if err != nil {
+		return err
+	}
+
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	o.bccAddrs = append(o.bccAddrs, addrs...)
+
+	return nil
+}
+
+// parseRecipients parses one or more comma separated lists of email
+// addresses.
+func parseRecipients(addresses []string) ([]*mail.Address, error) {
+	if len(addresses) == 0 {
+		return nil, errors.New("adding recipients requires one or more strings that contain comma " +
+			"separated email addresses")
+	}
+
+	return mail.ParseAddressList(strings.Join(addresses, ","))
+}
+
 // Validate checks the contents of the SMTPOptions struct and sets
 // default values in appropriate cases. Returns an error if the struct
 // is not valid. The constructor for the SMTP sender calls this method
@@ -210,8 +555,24 @@ func (o *SMTPOptions) Validate() error {
 
 	// setup defaults
 
+	errs := []string{}
+	if o.UseSSL {
+		if o.TLSMode == SMTPStartTLS {
+			errs = append(errs, "use ssl conflicts with the starttls mode")
+		} else {
+			o.TLSMode = SMTPImplicitTLS
+		}
+	}
+
 	if o.Port == 0 {
-		o.Port = 25
+		switch o.TLSMode {
+		case SMTPStartTLS:
+			o.Port = 587
+		case SMTPImplicitTLS:
+			o.Port = 465
+		default:
+			o.Port = 25
+		}
 	}
 
 	if o.Server == "" {
@@ -225,11 +586,12 @@ func (o *SMTPOptions) Validate() error {
 	if o.GetContents == nil {
 		o.PlainTextContents = true
 		o.GetContents = func(opts *SMTPOptions, m message.Composer) (string, string) {
-			msg := m.String()
+			// messages without text have a body with one field per line.
+			msg := messageText(m, "\n")
 
 			// we can assume that it's valid because Validate has already run
 			if o.MessageAsSubject {
-				return m.String(), ""
+				return messageText(m, " "), ""
 			}
 
 			if o.NameAsSubject {
@@ -259,7 +621,24 @@ func (o *SMTPOptions) Validate() error {
 
 	// validate user configuration options
 
-	errs := []string{}
+	if err := o.TLSMode.Validate(); err != nil {
+		errs = append(errs, err.Error())
+	}
+
+	// servers do not use implicit tls on the standard port for
+	// relaying, or starttls on the standard port for implicit tls.
+	if o.TLSMode == SMTPImplicitTLS && o.Port == 25 {
+		errs = append(errs, "implicit tls is not supported on port 25; use starttls")
+	}
+
+	if o.TLSMode == SMTPStartTLS && o.Port == 465 {
+		errs = append(errs, "starttls is not supported on port 465; use implicit tls")
+	}
+
+	if o.TLSConfig != nil && o.TLSMode == SMTPTLSNone {
+		errs = append(errs, "tls config specified without a tls mode")
+	}
+
 	if !o.MessageAsSubject && !o.NameAsSubject && o.TruncatedMessageSubjectLength == 0 && o.Subject == "" {
 		errs = append(errs, "no subject policy defined in SMTP options")
 	}
@@ -272,10 +651,27 @@ func (o *SMTPOptions) Validate() error {
 		errs = append(errs, "no name specified")
 	}
 
+	// Cc and Bcc recipients are in addition to the To recipients.
 	if len(o.toAddrs) < 1 {
 		errs = append(errs, "no recipient addresses defined.")
 	}
 
+	if o.KeepAlive < 0 {
+		errs = append(errs, "keep alive cannot be negative")
+	}
+
+	if err := o.RetryPolicy.Validate(); err != nil {
+		errs = append(errs, err.Error())
+	}
+
+	if err := o.BodyFormat.Validate(); err != nil {
+		errs = append(errs, err.Error())
+	}
+
+	if _, err := smtpCustomHeaders(o.Headers); err != nil {
+		errs = append(errs, err.Error())
+	}
+
 	// put additional pre-flight checks above this line, as needed.
 
 	if len(errs) > 0 {
@@ -285,66 +681,550 @@ func (o *SMTPOptions) Validate() error {
 	return nil
 }
 
+// smtpReservedHeaders are the headers that the sender generates, which
+// custom headers cannot set.
+var smtpReservedHeaders = map[string]bool{
+	"From":                      true,
+	"To":                        true,
+	"Cc":                        true,
+	"Bcc":                       true,
+	"Subject":                   true,
+	"Mime-Version":              true,
+	"Content-Type":              true,
+	"Content-Transfer-Encoding": true,
+}
+
+// smtpCustomHeaders returns the header lines for the custom headers,
+// sorted by name, or an error if any of the headers are reserved, or
+// have invalid names or values that could end the header.
+func smtpCustomHeaders(headers map[string]string) ([]string, error) {
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	errs := []string{}
+	lines := make([]string, 0, len(names))
+	for _, name := range names {
+		value := headers[name]
+		switch {
+		case !validHeaderName(name):
+			errs = append(errs, fmt.Sprintf("'%s' is not a valid header name", name))
+		case smtpReservedHeaders[textproto.CanonicalMIMEHeaderKey(name)]:
+			errs = append(errs, fmt.Sprintf("header '%s' cannot be set", name))
+		case strings.ContainsAny(value, "\r\n"):
+			errs = append(errs, fmt.Sprintf("header '%s' contains a line break", name))
+		default:
+			lines = append(lines, fmt.Sprintf("%s: %s", name, value))
+		}
+	}
+
+	if len(errs) > 0 {
+		return nil, errors.New(strings.Join(errs, "; "))
+	}
+
+	return lines, nil
+}
+
+// validHeaderName returns true if the name is a valid header field
+// name: printable ASCII characters other than the colon.
+func validHeaderName(name string) bool {
+	if name == "" {
+		return false
+	}
+
+	for _, r := range name {
+		if r < '!' || r > '~' || r == ':' {
+			return false
+		}
+	}
+
+	return true
+}
+
 /* Connects an SMTP server (usually localhost:25 in prod) and uses that to
    send an email with the body encoded in base64. */
 func (o *SMTPOptions) sendMail(m message.Composer) error {
+	// take a snapshot of the configuration, so that callers can
+	// modify the recipients while messages are in flight.
 	o.mutex.Lock()
-	defer o.mutex.Unlock()
+	toAddrs := append([]*mail.Address{}, o.toAddrs...)
+	ccAddrs := append([]*mail.Address{}, o.ccAddrs...)
+	bccAddrs := append([]*mail.Address{}, o.bccAddrs...)
+	from := o.From
+	fromAddr := o.fromAddr
+	getContents := o.GetContents
+	attachments := append([]SMTPAttachment{}, o.Attachments...)
+	getAttachments := o.GetAttachments
+	plainText := o.PlainTextContents
+	bodyFormat := o.BodyFormat
+	keepAlive := o.KeepAlive
+	retry := o.RetryPolicy
+	customHeaders, err := smtpCustomHeaders(o.Headers)
+	o.mutex.Unlock()
+
+	// messages with invalid recipients are sent to the configured
+	// recipients, and the error is reported after the email is
+	// sent.
+	m, recipients, overrideSubject := smtpOverrides(m)
+	var overrideErr error
+	if recipients != nil {
+		var addrs []*mail.Address
+		if addrs, overrideErr = smtpOverrideRecipients(recipients); overrideErr == nil {
+			toAddrs = addrs
+		}
+	}
 
-	if len(o.toAddrs) == 0 {
+	if len(toAddrs) == 0 {
 		return fmt.Errorf("no recipients specified, cannot send mail")
 	}
 
-	if err := o.client.Mail(o.From); err != nil {
-		return fmt.Errorf("Error establishing mail sender (%s): %+v", o.From, err)
+	if err != nil {
+		return err
 	}
 
-	var err error
-	var errs []string
-	var recpients []string
+	subject, body := getContents(o, m)
+	if overrideSubject != "" {
+		subject = overrideSubject
+	}
 
-	// Set the recipients
-	for _, target := range o.toAddrs {
-		addr := target.String()
-		if err = o.client.Rcpt(addr); err != nil {
-			errs = append(errs,
-				fmt.Sprintf("Error establishing mail recipient (%s): %+v", addr, err))
-			continue
+	bodyType := "text/html; charset=\"utf-8\""
+	if plainText {
+		bodyType = "text/plain; charset=\"utf-8\""
+	}
+
+	// messages that cannot be encoded are sent as text, and the
+	// error is reported after the email is sent.
+	var encodeErr error
+	if bodyFormat != SMTPBodyContents {
+		body, bodyType, encodeErr = smtpJSONBody(m, bodyFormat)
+	}
+
+	if getAttachments != nil {
+		attachments = append(attachments, getAttachments(o, m)...)
+	}
+
+	// build the multipart body before starting the transaction, so
+	// that invalid attachments do not produce an empty email.
+	var mixedType, mixedBody string
+	if len(attachments) > 0 {
+		mixedType, mixedBody, err = smtpMixedBody(body, bodyType, messageText(m, "\n"), attachments)
+		if err != nil {
+			return err
+		}
+	}
+
+	// the subject often comes from the message or the name of the
+	// sender, so none of the header values may end the header.
+	headers := []string{
+		fmt.Sprintf("From: %s", sanitizeHeader(fromAddr.String())),
+		fmt.Sprintf("To: %s", sanitizeHeader(joinAddresses(toAddrs))),
+	}
+	if len(ccAddrs) > 0 {
+		headers = append(headers, fmt.Sprintf("Cc: %s", sanitizeHeader(joinAddresses(ccAddrs))))
+	}
+	headers = append(headers, fmt.Sprintf("Subject: %s", sanitizeHeader(subject)))
+	headers = append(headers, customHeaders...)
+	headers = append(headers, "MIME-Version: 1.0")
+
+	switch {
+	case len(attachments) > 0:
+		headers = append(headers, fmt.Sprintf("Content-Type: %s", mixedType), "", mixedBody)
+	default:
+		headers = append(headers,
+			fmt.Sprintf("Content-Type: %s", bodyType),
+			"Content-Transfer-Encoding: base64",
+			base64.StdEncoding.EncodeToString([]byte(body)))
+	}
+
+	o.sendMutex.Lock()
+	defer o.sendMutex.Unlock()
+
+	if keepAlive > 0 {
+		defer o.scheduleIdleClose(keepAlive)
+	}
+
+	attempts := retry.MaxAttempts
+	if attempts < 1 {
+		attempts = 1
+	}
+
+	for attempt := 1; attempt <= attempts; attempt++ {
+		if attempt > 1 {
+			time.Sleep(retry.delay(attempt - 1))
+			// abort the failed transaction, if the connection
+			// is still open.
+			_ = o.client.Reset()
+		}
+
+		var permanent bool
+		if permanent, err = o.deliver(from, toAddrs, ccAddrs, bccAddrs, headers, keepAlive); err == nil {
+			if overrideErr != nil {
+				return overrideErr
+			}
+			return encodeErr
+		}
+
+		if attempt == 1 && (permanent || attempts == 1) {
+			return err
+		}
+
+		if permanent || attempt == attempts {
+			return fmt.Errorf("sending mail failed after %d attempts: %s", attempt, err.Error())
+		}
+	}
+
+	return err
+}
+
+// smtpOverrides returns the message without the SMTPRecipientsKey and
+// SMTPSubjectKey fields, and the values of the fields, if the message
+// is a Fields message with either of them. Otherwise, it returns the
+// message unchanged.
+func smtpOverrides(m message.Composer) (message.Composer, interface{}, string) {
+	fields, ok := m.Raw().(message.Fields)
+	if !ok {
+		return m, nil, ""
+	}
+
+	recipients, hasRecipients := fields[SMTPRecipientsKey]
+	subject, hasSubject := fields[SMTPSubjectKey]
+	if !hasRecipients && !hasSubject {
+		return m, nil, ""
+	}
+
+	// the raw form of the message has its "msg" and "time" fields,
+	// so the copy keeps the text and the time of the message.
+	stripped := message.Fields{}
+	for k, v := range fields {
+		if k != SMTPRecipientsKey && k != SMTPSubjectKey && k != "msg" {
+			stripped[k] = v
+		}
+	}
+	text, _ := fields["msg"].(string)
+	out := message.NewFieldsMessage(m.Priority(), text, stripped)
+
+	if subject == nil {
+		return out, recipients, ""
+	}
+
+	return out, recipients, fmt.Sprintf("%v", subject)
+}
+
+// smtpOverrideRecipients parses the value of the SMTPRecipientsKey
+// field of a message.
+func smtpOverrideRecipients(value interface{}) ([]*mail.Address, error) {
+	var addresses []string
+	switch v := value.(type) {
+	case string:
+		addresses = []string{v}
+	case []string:
+		addresses = v
+	case []interface{}:
+		for _, addr := range v {
+			str, ok := addr.(string)
+			if !ok {
+				return nil, fmt.Errorf("invalid %s value %v, sent to configured recipients", SMTPRecipientsKey, value)
+			}
+			addresses = append(addresses, str)
+		}
+	default:
+		return nil, fmt.Errorf("invalid %s value of type %T, sent to configured recipients", SMTPRecipientsKey, value)
+	}
+
+	addrs, err := parseRecipients(addresses)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s value, sent to configured recipients: %s", SMTPRecipientsKey, err.Error())
+	}
+
+	return addrs, nil
+}
+
+// deliver sends one email, with the headers and body in contents, to
+// the recipients, and reports whether the error, if any, is
+// permanent: the server rejected the email with a permanent (5xx)
+// failure, which retrying cannot resolve. The caller must hold the
+// sendMutex.
+func (o *SMTPOptions) deliver(from string, toAddrs, ccAddrs, bccAddrs []*mail.Address, contents []string, keepAlive time.Duration) (bool, error) {
+	if keepAlive > 0 && o.disconnected {
+		if err := o.reconnect(); err != nil {
+			return false, err
+		}
+	}
+
+	err := o.client.Mail(from)
+	if err != nil && keepAlive > 0 && isDroppedConnection(err) {
+		// the server closed the idle connection; nothing has
+		// been sent, so retry on a new connection.
+		if err = o.reconnect(); err != nil {
+			return false, err
+		}
+		err = o.client.Mail(from)
+	}
+	if err != nil {
+		o.checkConnection(keepAlive, err)
+		return isPermanentSMTPFailure(err), fmt.Errorf("Error establishing mail sender (%s): %+v", from, err)
+	}
+
+	var errs []string
+	permanent := true
+
+	// Set the recipients. Bcc recipients are only part of the
+	// envelope, and never appear in the headers.
+	for _, group := range [][]*mail.Address{toAddrs, ccAddrs, bccAddrs} {
+		for _, target := range group {
+			addr := target.String()
+			if err = o.client.Rcpt(addr); err != nil {
+				o.checkConnection(keepAlive, err)
+				permanent = permanent && isPermanentSMTPFailure(err)
+				errs = append(errs,
+					fmt.Sprintf("Error establishing mail recipient (%s): %+v", addr, err))
+			}
 		}
-		recpients = append(recpients, addr)
 	}
 	if len(errs) > 0 {
-		return errors.New(strings.Join(errs, "; "))
+		return permanent, errors.New(strings.Join(errs, "; "))
 	}
 
 	// Send the email body.
 	wc, err := o.client.Data()
 	if err != nil {
-		return err
+		o.checkConnection(keepAlive, err)
+		return isPermanentSMTPFailure(err), err
+	}
+	defer func() {
+		if cerr := wc.Close(); cerr != nil {
+			o.checkConnection(keepAlive, cerr)
+		}
+	}()
+
+	// write the body
+	_, err = bytes.NewBufferString(strings.Join(contents, "\r\n")).WriteTo(wc)
+	o.checkConnection(keepAlive, err)
+	return false, err
+}
+
+// joinAddresses returns the addresses as a comma separated list.
+func joinAddresses(addrs []*mail.Address) string {
+	out := make([]string, 0, len(addrs))
+	for _, addr := range addrs {
+		out = append(out, addr.String())
+	}
+
+	return strings.Join(out, ", ")
+}
+
+// isPermanentSMTPFailure returns true if the server rejected the
+// command with a permanent (5xx) failure, which retrying cannot
+// resolve.
+func isPermanentSMTPFailure(err error) bool {
+	tperr, ok := err.(*textproto.Error)
+	return ok && tperr.Code >= 500 && tperr.Code < 600
+}
+
+// reconnect closes the connection to the server, if it is open, and
+// opens a new connection. The caller must hold the sendMutex.
+func (o *SMTPOptions) reconnect() error {
+	if !o.disconnected {
+		_ = o.client.Close()
+		o.disconnected = true
+	}
+
+	if err := o.client.Create(o); err != nil {
+		return fmt.Errorf("reconnecting to %s:%d: %s", o.Server, o.Port, err.Error())
+	}
+
+	o.disconnected = false
+	return nil
+}
+
+// checkConnection marks the connection as closed, so that the next
+// message reconnects, if the error shows that the connection dropped
+// and the options keep connections alive. The caller must hold the
+// sendMutex.
+func (o *SMTPOptions) checkConnection(keepAlive time.Duration, err error) {
+	if keepAlive > 0 && err != nil && isDroppedConnection(err) {
+		_ = o.client.Close()
+		o.disconnected = true
+	}
+}
+
+// scheduleIdleClose closes the connection after the keep alive
+// elapses without a message. The caller must hold the sendMutex.
+func (o *SMTPOptions) scheduleIdleClose(keepAlive time.Duration) {
+	o.lastSend = time.Now()
+	if o.idleTimer != nil {
+		o.idleTimer.Stop()
 	}
-	defer wc.Close()
 
-	subject, body := o.GetContents(o, m)
+	o.idleTimer = time.AfterFunc(keepAlive, func() {
+		o.sendMutex.Lock()
+		defer o.sendMutex.Unlock()
 
-	contents := []string{
-		fmt.Sprintf("From: %s", o.fromAddr.String()),
-		fmt.Sprintf("To: %s", strings.Join(recpients, ", ")),
-		fmt.Sprintf("Subject: %s", subject),
-		"MIME-Version: 1.0",
+		if o.disconnected || time.Since(o.lastSend) < keepAlive {
+			return
+		}
+
+		_ = o.client.Close()
+		o.disconnected = true
+	})
+}
+
+// close closes the connection to the server, if it is open.
+func (o *SMTPOptions) close() error {
+	o.sendMutex.Lock()
+	defer o.sendMutex.Unlock()
+
+	if o.idleTimer != nil {
+		o.idleTimer.Stop()
+	}
+
+	if o.disconnected {
+		return nil
+	}
+
+	o.disconnected = true
+	return o.client.Close()
+}
+
+// isDroppedConnection returns true for the errors that the SMTP
+// client returns when the server, or the network, closed the
+// connection.
+func isDroppedConnection(err error) bool {
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return true
+	}
+
+	if tperr, ok := err.(*textproto.Error); ok {
+		// 421: the service is not available, and is closing the
+		// connection.
+		return tperr.Code == 421
+	}
+
+	msg := err.Error()
+	for _, dropped := range []string{"use of closed network connection", "broken pipe", "connection reset by peer"} {
+		if strings.Contains(msg, dropped) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// smtpJSONBody returns the raw form of the message as indented JSON,
+// and its content type. Messages that cannot be encoded have their
+// text as a plain text body, and an error.
+func smtpJSONBody(m message.Composer, format SMTPBodyFormat) (string, string, error) {
+	out, err := json.MarshalIndent(m.Raw(), "", "  ")
+	if err != nil {
+		return messageText(m, "\n"), "text/plain; charset=\"utf-8\"",
+			fmt.Errorf("encoding message as json, sent as text: %s", err.Error())
+	}
+
+	if format == SMTPBodyJSONText {
+		return string(out), "text/plain; charset=\"utf-8\"", nil
 	}
 
-	if o.PlainTextContents {
-		contents = append(contents, "Content-Type: text/plain; charset=\"utf-8\"")
+	return string(out), "application/json; charset=\"utf-8\"", nil
+}
+
+// smtpMixedBody returns the content type and the content of a
+// multipart/mixed email body, with the body, which has the content
+// type bodyType, and the attachments. HTML bodies are a
+// multipart/alternative part, with the text as the plain text
+// alternative.
+func smtpMixedBody(body, bodyType, text string, attachments []SMTPAttachment) (string, string, error) {
+	buf := &bytes.Buffer{}
+	mixed := multipart.NewWriter(buf)
+
+	if !strings.HasPrefix(bodyType, "text/html") {
+		if err := writeBase64Part(mixed, bodyType, "", []byte(body)); err != nil {
+			return "", "", err
+		}
 	} else {
-		contents = append(contents, "Content-Type: text/html; charset=\"utf-8\"")
+		altBuf := &bytes.Buffer{}
+		alt := multipart.NewWriter(altBuf)
+		if err := writeBase64Part(alt, "text/plain; charset=\"utf-8\"", "", []byte(text)); err != nil {
+			return "", "", err
+		}
+		if err := writeBase64Part(alt, "text/html; charset=\"utf-8\"", "", []byte(body)); err != nil {
+			return "", "", err
+		}
+		if err := alt.Close(); err != nil {
+			return "", "", err
+		}
+
+		part, err := mixed.CreatePart(textproto.MIMEHeader{
+			"Content-Type": {mime.FormatMediaType("multipart/alternative", map[string]string{"boundary": alt.Boundary()})},
+		})
+		if err != nil {
+			return "", "", err
+		}
+		if _, err = altBuf.WriteTo(part); err != nil {
+			return "", "", err
+		}
 	}
 
-	contents = append(contents,
-		"Content-Transfer-Encoding: base64",
-		base64.StdEncoding.EncodeToString([]byte(body)))
+	for _, a := range attachments {
+		contentType := a.ContentType
+		if contentType == "" {
+			contentType = "application/octet-stream"
+		}
+		if _, _, err := mime.ParseMediaType(contentType); err != nil {
+			return "", "", fmt.Errorf("invalid content type '%s' for attachment '%s': %s", contentType, a.Filename, err.Error())
+		}
+
+		disposition := mime.FormatMediaType("attachment", map[string]string{"filename": a.Filename})
+		if disposition == "" {
+			return "", "", fmt.Errorf("invalid attachment filename '%s'", a.Filename)
+		}
 
-	// write the body
-	_, err = bytes.NewBufferString(strings.Join(contents, "\r\n")).WriteTo(wc)
+		content := a.Content
+		if a.Reader != nil {
+			var err error
+			if content, err = ioutil.ReadAll(a.Reader); err != nil {
+				return "", "", fmt.Errorf("reading attachment '%s': %s", a.Filename, err.Error())
+			}
+		}
+
+		if err := writeBase64Part(mixed, contentType, disposition, content); err != nil {
+			return "", "", err
+		}
+	}
+
+	if err := mixed.Close(); err != nil {
+		return "", "", err
+	}
+
+	return mime.FormatMediaType("multipart/mixed", map[string]string{"boundary": mixed.Boundary()}), buf.String(), nil
+}
+
+// writeBase64Part adds a base64 encoded part to the multipart body,
+// with lines of at most 76 characters, as MIME requires.
+func writeBase64Part(w *multipart.Writer, contentType, disposition string, content []byte) error {
+	header := textproto.MIMEHeader{
+		"Content-Type":              {contentType},
+		"Content-Transfer-Encoding": {"base64"},
+	}
+	if disposition != "" {
+		header.Set("Content-Disposition", disposition)
+	}
+
+	part, err := w.CreatePart(header)
+	if err != nil {
+		return err
+	}
+
+	encoded := base64.StdEncoding.EncodeToString(content)
+	lines := make([]string, 0, len(encoded)/76+1)
+	for len(encoded) > 76 {
+		lines = append(lines, encoded[:76])
+		encoded = encoded[76:]
+	}
+	lines = append(lines, encoded)
+
+	_, err = io.WriteString(part, strings.Join(lines, "\r\n"))
 	return err
 }
 
@@ -359,30 +1239,105 @@ type smtpClient interface {
 	Mail(string) error
 	Rcpt(string) error
 	Data() (io.WriteCloser, error)
+	Reset() error
+	Close() error
+}
+
+// smtpTLSClient is the part of an SMTP client that upgrades the
+// connection to TLS.
+type smtpTLSClient interface {
+	Extension(string) (bool, string)
+	StartTLS(*tls.Config) error
+}
+
+// negotiateTLS upgrades the connection with STARTTLS, if the options
+// specify the STARTTLS mode, and returns an error if the server does
+// not support it.
+func negotiateTLS(c smtpTLSClient, opts *SMTPOptions) error {
+	if opts.tlsMode() != SMTPStartTLS {
+		return nil
+	}
+
+	if ok, _ := c.Extension("STARTTLS"); !ok {
+		return fmt.Errorf("smtp server %s does not support starttls", opts.Server)
+	}
+
+	if err := c.StartTLS(opts.tlsConfig()); err != nil {
+		return fmt.Errorf("starting tls with smtp server %s: %s", opts.Server, err.Error())
+	}
+
+	return nil
+}
+
+// tlsMode returns the TLS mode of the options, including the mode
+// that UseSSL implies.
+func (o *SMTPOptions) tlsMode() SMTPTLSMode {
+	if o.UseSSL && o.TLSMode == SMTPTLSNone {
+		return SMTPImplicitTLS
+	}
+
+	return o.TLSMode
+}
+
+// tlsConfig returns a copy of the TLS configuration of the options,
+// with the server name of the server, unless the configuration
+// specifies one.
+func (o *SMTPOptions) tlsConfig() *tls.Config {
+	conf := &tls.Config{}
+	if o.TLSConfig != nil {
+		conf = o.TLSConfig.Clone()
+	}
+
+	if conf.ServerName == "" {
+		conf.ServerName = o.Server
+	}
+
+	return conf
 }
 
 type smtpClientImpl struct {
 	*smtp.Client
 }
 
+// Close ends the session with the server, and closes the connection.
+func (c *smtpClientImpl) Close() error {
+	if c.Client == nil {
+		return nil
+	}
+
+	if err := c.Client.Quit(); err != nil {
+		return c.Client.Close()
+	}
+
+	return nil
+}
+
 func (c *smtpClientImpl) Create(opts *SMTPOptions) error {
 	var err error
 
-	if opts.UseSSL {
+	addr := fmt.Sprintf("%v:%v", opts.Server, opts.Port)
+	if opts.tlsMode() == SMTPImplicitTLS {
 		var tlsCon *tls.Conn
-		tlsCon, err = tls.Dial("tcp", fmt.Sprintf("%v:%v", opts.Server, opts.Port), &tls.Config{})
+		tlsCon, err = tls.Dial("tcp", addr, opts.tlsConfig())
 		if err != nil {
 			return err
 		}
 		c.Client, err = smtp.NewClient(tlsCon, opts.Server)
 	} else {
-		c.Client, err = smtp.Dial(fmt.Sprintf("%v:%v", opts.Server, opts.Port))
+		c.Client, err = smtp.Dial(addr)
 	}
 
 	if err != nil {
 		return err
 	}
 
+	// never authenticate or send email over a connection that
+	// should have been upgraded.
+	if err = negotiateTLS(c.Client, opts); err != nil {
+		_ = c.Client.Close()
+		return err
+	}
+
 	if opts.Username != "" {
 		if err = c.Client.Auth(smtp.PlainAuth("", opts.Username, opts.Password, opts.Server)); err != nil {
 			return err
@@ -2,8 +2,10 @@ package send
 
 import (
 	"bytes"
+	"crypto/tls"
 	"errors"
 	"io"
+	"net/textproto"
 )
 
 type bufferCloser struct {
@@ -21,6 +23,54 @@ type smtpClientMock struct {
 	failData   bool
 	message    bufferCloser
 	numMsgs    int
+
+	// dropped makes the client act as though the server closed
+	// the connection, until the next call to Create.
+	dropped    bool
+	numCreates int
+	numCloses  int
+
+	// recipients are the envelope recipients of the last email.
+	recipients []string
+
+	// transientFailures is the number of calls to Mail that fail
+	// with a transient error, before Mail fails with mailFailure,
+	// if set, or succeeds.
+	transientFailures int
+	mailFailure       error
+	numMailCalls      int
+	numResets         int
+
+	// startTLS makes the client advertise the STARTTLS extension,
+	// and failStartTLS makes the upgrade fail. tlsConfig is the
+	// configuration of the last upgrade.
+	startTLS     bool
+	failStartTLS bool
+	numStartTLS  int
+	tlsConfig    *tls.Config
+
+	// hang, if set, makes Mail wait until it is closed, like a
+	// server that does not respond.
+	hang chan struct{}
+}
+
+func (c *smtpClientMock) Extension(name string) (bool, string) {
+	return name == "STARTTLS" && c.startTLS, ""
+}
+
+func (c *smtpClientMock) StartTLS(conf *tls.Config) error {
+	if c.failStartTLS {
+		return errors.New("tls handshake failed")
+	}
+
+	c.numStartTLS++
+	c.tlsConfig = conf
+	return nil
+}
+
+func (c *smtpClientMock) Reset() error {
+	c.numResets++
+	return nil
 }
 
 func (c *smtpClientMock) Create(opts *SMTPOptions) error {
@@ -28,14 +78,46 @@ func (c *smtpClientMock) Create(opts *SMTPOptions) error {
 		return errors.New("failed creation")
 	}
 
+	if err := negotiateTLS(c, opts); err != nil {
+		return err
+	}
+
+	c.numCreates++
+	c.dropped = false
+
+	return nil
+}
+
+func (c *smtpClientMock) Close() error {
+	c.numCloses++
 	return nil
 }
 
 func (c *smtpClientMock) Mail(to string) error {
+	if c.hang != nil {
+		<-c.hang
+	}
+
 	if c.failMail {
 		return errors.New("failed to send mail")
 	}
 
+	if c.dropped {
+		return io.EOF
+	}
+
+	c.numMailCalls++
+	if c.transientFailures > 0 {
+		c.transientFailures--
+		return &textproto.Error{Code: 451, Msg: "try again later"}
+	}
+
+	if c.mailFailure != nil {
+		return c.mailFailure
+	}
+
+	c.recipients = nil
+
 	return nil
 }
 
@@ -44,6 +126,8 @@ func (c *smtpClientMock) Rcpt(addr string) error {
 		return errors.New("fail recpt")
 	}
 
+	c.recipients = append(c.recipients, addr)
+
 	return nil
 }
 
@@ -1,12 +1,23 @@
 package send
 
 import (
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
 	"net/mail"
+	"net/textproto"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/mongodb/grip/level"
 	"github.com/mongodb/grip/message"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/suite"
 )
 
@@ -181,6 +192,11 @@ func (s *SMTPSuite) TestSendMailErrorsIfNoAddresses() {
 
 	m := message.NewString("hello world!")
 	s.Error(s.opts.sendMail(m))
+
+	s.NoError(s.opts.AddCC("team", "team@example.com"))
+	s.NoError(s.opts.AddBCC("audit", "audit@example.com"))
+	s.Error(s.opts.sendMail(m))
+	s.Error(s.opts.Validate())
 }
 
 func (s *SMTPSuite) TestSendMailErrorsIfMailCallFails() {
@@ -280,3 +296,695 @@ func (s *SMTPSuite) TestSendMethodWithError() {
 	sender.Send(m)
 	s.Equal(mock.numMsgs, 1)
 }
+
+func (s *SMTPSuite) TestErrorHandlerReceivesFailedMessages() {
+	sender, err := NewSMTPLogger(s.opts, LevelInfo{level.Trace, level.Info})
+	s.Require().NoError(err)
+
+	var failures []error
+	var failed []message.Composer
+	s.NoError(sender.SetErrorHandler(func(err error, m message.Composer) {
+		if err != nil {
+			failures = append(failures, err)
+			failed = append(failed, m)
+		}
+	}))
+
+	mock, ok := s.opts.client.(*smtpClientMock)
+	s.Require().True(ok)
+
+	sender.Send(message.NewDefaultMessage(level.Alert, "delivered"))
+	s.Empty(failures)
+
+	mock.failData = true
+	m := message.NewDefaultMessage(level.Alert, "dropped")
+	sender.Send(m)
+	s.Require().Len(failures, 1)
+	s.Contains(failures[0].Error(), "failed data")
+	s.True(m == failed[0])
+}
+
+func (s *SMTPSuite) TestModifyRecipientsWhileSending() {
+	sender, err := NewSMTPLogger(s.opts, LevelInfo{level.Trace, level.Info})
+	s.Require().NoError(err)
+	// sends fail when they happen between resetting and adding recipients
+	s.NoError(sender.SetErrorHandler(func(error, message.Composer) {}))
+
+	wg := &sync.WaitGroup{}
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				sender.Send(message.NewDefaultMessage(level.Alert, "world"))
+			}
+		}()
+	}
+
+	for i := 0; i < 50; i++ {
+		s.NoError(s.opts.AddRecipient("one", "one@example.net"))
+		s.NoError(s.opts.AddRecipients("two@example.net", "three@example.net"))
+		s.opts.ResetRecipients()
+		s.NoError(s.opts.AddRecipient("four", "four@example.net"))
+	}
+	wg.Wait()
+
+	mock, ok := s.opts.client.(*smtpClientMock)
+	s.Require().True(ok)
+	s.True(mock.numMsgs > 0)
+	s.Len(s.opts.toAddrs, 1)
+}
+
+func (s *SMTPSuite) TestHeadersNeutralizeInjectedLines() {
+	mock, ok := s.opts.client.(*smtpClientMock)
+	s.Require().True(ok)
+
+	headers := func() []string {
+		lines := strings.Split(mock.message.String(), "\r\n")
+		// the last line is the encoded body.
+		return lines[:len(lines)-1]
+	}
+
+	hostile := "hello\r\nBcc: attacker@example.com\nX-Injected: yes"
+
+	s.opts.NameAsSubject = false
+	s.opts.MessageAsSubject = true
+	s.NoError(s.opts.sendMail(message.NewString(hostile)))
+	s.Require().Len(headers(), 6)
+	for _, line := range headers() {
+		s.False(strings.HasPrefix(line, "Bcc:"), line)
+		s.False(strings.HasPrefix(line, "X-Injected:"), line)
+	}
+	s.Contains(headers(), "Subject: hello  Bcc: attacker@example.com X-Injected: yes")
+
+	s.opts.MessageAsSubject = false
+	s.opts.NameAsSubject = true
+	s.opts.Name = hostile
+	s.NoError(s.opts.sendMail(message.NewString("body\nwith lines")))
+	s.Require().Len(headers(), 6)
+	for _, line := range headers() {
+		s.False(strings.HasPrefix(line, "Bcc:"), line)
+		s.False(strings.HasPrefix(line, "X-Injected:"), line)
+	}
+
+	// bodies keep their newlines.
+	lines := strings.Split(mock.message.String(), "\r\n")
+	body, err := base64.StdEncoding.DecodeString(lines[len(lines)-1])
+	s.NoError(err)
+	s.Equal("body\nwith lines", string(body))
+}
+
+func (s *SMTPSuite) TestSendMailWithCustomHeaders() {
+	mock, ok := s.opts.client.(*smtpClientMock)
+	s.Require().True(ok)
+
+	s.opts.Headers = map[string]string{
+		"Reply-To":         "team@example.com",
+		"List-Unsubscribe": "<mailto:unsubscribe@example.com>",
+	}
+	s.NoError(s.opts.Validate())
+	s.NoError(s.opts.sendMail(message.NewString("hello world!")))
+
+	lines := strings.Split(mock.message.String(), "\r\n")
+	s.Require().True(len(lines) > 6)
+	s.True(strings.HasPrefix(lines[2], "Subject: "), lines[2])
+	s.Equal("List-Unsubscribe: <mailto:unsubscribe@example.com>", lines[3])
+	s.Equal("Reply-To: team@example.com", lines[4])
+	s.Equal("MIME-Version: 1.0", lines[5])
+
+	for _, headers := range []map[string]string{
+		{"Reply-To": "team@example.com\r\nBcc: attacker@example.com"},
+		{"X-Note": "line\nbreak"},
+		{"subject": "override"},
+		{"From": "someone@example.com"},
+		{"content-type": "text/html"},
+		{"Bad Name": "value"},
+		{"": "value"},
+	} {
+		s.opts.Headers = headers
+		s.Error(s.opts.Validate(), "%v", headers)
+		s.Error(s.opts.sendMail(message.NewString("hello world!")), "%v", headers)
+	}
+}
+
+func (s *SMTPSuite) TestSendMailWithJSONBody() {
+	mock, ok := s.opts.client.(*smtpClientMock)
+	s.Require().True(ok)
+
+	sent := func() ([]string, string) {
+		lines := strings.Split(mock.message.String(), "\r\n")
+		body, err := base64.StdEncoding.DecodeString(lines[len(lines)-1])
+		s.Require().NoError(err)
+		return lines[:len(lines)-1], string(body)
+	}
+
+	s.opts.BodyFormat = SMTPBodyJSON
+	s.NoError(s.opts.Validate())
+
+	m := message.NewFieldsMessage(level.Alert, "disk full", message.Fields{"host": "db-1", "used": 95})
+	s.NoError(s.opts.sendMail(m))
+
+	headers, body := sent()
+	s.Contains(headers, "Subject: test smtp sender")
+	s.Contains(headers, "Content-Type: application/json; charset=\"utf-8\"")
+
+	doc := map[string]interface{}{}
+	s.Require().NoError(json.Unmarshal([]byte(body), &doc))
+	s.Equal("disk full", doc["msg"])
+	s.Equal("db-1", doc["host"])
+	s.Equal(float64(95), doc["used"])
+	s.Contains(body, "\n  \"host\": \"db-1\"")
+
+	s.opts.BodyFormat = SMTPBodyJSONText
+	s.NoError(s.opts.sendMail(m))
+	headers, body = sent()
+	s.Contains(headers, "Content-Type: text/plain; charset=\"utf-8\"")
+	s.True(json.Valid([]byte(body)))
+
+	// messages that cannot be encoded are sent as text.
+	unencodable := message.NewFieldsMessage(level.Alert, "callback", message.Fields{"fn": func() {}})
+	err := s.opts.sendMail(unencodable)
+	s.Require().Error(err)
+	s.Contains(err.Error(), "encoding message as json")
+	s.Equal(3, mock.numMsgs)
+	headers, body = sent()
+	s.Contains(headers, "Content-Type: text/plain; charset=\"utf-8\"")
+	s.Equal(unencodable.String(), body)
+
+	s.opts.BodyFormat = SMTPBodyFormat(5)
+	s.Error(s.opts.Validate())
+}
+
+func (s *SMTPSuite) TestSendMailWithAttachments() {
+	mock, ok := s.opts.client.(*smtpClientMock)
+	s.Require().True(ok)
+	m := message.NewString("nightly report")
+
+	// without attachments the email has a single part.
+	s.NoError(s.opts.sendMail(m))
+	single := mock.message.String()
+	s.opts.GetAttachments = func(*SMTPOptions, message.Composer) []SMTPAttachment { return nil }
+	s.NoError(s.opts.sendMail(m))
+	s.Equal(single, mock.message.String())
+
+	report := []byte(strings.Repeat(`{"ok":true}`, 20))
+	binary := make([]byte, 300)
+	for i := range binary {
+		binary[i] = byte(i)
+	}
+	s.opts.GetAttachments = func(*SMTPOptions, message.Composer) []SMTPAttachment {
+		return []SMTPAttachment{
+			{Filename: "report.json", ContentType: "application/json", Content: report},
+			{Filename: "data.bin", Content: binary},
+		}
+	}
+
+	parse := func() (*multipart.Reader, string) {
+		msg, err := mail.ReadMessage(strings.NewReader(mock.message.String()))
+		s.Require().NoError(err)
+		mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+		s.Require().NoError(err)
+		return multipart.NewReader(msg.Body, params["boundary"]), mediaType
+	}
+	readPart := func(part *multipart.Part) []byte {
+		s.Equal("base64", part.Header.Get("Content-Transfer-Encoding"))
+		encoded, err := ioutil.ReadAll(part)
+		s.Require().NoError(err)
+		for _, line := range strings.Split(string(encoded), "\r\n") {
+			s.True(len(line) <= 76, line)
+		}
+		content, err := base64.StdEncoding.DecodeString(strings.Replace(string(encoded), "\r\n", "", -1))
+		s.Require().NoError(err)
+		return content
+	}
+	checkAttachments := func(r *multipart.Reader) {
+		part, err := r.NextPart()
+		s.Require().NoError(err)
+		s.Equal("application/json", part.Header.Get("Content-Type"))
+		s.Equal("report.json", part.FileName())
+		s.Equal(report, readPart(part))
+
+		part, err = r.NextPart()
+		s.Require().NoError(err)
+		s.Equal("application/octet-stream", part.Header.Get("Content-Type"))
+		s.Equal("data.bin", part.FileName())
+		s.Equal(binary, readPart(part))
+
+		_, err = r.NextPart()
+		s.Equal(io.EOF, err)
+	}
+
+	s.NoError(s.opts.sendMail(m))
+	r, mediaType := parse()
+	s.Equal("multipart/mixed", mediaType)
+	part, err := r.NextPart()
+	s.Require().NoError(err)
+	s.Contains(part.Header.Get("Content-Type"), "text/plain")
+	s.Equal("nightly report", string(readPart(part)))
+	checkAttachments(r)
+
+	// html bodies are an alternative to a plain text version.
+	s.opts.PlainTextContents = false
+	s.NoError(s.opts.sendMail(m))
+	r, _ = parse()
+	part, err = r.NextPart()
+	s.Require().NoError(err)
+	mediaType, params, err := mime.ParseMediaType(part.Header.Get("Content-Type"))
+	s.Require().NoError(err)
+	s.Equal("multipart/alternative", mediaType)
+	alt := multipart.NewReader(part, params["boundary"])
+	for _, contentType := range []string{"text/plain", "text/html"} {
+		altPart, err := alt.NextPart()
+		s.Require().NoError(err)
+		s.Contains(altPart.Header.Get("Content-Type"), contentType)
+		s.Equal("nightly report", string(readPart(altPart)))
+	}
+	checkAttachments(r)
+
+	// invalid attachments do not send an email.
+	sent := mock.numMsgs
+	s.opts.GetAttachments = func(*SMTPOptions, message.Composer) []SMTPAttachment {
+		return []SMTPAttachment{{Filename: "report", ContentType: "not a type"}}
+	}
+	s.Error(s.opts.sendMail(m))
+	s.Equal(sent, mock.numMsgs)
+}
+
+func (s *SMTPSuite) TestKeepAliveReusesAndReestablishesConnections() {
+	mock := &smtpClientMock{}
+	s.opts.client = mock
+	sender, err := NewSMTPLogger(s.opts, LevelInfo{level.Trace, level.Info})
+	s.Require().NoError(err)
+	s.Equal(1, mock.numCreates)
+
+	// without keep alive, the sender uses one connection, and does
+	// not reconnect.
+	for i := 0; i < 5; i++ {
+		s.NoError(s.opts.sendMail(message.NewString("hello")))
+	}
+	s.Equal(1, mock.numCreates)
+	mock.dropped = true
+	s.Error(s.opts.sendMail(message.NewString("hello")))
+	s.Equal(1, mock.numCreates)
+	mock.dropped = false
+
+	s.opts.KeepAlive = 100 * time.Millisecond
+	for i := 0; i < 5; i++ {
+		s.NoError(s.opts.sendMail(message.NewString("hello")))
+	}
+	s.Equal(1, mock.numCreates)
+
+	// dropped connections reconnect transparently.
+	mock.dropped = true
+	s.NoError(s.opts.sendMail(message.NewString("hello")))
+	s.Equal(2, mock.numCreates)
+	s.Equal(1, mock.numCloses)
+
+	// idle connections close, and reconnect on the next message.
+	idle := func() bool {
+		s.opts.sendMutex.Lock()
+		defer s.opts.sendMutex.Unlock()
+		return s.opts.disconnected
+	}
+	for deadline := time.Now().Add(time.Second); !idle() && time.Now().Before(deadline); {
+		time.Sleep(10 * time.Millisecond)
+	}
+	s.True(idle())
+	s.Equal(2, mock.numCloses)
+	s.NoError(s.opts.sendMail(message.NewString("hello")))
+	s.Equal(3, mock.numCreates)
+
+	s.NoError(sender.Close())
+	s.Equal(3, mock.numCloses)
+}
+
+func (s *SMTPSuite) TestCCAndBCCRecipients() {
+	mock, ok := s.opts.client.(*smtpClientMock)
+	s.Require().True(ok)
+
+	s.Error(s.opts.AddCCRecipients())
+	s.Error(s.opts.AddBCCRecipients())
+	s.Error(s.opts.AddCCRecipients("not an address"))
+	s.Error(s.opts.AddBCCRecipients("not an address"))
+
+	s.NoError(s.opts.AddCCRecipients("team <team@example.com>"))
+	s.NoError(s.opts.AddBCCRecipients("audit@example.com, archive@example.com"))
+	s.NoError(s.opts.sendMail(message.NewString("hello")))
+
+	s.Equal([]string{
+		"\"one\" <two@>",
+		"\"team\" <team@example.com>",
+		"<audit@example.com>",
+		"<archive@example.com>",
+	}, mock.recipients)
+
+	headers := strings.Split(mock.message.String(), "\r\n")
+	s.Contains(headers, "To: \"one\" <two@>")
+	s.Contains(headers, "Cc: \"team\" <team@example.com>")
+	s.NotContains(mock.message.String(), "audit@example.com")
+	s.NotContains(mock.message.String(), "archive@example.com")
+
+	// cc and bcc recipients require a to recipient.
+	s.opts.toAddrs = nil
+	s.Error(s.opts.Validate())
+	s.Error(s.opts.sendMail(message.NewString("hello")))
+
+	s.opts.ResetRecipients()
+	s.Len(s.opts.toAddrs, 0)
+	s.Len(s.opts.ccAddrs, 0)
+	s.Len(s.opts.bccAddrs, 0)
+
+	s.Error(s.opts.AddCC("team", "not an address"))
+	s.Error(s.opts.AddBCC("audit", "not an address"))
+	s.NoError(s.opts.AddRecipient("one", "one@example.com"))
+	s.NoError(s.opts.AddCC("team", "team@example.com"))
+	s.NoError(s.opts.AddBCC("audit", "audit@example.com"))
+	s.NoError(s.opts.Validate())
+	s.NoError(s.opts.sendMail(message.NewString("hello")))
+	s.Equal([]string{
+		"\"one\" <one@example.com>",
+		"\"team\" <team@example.com>",
+		"\"audit\" <audit@example.com>",
+	}, mock.recipients)
+	s.NotContains(mock.message.String(), "audit@example.com")
+
+	s.opts.ResetCCRecipients()
+	s.opts.ResetBCCRecipients()
+	s.NoError(s.opts.sendMail(message.NewString("hello")))
+	s.Equal([]string{"\"one\" <one@example.com>"}, mock.recipients)
+	for _, line := range strings.Split(mock.message.String(), "\r\n") {
+		s.False(strings.HasPrefix(line, "Cc:"), line)
+	}
+}
+
+func (s *SMTPSuite) TestPerMessageRecipientsAndSubject() {
+	mock, ok := s.opts.client.(*smtpClientMock)
+	s.Require().True(ok)
+
+	sender, err := NewSMTPLogger(s.opts, LevelInfo{level.Trace, level.Info})
+	s.Require().NoError(err)
+
+	var failures []error
+	s.NoError(sender.SetErrorHandler(func(err error, m message.Composer) {
+		if err != nil {
+			failures = append(failures, err)
+		}
+	}))
+
+	decodedBody := func() string {
+		lines := strings.Split(mock.message.String(), "\r\n")
+		body, err := base64.StdEncoding.DecodeString(lines[len(lines)-1])
+		s.Require().NoError(err)
+		return string(body)
+	}
+
+	sender.Send(message.NewFieldsMessage(level.Alert, "disk full", message.Fields{
+		SMTPRecipientsKey: []string{"oncall <oncall@example.com>", "db@example.com"},
+		SMTPSubjectKey:    "database alert",
+		"host":            "db-1",
+	}))
+	s.Empty(failures)
+	s.Equal(1, mock.numMsgs)
+	s.Equal([]string{"\"oncall\" <oncall@example.com>", "<db@example.com>"}, mock.recipients)
+
+	headers := strings.Split(mock.message.String(), "\r\n")
+	s.Contains(headers, "To: \"oncall\" <oncall@example.com>, <db@example.com>")
+	s.Contains(headers, "Subject: database alert")
+	body := decodedBody()
+	s.Equal("[msg='disk full' host='db-1']", body)
+	s.NotContains(body, SMTPRecipientsKey)
+	s.NotContains(body, SMTPSubjectKey)
+
+	// either key overrides its part alone, and a string is a comma
+	// separated list.
+	sender.Send(message.NewFields(level.Alert, message.Fields{SMTPRecipientsKey: "a@example.com, b@example.com", "n": 1}))
+	s.Empty(failures)
+	s.Equal([]string{"<a@example.com>", "<b@example.com>"}, mock.recipients)
+	s.Contains(strings.Split(mock.message.String(), "\r\n"), "Subject: "+s.opts.Name)
+	s.Equal("[n='1']", decodedBody())
+
+	sender.Send(message.NewFieldsMessage(level.Alert, "subject only", message.Fields{SMTPSubjectKey: "custom"}))
+	s.Empty(failures)
+	s.Equal([]string{"\"one\" <two@>"}, mock.recipients)
+	s.Contains(strings.Split(mock.message.String(), "\r\n"), "Subject: custom")
+
+	// other messages, and fields without the keys, are unchanged.
+	sender.Send(message.NewFieldsMessage(level.Alert, "plain", message.Fields{"n": 2}))
+	s.Equal([]string{"\"one\" <two@>"}, mock.recipients)
+	s.Equal("[msg='plain' n='2']", decodedBody())
+	s.Equal(4, mock.numMsgs)
+}
+
+func (s *SMTPSuite) TestInvalidPerMessageRecipientsFallBack() {
+	mock, ok := s.opts.client.(*smtpClientMock)
+	s.Require().True(ok)
+
+	sender, err := NewSMTPLogger(s.opts, LevelInfo{level.Trace, level.Info})
+	s.Require().NoError(err)
+
+	var failures []error
+	var failed []message.Composer
+	s.NoError(sender.SetErrorHandler(func(err error, m message.Composer) {
+		if err != nil {
+			failures = append(failures, err)
+			failed = append(failed, m)
+		}
+	}))
+
+	for idx, recipients := range []interface{}{
+		[]string{"not an address"},
+		"oncall@example.com, not an address",
+		[]string{},
+		42,
+		[]interface{}{"oncall@example.com", 42},
+	} {
+		m := message.NewFieldsMessage(level.Alert, "disk full", message.Fields{
+			SMTPRecipientsKey: recipients,
+			SMTPSubjectKey:    "database alert",
+		})
+		sender.Send(m)
+
+		s.Equal(idx+1, mock.numMsgs, "the email is sent to the configured recipients")
+		s.Equal([]string{"\"one\" <two@>"}, mock.recipients)
+		s.Contains(strings.Split(mock.message.String(), "\r\n"), "Subject: database alert")
+		s.NotContains(mock.message.String(), SMTPRecipientsKey)
+
+		s.Require().Len(failures, idx+1)
+		s.Contains(failures[idx].Error(), SMTPRecipientsKey)
+		s.Contains(failures[idx].Error(), "sent to configured recipients")
+		s.True(m == failed[idx])
+	}
+}
+
+func (s *SMTPSuite) TestRetryPolicy() {
+	mock, ok := s.opts.client.(*smtpClientMock)
+	s.Require().True(ok)
+
+	s.opts.RetryPolicy = SMTPRetryPolicy{MaxAttempts: -1}
+	s.Error(s.opts.Validate())
+	s.opts.RetryPolicy = SMTPRetryPolicy{BaseDelay: time.Second, MaxDelay: time.Millisecond}
+	s.Error(s.opts.Validate())
+
+	s.opts.RetryPolicy = SMTPRetryPolicy{}
+	s.NoError(s.opts.Validate())
+	s.Equal(500*time.Millisecond, s.opts.RetryPolicy.BaseDelay)
+	s.Equal(30*time.Second, s.opts.RetryPolicy.MaxDelay)
+
+	// by default, the sender does not retry.
+	mock.transientFailures = 1
+	s.Error(s.opts.sendMail(message.NewString("hello")))
+	s.Equal(1, mock.numMailCalls)
+
+	s.opts.RetryPolicy = SMTPRetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: 5 * time.Millisecond}
+	mock.numMailCalls = 0
+	mock.numMsgs = 0
+	mock.transientFailures = 2
+	s.NoError(s.opts.sendMail(message.NewString("hello")))
+	s.Equal(3, mock.numMailCalls)
+	s.Equal(2, mock.numResets)
+	s.Equal(1, mock.numMsgs)
+
+	mock.numMailCalls = 0
+	mock.transientFailures = 3
+	err := s.opts.sendMail(message.NewString("hello"))
+	s.Require().Error(err)
+	s.Contains(err.Error(), "after 3 attempts")
+	s.Contains(err.Error(), "try again later")
+	s.Equal(3, mock.numMailCalls)
+
+	// permanent failures are not retried.
+	mock.numMailCalls = 0
+	mock.mailFailure = &textproto.Error{Code: 550, Msg: "mailbox unavailable"}
+	err = s.opts.sendMail(message.NewString("hello"))
+	s.Require().Error(err)
+	s.NotContains(err.Error(), "attempts")
+	s.Equal(1, mock.numMailCalls)
+
+	mock.numMailCalls = 0
+	mock.transientFailures = 1
+	err = s.opts.sendMail(message.NewString("hello"))
+	s.Require().Error(err)
+	s.Contains(err.Error(), "after 2 attempts")
+	s.Equal(2, mock.numMailCalls)
+}
+
+func TestSMTPRetryPolicyDelay(t *testing.T) {
+	assert := assert.New(t)
+
+	p := SMTPRetryPolicy{BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second}
+	for retry, max := range map[int]time.Duration{
+		1: 100 * time.Millisecond,
+		2: 200 * time.Millisecond,
+		3: 400 * time.Millisecond,
+		4: 800 * time.Millisecond,
+		5: time.Second,
+		9: time.Second,
+	} {
+		for i := 0; i < 20; i++ {
+			d := p.delay(retry)
+			assert.True(d <= max, "retry %d: %s", retry, d)
+			assert.True(d >= max/2, "retry %d: %s", retry, d)
+		}
+	}
+}
+
+func (s *SMTPSuite) TestSendMailWithStaticAndStreamedAttachments() {
+	mock, ok := s.opts.client.(*smtpClientMock)
+	s.Require().True(ok)
+
+	csv := []byte(strings.Repeat("job,status,duration\nbuild,ok,12s\n", 200))
+	logs := strings.Repeat("line of the job log\n", 100)
+	s.opts.Attachments = []SMTPAttachment{{Filename: "summary.csv", ContentType: "text/csv", Content: csv}}
+	s.opts.GetAttachments = func(*SMTPOptions, message.Composer) []SMTPAttachment {
+		return []SMTPAttachment{{Filename: "job log.txt", ContentType: "text/plain", Reader: strings.NewReader(logs)}}
+	}
+
+	// bodies that look like multipart boundaries do not end the part.
+	body := "--boundary\r\nContent-Type: text/plain\r\n\r\n--boundary--"
+	s.opts.GetContents = func(*SMTPOptions, message.Composer) (string, string) { return "nightly jobs", body }
+
+	read := func(part *multipart.Part) string {
+		encoded, err := ioutil.ReadAll(part)
+		s.Require().NoError(err)
+		content, err := base64.StdEncoding.DecodeString(strings.Replace(string(encoded), "\r\n", "", -1))
+		s.Require().NoError(err)
+		return string(content)
+	}
+
+	for i := 0; i < 2; i++ {
+		s.NoError(s.opts.sendMail(message.NewString("hello")))
+
+		msg, err := mail.ReadMessage(strings.NewReader(mock.message.String()))
+		s.Require().NoError(err)
+		_, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+		s.Require().NoError(err)
+		r := multipart.NewReader(msg.Body, params["boundary"])
+
+		part, err := r.NextPart()
+		s.Require().NoError(err)
+		s.Equal(body, read(part))
+
+		part, err = r.NextPart()
+		s.Require().NoError(err)
+		s.Equal("summary.csv", part.FileName())
+		s.Equal(`attachment; filename=summary.csv`, part.Header.Get("Content-Disposition"))
+		s.Equal(string(csv), read(part))
+
+		part, err = r.NextPart()
+		s.Require().NoError(err)
+		s.Equal("job log.txt", part.FileName())
+		s.Equal(`attachment; filename="job log.txt"`, part.Header.Get("Content-Disposition"))
+		s.Equal(logs, read(part))
+
+		_, err = r.NextPart()
+		s.Equal(io.EOF, err)
+	}
+}
+
+func (s *SMTPSuite) TestTLSModeValidation() {
+	opts := func() *SMTPOptions {
+		return &SMTPOptions{
+			client:        &smtpClientMock{},
+			Name:          "tls",
+			NameAsSubject: true,
+			toAddrs:       []*mail.Address{{Name: "one", Address: "two"}},
+		}
+	}
+
+	o := opts()
+	o.TLSMode = SMTPStartTLS
+	s.NoError(o.Validate())
+	s.Equal(587, o.Port)
+
+	o = opts()
+	o.UseSSL = true
+	s.NoError(o.Validate())
+	s.Equal(SMTPImplicitTLS, o.TLSMode)
+	s.Equal(465, o.Port)
+
+	o = opts()
+	o.UseSSL = true
+	o.TLSMode = SMTPStartTLS
+	s.Error(o.Validate())
+
+	o = opts()
+	o.TLSMode = SMTPImplicitTLS
+	o.Port = 25
+	s.Error(o.Validate())
+
+	o = opts()
+	o.TLSMode = SMTPStartTLS
+	o.Port = 465
+	s.Error(o.Validate())
+
+	o = opts()
+	o.TLSConfig = &tls.Config{}
+	s.Error(o.Validate())
+
+	o = opts()
+	o.TLSMode = SMTPTLSMode(10)
+	s.Error(o.Validate())
+}
+
+func (s *SMTPSuite) TestStartTLSNegotiation() {
+	mock := &smtpClientMock{startTLS: true}
+	s.opts.client = mock
+	s.opts.Server = "relay.example.com"
+	s.opts.TLSMode = SMTPStartTLS
+	s.opts.TLSConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+
+	sender, err := NewSMTPLogger(s.opts, LevelInfo{level.Trace, level.Info})
+	s.Require().NoError(err)
+	s.Equal(1, mock.numStartTLS)
+	s.Require().NotNil(mock.tlsConfig)
+	s.Equal("relay.example.com", mock.tlsConfig.ServerName)
+	s.Equal(uint16(tls.VersionTLS12), mock.tlsConfig.MinVersion)
+	// the sender does not modify the caller's configuration.
+	s.Equal("", s.opts.TLSConfig.ServerName)
+
+	sender.Send(message.NewDefaultMessage(level.Info, "secure"))
+	s.Equal(1, mock.numMsgs)
+	s.NoError(sender.Close())
+
+	// the sender refuses servers that do not support starttls,
+	// rather than sending email in the clear.
+	mock = &smtpClientMock{}
+	s.opts.client = mock
+	_, err = NewSMTPLogger(s.opts, LevelInfo{level.Trace, level.Info})
+	s.Error(err)
+	s.Contains(err.Error(), "does not support starttls")
+	s.Equal(0, mock.numCreates)
+
+	s.opts.client = &smtpClientMock{startTLS: true, failStartTLS: true}
+	_, err = NewSMTPLogger(s.opts, LevelInfo{level.Trace, level.Info})
+	s.Error(err)
+
+	// other modes do not negotiate.
+	mock = &smtpClientMock{startTLS: true}
+	s.opts.client = mock
+	s.opts.TLSMode = SMTPTLSNone
+	s.opts.TLSConfig = nil
+	_, err = NewSMTPLogger(s.opts, LevelInfo{level.Trace, level.Info})
+	s.NoError(err)
+	s.Equal(0, mock.numStartTLS)
+}
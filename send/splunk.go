@@ -0,0 +1,418 @@
+package send
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mongodb/grip/message"
+)
+
+const splunkEventEndpoint = "/services/collector/event"
+
+// SplunkConnectionInfo describes the Splunk HTTP Event Collector (HEC)
+// that the Splunk sender posts events to.
+type SplunkConnectionInfo struct {
+	// ServerURL is the base URL of the collector (e.g.
+	// "https://splunk.example.net:8088").
+	ServerURL string
+
+	// Token is the HEC token that authorizes the requests.
+	Token string
+
+	// Channel, if specified, is the channel identifier that the
+	// sender sends with every request, which collectors that
+	// acknowledge indexing require.
+	Channel string
+}
+
+// Validate checks that the connection information is complete.
+func (info SplunkConnectionInfo) Validate() error {
+	errs := []string{}
+
+	if info.ServerURL == "" {
+		errs = append(errs, "no splunk server url specified")
+	} else if _, err := url.ParseRequestURI(info.ServerURL); err != nil {
+		errs = append(errs, fmt.Sprintf("invalid splunk server url: %s", err.Error()))
+	}
+
+	if info.Token == "" {
+		errs = append(errs, "no splunk token specified")
+	}
+
+	if len(errs) > 0 {
+		return errors.New(strings.Join(errs, "; "))
+	}
+
+	return nil
+}
+
+// SplunkOptions configures the Splunk sender.
+type SplunkOptions struct {
+	// Host, Source, SourceType, and Index are the metadata of
+	// every event. Host defaults to the host name of the system,
+	// and Source defaults to the name of the sender. Unspecified
+	// values use the defaults of the HEC token.
+	Host       string
+	Source     string
+	SourceType string
+	Index      string
+
+	// BatchSize is the number of events at which the sender posts
+	// the buffered events. Defaults to 100.
+	BatchSize int
+
+	// MaxBatchBytes is the size, in bytes, of the encoded events at
+	// which the sender posts the buffered events, which must not
+	// exceed the maximum content length of the collector. Defaults
+	// to 1MB.
+	MaxBatchBytes int
+
+	// FlushInterval is how often the sender posts the buffered
+	// events. Defaults to 5 seconds.
+	FlushInterval time.Duration
+
+	// Timeout limits the time the sender waits for each request.
+	// Defaults to 10 seconds.
+	Timeout time.Duration
+}
+
+// Validate checks the options, and sets defaults for unspecified
+// values.
+func (o *SplunkOptions) Validate() error {
+	errs := []string{}
+
+	if o.BatchSize < 0 {
+		errs = append(errs, "batch size cannot be negative")
+	}
+
+	if o.MaxBatchBytes < 0 {
+		errs = append(errs, "max batch bytes cannot be negative")
+	}
+
+	if o.FlushInterval < 0 {
+		errs = append(errs, "flush interval cannot be negative")
+	}
+
+	if o.Timeout < 0 {
+		errs = append(errs, "timeout cannot be negative")
+	}
+
+	if o.Host == "" {
+		o.Host, _ = os.Hostname()
+	}
+
+	if o.BatchSize == 0 {
+		o.BatchSize = 100
+	}
+
+	if o.MaxBatchBytes == 0 {
+		o.MaxBatchBytes = 1024 * 1024
+	}
+
+	if o.FlushInterval == 0 {
+		o.FlushInterval = 5 * time.Second
+	}
+
+	if o.Timeout == 0 {
+		o.Timeout = 10 * time.Second
+	}
+
+	if len(errs) > 0 {
+		return errors.New(strings.Join(errs, "; "))
+	}
+
+	return nil
+}
+
+// splunkEvent is the HEC envelope of a message. The time is in
+// seconds since the epoch, and the fields are indexed fields.
+type splunkEvent struct {
+	Time       float64           `json:"time"`
+	Host       string            `json:"host,omitempty"`
+	Source     string            `json:"source,omitempty"`
+	SourceType string            `json:"sourcetype,omitempty"`
+	Index      string            `json:"index,omitempty"`
+	Event      interface{}       `json:"event"`
+	Fields     map[string]string `json:"fields"`
+}
+
+// splunkResponse is the response of the collector. Requests with an
+// event that the collector cannot process identify the event, by its
+// position in the request.
+type splunkResponse struct {
+	Text               string `json:"text"`
+	Code               int    `json:"code"`
+	InvalidEventNumber *int   `json:"invalid-event-number"`
+}
+
+// splunkBatch is the encoded events of a request, and the messages
+// that they encode, in order.
+type splunkBatch struct {
+	body     []byte
+	messages []message.Composer
+}
+
+type splunkSender struct {
+	endpoint  string
+	info      SplunkConnectionInfo
+	opts      SplunkOptions
+	client    *http.Client
+	batch     splunkBatch
+	closed    bool
+	mutex     sync.Mutex
+	postMutex sync.Mutex
+	stop      chan struct{}
+	done      chan struct{}
+	*Base
+}
+
+// NewSplunkLogger constructs a Sender that posts messages to a Splunk
+// HTTP Event Collector, in batches, with the default options.
+func NewSplunkLogger(name string, info SplunkConnectionInfo, l LevelInfo) (Sender, error) {
+	return NewSplunkLoggerWithOptions(name, info, SplunkOptions{}, l)
+}
+
+// NewSplunkLoggerWithOptions constructs a Sender that posts messages
+// to a Splunk HTTP Event Collector. The sender buffers events, and
+// posts them in a single request, as newline-delimited JSON, when the
+// buffer holds BatchSize events or MaxBatchBytes of encoded events,
+// every FlushInterval, on Flush, and on Close.
+//
+// Each event records the time that its message was created, and has
+// a "level" indexed field with the priority of the message. The event
+// is the fields of Fields messages, as a JSON object, and the text of
+// the message otherwise. The sender reports requests that fail to its error
+// handler, and, when the collector rejects an event of a request, the
+// position of the event in the request, with its message.
+func NewSplunkLoggerWithOptions(name string, info SplunkConnectionInfo, opts SplunkOptions, l LevelInfo) (Sender, error) {
+	if err := info.Validate(); err != nil {
+		return nil, err
+	}
+
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+
+	if opts.Source == "" {
+		opts.Source = name
+	}
+
+	s := &splunkSender{
+		endpoint: strings.TrimSuffix(info.ServerURL, "/") + splunkEventEndpoint,
+		info:     info,
+		opts:     opts,
+		client:   &http.Client{Timeout: opts.Timeout},
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+		Base:     NewBase(name),
+	}
+
+	s.closer = func() error {
+		close(s.stop)
+		<-s.done
+
+		s.mutex.Lock()
+		s.closed = true
+		s.mutex.Unlock()
+
+		return s.flush(context.Background())
+	}
+
+	go s.flushPeriodically()
+
+	return setup(s, name, l)
+}
+
+func (s *splunkSender) Send(m message.Composer) {
+	if !s.Level().ShouldLog(m) || s.reportClosed(m) {
+		return
+	}
+
+	event, err := json.Marshal(s.event(m))
+	if err != nil {
+		s.ErrorHandler(err, m)
+		return
+	}
+	event = append(event, '\n')
+
+	s.mutex.Lock()
+
+	// the sender may have closed while encoding the event.
+	if s.closed {
+		s.mutex.Unlock()
+		s.ErrorHandler(ErrSenderClosed, m)
+		return
+	}
+
+	batches := []splunkBatch{}
+	if len(s.batch.messages) > 0 && len(s.batch.body)+len(event) > s.opts.MaxBatchBytes {
+		batches = append(batches, s.take())
+	}
+
+	s.batch.body = append(s.batch.body, event...)
+	s.batch.messages = append(s.batch.messages, m)
+
+	if len(s.batch.messages) >= s.opts.BatchSize || len(s.batch.body) >= s.opts.MaxBatchBytes {
+		batches = append(batches, s.take())
+	}
+
+	if len(batches) == 0 {
+		s.mutex.Unlock()
+		return
+	}
+
+	// hold the post mutex before releasing the mutex, so that
+	// batches are posted in the order that they were taken from the
+	// buffer, even when several goroutines post batches at once.
+	s.postMutex.Lock()
+	defer s.postMutex.Unlock()
+	s.mutex.Unlock()
+
+	for _, batch := range batches {
+		s.report(s.post(context.Background(), batch), m)
+	}
+}
+
+// take returns the buffered events, and empties the buffer. The
+// caller must hold the mutex.
+func (s *splunkSender) take() splunkBatch {
+	batch := s.batch
+	s.batch = splunkBatch{}
+
+	return batch
+}
+
+// report reports the error of a request to the error handler, with
+// the message of the event that the collector rejected, if any, and
+// otherwise with the message.
+func (s *splunkSender) report(err error, m message.Composer) {
+	if rejected, ok := err.(*splunkRejectedEventError); ok {
+		m = rejected.message
+	}
+
+	s.ErrorHandler(err, m)
+}
+
+// event returns the HEC envelope of the message.
+func (s *splunkSender) event(m message.Composer) splunkEvent {
+	event := splunkEvent{
+		Time:       float64(s.timestamp(m).UnixNano()) / float64(time.Second),
+		Host:       s.opts.Host,
+		Source:     s.opts.Source,
+		SourceType: s.opts.SourceType,
+		Index:      s.opts.Index,
+		Event:      messageText(m, " "),
+		Fields:     map[string]string{"level": m.Priority().String()},
+	}
+
+	switch raw := m.Raw().(type) {
+	case message.Fields, map[string]interface{}:
+		event.Event = raw
+	}
+
+	return event
+}
+
+// Flush posts the buffered events.
+func (s *splunkSender) Flush(ctx context.Context) error { return s.flush(ctx) }
+
+// flush posts the buffered events, in order with the batches that
+// Send posts.
+func (s *splunkSender) flush(ctx context.Context) error {
+	s.mutex.Lock()
+	batch := s.take()
+	s.postMutex.Lock()
+	defer s.postMutex.Unlock()
+	s.mutex.Unlock()
+
+	return s.post(ctx, batch)
+}
+
+func (s *splunkSender) flushPeriodically() {
+	defer close(s.done)
+
+	ticker := time.NewTicker(s.opts.FlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+			s.report(s.flush(context.Background()), message.NewString(s.endpoint))
+		}
+	}
+}
+
+// post posts the events of the batch in a single request. The caller
+// must hold the post mutex.
+func (s *splunkSender) post(ctx context.Context, batch splunkBatch) error {
+	if len(batch.messages) == 0 {
+		return nil
+	}
+
+	req, err := http.NewRequest("POST", s.endpoint, bytes.NewReader(batch.body))
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+
+	req.Header.Set("Authorization", "Splunk "+s.info.Token)
+	req.Header.Set("Content-Type", "application/json")
+	if s.info.Channel != "" {
+		req.Header.Set("X-Splunk-Request-Channel", s.info.Channel)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("splunk request with %d events failed: %s", len(batch.messages), err.Error())
+	}
+	defer resp.Body.Close()
+
+	body, _ := ioutil.ReadAll(resp.Body)
+	if resp.StatusCode >= 200 && resp.StatusCode <= 299 {
+		return nil
+	}
+
+	hecResp := splunkResponse{}
+	if err = json.Unmarshal(body, &hecResp); err != nil || hecResp.Text == "" {
+		return fmt.Errorf("splunk request with %d events failed with status %s", len(batch.messages), resp.Status)
+	}
+
+	if n := hecResp.InvalidEventNumber; n != nil && *n >= 0 && *n < len(batch.messages) {
+		return &splunkRejectedEventError{
+			index:   *n,
+			total:   len(batch.messages),
+			text:    hecResp.Text,
+			code:    hecResp.Code,
+			message: batch.messages[*n],
+		}
+	}
+
+	return fmt.Errorf("splunk request with %d events failed with status %s: %s (code %d)",
+		len(batch.messages), resp.Status, hecResp.Text, hecResp.Code)
+}
+
+// splunkRejectedEventError reports the event of a request that the
+// collector rejected, by its position in the request.
+type splunkRejectedEventError struct {
+	index   int
+	total   int
+	text    string
+	code    int
+	message message.Composer
+}
+
+func (e *splunkRejectedEventError) Error() string {
+	return fmt.Sprintf("splunk rejected event %d of %d: %s (code %d)", e.index, e.total, e.text, e.code)
+}
@@ -0,0 +1,287 @@
+package send
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mongodb/grip/level"
+	"github.com/mongodb/grip/message"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// hecServer records the events of the requests posted to it, and
+// responds with the configured responses, in order, followed by
+// successes.
+type hecServer struct {
+	*httptest.Server
+	mutex     sync.Mutex
+	requests  [][]map[string]interface{}
+	headers   []http.Header
+	responses []hecTestResponse
+}
+
+type hecTestResponse struct {
+	status int
+	body   string
+}
+
+func newHECServer() *hecServer {
+	s := &hecServer{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != splunkEventEndpoint {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		body, _ := ioutil.ReadAll(r.Body)
+		events := []map[string]interface{}{}
+		scanner := bufio.NewScanner(bytes.NewReader(body))
+		scanner.Buffer(make([]byte, 1024*1024), 4*1024*1024)
+		for scanner.Scan() {
+			event := map[string]interface{}{}
+			if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			events = append(events, event)
+		}
+
+		s.mutex.Lock()
+		s.requests = append(s.requests, events)
+		s.headers = append(s.headers, r.Header)
+		resp := hecTestResponse{status: http.StatusOK, body: `{"text":"Success","code":0}`}
+		if len(s.responses) > 0 {
+			resp, s.responses = s.responses[0], s.responses[1:]
+		}
+		s.mutex.Unlock()
+
+		w.WriteHeader(resp.status)
+		_, _ = w.Write([]byte(resp.body))
+	}))
+
+	return s
+}
+
+func (s *hecServer) received() [][]map[string]interface{} {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return append([][]map[string]interface{}{}, s.requests...)
+}
+
+func (s *hecServer) info() SplunkConnectionInfo {
+	return SplunkConnectionInfo{ServerURL: s.URL, Token: "hec-token", Channel: "channel"}
+}
+
+func TestSplunkOptionsValidation(t *testing.T) {
+	assert := assert.New(t)
+
+	_, err := NewSplunkLogger("splunk", SplunkConnectionInfo{}, LevelInfo{level.Info, level.Info})
+	assert.Error(err)
+	assert.Contains(err.Error(), "no splunk server url specified")
+	assert.Contains(err.Error(), "no splunk token specified")
+
+	_, err = NewSplunkLogger("splunk", SplunkConnectionInfo{ServerURL: "not a url", Token: "token"}, LevelInfo{level.Info, level.Info})
+	assert.Error(err)
+
+	opts := SplunkOptions{BatchSize: -1, MaxBatchBytes: -1, FlushInterval: -1, Timeout: -1}
+	err = opts.Validate()
+	assert.Error(err)
+	assert.Len(strings.Split(err.Error(), "; "), 4)
+
+	opts = SplunkOptions{}
+	assert.NoError(opts.Validate())
+	assert.Equal(100, opts.BatchSize)
+	assert.Equal(1024*1024, opts.MaxBatchBytes)
+	assert.Equal(5*time.Second, opts.FlushInterval)
+	assert.Equal(10*time.Second, opts.Timeout)
+}
+
+func TestSplunkSenderBatchesEvents(t *testing.T) {
+	assert := assert.New(t)
+
+	srv := newHECServer()
+	defer srv.Close()
+
+	sender, err := NewSplunkLoggerWithOptions("splunk", srv.info(), SplunkOptions{
+		Host:          "host.example.net",
+		SourceType:    "grip",
+		Index:         "logs",
+		BatchSize:     3,
+		FlushInterval: time.Hour,
+	}, LevelInfo{level.Info, level.Info})
+	require.NoError(t, err)
+	defer sender.Close()
+
+	created := time.Now().Add(-time.Minute)
+	fields := timestampedComposer{created: created,
+		Composer: message.NewFieldsMessage(level.Error, "request failed", message.Fields{"status": 500})}
+
+	sender.Send(message.NewDefaultMessage(level.Info, "one"))
+	sender.Send(message.NewDefaultMessage(level.Debug, "not logged"))
+	sender.Send(fields)
+	assert.Len(srv.received(), 0, "events are buffered until the batch is full")
+
+	sender.Send(message.NewDefaultMessage(level.Warning, "three"))
+	requests := srv.received()
+	require.Len(t, requests, 1)
+	require.Len(t, requests[0], 3)
+
+	srv.mutex.Lock()
+	header := srv.headers[0]
+	srv.mutex.Unlock()
+	assert.Equal("Splunk hec-token", header.Get("Authorization"))
+	assert.Equal("channel", header.Get("X-Splunk-Request-Channel"))
+
+	events := requests[0]
+	assert.Equal("one", events[0]["event"])
+	assert.Equal(map[string]interface{}{"level": "info"}, events[0]["fields"])
+	assert.Equal("host.example.net", events[0]["host"])
+	assert.Equal("splunk", events[0]["source"])
+	assert.Equal("grip", events[0]["sourcetype"])
+	assert.Equal("logs", events[0]["index"])
+
+	doc, ok := events[1]["event"].(map[string]interface{})
+	require.True(t, ok, "%T", events[1]["event"])
+	assert.Equal("request failed", doc["msg"])
+	assert.Equal(float64(500), doc["status"])
+	assert.Equal(map[string]interface{}{"level": "error"}, events[1]["fields"])
+	assert.InDelta(float64(created.UnixNano())/float64(time.Second), events[1]["time"], 0.001)
+
+	assert.Equal("three", events[2]["event"])
+	assert.Equal(map[string]interface{}{"level": "warning"}, events[2]["fields"])
+
+	// flush and close post the partial batches.
+	sender.Send(message.NewDefaultMessage(level.Info, "four"))
+	require.NoError(t, sender.Flush(context.Background()))
+	sender.Send(message.NewDefaultMessage(level.Info, "five"))
+	require.NoError(t, sender.Close())
+
+	requests = srv.received()
+	require.Len(t, requests, 3)
+	assert.Equal("four", requests[1][0]["event"])
+	assert.Equal("five", requests[2][0]["event"])
+}
+
+func TestSplunkSenderFlushesOnSizeAndInterval(t *testing.T) {
+	assert := assert.New(t)
+
+	srv := newHECServer()
+	defer srv.Close()
+
+	sender, err := NewSplunkLoggerWithOptions("splunk", srv.info(), SplunkOptions{
+		MaxBatchBytes: 512,
+		FlushInterval: time.Hour,
+	}, LevelInfo{level.Info, level.Info})
+	require.NoError(t, err)
+
+	for i := 0; i < 5; i++ {
+		sender.Send(message.NewDefaultMessage(level.Info, strings.Repeat("x", 200)))
+	}
+	require.NoError(t, sender.Close())
+
+	requests := srv.received()
+	require.True(t, len(requests) > 1, "requests: %d", len(requests))
+	total := 0
+	for _, events := range requests {
+		total += len(events)
+		body, _ := json.Marshal(events)
+		assert.True(len(events) == 1 || len(body) <= 512+len(events), "batch of %d events", len(events))
+	}
+	assert.Equal(5, total)
+
+	srv = newHECServer()
+	defer srv.Close()
+
+	sender, err = NewSplunkLoggerWithOptions("splunk", srv.info(), SplunkOptions{
+		FlushInterval: 10 * time.Millisecond,
+	}, LevelInfo{level.Info, level.Info})
+	require.NoError(t, err)
+	defer sender.Close()
+
+	sender.Send(message.NewDefaultMessage(level.Info, "eventually"))
+	deadline := time.Now().Add(5 * time.Second)
+	for len(srv.received()) == 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	requests = srv.received()
+	require.Len(t, requests, 1)
+	assert.Equal("eventually", requests[0][0]["event"])
+}
+
+func TestSplunkSenderReportsRejectedEvents(t *testing.T) {
+	assert := assert.New(t)
+
+	srv := newHECServer()
+	defer srv.Close()
+	srv.responses = []hecTestResponse{
+		{status: http.StatusBadRequest, body: `{"text":"Invalid data format","code":6,"invalid-event-number":1}`},
+		{status: http.StatusForbidden, body: `{"text":"Invalid token","code":4}`},
+		{status: http.StatusServiceUnavailable, body: "unavailable"},
+	}
+
+	sender, err := NewSplunkLoggerWithOptions("splunk", srv.info(), SplunkOptions{
+		BatchSize:     2,
+		FlushInterval: time.Hour,
+	}, LevelInfo{level.Info, level.Info})
+	require.NoError(t, err)
+	defer sender.Close()
+
+	var (
+		errs     []error
+		messages []string
+	)
+	require.NoError(t, sender.SetErrorHandler(func(err error, m message.Composer) {
+		if err != nil {
+			errs = append(errs, err)
+			messages = append(messages, m.String())
+		}
+	}))
+
+	for i := 0; i < 6; i++ {
+		sender.Send(message.NewDefaultMessage(level.Info, fmt.Sprintf("event %d", i)))
+	}
+
+	require.Len(t, errs, 3)
+	assert.Contains(errs[0].Error(), "rejected event 1 of 2")
+	assert.Contains(errs[0].Error(), "Invalid data format (code 6)")
+	assert.Equal("event 1", messages[0])
+
+	assert.Contains(errs[1].Error(), "2 events")
+	assert.Contains(errs[1].Error(), "Invalid token (code 4)")
+
+	assert.Contains(errs[2].Error(), "503")
+}
+
+func benchmarkSplunkSender(b *testing.B, batchSize int) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = ioutil.ReadAll(r.Body)
+		_, _ = w.Write([]byte(`{"text":"Success","code":0}`))
+	}))
+	defer srv.Close()
+
+	sender, err := NewSplunkLoggerWithOptions("splunk", SplunkConnectionInfo{ServerURL: srv.URL, Token: "token"},
+		SplunkOptions{BatchSize: batchSize, FlushInterval: time.Hour}, LevelInfo{level.Info, level.Info})
+	require.NoError(b, err)
+
+	m := message.NewFieldsMessage(level.Info, "benchmark", message.Fields{"iteration": 1})
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		sender.Send(m)
+	}
+	require.NoError(b, sender.Close())
+}
+
+func BenchmarkSplunkSenderSingleEvent(b *testing.B) { benchmarkSplunkSender(b, 1) }
+func BenchmarkSplunkSenderBatched(b *testing.B)     { benchmarkSplunkSender(b, 100) }
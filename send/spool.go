@@ -0,0 +1,613 @@
+package send
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/mongodb/grip/level"
+	"github.com/mongodb/grip/message"
+)
+
+// SpoolOptions configures the behavior of the spooling sender.
+type SpoolOptions struct {
+	// MaxFileSize is the size, in bytes, at which the sender
+	// rotates to a new spool file. Defaults to 1MB.
+	MaxFileSize int64
+
+	// MaxSize caps the total size, in bytes, of the spool
+	// files. When the spool exceeds the cap, the sender deletes
+	// the oldest spool files, and counts their messages as
+	// dropped. Defaults to 100 times MaxFileSize.
+	MaxSize int64
+
+	// ProbeInterval controls how often the sender checks whether
+	// the underlying sender has recovered. Defaults to 10 seconds.
+	ProbeInterval time.Duration
+
+	// Probe, if specified, reports whether the backend of the
+	// underlying sender is available. The sender only attempts
+	// to replay spooled messages when Probe returns nil. Without
+	// a Probe, the sender detects recovery by replaying the
+	// oldest spooled message.
+	Probe func() error
+}
+
+// Validate checks the options, and sets defaults for unspecified
+// values.
+func (o *SpoolOptions) Validate() error {
+	errs := []string{}
+
+	if o.MaxFileSize < 0 {
+		errs = append(errs, "max file size cannot be negative")
+	}
+
+	if o.MaxSize < 0 {
+		errs = append(errs, "max spool size cannot be negative")
+	}
+
+	if o.ProbeInterval < 0 {
+		errs = append(errs, "probe interval cannot be negative")
+	}
+
+	if o.MaxFileSize == 0 {
+		o.MaxFileSize = 1024 * 1024
+	}
+
+	if o.MaxSize == 0 {
+		o.MaxSize = 100 * o.MaxFileSize
+	}
+
+	if o.ProbeInterval == 0 {
+		o.ProbeInterval = 10 * time.Second
+	}
+
+	if o.MaxSize < o.MaxFileSize {
+		errs = append(errs, "max spool size cannot be less than the max file size")
+	}
+
+	if len(errs) > 0 {
+		return errors.New(strings.Join(errs, "; "))
+	}
+
+	return nil
+}
+
+type spoolFile struct {
+	path  string
+	size  int64
+	count int
+}
+
+type spoolRecord struct {
+	Priority level.Priority  `json:"priority"`
+	Text     string          `json:"text"`
+	Time     time.Time       `json:"time"`
+	Raw      json.RawMessage `json:"raw,omitempty"`
+}
+
+type spoolingSender struct {
+	flushes     flushTimer
+	dir         string
+	opts        SpoolOptions
+	files       []*spoolFile
+	writing     *spoolFile
+	current     *os.File
+	outage      bool
+	seq         int64
+	dropped     int64
+	enqueued    int64
+	mutex       sync.Mutex
+	sendMutex   sync.Mutex
+	delivering  int32
+	failed      int32
+	errMutex    sync.RWMutex
+	errHandlers []SendErrorHandler
+	stop        chan struct{}
+	done        chan struct{}
+	closeOnce   sync.Once
+	closed      int32
+	Sender
+}
+
+// NewSpoolingSender wraps a Sender so that messages survive outages
+// of the underlying sender's backend. When the underlying sender
+// reports an error sending a message, the spooling sender writes the
+// message, and all following messages, to files in the spool
+// directory, and periodically attempts to replay the spooled
+// messages, in order, until the underlying sender has recovered.
+//
+// The spooling sender detects failures using an error handler that it
+// adds to the underlying sender, which must support AddErrorHandler,
+// and delivers messages to the underlying sender one at a time.
+// Replacing the underlying sender's error handlers, via its own
+// SetErrorHandler, stops the detection of failures; the error
+// handlers of the spooling sender receive the errors of the spool
+// itself, such as failures to write spool files. Messages are
+// delivered at least once: errors that the underlying sender reports
+// asynchronously may cause the sender to replay messages that were
+// delivered. Spooled messages are replayed with their priority,
+// String() form, and Raw() form (as JSON), but not as their original
+// Composer type.
+//
+// The spool files are rotated when they reach the MaxFileSize, and
+// the oldest files are deleted when the spool reaches the MaxSize;
+// use DroppedMessages to find the number of messages the sender has
+// deleted. Spool files that remain in the spool directory, from a
+// previous process, are replayed when the underlying sender is
+// available.
+func NewSpoolingSender(underlying Sender, spoolDir string, opts SpoolOptions) (Sender, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(spoolDir, 0700); err != nil {
+		return nil, err
+	}
+
+	s := &spoolingSender{
+		dir:         spoolDir,
+		opts:        opts,
+		errHandlers: []SendErrorHandler{MakeDefaultErrorHandler()},
+		stop:        make(chan struct{}),
+		done:        make(chan struct{}),
+		Sender:      underlying,
+	}
+
+	if err := s.loadSpool(); err != nil {
+		return nil, err
+	}
+
+	if err := AddErrorHandler(underlying, s.handleError); err != nil {
+		return nil, err
+	}
+
+	go s.probe()
+
+	return s, nil
+}
+
+func (s *spoolingSender) Send(m message.Composer) {
+	if !s.Level().ShouldLog(m) {
+		return
+	}
+
+	if atomic.LoadInt32(&s.closed) == 1 {
+		s.reportError(ErrSenderClosed, m)
+		return
+	}
+
+	s.mutex.Lock()
+	outage := s.outage
+	s.mutex.Unlock()
+
+	if !outage && s.deliver(m) {
+		return
+	}
+
+	s.spool(m)
+}
+
+// Flush writes the current spool file to disk, and flushes the
+// underlying sender. Flush does not wait for the sender to replay
+// spooled messages.
+func (s *spoolingSender) Flush(ctx context.Context) error {
+	defer s.flushes.observe(time.Now())
+
+	s.mutex.Lock()
+	var err error
+	if s.current != nil {
+		err = s.current.Sync()
+	}
+	s.mutex.Unlock()
+
+	if err != nil {
+		return err
+	}
+
+	return s.Sender.Flush(ctx)
+}
+
+func (s *spoolingSender) Close() error {
+	atomic.StoreInt32(&s.closed, 1)
+	s.closeOnce.Do(func() {
+		close(s.stop)
+		<-s.done
+	})
+
+	s.mutex.Lock()
+	s.closeCurrent()
+	s.mutex.Unlock()
+
+	return s.Sender.Close()
+}
+
+// BufferMetrics reports the number of spooled messages that the
+// sender has not yet replayed, other than those that it is replaying,
+// and the number of messages that the sender has spooled and dropped.
+func (s *spoolingSender) BufferMetrics() BufferMetrics {
+	s.mutex.Lock()
+	m := BufferMetrics{Enqueued: s.enqueued, Dropped: s.dropped}
+	for _, f := range s.files {
+		m.QueueDepth += int64(f.count)
+	}
+	s.mutex.Unlock()
+
+	s.flushes.load(&m)
+
+	return m
+}
+
+// SetErrorHandler replaces the handlers of the errors of the spool.
+// The underlying sender keeps its error handlers, so that the sender
+// continues to detect its failures.
+func (s *spoolingSender) SetErrorHandler(eh ErrorHandler) error {
+	if eh == nil {
+		return errors.New("error handler must be non-nil")
+	}
+
+	s.errMutex.Lock()
+	defer s.errMutex.Unlock()
+	s.errHandlers = []SendErrorHandler{func(_ string, err error, m message.Composer) { eh(err, m) }}
+
+	return nil
+}
+
+// AddErrorHandler registers an additional handler of the errors of
+// the spool.
+func (s *spoolingSender) AddErrorHandler(eh SendErrorHandler) error {
+	if eh == nil {
+		return errors.New("error handler must be non-nil")
+	}
+
+	s.errMutex.Lock()
+	defer s.errMutex.Unlock()
+	s.errHandlers = append(s.errHandlers, eh)
+
+	return nil
+}
+
+func (s *spoolingSender) reportError(err error, m message.Composer) {
+	if err == nil {
+		return
+	}
+
+	s.errMutex.RLock()
+	handlers := s.errHandlers
+	s.errMutex.RUnlock()
+
+	for _, eh := range handlers {
+		eh(s.Name(), err, m)
+	}
+}
+
+// deliver sends the message to the underlying sender, and reports
+// whether the underlying sender sent the message without error.
+func (s *spoolingSender) deliver(m message.Composer) bool {
+	s.sendMutex.Lock()
+	defer s.sendMutex.Unlock()
+
+	atomic.StoreInt32(&s.failed, 0)
+	atomic.StoreInt32(&s.delivering, 1)
+	s.Sender.Send(m)
+	atomic.StoreInt32(&s.delivering, 0)
+
+	return atomic.LoadInt32(&s.failed) == 0
+}
+
+func (s *spoolingSender) handleError(_ string, err error, m message.Composer) {
+	if atomic.LoadInt32(&s.closed) == 1 {
+		return
+	}
+
+	if atomic.LoadInt32(&s.delivering) == 1 {
+		atomic.StoreInt32(&s.failed, 1)
+		return
+	}
+
+	// the underlying sender reported the error asynchronously,
+	// so the message is no longer in flight.
+	s.spool(m)
+}
+
+func (s *spoolingSender) spool(m message.Composer) {
+	rec := spoolRecord{Priority: m.Priority(), Text: m.String(), Time: messageTime(m)}
+	if raw, err := json.Marshal(m.Raw()); err == nil {
+		rec.Raw = raw
+	}
+
+	line, err := json.Marshal(rec)
+	if err != nil {
+		s.reportError(err, m)
+		return
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	// the sender may have closed while delivering the message.
+	if atomic.LoadInt32(&s.closed) == 1 {
+		s.reportError(ErrSenderClosed, m)
+		return
+	}
+
+	s.outage = true
+	if err := s.write(append(line, '\n')); err != nil {
+		s.reportError(err, m)
+	} else {
+		s.enqueued++
+	}
+	s.enforceMaxSize()
+}
+
+// write appends the line to the current spool file, rotating the file
+// as needed. The caller must hold the mutex.
+func (s *spoolingSender) write(line []byte) error {
+	if s.current == nil || (s.writing.size > 0 && s.writing.size+int64(len(line)) > s.opts.MaxFileSize) {
+		if err := s.rotate(); err != nil {
+			return err
+		}
+	}
+
+	n, err := s.current.Write(line)
+	s.writing.size += int64(n)
+	if err != nil {
+		return err
+	}
+	s.writing.count++
+
+	return nil
+}
+
+func (s *spoolingSender) rotate() error {
+	s.closeCurrent()
+
+	s.seq++
+	path := filepath.Join(s.dir, fmt.Sprintf("%020d.spool", s.seq))
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+
+	s.current = f
+	s.writing = &spoolFile{path: path}
+	s.files = append(s.files, s.writing)
+
+	return nil
+}
+
+func (s *spoolingSender) closeCurrent() {
+	if s.current != nil {
+		_ = s.current.Close()
+		s.current = nil
+		s.writing = nil
+	}
+}
+
+// enforceMaxSize deletes the oldest spool files, other than the file
+// that the sender is writing, until the spool is smaller than the
+// MaxSize. The caller must hold the mutex.
+func (s *spoolingSender) enforceMaxSize() {
+	var total int64
+	for _, f := range s.files {
+		total += f.size
+	}
+
+	for total > s.opts.MaxSize && len(s.files) > 1 {
+		oldest := s.files[0]
+		if err := os.Remove(oldest.path); err != nil && !os.IsNotExist(err) {
+			s.reportError(err, message.NewString(oldest.path))
+		}
+
+		total -= oldest.size
+		s.dropped += int64(oldest.count)
+		s.files = s.files[1:]
+	}
+}
+
+func (s *spoolingSender) probe() {
+	defer close(s.done)
+
+	ticker := time.NewTicker(s.opts.ProbeInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+			s.mutex.Lock()
+			outage := s.outage
+			s.mutex.Unlock()
+
+			if outage {
+				s.replay()
+			}
+		}
+	}
+}
+
+// replay delivers the spooled messages, oldest first, until the spool
+// is empty, or the underlying sender fails to deliver a message.
+func (s *spoolingSender) replay() {
+	if s.opts.Probe != nil && s.opts.Probe() != nil {
+		return
+	}
+
+	for {
+		select {
+		case <-s.stop:
+			return
+		default:
+		}
+
+		s.mutex.Lock()
+		if len(s.files) == 0 {
+			s.outage = false
+			s.mutex.Unlock()
+			return
+		}
+
+		file := s.files[0]
+		if file == s.writing {
+			s.closeCurrent()
+		}
+		s.files = s.files[1:]
+		s.mutex.Unlock()
+
+		records, err := readSpoolFile(file.path)
+		if err != nil {
+			s.reportError(err, message.NewString(file.path))
+			s.mutex.Lock()
+			s.dropped += int64(file.count)
+			s.mutex.Unlock()
+			_ = os.Remove(file.path)
+			continue
+		}
+
+		for idx, rec := range records {
+			if !s.deliver(rec.composer()) {
+				s.requeue(file, records[idx:])
+				return
+			}
+		}
+
+		if err := os.Remove(file.path); err != nil && !os.IsNotExist(err) {
+			s.reportError(err, message.NewString(file.path))
+		}
+	}
+}
+
+// requeue replaces the content of the spool file with the records
+// that the sender did not deliver, and returns the file to the front
+// of the spool.
+func (s *spoolingSender) requeue(file *spoolFile, records []spoolRecord) {
+	buf := &bytes.Buffer{}
+	for _, rec := range records {
+		line, err := json.Marshal(rec)
+		if err != nil {
+			continue
+		}
+		buf.Write(line)
+		buf.WriteByte('\n')
+	}
+
+	tmp := file.path + ".tmp"
+	err := ioutil.WriteFile(tmp, buf.Bytes(), 0600)
+	if err == nil {
+		err = os.Rename(tmp, file.path)
+	}
+	if err != nil {
+		s.reportError(err, message.NewString(file.path))
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	file.size = int64(buf.Len())
+	file.count = len(records)
+	s.files = append([]*spoolFile{file}, s.files...)
+	s.enforceMaxSize()
+}
+
+// loadSpool finds the spool files that remain from a previous
+// process.
+func (s *spoolingSender) loadSpool() error {
+	paths, err := filepath.Glob(filepath.Join(s.dir, "*.spool"))
+	if err != nil {
+		return err
+	}
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		var seq int64
+		if _, err = fmt.Sscanf(filepath.Base(path), "%d.spool", &seq); err != nil {
+			continue
+		}
+
+		records, err := readSpoolFile(path)
+		if err != nil {
+			return err
+		}
+
+		info, err := os.Stat(path)
+		if err != nil {
+			return err
+		}
+
+		s.files = append(s.files, &spoolFile{path: path, size: info.Size(), count: len(records)})
+		if seq > s.seq {
+			s.seq = seq
+		}
+	}
+
+	s.outage = len(s.files) > 0
+
+	return nil
+}
+
+func readSpoolFile(path string) ([]spoolRecord, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	records := []spoolRecord{}
+	reader := bufio.NewReader(f)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if len(bytes.TrimSpace(line)) > 0 {
+			rec := spoolRecord{}
+			if jerr := json.Unmarshal(line, &rec); jerr != nil {
+				return nil, fmt.Errorf("problem reading spool file %s: %v", path, jerr)
+			}
+			records = append(records, rec)
+		}
+
+		if err == io.EOF {
+			return records, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (r spoolRecord) composer() message.Composer {
+	m := &spooledMessage{text: r.Text, raw: r.Raw}
+	m.Time = r.Time
+	_ = m.SetPriority(r.Priority)
+	return m
+}
+
+// spooledMessage is the Composer that the spooling sender replays,
+// which reproduces the String() and Raw() forms, and the timestamp,
+// of the original message.
+type spooledMessage struct {
+	text string
+	raw  json.RawMessage
+	message.Base
+}
+
+func (m *spooledMessage) String() string { return m.text }
+func (m *spooledMessage) Loggable() bool { return true }
+func (m *spooledMessage) Raw() interface{} {
+	if len(m.raw) == 0 {
+		return m.text
+	}
+
+	return m.raw
+}
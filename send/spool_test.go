@@ -0,0 +1,306 @@
+package send
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mongodb/grip/level"
+	"github.com/mongodb/grip/message"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// flakySender records the messages that it sends, and reports an
+// error for every message while it is failing.
+type flakySender struct {
+	failing  int32
+	mutex    sync.Mutex
+	messages []string
+	times    []time.Time
+	*Base
+}
+
+func newFlakySender(t *testing.T) *flakySender {
+	s := &flakySender{Base: NewBase("flaky")}
+	require.NoError(t, s.SetLevel(LevelInfo{level.Info, level.Info}))
+	require.NoError(t, s.SetErrorHandler(func(error, message.Composer) {}))
+	return s
+}
+
+func (s *flakySender) setFailing(failing bool) {
+	if failing {
+		atomic.StoreInt32(&s.failing, 1)
+	} else {
+		atomic.StoreInt32(&s.failing, 0)
+	}
+}
+
+func (s *flakySender) Send(m message.Composer) {
+	if !s.Level().ShouldLog(m) {
+		return
+	}
+
+	if atomic.LoadInt32(&s.failing) == 1 {
+		s.ErrorHandler(errors.New("backend unavailable"), m)
+		return
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.messages = append(s.messages, m.String())
+	s.times = append(s.times, messageTime(m))
+}
+
+func (s *flakySender) sent() []string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return append([]string{}, s.messages...)
+}
+
+func (s *flakySender) sentTimes() []time.Time {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return append([]time.Time{}, s.times...)
+}
+
+func (s *flakySender) waitForMessages(t *testing.T, n int) []string {
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if msgs := s.sent(); len(msgs) >= n {
+			return msgs
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	t.Fatalf("expected %d messages, got %d", n, len(s.sent()))
+	return nil
+}
+
+func spoolFiles(t *testing.T, dir string) []string {
+	files, err := filepath.Glob(filepath.Join(dir, "*.spool"))
+	require.NoError(t, err)
+	return files
+}
+
+func newSpoolDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "grip-spool")
+	require.NoError(t, err)
+	return dir
+}
+
+func TestSpoolOptionsValidate(t *testing.T) {
+	assert := assert.New(t)
+
+	opts := SpoolOptions{}
+	assert.NoError(opts.Validate())
+	assert.Equal(int64(1024*1024), opts.MaxFileSize)
+	assert.Equal(100*opts.MaxFileSize, opts.MaxSize)
+	assert.Equal(10*time.Second, opts.ProbeInterval)
+
+	for _, opts := range []SpoolOptions{
+		{MaxFileSize: -1},
+		{MaxSize: -1},
+		{ProbeInterval: -1},
+		{MaxFileSize: 100, MaxSize: 10},
+	} {
+		assert.Error(opts.Validate())
+	}
+}
+
+func TestSpoolingSenderReplaysMessagesInOrder(t *testing.T) {
+	assert := assert.New(t)
+	dir := newSpoolDir(t)
+	defer os.RemoveAll(dir)
+
+	underlying := newFlakySender(t)
+	sender, err := NewSpoolingSender(underlying, dir, SpoolOptions{ProbeInterval: 10 * time.Millisecond, MaxFileSize: 200})
+	require.NoError(t, err)
+	defer sender.Close()
+
+	sender.Send(message.NewDefaultMessage(level.Info, "msg-0"))
+	assert.Equal([]string{"msg-0"}, underlying.sent())
+
+	underlying.setFailing(true)
+	for i := 1; i < 10; i++ {
+		sender.Send(message.NewDefaultMessage(level.Info, fmt.Sprintf("msg-%d", i)))
+	}
+	sender.Send(message.NewDefaultMessage(level.Debug, "below threshold"))
+	assert.Len(underlying.sent(), 1)
+	assert.True(len(spoolFiles(t, dir)) > 1, "spool files should rotate")
+
+	underlying.setFailing(false)
+	sender.Send(message.NewDefaultMessage(level.Info, "msg-10"))
+
+	expected := []string{}
+	for i := 0; i <= 10; i++ {
+		expected = append(expected, fmt.Sprintf("msg-%d", i))
+	}
+	assert.Equal(expected, underlying.waitForMessages(t, len(expected)))
+
+	time.Sleep(50 * time.Millisecond)
+	assert.Len(spoolFiles(t, dir), 0)
+
+	sender.Send(message.NewDefaultMessage(level.Info, "msg-11"))
+	assert.Equal("msg-11", underlying.sent()[len(expected)])
+
+	dropped, err := DroppedMessages(sender)
+	assert.NoError(err)
+	assert.Equal(int64(0), dropped)
+}
+
+func TestSpoolingSenderErrorHandlersKeepSpooling(t *testing.T) {
+	assert := assert.New(t)
+	dir := newSpoolDir(t)
+	defer os.RemoveAll(dir)
+
+	underlying := newFlakySender(t)
+	sender, err := NewSpoolingSender(underlying, dir, SpoolOptions{ProbeInterval: 10 * time.Millisecond})
+	require.NoError(t, err)
+	defer sender.Close()
+
+	errs := &errorCollector{}
+	assert.Error(sender.SetErrorHandler(nil))
+	require.NoError(t, sender.SetErrorHandler(errs.handler))
+	require.NoError(t, AddErrorHandler(sender, func(string, error, message.Composer) {}))
+
+	underlying.setFailing(true)
+	sender.Send(message.NewDefaultMessage(level.Info, "spooled"))
+	assert.Len(underlying.sent(), 0)
+	assert.Len(spoolFiles(t, dir), 1, "failures are spooled")
+	assert.Len(errs.get(), 0, "spooled failures are not errors of the spool")
+
+	underlying.setFailing(false)
+	assert.Equal([]string{"spooled"}, underlying.waitForMessages(t, 1))
+}
+
+func TestSpoolingSenderDropsOldestFilesAtCap(t *testing.T) {
+	assert := assert.New(t)
+	dir := newSpoolDir(t)
+	defer os.RemoveAll(dir)
+
+	underlying := newFlakySender(t)
+	underlying.setFailing(true)
+	sender, err := NewSpoolingSender(underlying, dir, SpoolOptions{ProbeInterval: time.Hour, MaxFileSize: 200, MaxSize: 400})
+	require.NoError(t, err)
+	defer sender.Close()
+
+	for i := 0; i < 50; i++ {
+		sender.Send(message.NewDefaultMessage(level.Info, fmt.Sprintf("msg-%02d", i)))
+	}
+
+	dropped, err := DroppedMessages(sender)
+	assert.NoError(err)
+	assert.True(dropped > 0)
+
+	var total int64
+	for _, fn := range spoolFiles(t, dir) {
+		info, err := os.Stat(fn)
+		require.NoError(t, err)
+		total += info.Size()
+	}
+	assert.True(total <= 400, "spool size %d exceeds the cap", total)
+
+	// the remaining messages are the most recent messages.
+	underlying.setFailing(false)
+	sender.(*spoolingSender).replay()
+	sent := underlying.sent()
+	assert.Equal(50-int(dropped), len(sent))
+	assert.Equal("msg-49", sent[len(sent)-1])
+}
+
+func TestSpoolingSenderReplaysSpoolFromPreviousProcess(t *testing.T) {
+	assert := assert.New(t)
+	dir := newSpoolDir(t)
+	defer os.RemoveAll(dir)
+
+	broken := newFlakySender(t)
+	broken.setFailing(true)
+	sender, err := NewSpoolingSender(broken, dir, SpoolOptions{ProbeInterval: time.Hour})
+	require.NoError(t, err)
+	sender.Send(message.NewFields(level.Warning, message.Fields{"a": 1}))
+	sender.Send(message.NewDefaultMessage(level.Info, "second"))
+	assert.NoError(sender.Close())
+	assert.Len(spoolFiles(t, dir), 1)
+
+	// messages sent before the spool is replayed follow the
+	// spooled messages.
+	underlying := newFlakySender(t)
+	sender, err = NewSpoolingSender(underlying, dir, SpoolOptions{ProbeInterval: 10 * time.Millisecond})
+	require.NoError(t, err)
+	defer sender.Close()
+	sender.Send(message.NewDefaultMessage(level.Info, "third"))
+
+	sent := underlying.waitForMessages(t, 3)
+	assert.Equal("[a='1']", sent[0])
+	assert.Equal([]string{"second", "third"}, sent[1:])
+}
+
+func TestSpoolingSenderWaitsForProbe(t *testing.T) {
+	assert := assert.New(t)
+	dir := newSpoolDir(t)
+	defer os.RemoveAll(dir)
+
+	var available int32
+	underlying := newFlakySender(t)
+	underlying.setFailing(true)
+	sender, err := NewSpoolingSender(underlying, dir, SpoolOptions{
+		ProbeInterval: time.Hour,
+		Probe: func() error {
+			if atomic.LoadInt32(&available) == 0 {
+				return errors.New("unavailable")
+			}
+			return nil
+		},
+	})
+	require.NoError(t, err)
+	defer sender.Close()
+
+	sender.Send(message.NewDefaultMessage(level.Info, "hello"))
+	underlying.setFailing(false)
+
+	spooler := sender.(*spoolingSender)
+	spooler.replay()
+	assert.Len(underlying.sent(), 0)
+
+	atomic.StoreInt32(&available, 1)
+	spooler.replay()
+	assert.Equal([]string{"hello"}, underlying.sent())
+}
+
+func TestSpoolingSenderPreservesMessageTimestamps(t *testing.T) {
+	assert := assert.New(t)
+	dir := newSpoolDir(t)
+	defer os.RemoveAll(dir)
+
+	underlying := newFlakySender(t)
+	underlying.setFailing(true)
+	sender, err := NewSpoolingSender(underlying, dir, SpoolOptions{ProbeInterval: 10 * time.Millisecond})
+	require.NoError(t, err)
+	defer sender.Close()
+
+	created := []time.Time{}
+	for i := 0; i < 3; i++ {
+		m := message.NewDefaultMessage(level.Info, fmt.Sprintf("msg-%d", i))
+		created = append(created, m.(message.Timestamped).Timestamp())
+		sender.Send(m)
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	underlying.setFailing(false)
+	underlying.waitForMessages(t, 3)
+
+	sent := underlying.sentTimes()
+	require.Len(t, sent, 3)
+	for idx := range created {
+		assert.True(created[idx].Equal(sent[idx]), "expected %s, got %s", created[idx], sent[idx])
+		assert.True(time.Since(sent[idx]) > 50*time.Millisecond)
+	}
+}
@@ -0,0 +1,248 @@
+package send
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mongodb/grip/message"
+)
+
+// StatsDOptions configures the StatsD sender.
+type StatsDOptions struct {
+	// Address is the host:port of the StatsD server (e.g.
+	// "localhost:8125").
+	Address string
+
+	// Prefix, if specified, is prepended to the name of every
+	// metric, separated by a period.
+	Prefix string
+
+	// NameKey, TypeKey, and ValueKey are the fields of messages
+	// that hold the name, type, and value of the metric. The type
+	// is "counter", "gauge", or "timing", or the StatsD code for
+	// the type ("c", "g", or "ms"), and defaults to "counter". The
+	// value is a number, a numeric string, or, for timings, a
+	// time.Duration, and defaults to 1 for counters. The keys
+	// default to "metric", "metric_type", and "value".
+	NameKey  string
+	TypeKey  string
+	ValueKey string
+
+	// Underlying, if specified, is a Sender that the sender
+	// delivers messages to, in addition to emitting their
+	// metrics. The StatsD sender closes the underlying sender
+	// when it closes.
+	Underlying Sender
+
+	// MetricsOnly stops the sender from delivering messages that
+	// have metrics to the underlying sender.
+	MetricsOnly bool
+
+	// IgnoreUnmetered stops the sender from delivering messages
+	// that do not have metrics to the underlying sender.
+	IgnoreUnmetered bool
+}
+
+// Validate checks the options, and sets defaults for unspecified
+// values.
+func (o *StatsDOptions) Validate() error {
+	errs := []string{}
+
+	if o.Address == "" {
+		errs = append(errs, "no statsd address specified")
+	} else if _, _, err := net.SplitHostPort(o.Address); err != nil {
+		errs = append(errs, fmt.Sprintf("invalid statsd address: %s", err.Error()))
+	}
+
+	if o.NameKey == "" {
+		o.NameKey = "metric"
+	}
+
+	if o.TypeKey == "" {
+		o.TypeKey = "metric_type"
+	}
+
+	if o.ValueKey == "" {
+		o.ValueKey = "value"
+	}
+
+	if len(errs) > 0 {
+		return errors.New(strings.Join(errs, "; "))
+	}
+
+	return nil
+}
+
+type statsdSender struct {
+	opts StatsDOptions
+	conn net.Conn
+	*Base
+}
+
+// NewStatsDSender constructs a Sender that emits a StatsD metric over
+// UDP for every message that has a field that names a metric, with
+// the type and value of the metric from the fields named by the
+// options, so that log events can also update counters, gauges, and
+// timings. The sender looks for the fields in messages whose raw
+// form is message.Fields or a map with string keys.
+//
+// If the options specify an Underlying sender, the sender also
+// delivers every message to it, unless MetricsOnly or
+// IgnoreUnmetered exclude the message.
+func NewStatsDSender(name string, opts StatsDOptions, l LevelInfo) (Sender, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+
+	conn, err := net.Dial("udp", opts.Address)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &statsdSender{
+		opts: opts,
+		conn: conn,
+		Base: NewBase(name),
+	}
+
+	s.closer = func() error {
+		err := s.conn.Close()
+		if s.opts.Underlying != nil {
+			if uerr := s.opts.Underlying.Close(); err == nil {
+				err = uerr
+			}
+		}
+
+		return err
+	}
+
+	return setup(s, name, l)
+}
+
+func (s *statsdSender) Send(m message.Composer) {
+	if !s.Level().ShouldLog(m) || s.reportClosed(m) {
+		return
+	}
+
+	packet, metered, err := s.metric(m)
+	if err != nil {
+		s.ErrorHandler(err, m)
+	} else if metered {
+		if _, err = s.conn.Write([]byte(packet)); err != nil {
+			s.ErrorHandler(err, m)
+		}
+	}
+
+	if s.opts.Underlying == nil || (metered && s.opts.MetricsOnly) || (!metered && s.opts.IgnoreUnmetered) {
+		return
+	}
+
+	s.opts.Underlying.Send(m)
+}
+
+// Flush flushes the underlying sender, if any. The sender writes each
+// metric as it is sent.
+func (s *statsdSender) Flush(ctx context.Context) error {
+	if s.opts.Underlying == nil {
+		return nil
+	}
+
+	return s.opts.Underlying.Flush(ctx)
+}
+
+// metric returns the StatsD packet for the metric of the message,
+// and whether the message has a metric. Messages that name a metric
+// with an invalid type or value have a metric and an error.
+func (s *statsdSender) metric(m message.Composer) (string, bool, error) {
+	var fields map[string]interface{}
+	switch raw := m.Raw().(type) {
+	case message.Fields:
+		fields = raw
+	case map[string]interface{}:
+		fields = raw
+	}
+
+	name, ok := fields[s.opts.NameKey].(string)
+	if !ok || name == "" {
+		return "", false, nil
+	}
+
+	if s.opts.Prefix != "" {
+		name = s.opts.Prefix + "." + name
+	}
+
+	code := "c"
+	if kind, ok := fields[s.opts.TypeKey]; ok {
+		switch kind {
+		case "counter", "c":
+		case "gauge", "g":
+			code = "g"
+		case "timing", "ms":
+			code = "ms"
+		default:
+			return "", true, fmt.Errorf("metric '%s' has invalid type '%v'", name, kind)
+		}
+	}
+
+	value, ok := fields[s.opts.ValueKey]
+	if !ok {
+		if code != "c" {
+			return "", true, fmt.Errorf("metric '%s' has no value", name)
+		}
+		value = 1
+	}
+
+	number, err := statsdValue(value)
+	if err != nil {
+		return "", true, fmt.Errorf("metric '%s' has invalid value: %s", name, err.Error())
+	}
+
+	return fmt.Sprintf("%s:%s|%s", statsdName(name), strconv.FormatFloat(number, 'f', -1, 64), code), true, nil
+}
+
+// statsdValue converts the value of a metric to a number. Durations
+// are converted to milliseconds.
+func statsdValue(value interface{}) (float64, error) {
+	switch v := value.(type) {
+	case time.Duration:
+		return float64(v) / float64(time.Millisecond), nil
+	case int:
+		return float64(v), nil
+	case int32:
+		return float64(v), nil
+	case int64:
+		return float64(v), nil
+	case uint:
+		return float64(v), nil
+	case uint32:
+		return float64(v), nil
+	case uint64:
+		return float64(v), nil
+	case float32:
+		return float64(v), nil
+	case float64:
+		return v, nil
+	case string:
+		return strconv.ParseFloat(v, 64)
+	default:
+		return 0, fmt.Errorf("%T is not a number", value)
+	}
+}
+
+// statsdName replaces the characters that separate the parts of a
+// StatsD packet, and whitespace, in the metric name with underscores.
+func statsdName(name string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case ':', '|', '@', ' ', '\t', '\n', '\r':
+			return '_'
+		default:
+			return r
+		}
+	}, name)
+}
@@ -0,0 +1,131 @@
+package send
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/mongodb/grip/level"
+	"github.com/mongodb/grip/message"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newStatsDServer returns a UDP listener that stands in for a StatsD
+// server.
+func newStatsDServer(t *testing.T) net.PacketConn {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	return conn
+}
+
+// readStatsDPacket returns the next packet that the server receives,
+// or an empty string if it receives none.
+func readStatsDPacket(t *testing.T, conn net.PacketConn) string {
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(200*time.Millisecond)))
+
+	buf := make([]byte, 1024)
+	n, _, err := conn.ReadFrom(buf)
+	if err != nil {
+		return ""
+	}
+
+	return string(buf[:n])
+}
+
+func TestStatsDSenderEmitsMetrics(t *testing.T) {
+	server := newStatsDServer(t)
+	defer server.Close()
+
+	s, err := NewStatsDSender("statsd", StatsDOptions{Address: server.LocalAddr().String(), Prefix: "api"}, LevelInfo{level.Info, level.Info})
+	require.NoError(t, err)
+	defer s.Close()
+
+	errs := []error{}
+	require.NoError(t, s.SetErrorHandler(func(err error, _ message.Composer) { errs = append(errs, err) }))
+
+	for _, test := range []struct {
+		fields message.Fields
+		packet string
+	}{
+		{fields: message.Fields{"metric": "logins"}, packet: "api.logins:1|c"},
+		{fields: message.Fields{"metric": "bytes", "value": 512}, packet: "api.bytes:512|c"},
+		{fields: message.Fields{"metric": "queue", "metric_type": "gauge", "value": 3.5}, packet: "api.queue:3.5|g"},
+		{fields: message.Fields{"metric": "latency", "metric_type": "timing", "value": 1500 * time.Microsecond}, packet: "api.latency:1.5|ms"},
+		{fields: message.Fields{"metric": "disk used", "metric_type": "g", "value": "42"}, packet: "api.disk_used:42|g"},
+	} {
+		s.Send(message.NewFields(level.Info, test.fields))
+		assert.Equal(t, test.packet, readStatsDPacket(t, server))
+	}
+	assert.Empty(t, errs)
+
+	for _, fields := range []message.Fields{
+		{"metric": "queue", "metric_type": "histogram", "value": 1},
+		{"metric": "queue", "metric_type": "gauge"},
+		{"metric": "queue", "value": "many"},
+	} {
+		s.Send(message.NewFields(level.Info, fields))
+	}
+	assert.Len(t, errs, 3)
+
+	// messages without metrics, or below the threshold, emit
+	// nothing.
+	s.Send(message.NewFields(level.Info, message.Fields{"msg": "hello"}))
+	s.Send(message.NewFields(level.Debug, message.Fields{"metric": "logins"}))
+	assert.Equal(t, "", readStatsDPacket(t, server))
+}
+
+func TestStatsDSenderDeliversToUnderlyingSender(t *testing.T) {
+	metered := message.Fields{"metric": "logins"}
+	unmetered := message.Fields{"msg": "hello"}
+
+	for name, test := range map[string]struct {
+		opts      StatsDOptions
+		delivered int
+	}{
+		"All":             {delivered: 2},
+		"MetricsOnly":     {opts: StatsDOptions{MetricsOnly: true}, delivered: 1},
+		"IgnoreUnmetered": {opts: StatsDOptions{IgnoreUnmetered: true}, delivered: 1},
+		"Neither":         {opts: StatsDOptions{MetricsOnly: true, IgnoreUnmetered: true}, delivered: 0},
+	} {
+		t.Run(name, func(t *testing.T) {
+			server := newStatsDServer(t)
+			defer server.Close()
+
+			sink, err := NewInternalLogger("sink", LevelInfo{level.Info, level.Info})
+			require.NoError(t, err)
+
+			opts := test.opts
+			opts.Address = server.LocalAddr().String()
+			opts.Underlying = sink
+			s, err := NewStatsDSender("statsd", opts, LevelInfo{level.Info, level.Info})
+			require.NoError(t, err)
+
+			s.Send(message.NewFields(level.Info, metered))
+			s.Send(message.NewFields(level.Info, unmetered))
+			assert.Equal(t, "logins:1|c", readStatsDPacket(t, server))
+			assert.Equal(t, test.delivered, sink.Len())
+
+			require.NoError(t, s.Close())
+			for i := 0; i < test.delivered; i++ {
+				_ = sink.GetMessage()
+			}
+			assert.Nil(t, sink.GetMessage(), "closing the sender should close the underlying sender")
+		})
+	}
+}
+
+func TestStatsDOptions(t *testing.T) {
+	assert := assert.New(t)
+
+	opts := StatsDOptions{Address: "localhost:8125"}
+	assert.NoError(opts.Validate())
+	assert.Equal("metric", opts.NameKey)
+	assert.Equal("metric_type", opts.TypeKey)
+	assert.Equal("value", opts.ValueKey)
+
+	for _, addr := range []string{"", "localhost"} {
+		opts = StatsDOptions{Address: addr}
+		assert.Error(opts.Validate())
+	}
+}
@@ -1,9 +1,7 @@
 package send
 
 import (
-	"fmt"
-	"log"
-	"os"
+	"context"
 	"strings"
 
 	"github.com/mongodb/grip/message"
@@ -37,26 +35,36 @@ func MakeStreamLogger(ws WriteStringer) Sender {
 		Base: NewBase(""),
 	}
 
-	fallback := log.New(os.Stdout, "", log.LstdFlags)
-	_ = s.SetErrorHandler(ErrorHandlerFromLogger(fallback))
-
-	s.reset = func() {
-		fallback.SetPrefix(fmt.Sprintf("[%s]", s.Name()))
-	}
-
 	return s
 }
 
 func (s *streamLogger) Send(m message.Composer) {
-	if s.level.ShouldLog(m) {
-		msg := m.String()
+	if s.Level().ShouldLog(m) && !s.reportClosed(m) {
+		msg := messageText(m, " ")
 
 		if !strings.HasSuffix(msg, "\n") {
 			msg += "\n"
 		}
 
 		if _, err := s.fobj.WriteString(msg); err != nil {
-			s.errHandler(err, m)
+			s.ErrorHandler(err, m)
 		}
 	}
 }
+
+// Flush flushes the underlying writer, if it implements a Flush (e.g.
+// bufio.Writer) or Sync (e.g. os.File) method.
+func (s *streamLogger) Flush(_ context.Context) error {
+	switch w := s.fobj.(type) {
+	case interface {
+		Flush() error
+	}:
+		return w.Flush()
+	case interface {
+		Sync() error
+	}:
+		return w.Sync()
+	default:
+		return nil
+	}
+}
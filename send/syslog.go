@@ -3,17 +3,36 @@
 package send
 
 import (
+	"errors"
 	"fmt"
-	"log"
 	"log/syslog"
+	"net"
 	"os"
+	"strconv"
+	"strings"
+	"sync"
 
 	"github.com/mongodb/grip/level"
 	"github.com/mongodb/grip/message"
 )
 
+// syslogWriter writes messages to syslog at each severity, and is
+// implemented by the standard library's syslog.Writer.
+type syslogWriter interface {
+	Emerg(string) error
+	Alert(string) error
+	Crit(string) error
+	Err(string) error
+	Warning(string) error
+	Notice(string) error
+	Info(string) error
+	Debug(string) error
+	Close() error
+}
+
 type syslogger struct {
-	logger *syslog.Writer
+	logger   syslogWriter
+	facility SyslogFacility
 	*Base
 }
 
@@ -23,7 +42,8 @@ type syslogger struct {
 // only available on Unix systems. Use this constructor to return a
 // connection to a remote Syslog interface, but will fall back first
 // to the local syslog interface before writing messages to standard
-// output.
+// output. Messages use the "kern" facility; use SetSyslogFacility to
+// change it.
 func NewSyslogLogger(name, network, raddr string, l LevelInfo) (Sender, error) {
 	return setup(MakeSysLogger(network, raddr), name, l)
 }
@@ -34,22 +54,17 @@ func NewSyslogLogger(name, network, raddr string, l LevelInfo) (Sender, error) {
 func MakeSysLogger(network, raddr string) Sender {
 	s := &syslogger{Base: NewBase("")}
 
-	fallback := log.New(os.Stdout, "", log.LstdFlags)
-	_ = s.SetErrorHandler(ErrorHandlerFromLogger(fallback))
-
 	s.reset = func() {
-		fallback.SetPrefix(fmt.Sprintf("[%s]", s.Name()))
-
 		if s.logger != nil {
 			if err := s.logger.Close(); err != nil {
-				s.errHandler(err, message.NewErrorWrapMessage(level.Error, err,
+				s.ErrorHandler(err, message.NewErrorWrapMessage(level.Error, err,
 					"problem closing syslogger"))
 			}
 		}
 
-		w, err := syslog.Dial(network, raddr, syslog.LOG_DEBUG, s.Name())
+		w, err := syslog.Dial(network, raddr, syslog.Priority(syslogPriority(s.facility, level.Debug)), sanitizeHeader(s.Name()))
 		if err != nil {
-			s.errHandler(err, message.NewErrorWrapMessage(level.Error, err,
+			s.ErrorHandler(err, message.NewErrorWrapMessage(level.Error, err,
 				"error restarting syslog [%s] for logger: %s", err.Error(), s.Name()))
 			return
 		}
@@ -73,35 +88,292 @@ func MakeLocalSyslogLogger() Sender {
 	return MakeSysLogger("", "")
 }
 
-func (s *syslogger) Close() error { return s.logger.Close() }
+// SetFacility sets the syslog facility of the messages that the
+// sender writes, which defaults to the "kern" facility, and
+// reconnects to syslog.
+func (s *syslogger) SetFacility(f SyslogFacility) error {
+	if err := f.Validate(); err != nil {
+		return err
+	}
+
+	s.facility = f
+	s.reset()
+
+	return nil
+}
 
 func (s *syslogger) Send(m message.Composer) {
-	if s.level.ShouldLog(m) {
-		if err := s.sendToSysLog(m.Priority(), m.String()); err != nil {
-			s.errHandler(err, m)
+	if s.Level().ShouldLog(m) && !s.reportClosed(m) {
+		if err := s.sendToSysLog(s.Level().syslogSeverity(m.Priority()), messageText(m, " ")); err != nil {
+			s.ErrorHandler(err, m)
 		}
 	}
 }
 
-func (s *syslogger) sendToSysLog(p level.Priority, message string) error {
-	switch p {
-	case level.Emergency:
+// sendToSysLog writes the message at the syslog severity (0, for
+// emergencies, to 7, for debugging).
+func (s *syslogger) sendToSysLog(severity int, message string) error {
+	if s.logger == nil {
+		return errors.New("syslog is not connected")
+	}
+
+	switch severity {
+	case 0:
 		return s.logger.Emerg(message)
-	case level.Alert:
+	case 1:
 		return s.logger.Alert(message)
-	case level.Critical:
+	case 2:
 		return s.logger.Crit(message)
-	case level.Error:
+	case 3:
 		return s.logger.Err(message)
-	case level.Warning:
+	case 4:
 		return s.logger.Warning(message)
-	case level.Notice:
+	case 5:
 		return s.logger.Notice(message)
-	case level.Info:
+	case 6:
 		return s.logger.Info(message)
-	case level.Debug, level.Trace:
+	default:
 		return s.logger.Debug(message)
 	}
+}
+
+// SyslogFormat is the format of the messages that a syslog sender
+// writes.
+type SyslogFormat int
+
+const (
+	// SyslogRFC3164 is the traditional BSD syslog format, which the
+	// standard library's syslog package writes.
+	SyslogRFC3164 SyslogFormat = iota
+
+	// SyslogRFC5424 is the syslog protocol format, which has
+	// structured data.
+	SyslogRFC5424
+)
+
+// SyslogOptions configures a syslog sender.
+type SyslogOptions struct {
+	// Network and Address are the network (e.g. "udp" or "tcp")
+	// and the address of the syslog server. By default, the sender
+	// writes to the local syslog server.
+	Network string
+	Address string
+
+	// Format is the format of the messages. Defaults to RFC 3164.
+	Format SyslogFormat
+
+	// Facility is the facility of the messages. Defaults to the
+	// "kern" facility.
+	Facility SyslogFacility
+
+	// Hostname, AppName, and MsgID are the HOSTNAME, APP-NAME, and
+	// MSGID header fields of RFC 5424 messages. Hostname defaults
+	// to the hostname of the system, and AppName to the name of the
+	// sender. The PROCID header field is the pid of the process.
+	Hostname string
+	AppName  string
+	MsgID    string
+
+	// StructuredDataID is the SD-ID of the STRUCTURED-DATA element
+	// that holds the fields of Fields messages, in RFC 5424
+	// messages. Defaults to "fields@32473", which uses the example
+	// enterprise number; use an SD-ID with your organization's
+	// enterprise number where it matters.
+	StructuredDataID string
+}
+
+// Validate checks the options, and sets defaults for unspecified
+// values.
+func (o *SyslogOptions) Validate() error {
+	errs := []string{}
+
+	if o.Format != SyslogRFC3164 && o.Format != SyslogRFC5424 {
+		errs = append(errs, fmt.Sprintf("%d is not a valid syslog format", o.Format))
+	}
+
+	if err := o.Facility.Validate(); err != nil {
+		errs = append(errs, err.Error())
+	}
+
+	if (o.Network == "") != (o.Address == "") {
+		errs = append(errs, "syslog network and address must be specified together")
+	}
+
+	if o.Hostname == "" {
+		o.Hostname, _ = os.Hostname()
+	}
+
+	if o.StructuredDataID == "" {
+		o.StructuredDataID = "fields@32473"
+	}
+
+	if len(errs) > 0 {
+		return errors.New(strings.Join(errs, "; "))
+	}
+
+	return nil
+}
+
+// NewSyslogLoggerWithOptions is the same as NewSyslogLogger, but
+// configures the sender with the options, which can also write RFC
+// 5424 messages.
+//
+// RFC 5424 messages have the PRI of the facility and of the severity
+// that corresponds to the priority of the message, the time the
+// message was created, and the header fields from the options. The
+// fields of Fields messages, other than "msg" and "time", are the
+// SD-PARAMs of the STRUCTURED-DATA element, in the order of their
+// keys, and the "msg" field is the MSG. The sender writes one message
+// per datagram, and octet-counted messages (RFC 6587) to stream
+// connections, reconnecting on the next Send after a write fails.
+func NewSyslogLoggerWithOptions(name string, opts SyslogOptions, l LevelInfo) (Sender, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+
+	if opts.Format == SyslogRFC3164 {
+		s := MakeSysLogger(opts.Network, opts.Address).(*syslogger)
+		s.facility = opts.Facility
+		return setup(s, name, l)
+	}
+
+	s := &rfc5424Syslogger{
+		opts:     opts,
+		facility: opts.Facility,
+		Base:     NewBase(name),
+	}
+
+	s.closer = func() error {
+		s.mutex.Lock()
+		defer s.mutex.Unlock()
+
+		s.closed = true
+		if s.conn == nil {
+			return nil
+		}
+
+		err := s.conn.Close()
+		s.conn = nil
+		return err
+	}
+
+	return setup(s, name, l)
+}
+
+type rfc5424Syslogger struct {
+	opts     SyslogOptions
+	facility SyslogFacility
+	conn     net.Conn
+	stream   bool
+	closed   bool
+	mutex    sync.Mutex
+	*Base
+}
+
+func (s *rfc5424Syslogger) Send(m message.Composer) {
+	if !s.Level().ShouldLog(m) || s.reportClosed(m) {
+		return
+	}
+
+	if err := s.write(m); err != nil {
+		s.ErrorHandler(err, m)
+	}
+}
+
+func (s *rfc5424Syslogger) format(m message.Composer, facility SyslogFacility) string {
+	appName := s.opts.AppName
+	if appName == "" {
+		appName = s.Name()
+	}
+
+	header := rfc5424Header{
+		facility: facility,
+		hostname: s.opts.Hostname,
+		appName:  appName,
+		procID:   strconv.Itoa(os.Getpid()),
+		msgID:    s.opts.MsgID,
+	}
+
+	msg := messageText(m, " ")
+	switch raw := m.Raw().(type) {
+	case message.Fields:
+		msg, _ = raw["msg"].(string)
+	case map[string]interface{}:
+		msg, _ = raw["msg"].(string)
+	}
+
+	return header.format(s.Level().syslogSeverity(m.Priority()), s.timestamp(m), rfc5424StructuredData(s.opts.StructuredDataID, m), msg)
+}
+
+func (s *rfc5424Syslogger) write(m message.Composer) error {
+	s.mutex.Lock()
+	facility := s.facility
+	s.mutex.Unlock()
+
+	msg := s.format(m, facility)
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.closed {
+		return ErrSenderClosed
+	}
+
+	if s.conn == nil {
+		if err := s.dial(); err != nil {
+			return err
+		}
+	}
+
+	if s.stream {
+		msg = fmt.Sprintf("%d %s", len(msg), msg)
+	}
+
+	if _, err := s.conn.Write([]byte(msg)); err != nil {
+		_ = s.conn.Close()
+		s.conn = nil
+		return err
+	}
+
+	return nil
+}
+
+// dial connects to the syslog server, or, without an address, to the
+// local syslog server. The caller must hold the mutex.
+func (s *rfc5424Syslogger) dial() error {
+	if s.opts.Address != "" {
+		conn, err := net.Dial(s.opts.Network, s.opts.Address)
+		if err != nil {
+			return err
+		}
+
+		_, packet := conn.(net.PacketConn)
+		s.conn, s.stream = conn, !packet
+		return nil
+	}
+
+	for _, network := range []string{"unixgram", "unix"} {
+		for _, path := range []string{"/dev/log", "/var/run/syslog", "/var/run/log"} {
+			if conn, err := net.Dial(network, path); err == nil {
+				s.conn, s.stream = conn, network == "unix"
+				return nil
+			}
+		}
+	}
+
+	return errors.New("local syslog server is not available")
+}
+
+// SetFacility sets the syslog facility of the messages that the
+// sender writes.
+func (s *rfc5424Syslogger) SetFacility(f SyslogFacility) error {
+	if err := f.Validate(); err != nil {
+		return err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
 
-	return fmt.Errorf("encountered error trying to send: {%s}. Possibly, priority related", message)
+	s.facility = f
+	return nil
 }
@@ -0,0 +1,342 @@
+// +build linux freebsd solaris darwin
+
+package send
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mongodb/grip/level"
+	"github.com/mongodb/grip/message"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSyslogTagNeutralizesInjectedRecords(t *testing.T) {
+	assert := assert.New(t)
+
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer conn.Close()
+
+	sender, err := NewSyslogLogger("grip\n<11>Jan  1 00:00:00 host forged[1]: record", "udp", conn.LocalAddr().String(),
+		LevelInfo{level.Info, level.Info})
+	require.NoError(t, err)
+	defer sender.Close()
+
+	sender.Send(message.NewDefaultMessage(level.Info, "hello"))
+
+	buf := make([]byte, 1024)
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+	n, _, err := conn.ReadFrom(buf)
+	require.NoError(t, err)
+
+	record := strings.TrimSuffix(string(buf[:n]), "\n")
+	assert.NotContains(record, "\n")
+	assert.Contains(record, "grip <11>Jan  1 00:00:00 host forged[1]: record")
+	assert.True(strings.HasSuffix(record, ": hello"), record)
+}
+
+func TestSyslogFacility(t *testing.T) {
+	assert := assert.New(t)
+
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer conn.Close()
+
+	sender, err := NewSyslogLogger("grip", "udp", conn.LocalAddr().String(), LevelInfo{level.Info, level.Info})
+	require.NoError(t, err)
+	defer sender.Close()
+
+	read := func() string {
+		buf := make([]byte, 1024)
+		require.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+		n, _, err := conn.ReadFrom(buf)
+		require.NoError(t, err)
+		return string(buf[:n])
+	}
+
+	sender.Send(message.NewDefaultMessage(level.Error, "kern"))
+	assert.True(strings.HasPrefix(read(), "<3>"))
+
+	assert.Error(SetSyslogFacility(sender, SyslogFacility(-1)))
+	for _, test := range []struct {
+		facility SyslogFacility
+		priority level.Priority
+		pri      string
+	}{
+		{FacilityLocal0, level.Info, "<134>"},
+		{FacilityLocal7, level.Error, "<187>"},
+		{FacilityDaemon, level.Notice, "<29>"},
+	} {
+		require.NoError(t, SetSyslogFacility(sender, test.facility))
+		sender.Send(message.NewDefaultMessage(test.priority, "hello"))
+		record := read()
+		assert.True(strings.HasPrefix(record, test.pri), record)
+	}
+}
+
+type severityRecorder struct {
+	severities []string
+}
+
+func (r *severityRecorder) record(severity string) error {
+	r.severities = append(r.severities, severity)
+	return nil
+}
+
+func (r *severityRecorder) Emerg(string) error   { return r.record("emerg") }
+func (r *severityRecorder) Alert(string) error   { return r.record("alert") }
+func (r *severityRecorder) Crit(string) error    { return r.record("crit") }
+func (r *severityRecorder) Err(string) error     { return r.record("err") }
+func (r *severityRecorder) Warning(string) error { return r.record("warning") }
+func (r *severityRecorder) Notice(string) error  { return r.record("notice") }
+func (r *severityRecorder) Info(string) error    { return r.record("info") }
+func (r *severityRecorder) Debug(string) error   { return r.record("debug") }
+func (r *severityRecorder) Close() error         { return nil }
+
+func TestSyslogSeverityMapping(t *testing.T) {
+	assert := assert.New(t)
+
+	recorder := &severityRecorder{}
+	sender := &syslogger{logger: recorder, Base: NewBase("syslog")}
+	require.NoError(t, sender.SetLevel(LevelInfo{Default: level.Warning, Threshold: level.Trace}))
+
+	for _, p := range []level.Priority{level.Emergency, level.Alert, level.Critical, level.Error,
+		level.Warning, level.Notice, level.Info, level.Debug, level.Trace} {
+		sender.Send(message.NewDefaultMessage(p, "hello"))
+	}
+	assert.Equal([]string{"emerg", "alert", "crit", "err", "warning", "notice", "info", "debug", "debug"},
+		recorder.severities)
+
+	// priorities that are not levels use the default priority.
+	recorder.severities = nil
+	sender.sendToSysLog(sender.Level().syslogSeverity(level.Priority(42)), "hello")
+	sender.sendToSysLog(sender.Level().syslogSeverity(level.Invalid), "hello")
+	assert.Equal([]string{"warning", "warning"}, recorder.severities)
+}
+
+// rfc5424Message is a parsed RFC 5424 syslog message.
+type rfc5424Message struct {
+	pri, version, timestamp, hostname, appName, procID, msgID string
+	sdID                                                      string
+	params                                                    map[string]string
+	msg                                                       string
+}
+
+var (
+	rfc5424Pattern = regexp.MustCompile(`^<(\d{1,3})>(\d) (\S+) (\S+) (\S+) (\S+) (\S+) (-|\[(?:[^\]\\]|\\.)*\])(?: (.*))?$`)
+	sdParamPattern = regexp.MustCompile(`([^ =\]"]+)="((?:[^"\\]|\\.)*)"`)
+)
+
+// parseRFC5424 parses the message, and unescapes the values of its
+// SD-PARAMs.
+func parseRFC5424(t *testing.T, line string) rfc5424Message {
+	match := rfc5424Pattern.FindStringSubmatch(line)
+	require.NotNil(t, match, line)
+
+	m := rfc5424Message{
+		pri:       match[1],
+		version:   match[2],
+		timestamp: match[3],
+		hostname:  match[4],
+		appName:   match[5],
+		procID:    match[6],
+		msgID:     match[7],
+		params:    map[string]string{},
+		msg:       match[9],
+	}
+
+	if sd := match[8]; sd != "-" {
+		sd = strings.TrimSuffix(strings.TrimPrefix(sd, "["), "]")
+		idx := strings.IndexByte(sd, ' ')
+		require.True(t, idx > 0, sd)
+		m.sdID = sd[:idx]
+		for _, param := range sdParamPattern.FindAllStringSubmatch(sd[idx:], -1) {
+			m.params[param[1]] = strings.NewReplacer(`\"`, `"`, `\\`, `\`, `\]`, `]`).Replace(param[2])
+		}
+	}
+
+	return m
+}
+
+func TestSyslogRFC5424(t *testing.T) {
+	assert := assert.New(t)
+
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer conn.Close()
+
+	read := func() string {
+		buf := make([]byte, 4096)
+		require.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+		n, _, err := conn.ReadFrom(buf)
+		require.NoError(t, err)
+		return string(buf[:n])
+	}
+
+	sender, err := NewSyslogLoggerWithOptions("grip", SyslogOptions{
+		Network:  "udp",
+		Address:  conn.LocalAddr().String(),
+		Format:   SyslogRFC5424,
+		Facility: FacilityLocal3,
+		Hostname: "host.example.net",
+		MsgID:    "REQ",
+	}, LevelInfo{level.Info, level.Info})
+	require.NoError(t, err)
+	defer sender.Close()
+
+	created := time.Date(2020, time.March, 4, 5, 6, 7, 891234000, time.UTC)
+	sender.Send(timestampedComposer{created: created, Composer: message.NewFieldsMessage(level.Warning, "request done", message.Fields{
+		"path":    `/a]b`,
+		"quote":   `say "hi"`,
+		"slash":   `C:\tmp`,
+		"status":  200,
+		"ok":      true,
+		"request": message.Fields{"id": "abc"},
+		"bad key": "x",
+		"none":    nil,
+	})})
+
+	m := parseRFC5424(t, read())
+	assert.Equal("156", m.pri, "local3 (19) * 8 + warning (4)")
+	assert.Equal("1", m.version)
+	assert.Equal("2020-03-04T05:06:07.891234Z", m.timestamp)
+	assert.Equal("host.example.net", m.hostname)
+	assert.Equal("grip", m.appName)
+	assert.Equal(strconv.Itoa(os.Getpid()), m.procID)
+	assert.Equal("REQ", m.msgID)
+	assert.Equal("fields@32473", m.sdID)
+	assert.Equal(map[string]string{
+		"path":       "/a]b",
+		"quote":      `say "hi"`,
+		"slash":      `C:\tmp`,
+		"status":     "200",
+		"ok":         "true",
+		"request.id": "abc",
+		"bad_key":    "x",
+	}, m.params)
+	assert.Equal("request done", m.msg)
+
+	// other messages have no structured data, and facilities can
+	// change.
+	require.NoError(t, SetSyslogFacility(sender, FacilityDaemon))
+	sender.Send(message.NewDefaultMessage(level.Error, "plain"))
+	m = parseRFC5424(t, read())
+	assert.Equal("27", m.pri)
+	assert.Equal("", m.sdID)
+	assert.Equal("plain", m.msg)
+}
+
+func TestSyslogRFC5424OverStreams(t *testing.T) {
+	assert := assert.New(t)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer ln.Close()
+
+	lines := make(chan string, 2)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		r := bufio.NewReader(conn)
+		for {
+			var size int
+			if _, err := fmt.Fscanf(r, "%d ", &size); err != nil {
+				return
+			}
+			buf := make([]byte, size)
+			if _, err := io.ReadFull(r, buf); err != nil {
+				return
+			}
+			lines <- string(buf)
+		}
+	}()
+
+	sender, err := NewSyslogLoggerWithOptions("grip", SyslogOptions{
+		Network: "tcp",
+		Address: ln.Addr().String(),
+		Format:  SyslogRFC5424,
+		AppName: "app",
+	}, LevelInfo{level.Info, level.Info})
+	require.NoError(t, err)
+	defer sender.Close()
+
+	sender.Send(message.NewFieldsMessage(level.Info, "multi\nline", message.Fields{"n": 1}))
+	sender.Send(message.NewDefaultMessage(level.Info, "second"))
+
+	for _, expected := range []string{"multi\nline", "second"} {
+		select {
+		case line := <-lines:
+			m := parseRFC5424(t, strings.Replace(line, "\n", " ", -1))
+			assert.Equal("app", m.appName)
+			assert.Equal(strings.Replace(expected, "\n", " ", -1), m.msg)
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for syslog message")
+		}
+	}
+}
+
+func TestSyslogOptionsValidate(t *testing.T) {
+	assert := assert.New(t)
+
+	for _, opts := range []SyslogOptions{
+		{Format: SyslogFormat(5)},
+		{Facility: SyslogFacility(40)},
+		{Network: "udp"},
+		{Address: "localhost:514"},
+	} {
+		assert.Error(opts.Validate())
+	}
+
+	opts := SyslogOptions{}
+	assert.NoError(opts.Validate())
+	assert.Equal("fields@32473", opts.StructuredDataID)
+	assert.NotEmpty(opts.Hostname)
+}
+
+func TestRFC5424StructuredDataEscaping(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal(`a\]b\"c\\d`, escapeSDParamValue(`a]b"c\d`))
+	assert.Equal("a_b_c_d", syslogSDName(`a=b c"d`))
+	assert.Equal(strings.Repeat("x", 32), syslogSDName(strings.Repeat("x", 40)))
+	assert.Equal("-", rfc5424StructuredData("id", message.NewDefaultMessage(level.Info, "hello")))
+	assert.Equal("-", rfc5424StructuredData("id", message.NewFieldsMessage(level.Info, "hello", message.Fields{})))
+	assert.Equal(`[id a="1" b="x\]"]`, rfc5424StructuredData("id", message.NewFields(level.Info, message.Fields{"b": "x]", "a": 1})))
+}
+
+func init() {
+	closeConformanceSenders["syslog"] = func(t *testing.T, _ string) (Sender, func()) {
+		conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+		require.NoError(t, err)
+
+		s, err := NewSyslogLogger("syslog", "udp", conn.LocalAddr().String(), closeConformanceLevel)
+		require.NoError(t, err)
+		return s, func() { _ = conn.Close() }
+	}
+	closeConformanceSenders["syslog-rfc5424"] = func(t *testing.T, _ string) (Sender, func()) {
+		conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+		require.NoError(t, err)
+
+		s, err := NewSyslogLoggerWithOptions("syslog-rfc5424", SyslogOptions{
+			Network: "udp",
+			Address: conn.LocalAddr().String(),
+			Format:  SyslogRFC5424,
+		}, closeConformanceLevel)
+		require.NoError(t, err)
+		return s, func() { _ = conn.Close() }
+	}
+}
@@ -4,8 +4,8 @@ package send
 
 import (
 	"fmt"
-	"log"
-	"os"
+	"sort"
+	"strings"
 
 	"github.com/coreos/go-systemd/journal"
 	"github.com/mongodb/grip/level"
@@ -14,13 +14,22 @@ import (
 
 type systemdJournal struct {
 	options map[string]string
+	send    func(string, journal.Priority, map[string]string) error
 	*Base
 }
 
 // NewSystemdLogger creates a Sender object that writes log messages
 // to the system's systemd journald logging facility. If there's an
-// error with the sending to the journald, messages fallback to
-// writing to standard output.
+// error with the sending to the journald, the sender reports the
+// error to its error handlers.
+//
+// Entries have the priority that corresponds to the priority of the
+// message, or to the default priority of the sender, for messages
+// whose priority is not one of the levels. The fields of Fields
+// messages are journal fields of the entry, in addition to MESSAGE,
+// with names that are the keys in upper case, with characters other
+// than letters, digits, and underscores replaced by underscores (e.g.
+// "request.id" becomes REQUEST_ID).
 func NewSystemdLogger(name string, l LevelInfo) (Sender, error) {
 	return setup(MakeSystemdLogger(), name, l)
 }
@@ -30,49 +39,103 @@ func NewSystemdLogger(name string, l LevelInfo) (Sender, error) {
 func MakeSystemdLogger() Sender {
 	s := &systemdJournal{
 		options: make(map[string]string),
+		send:    journal.Send,
 		Base:    NewBase(""),
 	}
 
-	fallback := log.New(os.Stdout, "", log.LstdFlags)
-	_ = s.SetErrorHandler(ErrorHandlerFromLogger(fallback))
-
-	s.reset = func() {
-		fallback.SetPrefix(fmt.Sprintf("[%s]", s.Name()))
-	}
-
 	return s
 }
 
-func (s *systemdJournal) Close() error { return nil }
-
 func (s *systemdJournal) Send(m message.Composer) {
-	if s.level.ShouldLog(m) {
-		err := journal.Send(m.String(), s.level.convertPrioritySystemd(m.Priority()), s.options)
+	if l := s.Level(); l.ShouldLog(m) && !s.reportClosed(m) {
+		err := s.send(messageText(m, " "), l.convertPrioritySystemd(m.Priority()), s.fields(m))
 		if err != nil {
-			s.errHandler(err, m)
+			s.ErrorHandler(err, m)
 		}
 	}
 }
 
 func (l LevelInfo) convertPrioritySystemd(p level.Priority) journal.Priority {
-	switch p {
-	case level.Emergency:
-		return journal.PriEmerg
-	case level.Alert:
-		return journal.PriAlert
-	case level.Critical:
-		return journal.PriCrit
-	case level.Error:
-		return journal.PriErr
-	case level.Warning:
-		return journal.PriWarning
-	case level.Notice:
-		return journal.PriNotice
-	case level.Info:
-		return journal.PriInfo
-	case level.Debug, level.Trace:
-		return journal.PriDebug
-	default:
-		return l.convertPrioritySystemd(l.Default)
+	return journal.Priority(l.syslogSeverity(p))
+}
+
+// fields returns the journal fields of the entry for the message: the
+// sender's options, and the fields of Fields messages, other than the
+// "msg" and "time" fields, which the entry already records.
+func (s *systemdJournal) fields(m message.Composer) map[string]string {
+	var fields map[string]interface{}
+	switch raw := m.Raw().(type) {
+	case message.Fields:
+		fields = raw
+	case map[string]interface{}:
+		fields = raw
+	}
+
+	if len(fields) == 0 {
+		return s.options
+	}
+
+	out := make(map[string]string, len(s.options)+len(fields))
+	for k, v := range s.options {
+		out[k] = v
+	}
+
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		if k != "msg" && k != "time" {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		addJournalField(out, k, fields[k])
+	}
+
+	return out
+}
+
+// addJournalField adds the value to the fields, with the journal field
+// name of the key. Nested fields add one field for each of their keys
+// (e.g. REQUEST_ID for the "id" key of a "request" field). Fields
+// with names that the journal reserves, or that are empty, are
+// skipped.
+func addJournalField(out map[string]string, key string, value interface{}) {
+	switch v := value.(type) {
+	case message.Fields:
+		for k, nested := range v {
+			addJournalField(out, key+"_"+k, nested)
+		}
+		return
+	case map[string]interface{}:
+		for k, nested := range v {
+			addJournalField(out, key+"_"+k, nested)
+		}
+		return
 	}
+
+	name := journalFieldName(key)
+	if name == "" || name == "MESSAGE" || name == "PRIORITY" {
+		return
+	}
+
+	out[name] = fmt.Sprintf("%v", value)
+}
+
+// journalFieldName converts the key to a valid journal field name:
+// upper case letters, digits, and underscores, which does not start
+// with an underscore, since those fields are reserved for the journal.
+func journalFieldName(key string) string {
+	name := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_':
+			return r
+		default:
+			return '_'
+		}
+	}, key)
+
+	return strings.TrimLeft(name, "_")
 }
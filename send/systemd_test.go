@@ -0,0 +1,108 @@
+// +build linux
+
+package send
+
+import (
+	"testing"
+
+	"github.com/coreos/go-systemd/journal"
+	"github.com/mongodb/grip/level"
+	"github.com/mongodb/grip/message"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSystemdPriorityMapping(t *testing.T) {
+	assert := assert.New(t)
+
+	l := LevelInfo{Default: level.Notice, Threshold: level.Trace}
+	for p, expected := range map[level.Priority]journal.Priority{
+		level.Emergency:     journal.PriEmerg,
+		level.Alert:         journal.PriAlert,
+		level.Critical:      journal.PriCrit,
+		level.Error:         journal.PriErr,
+		level.Warning:       journal.PriWarning,
+		level.Notice:        journal.PriNotice,
+		level.Info:          journal.PriInfo,
+		level.Debug:         journal.PriDebug,
+		level.Trace:         journal.PriDebug,
+		level.Invalid:       journal.PriNotice,
+		level.Priority(42):  journal.PriNotice,
+		level.Priority(-10): journal.PriNotice,
+	} {
+		assert.Equal(expected, l.convertPrioritySystemd(p), "priority %d", p)
+	}
+
+	// a default that is not a level must not recurse.
+	assert.Equal(journal.PriDebug, LevelInfo{}.convertPrioritySystemd(level.Priority(42)))
+}
+
+func TestJournalFieldName(t *testing.T) {
+	assert := assert.New(t)
+
+	for key, expected := range map[string]string{
+		"request.id":  "REQUEST_ID",
+		"Status Code": "STATUS_CODE",
+		"_hidden":     "HIDDEN",
+		"__cursor":    "CURSOR",
+		"user-42":     "USER_42",
+		"élan":        "LAN",
+		"___":         "",
+	} {
+		assert.Equal(expected, journalFieldName(key), key)
+	}
+}
+
+func TestSystemdSendsFieldsAsJournalFields(t *testing.T) {
+	assert := assert.New(t)
+
+	var (
+		text     string
+		priority journal.Priority
+		fields   map[string]string
+	)
+
+	sender := MakeSystemdLogger().(*systemdJournal)
+	sender.send = func(m string, p journal.Priority, f map[string]string) error {
+		text, priority, fields = m, p, f
+		return nil
+	}
+	sender.options["SYSLOG_IDENTIFIER"] = "grip"
+	require.NoError(t, sender.SetLevel(LevelInfo{Default: level.Info, Threshold: level.Info}))
+
+	sender.Send(message.NewFieldsMessage(level.Warning, "request done", message.Fields{
+		"request.id": "abc",
+		"status":     200,
+		"message":    "override",
+		"priority":   "override",
+		"user":       message.Fields{"name": "alice", "id": 7},
+	}))
+
+	assert.Equal(journal.PriWarning, priority)
+	assert.Contains(text, "request done")
+	assert.Equal(map[string]string{
+		"SYSLOG_IDENTIFIER": "grip",
+		"REQUEST_ID":        "abc",
+		"STATUS":            "200",
+		"USER_NAME":         "alice",
+		"USER_ID":           "7",
+	}, fields)
+	assert.Len(sender.options, 1, "the sender's options must not change")
+
+	sender.Send(message.NewDefaultMessage(level.Error, "plain"))
+	assert.Equal("plain", text)
+	assert.Equal(journal.PriErr, priority)
+	assert.Equal(map[string]string{"SYSLOG_IDENTIFIER": "grip"}, fields)
+}
+
+func init() {
+	// the journal may not be available, but the sender must still
+	// close cleanly.
+	closeConformanceSenders["systemd"] = func(t *testing.T, _ string) (Sender, func()) {
+		s, err := NewSystemdLogger("systemd", closeConformanceLevel)
+		if err != nil {
+			t.Skip("journal is not available:", err)
+		}
+		return s, noCleanup
+	}
+}
@@ -0,0 +1,171 @@
+package send
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mongodb/grip/level"
+	"github.com/mongodb/grip/message"
+)
+
+// teamsSummaryLength is the maximum length of the summary of a card,
+// which Teams shows in notifications.
+const teamsSummaryLength = 100
+
+type teamsSender struct {
+	webhookURL string
+	client     *http.Client
+	*Base
+}
+
+// NewTeamsLogger constructs a Sender that posts messages to a
+// Microsoft Teams channel, through an incoming webhook, as message
+// cards. Cards have a theme color that corresponds to the priority of
+// the message, and the text of the message; the fields of Fields
+// messages are facts of the card. The sender posts each message as it
+// is sent, and reports errors to its error handler.
+func NewTeamsLogger(name, webhookURL string, l LevelInfo) (Sender, error) {
+	return NewTeamsLoggerWithClient(name, webhookURL, &http.Client{Timeout: 10 * time.Second}, l)
+}
+
+// NewTeamsLoggerWithClient is equivalent to NewTeamsLogger, but posts
+// messages with the HTTP client.
+func NewTeamsLoggerWithClient(name, webhookURL string, client *http.Client, l LevelInfo) (Sender, error) {
+	if webhookURL == "" {
+		return nil, errors.New("no teams webhook url specified")
+	}
+
+	if _, err := url.ParseRequestURI(webhookURL); err != nil {
+		return nil, fmt.Errorf("invalid teams webhook url: %s", err.Error())
+	}
+
+	if client == nil {
+		return nil, errors.New("no http client specified")
+	}
+
+	s := &teamsSender{
+		webhookURL: webhookURL,
+		client:     client,
+		Base:       NewBase(name),
+	}
+
+	return setup(s, name, l)
+}
+
+func (s *teamsSender) Send(m message.Composer) { s.SendContext(context.Background(), m) }
+
+// SendContext posts the message, and cancels the request when the
+// context is canceled.
+func (s *teamsSender) SendContext(ctx context.Context, m message.Composer) {
+	if !s.Level().ShouldLog(m) || s.reportClosed(m) {
+		return
+	}
+
+	body, err := json.Marshal(s.card(m))
+	if err != nil {
+		s.ErrorHandler(err, m)
+		return
+	}
+
+	s.ErrorHandler(s.post(ctx, body), m)
+}
+
+func (s *teamsSender) post(ctx context.Context, body []byte) error {
+	req, err := http.NewRequest("POST", s.webhookURL, bytes.NewBuffer(body))
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	// teams describes the problem in the body of failed responses.
+	text, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 512))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		if detail := strings.TrimSpace(string(text)); detail != "" {
+			return fmt.Errorf("teams responded with status %s: %s", resp.Status, detail)
+		}
+
+		return fmt.Errorf("teams responded with status %s", resp.Status)
+	}
+
+	return nil
+}
+
+// teamsFact is a name and value pair of a message card.
+type teamsFact struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+func (s *teamsSender) card(m message.Composer) map[string]interface{} {
+	text := messageText(m, "\n")
+
+	summary := sanitizeHeader(text)
+	if len(summary) > teamsSummaryLength {
+		summary = summary[:teamsSummaryLength]
+	}
+
+	card := map[string]interface{}{
+		"@type":      "MessageCard",
+		"@context":   "https://schema.org/extensions",
+		"themeColor": teamsColor(m.Priority()),
+		"summary":    summary,
+		"title":      fmt.Sprintf("[%s] %s", m.Priority(), s.Name()),
+		"text":       text,
+	}
+
+	if fields, ok := m.Raw().(message.Fields); ok {
+		keys := make([]string, 0, len(fields))
+		for k := range fields {
+			if k == "msg" || k == "time" {
+				continue
+			}
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		facts := make([]teamsFact, 0, len(keys))
+		for _, k := range keys {
+			facts = append(facts, teamsFact{Name: k, Value: fmt.Sprintf("%v", fields[k])})
+		}
+
+		if len(facts) > 0 {
+			card["sections"] = []map[string]interface{}{{"facts": facts}}
+		}
+
+		if msg, ok := fields["msg"].(string); ok && msg != "" {
+			card["text"] = msg
+		}
+	}
+
+	return card
+}
+
+// teamsColor returns the theme color of a card for the priority.
+func teamsColor(p level.Priority) string {
+	switch {
+	case p >= level.Error:
+		return "D13438" // red
+	case p >= level.Notice:
+		return "FFB900" // amber
+	default:
+		return "107C10" // green
+	}
+}
@@ -0,0 +1,117 @@
+package send
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/mongodb/grip/level"
+	"github.com/mongodb/grip/message"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// teamsServer records the cards posted to it, and responds with the
+// configured status.
+type teamsServer struct {
+	*httptest.Server
+	mutex  sync.Mutex
+	cards  []map[string]interface{}
+	status int
+}
+
+func newTeamsServer() *teamsServer {
+	s := &teamsServer{status: http.StatusOK}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		card := map[string]interface{}{}
+		_ = json.NewDecoder(r.Body).Decode(&card)
+
+		s.mutex.Lock()
+		defer s.mutex.Unlock()
+		s.cards = append(s.cards, card)
+		w.WriteHeader(s.status)
+		if s.status != http.StatusOK {
+			_, _ = w.Write([]byte("Webhook message delivery failed"))
+		}
+	}))
+
+	return s
+}
+
+func (s *teamsServer) received() []map[string]interface{} {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return append([]map[string]interface{}{}, s.cards...)
+}
+
+func TestTeamsLoggerPostsMessageCards(t *testing.T) {
+	assert := assert.New(t)
+
+	srv := newTeamsServer()
+	defer srv.Close()
+
+	s, err := NewTeamsLoggerWithClient("alerts", srv.URL, srv.Client(), LevelInfo{level.Info, level.Info})
+	require.NoError(t, err)
+
+	s.Send(message.NewDefaultMessage(level.Debug, "below threshold"))
+	s.Send(message.NewDefaultMessage(level.Critical, "database down"))
+	s.Send(message.NewFieldsMessage(level.Warning, "disk almost full", message.Fields{"host": "db-1", "used": 95}))
+	s.Send(message.NewDefaultMessage(level.Info, "deployed"))
+
+	cards := srv.received()
+	require.Len(t, cards, 3)
+
+	assert.Equal("MessageCard", cards[0]["@type"])
+	assert.Equal("D13438", cards[0]["themeColor"])
+	assert.Equal("[critical] alerts", cards[0]["title"])
+	assert.Equal("database down", cards[0]["text"])
+	assert.Equal("database down", cards[0]["summary"])
+	assert.Nil(cards[0]["sections"])
+
+	assert.Equal("FFB900", cards[1]["themeColor"])
+	assert.Equal("disk almost full", cards[1]["text"])
+	assert.Equal([]interface{}{map[string]interface{}{"facts": []interface{}{
+		map[string]interface{}{"name": "host", "value": "db-1"},
+		map[string]interface{}{"name": "used", "value": "95"},
+	}}}, cards[1]["sections"])
+
+	assert.Equal("107C10", cards[2]["themeColor"])
+}
+
+func TestTeamsLoggerReportsHTTPErrors(t *testing.T) {
+	srv := newTeamsServer()
+	defer srv.Close()
+	srv.status = http.StatusBadRequest
+
+	s, err := NewTeamsLoggerWithClient("alerts", srv.URL, srv.Client(), LevelInfo{level.Info, level.Info})
+	require.NoError(t, err)
+
+	var failures []error
+	var failed []message.Composer
+	require.NoError(t, s.SetErrorHandler(func(err error, m message.Composer) {
+		if err != nil {
+			failures = append(failures, err)
+			failed = append(failed, m)
+		}
+	}))
+
+	m := message.NewDefaultMessage(level.Error, "failed")
+	s.Send(m)
+
+	require.Len(t, failures, 1)
+	assert.Equal(t, "teams responded with status 400 Bad Request: Webhook message delivery failed", failures[0].Error())
+	assert.True(t, m == failed[0])
+}
+
+func TestTeamsLoggerValidatesConfiguration(t *testing.T) {
+	_, err := NewTeamsLogger("alerts", "", LevelInfo{level.Info, level.Info})
+	assert.Error(t, err)
+
+	_, err = NewTeamsLogger("alerts", "not a url", LevelInfo{level.Info, level.Info})
+	assert.Error(t, err)
+
+	_, err = NewTeamsLoggerWithClient("alerts", "https://example.webhook.office.com/webhookb2/id", nil, LevelInfo{level.Info, level.Info})
+	assert.Error(t, err)
+}
@@ -0,0 +1,65 @@
+package send
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/mongodb/grip/message"
+)
+
+type testSender struct {
+	tb       testing.TB
+	finished bool
+	mutex    sync.RWMutex
+	*Base
+}
+
+// NewTestSender constructs a Sender that writes formatted messages to
+// the test or benchmark with tb.Log, so that the output is attributed
+// to the test, including subtests, and appears with "go test -v" or
+// when the test fails. The sender's name is the name of the test.
+//
+// Calling tb.Log after a test completes panics, so the sender drops
+// messages sent after the test and its cleanup functions finish (e.g.
+// from goroutines that outlive the test).
+func NewTestSender(tb testing.TB, l LevelInfo) (Sender, error) {
+	s := &testSender{tb: tb, Base: NewBase(tb.Name())}
+
+	if err := s.SetFormatter(MakeDefaultFormatter()); err != nil {
+		return nil, err
+	}
+
+	tb.Cleanup(func() {
+		s.mutex.Lock()
+		defer s.mutex.Unlock()
+		s.finished = true
+	})
+
+	return setup(s, tb.Name(), l)
+}
+
+func (s *testSender) Send(m message.Composer) {
+	if !s.Level().ShouldLog(m) || s.reportClosed(m) {
+		return
+	}
+
+	out, err := s.formatter(m)
+	if err != nil {
+		s.ErrorHandler(err, m)
+		return
+	}
+
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	if s.finished {
+		return
+	}
+
+	// the test may complete without cleanup, for example when a
+	// subtest's parent logs after the subtest completes.
+	defer func() { _ = recover() }()
+
+	s.tb.Helper()
+	s.tb.Log(out)
+}
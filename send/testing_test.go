@@ -0,0 +1,96 @@
+package send
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/mongodb/grip/level"
+	"github.com/mongodb/grip/message"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingTB records the arguments of calls to Log, and panics, as
+// the testing package does, when Log is called after the test
+// completes.
+type recordingTB struct {
+	testing.TB
+	mutex    sync.Mutex
+	logs     []string
+	cleanups []func()
+	done     bool
+}
+
+func (tb *recordingTB) Helper()          {}
+func (tb *recordingTB) Name() string     { return "TestRecording" }
+func (tb *recordingTB) Cleanup(f func()) { tb.cleanups = append(tb.cleanups, f) }
+
+func (tb *recordingTB) Log(args ...interface{}) {
+	tb.mutex.Lock()
+	defer tb.mutex.Unlock()
+
+	if tb.done {
+		panic("Log in goroutine after TestRecording has completed")
+	}
+	tb.logs = append(tb.logs, fmt.Sprint(args...))
+}
+
+func (tb *recordingTB) finish() {
+	for i := len(tb.cleanups) - 1; i >= 0; i-- {
+		tb.cleanups[i]()
+	}
+
+	tb.mutex.Lock()
+	tb.done = true
+	tb.mutex.Unlock()
+}
+
+func TestTestSenderLogsToTest(t *testing.T) {
+	assert := assert.New(t)
+	tb := &recordingTB{}
+
+	sender, err := NewTestSender(tb, LevelInfo{level.Info, level.Info})
+	require.NoError(t, err)
+	assert.Equal("TestRecording", sender.Name())
+
+	sender.Send(message.NewDefaultMessage(level.Error, "hello"))
+	sender.Send(message.NewDefaultMessage(level.Debug, "below threshold"))
+	assert.Equal([]string{"[p=error]: hello"}, tb.logs)
+
+	tb.finish()
+	assert.NotPanics(func() { sender.Send(message.NewDefaultMessage(level.Error, "late")) })
+	assert.Len(tb.logs, 1)
+}
+
+func TestTestSenderDropsLogsAfterTestCompletes(t *testing.T) {
+	assert := assert.New(t)
+	tb := &recordingTB{}
+
+	sender, err := NewTestSender(tb, LevelInfo{level.Info, level.Info})
+	require.NoError(t, err)
+
+	// simulate a test that completes without running cleanup
+	// functions.
+	tb.done = true
+	assert.NotPanics(func() { sender.Send(message.NewDefaultMessage(level.Error, "late")) })
+	assert.Len(tb.logs, 0)
+}
+
+func TestTestSenderWithSubtests(t *testing.T) {
+	t.Run("Subtest", func(t *testing.T) {
+		sender, err := NewTestSender(t, LevelInfo{level.Info, level.Info})
+		require.NoError(t, err)
+		assert.Equal(t, "TestTestSenderWithSubtests/Subtest", sender.Name())
+		sender.Send(message.NewDefaultMessage(level.Info, "from the subtest"))
+	})
+}
+
+func init() {
+	closeConformanceSenders["testing"] = func(t *testing.T, _ string) (Sender, func()) {
+		tb := &recordingTB{}
+		s, err := NewTestSender(tb, closeConformanceLevel)
+		require.NoError(t, err)
+		return s, tb.finish
+	}
+}
@@ -0,0 +1,230 @@
+package send
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/mongodb/grip/message"
+)
+
+// WebhookOptions configures the webhook sender.
+type WebhookOptions struct {
+	// Headers are headers (e.g. "Authorization") to add to every
+	// request. The sender sets the Content-Type header to
+	// "application/json".
+	Headers map[string]string
+
+	// Timeout limits the time the sender waits for each request.
+	// Defaults to 10 seconds.
+	Timeout time.Duration
+
+	// MaxRetries is the number of times that the sender retries
+	// requests that fail with a server error (5xx) response. By
+	// default, the sender does not retry.
+	MaxRetries int
+
+	// BaseDelay is the delay before the first retry, which doubles
+	// for every retry that follows, up to the MaxDelay. The delays
+	// default to 500 milliseconds and 30 seconds.
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+}
+
+// Validate checks the options, and sets defaults for unspecified
+// values.
+func (o *WebhookOptions) Validate() error {
+	errs := []string{}
+
+	if o.Timeout < 0 {
+		errs = append(errs, "timeout cannot be negative")
+	}
+
+	if o.MaxRetries < 0 {
+		errs = append(errs, "max retries cannot be negative")
+	}
+
+	if o.BaseDelay < 0 || o.MaxDelay < 0 {
+		errs = append(errs, "retry delays cannot be negative")
+	}
+
+	for name, value := range o.Headers {
+		if !validHeaderName(name) {
+			errs = append(errs, fmt.Sprintf("'%s' is not a valid header name", name))
+		} else if strings.ContainsAny(value, "\r\n") {
+			errs = append(errs, fmt.Sprintf("header '%s' contains a line break", name))
+		}
+	}
+
+	if o.Timeout == 0 {
+		o.Timeout = 10 * time.Second
+	}
+
+	if o.BaseDelay == 0 {
+		o.BaseDelay = 500 * time.Millisecond
+	}
+
+	if o.MaxDelay == 0 {
+		o.MaxDelay = 30 * time.Second
+	}
+
+	if o.MaxDelay < o.BaseDelay {
+		errs = append(errs, "max delay cannot be less than the base delay")
+	}
+
+	if len(errs) > 0 {
+		return errors.New(strings.Join(errs, "; "))
+	}
+
+	return nil
+}
+
+type webhookSender struct {
+	url    string
+	opts   WebhookOptions
+	client *http.Client
+	ctx    context.Context
+	*Base
+}
+
+// NewWebhookSender constructs a Sender that posts every message to
+// the URL as a JSON document, with the headers, for services that
+// accept log events over HTTP. The document is the raw form of the
+// message; messages whose raw form is not a JSON object post a
+// {"message": <text>} document instead.
+//
+// The sender posts each message as it is sent, and reports requests
+// that fail, or that receive responses other than 2xx, to its error
+// handler. Close cancels requests that are in progress.
+func NewWebhookSender(url string, headers map[string]string, l LevelInfo) (Sender, error) {
+	return NewWebhookSenderWithOptions("webhook", url, WebhookOptions{Headers: headers}, l)
+}
+
+// NewWebhookSenderWithOptions is the same as NewWebhookSender, but
+// names the sender, and configures the sender with the options, which
+// can also retry requests that fail with server errors.
+func NewWebhookSenderWithOptions(name, webhookURL string, opts WebhookOptions, l LevelInfo) (Sender, error) {
+	if webhookURL == "" {
+		return nil, errors.New("no webhook url specified")
+	}
+
+	if _, err := url.ParseRequestURI(webhookURL); err != nil {
+		return nil, fmt.Errorf("invalid webhook url: %s", err.Error())
+	}
+
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s := &webhookSender{
+		url:    webhookURL,
+		opts:   opts,
+		client: &http.Client{Timeout: opts.Timeout},
+		ctx:    ctx,
+		Base:   NewBase(name),
+	}
+
+	s.closer = func() error {
+		cancel()
+		return nil
+	}
+
+	return setup(s, name, l)
+}
+
+func (s *webhookSender) Send(m message.Composer) { s.SendContext(context.Background(), m) }
+
+// SendContext posts the message, and cancels the request, and any
+// retries, when the context is canceled or the sender closes.
+func (s *webhookSender) SendContext(ctx context.Context, m message.Composer) {
+	if !s.Level().ShouldLog(m) || s.reportClosed(m) {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// stop the request when the sender closes.
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		select {
+		case <-s.ctx.Done():
+			cancel()
+		case <-stop:
+		}
+	}()
+
+	s.ErrorHandler(s.post(ctx, webhookDocument(m)), m)
+}
+
+// webhookDocument returns the raw form of the message as JSON, if it
+// is a JSON object, and otherwise a document with the text of the
+// message.
+func webhookDocument(m message.Composer) []byte {
+	if doc, err := json.Marshal(m.Raw()); err == nil && bytes.HasPrefix(doc, []byte("{")) {
+		return doc
+	}
+
+	doc, _ := json.Marshal(map[string]string{"message": m.String()})
+	return doc
+}
+
+// post posts the document, retrying server errors up to the
+// MaxRetries.
+func (s *webhookSender) post(ctx context.Context, doc []byte) error {
+	var err error
+	for attempt := 0; attempt <= s.opts.MaxRetries; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-time.After(backoffDelay(s.opts.BaseDelay, s.opts.MaxDelay, attempt)):
+			case <-ctx.Done():
+				return fmt.Errorf("webhook request canceled: %s", err.Error())
+			}
+		}
+
+		var retry bool
+		if retry, err = s.postOnce(ctx, doc); err == nil || !retry {
+			return err
+		}
+	}
+
+	return fmt.Errorf("webhook request failed after %d attempts: %s", s.opts.MaxRetries+1, err.Error())
+}
+
+// postOnce posts the document, and reports whether the request can be
+// retried if it failed.
+func (s *webhookSender) postOnce(ctx context.Context, doc []byte) (bool, error) {
+	req, err := http.NewRequest("POST", s.url, bytes.NewBuffer(doc))
+	if err != nil {
+		return false, err
+	}
+	req = req.WithContext(ctx)
+
+	for name, value := range s.opts.Headers {
+		req.Header.Set(name, value)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(ioutil.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode >= 500, fmt.Errorf("webhook responded with status %s", resp.Status)
+	}
+
+	return false, nil
+}
@@ -0,0 +1,206 @@
+package send
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mongodb/grip/level"
+	"github.com/mongodb/grip/message"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// webhookServer records the documents and headers posted to it, and
+// responds with the configured statuses, in order, followed by 200s.
+type webhookServer struct {
+	*httptest.Server
+	mutex    sync.Mutex
+	docs     []string
+	headers  []http.Header
+	statuses []int
+	delay    time.Duration
+}
+
+func newWebhookServer() *webhookServer {
+	s := &webhookServer{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+
+		s.mutex.Lock()
+		s.docs = append(s.docs, string(body))
+		s.headers = append(s.headers, r.Header)
+		status := http.StatusOK
+		if len(s.statuses) > 0 {
+			status, s.statuses = s.statuses[0], s.statuses[1:]
+		}
+		delay := s.delay
+		s.mutex.Unlock()
+
+		select {
+		case <-time.After(delay):
+		case <-r.Context().Done():
+			return
+		}
+
+		w.WriteHeader(status)
+	}))
+
+	return s
+}
+
+func (s *webhookServer) received() []string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return append([]string{}, s.docs...)
+}
+
+func TestWebhookSenderPostsDocuments(t *testing.T) {
+	assert := assert.New(t)
+
+	srv := newWebhookServer()
+	defer srv.Close()
+
+	s, err := NewWebhookSender(srv.URL, map[string]string{"Authorization": "Bearer token", "X-Source": "grip"}, LevelInfo{level.Info, level.Info})
+	require.NoError(t, err)
+	assert.Equal("webhook", s.Name())
+	errs := &errorCollector{}
+	require.NoError(t, s.SetErrorHandler(errs.handler))
+
+	s.Send(message.NewDefaultMessage(level.Debug, "below threshold"))
+	s.Send(message.NewFieldsMessage(level.Info, "deployed", message.Fields{"version": "1.2.3"}))
+	s.Send(message.NewDefaultMessage(level.Warning, "disk almost full"))
+	unencodable := message.NewFieldsMessage(level.Info, "callback", message.Fields{"fn": func() {}})
+	s.Send(unencodable)
+
+	docs := srv.received()
+	require.Len(t, docs, 3)
+	assert.Empty(errs.errs)
+
+	fields := map[string]interface{}{}
+	require.NoError(t, json.Unmarshal([]byte(docs[0]), &fields))
+	assert.Equal("deployed", fields["msg"])
+	assert.Equal("1.2.3", fields["version"])
+
+	text := map[string]interface{}{}
+	require.NoError(t, json.Unmarshal([]byte(docs[1]), &text))
+	assert.Equal("disk almost full", text["message"])
+
+	envelope := map[string]interface{}{}
+	require.NoError(t, json.Unmarshal([]byte(docs[2]), &envelope))
+	assert.Equal(map[string]interface{}{"message": unencodable.String()}, envelope)
+
+	for _, h := range srv.headers {
+		assert.Equal("Bearer token", h.Get("Authorization"))
+		assert.Equal("grip", h.Get("X-Source"))
+		assert.Equal("application/json", h.Get("Content-Type"))
+	}
+}
+
+func TestWebhookSenderRetriesServerErrors(t *testing.T) {
+	srv := newWebhookServer()
+	defer srv.Close()
+
+	s, err := NewWebhookSenderWithOptions("hook", srv.URL, WebhookOptions{
+		MaxRetries: 2,
+		BaseDelay:  time.Millisecond,
+		MaxDelay:   2 * time.Millisecond,
+	}, LevelInfo{level.Info, level.Info})
+	require.NoError(t, err)
+	errs := &errorCollector{}
+	require.NoError(t, s.SetErrorHandler(errs.handler))
+
+	srv.statuses = []int{http.StatusServiceUnavailable, http.StatusBadGateway}
+	s.Send(message.NewDefaultMessage(level.Info, "recovers"))
+	assert.Len(t, srv.received(), 3)
+	assert.Empty(t, errs.errs)
+
+	srv.statuses = []int{http.StatusServiceUnavailable, http.StatusServiceUnavailable, http.StatusServiceUnavailable}
+	s.Send(message.NewDefaultMessage(level.Info, "fails"))
+	assert.Len(t, srv.received(), 6)
+	require.Len(t, errs.errs, 1)
+	assert.Equal(t, "webhook request failed after 3 attempts: webhook responded with status 503 Service Unavailable", errs.errs[0].Error())
+
+	// client errors are not retried.
+	srv.statuses = []int{http.StatusBadRequest}
+	s.Send(message.NewDefaultMessage(level.Info, "rejected"))
+	assert.Len(t, srv.received(), 7)
+	require.Len(t, errs.errs, 2)
+	assert.Equal(t, "webhook responded with status 400 Bad Request", errs.errs[1].Error())
+}
+
+func TestWebhookSenderTimesOut(t *testing.T) {
+	srv := newWebhookServer()
+	defer srv.Close()
+	srv.delay = time.Second
+
+	s, err := NewWebhookSenderWithOptions("hook", srv.URL, WebhookOptions{Timeout: 20 * time.Millisecond}, LevelInfo{level.Info, level.Info})
+	require.NoError(t, err)
+	errs := &errorCollector{}
+	require.NoError(t, s.SetErrorHandler(errs.handler))
+
+	start := time.Now()
+	s.Send(message.NewDefaultMessage(level.Info, "slow"))
+	assert.True(t, time.Since(start) < 500*time.Millisecond)
+	assert.Len(t, errs.errs, 1)
+}
+
+func TestWebhookSenderCloseCancelsRequests(t *testing.T) {
+	srv := newWebhookServer()
+	defer srv.Close()
+	srv.delay = 5 * time.Second
+
+	s, err := NewWebhookSender(srv.URL, nil, LevelInfo{level.Info, level.Info})
+	require.NoError(t, err)
+	errs := &errorCollector{}
+	require.NoError(t, s.SetErrorHandler(errs.handler))
+
+	sent := make(chan struct{})
+	go func() {
+		defer close(sent)
+		s.Send(message.NewDefaultMessage(level.Info, "in flight"))
+	}()
+
+	deadline := time.Now().Add(time.Second)
+	for len(srv.received()) == 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	require.NoError(t, s.Close())
+
+	select {
+	case <-sent:
+	case <-time.After(time.Second):
+		t.Fatal("close did not cancel the request")
+	}
+
+	errs.mutex.Lock()
+	defer errs.mutex.Unlock()
+	assert.Len(t, errs.errs, 1)
+}
+
+func TestWebhookOptionsValidate(t *testing.T) {
+	opts := WebhookOptions{}
+	require.NoError(t, opts.Validate())
+	assert.Equal(t, 10*time.Second, opts.Timeout)
+	assert.Equal(t, 500*time.Millisecond, opts.BaseDelay)
+	assert.Equal(t, 30*time.Second, opts.MaxDelay)
+
+	opts = WebhookOptions{
+		Timeout:    -time.Second,
+		MaxRetries: -1,
+		Headers:    map[string]string{"X-Note": "line\r\nbreak"},
+	}
+	err := opts.Validate()
+	require.Error(t, err)
+	assert.Equal(t, "timeout cannot be negative; max retries cannot be negative; header 'X-Note' contains a line break", err.Error())
+
+	_, err = NewWebhookSender("", nil, LevelInfo{level.Info, level.Info})
+	assert.Error(t, err)
+
+	_, err = NewWebhookSender("not a url", nil, LevelInfo{level.Info, level.Info})
+	assert.Error(t, err)
+}
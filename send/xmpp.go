@@ -2,7 +2,6 @@ package send
 
 import (
 	"fmt"
-	"log"
 	"os"
 	"strings"
 
@@ -113,28 +112,22 @@ func constructXMPPLogger(name, target string, info XMPPConnectionInfo) (Sender,
 		return s.info.client.Close()
 	}
 
-	fallback := log.New(os.Stdout, "", log.LstdFlags)
-	if err := s.SetErrorHandler(ErrorHandlerFromLogger(fallback)); err != nil {
-		return nil, err
-	}
-
 	if err := s.SetFormatter(MakeXMPPFormatter(s.Name())); err != nil {
 		return nil, err
 	}
 
 	s.reset = func() {
 		_ = s.SetFormatter(MakeXMPPFormatter(s.Name()))
-		fallback.SetPrefix(fmt.Sprintf("[%s] ", s.Name()))
 	}
 
 	return s, nil
 }
 
 func (s *xmppLogger) Send(m message.Composer) {
-	if s.level.ShouldLog(m) {
+	if s.Level().ShouldLog(m) && !s.reportClosed(m) {
 		text, err := s.formatter(m)
 		if err != nil {
-			s.errHandler(err, m)
+			s.ErrorHandler(err, m)
 			return
 		}
 
@@ -145,7 +138,7 @@ func (s *xmppLogger) Send(m message.Composer) {
 		}
 
 		if _, err := s.info.client.Send(c); err != nil {
-			s.errHandler(err, m)
+			s.ErrorHandler(err, m)
 		}
 	}
 }
@@ -2,6 +2,7 @@ package send
 
 import (
 	"errors"
+	"sync"
 
 	xmpp "github.com/mattn/go-xmpp"
 )
@@ -12,6 +13,8 @@ type xmppClientMock struct {
 
 	numCloses int
 	numSent   int
+	lastText  string
+	mutex     sync.Mutex
 }
 
 func (c *xmppClientMock) Create(_ XMPPConnectionInfo) error {
@@ -22,17 +25,24 @@ func (c *xmppClientMock) Create(_ XMPPConnectionInfo) error {
 	return nil
 }
 
-func (c *xmppClientMock) Send(_ xmpp.Chat) (int, error) {
+func (c *xmppClientMock) Send(chat xmpp.Chat) (int, error) {
 	if c.failSend {
 		return 0, errors.New("sending failed")
 	}
 
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
 	c.numSent++
+	c.lastText = chat.Text
 
 	return 0, nil
 }
 
 func (c *xmppClientMock) Close() error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
 	c.numCloses++
 	return nil
 }
@@ -1,6 +1,7 @@
 package slogger
 
 import (
+	"context"
 	"fmt"
 	"os"
 
@@ -47,6 +48,7 @@ func WrapAppender(a Appender) send.Sender {
 
 // TODO: we may want to add a mutex here
 func (a *appenderSender) Close() error                             { return nil }
+func (a *appenderSender) Flush(_ context.Context) error            { return nil }
 func (a *appenderSender) Name() string                             { return a.name }
 func (a *appenderSender) SetName(n string)                         { a.name = n }
 func (a *appenderSender) Level() send.LevelInfo                    { return a.level }
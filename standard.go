@@ -5,10 +5,16 @@ import (
 	"path/filepath"
 	"strings"
 
+	"github.com/mongodb/grip/logging"
 	"github.com/mongodb/grip/send"
 )
 
-var std = NewJournaler("grip")
+// std is a concrete *logging.Grip, rather than a Journaler, so that
+// the package level functions can use methods (e.g. RunJob) that are
+// not part of the Journaler interface, and so that escape analysis
+// can see through the package level logging functions, which avoids
+// allocations for messages below threshold.
+var std = logging.NewGrip("grip")
 
 func init() {
 	if !strings.Contains(os.Args[0], "go-build") {
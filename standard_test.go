@@ -40,6 +40,11 @@ func TestLoggingMethodSuite(t *testing.T) {
 func (s *LoggingMethodSuite) SetupSuite() {
 	s.logger = logging.NewGrip("test")
 
+	// messages below the threshold never reach the sender, so
+	// lower the threshold to compare the output of all methods.
+	s.logger.SetThreshold(level.Trace)
+	SetThreshold(level.Trace)
+
 	s.stdSender = send.MakeInternalLogger()
 	s.NoError(SetSender(s.stdSender))
 	s.Exactly(GetSender(), s.stdSender)
@@ -361,6 +366,7 @@ func (s *LoggingMethodSuite) TestProgramaticLevelMethods() {
 		lg         func(level.Priority, interface{})
 		lgln       func(level.Priority, ...interface{})
 		lgf        func(level.Priority, string, ...interface{})
+		lgstring   func(level.Priority, string)
 		lgmany     func(level.Priority, ...message.Composer)
 	)
 
@@ -373,6 +379,7 @@ func (s *LoggingMethodSuite) TestProgramaticLevelMethods() {
 		"lgln":     []lgln{Logln, s.logger.Logln},
 		"lgf":      []lgf{Logf, s.logger.Logf},
 		"lgmany":   []lgmany{LogMany, s.logger.LogMany},
+		"lgstring": []lgstring{LogString, s.logger.(*logging.Grip).LogString},
 	}
 
 	const l = level.Emergency
@@ -401,6 +408,9 @@ func (s *LoggingMethodSuite) TestProgramaticLevelMethods() {
 		case []lgf:
 			log[0](l, "%T: (%s) %s", log, kind, testMessage)
 			log[1](l, "%T: (%s) %s", log, kind, testMessage)
+		case []lgstring:
+			log[0](l, testMessage)
+			log[1](l, testMessage)
 		case []lgmany:
 			log[0](l, message.ConvertToComposer(l, testMessage))
 			log[1](l, message.ConvertToComposer(l, testMessage))
@@ -419,3 +429,19 @@ func (s *LoggingMethodSuite) TestProgramaticLevelMethods() {
 			fmt.Sprintf("%s: \n\tlogger: %+v \n\tstandard: %+v", kind, lgrMsg, stdMsg))
 	}
 }
+
+// BenchmarkPackageBelowThresholdDebugf measures the package level
+// functions, which must not allocate for messages below the
+// threshold.
+func BenchmarkPackageBelowThresholdDebugf(b *testing.B) {
+	threshold := GetSender().Level().Threshold
+	defer SetThreshold(threshold)
+	SetThreshold(level.Info)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		Debugf("x=%d", 42)
+	}
+}